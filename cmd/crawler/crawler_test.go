@@ -340,6 +340,27 @@ func TestGetHighlightsByRecordLink_PrefersVoeReleaseDate(t *testing.T) {
 	}
 }
 
+func TestGetHighlightsByRecordLink_GenreAndReviewer(t *testing.T) {
+	html := `<html><body>
+<h1>Genre Band - Genre Album</h1>
+<p>VÖ: 29.05.2024<br>Genre: Indie-Rock</p>
+<p class="bewertung"><strong>8/10</strong></p>
+<p>Autor: Jane Doe</p>
+</body></html>`
+
+	srv := startMockServer(t, html)
+	defer srv.Close()
+
+	rec := getHighlightsByRecordLink(srv.URL)
+
+	if rec.Genre != "Indie-Rock" {
+		t.Errorf("Genre = %q, want %q", rec.Genre, "Indie-Rock")
+	}
+	if rec.Reviewer != "Jane Doe" {
+		t.Errorf("Reviewer = %q, want %q", rec.Reviewer, "Jane Doe")
+	}
+}
+
 func TestRecordHasFutureReleaseDate(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -383,3 +404,27 @@ func TestNewDocumentFromPlattentestsResponse_UTF8(t *testing.T) {
 		t.Errorf("expected text to contain %q, got %q", "Björk", text)
 	}
 }
+
+func TestRecordReviewID(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want string
+	}{
+		{name: "absolute review link", link: baseurl + "rezi.php?show=18199", want: "18199"},
+		{name: "relative review link", link: "rezi.php?show=42", want: "42"},
+		{name: "surrounding whitespace", link: " rezi.php?show=7 ", want: "7"},
+		{name: "non-numeric id", link: "rezi.php?show=abc", want: ""},
+		{name: "missing show parameter", link: baseurl + "index.php", want: ""},
+		{name: "empty link", link: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := Record{Link: tt.link}
+			if got := record.ReviewID(); got != tt.want {
+				t.Errorf("ReviewID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
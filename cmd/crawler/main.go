@@ -29,6 +29,11 @@ const maxSearchResults = 25
 var releaseDatePattern = regexp.MustCompile(`\b([0-9]{2}\.[0-9]{2}\.[0-9]{4})\b`)
 var releaseDateVoePattern = regexp.MustCompile(`VÖ:\s*([0-9]{2}\.[0-9]{2}\.[0-9]{4})`)
 
+// Genre and reviewer are printed as labelled lines ("Genre: Indie", "Autor: Name") on some
+// review pages; pages without them leave the fields empty.
+var genrePattern = regexp.MustCompile(`(?i)(?:^|[^\p{L}])(?:genre|stil):\s*([^\n\r|]+)`)
+var reviewerPattern = regexp.MustCompile(`(?i)(?:^|[^\p{L}])(?:autor(?:in)?|rezensent(?:in)?):\s*([^\n\r|]+)`)
+
 func newDocumentFromPlattentestsResponse(res *http.Response) (*goquery.Document, error) {
 	decodedReader, err := charset.NewReader(res.Body, res.Header.Get("Content-Type"))
 	if err != nil {
@@ -47,6 +52,18 @@ func extractReleaseDate(text string) string {
 	return ""
 }
 
+// extractLabelled returns the value of the first paragraph matching pattern.
+func extractLabelled(doc *goquery.Document, pattern *regexp.Regexp) string {
+	var value string
+	doc.Find("p").EachWithBreak(func(_ int, p *goquery.Selection) bool {
+		if match := pattern.FindStringSubmatch(p.Text()); len(match) == 2 {
+			value = strings.TrimSpace(match[1])
+		}
+		return value == ""
+	})
+	return value
+}
+
 // Record holds all information for a record
 type Record struct {
 	Image             string
@@ -59,6 +76,9 @@ type Record struct {
 	Tracks            []Track
 	Headline          string
 	Description       string
+	Review            []Paragraph
+	Genre             string
+	Reviewer          string
 	IsRecordOfTheWeek bool
 }
 
@@ -79,6 +99,20 @@ func (r Record) HasFutureReleaseDate() bool {
 	return releaseDay.After(today)
 }
 
+// ReviewID returns the Plattentests review identifier (the "show" parameter of
+// the rezi.php link), or an empty string when Link does not point at a review.
+func (r Record) ReviewID() string {
+	u, err := url.Parse(strings.TrimSpace(r.Link))
+	if err != nil {
+		return ""
+	}
+	id := u.Query().Get("show")
+	if _, err := strconv.Atoi(id); err != nil {
+		return ""
+	}
+	return id
+}
+
 // Track holds one highlight track for a record.
 type Track struct {
 	Band        string
@@ -177,9 +211,16 @@ func GetRecord(c *gin.Context) {
 		c.String(http.StatusBadRequest, "invalid record identifier")
 		return
 	}
-	recordUrl := "rezi.php?show="
-	recordLink := baseurl + recordUrl + strconv.Itoa(id)
-	c.IndentedJSON(http.StatusOK, getHighlightsByRecordLink(recordLink))
+	c.IndentedJSON(http.StatusOK, getHighlightsByRecordLink(recordLinkForID(id)))
+}
+
+// GetRecordByIDSafe fetches one record by review id and propagates HTTP/parse errors to caller.
+func GetRecordByIDSafe(id int) (Record, error) {
+	return getHighlightsByRecordLinkSafe(recordLinkForID(id))
+}
+
+func recordLinkForID(id int) string {
+	return baseurl + "rezi.php?show=" + strconv.Itoa(id)
 }
 
 // getting highlights of a particular record by recordLink
@@ -227,13 +268,21 @@ func getHighlightsByRecordLinkSafe(recordLink string) (Record, error) {
 
 	score, _ := strconv.Atoi(strings.Split(doc.Find("p.bewertung strong").First().Text(), "/")[0])
 
+	// Links inside the review are relative to the review page.
+	reviewBase, err := url.Parse(recordLink)
+	if err != nil {
+		reviewBase, _ = url.Parse(baseurl)
+	}
+
 	// Extract headline and description - the content follows h2 headings
 	// The layout changed from .rezitext class to regular paragraphs after h2
 	var headline string
 	var paragraphs []string
+	var review []Paragraph
 	doc.Find("h2").Each(func(i int, h2 *goquery.Selection) {
 		if i == 0 {
 			headline = strings.TrimSpace(h2.Text())
+			review = parseReviewBody(h2, reviewBase)
 			// Get siblings after h2 that are paragraphs, until next h2/h3/h4
 			h2.NextUntil("h2, h3, h4, hr").Each(func(j int, elem *goquery.Selection) {
 				if goquery.NodeName(elem) == "p" {
@@ -261,6 +310,9 @@ func getHighlightsByRecordLinkSafe(recordLink string) (Record, error) {
 		Tracks:      tracks,
 		Headline:    headline,
 		Description: description,
+		Review:      review,
+		Genre:       extractLabelled(doc, genrePattern),
+		Reviewer:    extractLabelled(doc, reviewerPattern),
 	}
 	log.Printf("%s - %s\n", bandname, recordname)
 	highlightNames := make(map[string]bool)
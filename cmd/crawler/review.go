@@ -0,0 +1,194 @@
+package crawler
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Paragraph kinds of a review body.
+const (
+	ParagraphText  = "text"
+	ParagraphQuote = "quote"
+)
+
+// Paragraph is one block of a review body in document order. Its content is kept as
+// structured spans instead of HTML so templates can render it without trusting markup
+// from Plattentests.
+type Paragraph struct {
+	Kind  string
+	Spans []Span
+}
+
+// Span is a run of review text with uniform formatting. Link is only set for absolute
+// http(s) URLs; a "\n" Text represents a line break inside the paragraph.
+type Span struct {
+	Text     string
+	Emphasis bool
+	Strong   bool
+	Link     string
+}
+
+// Text returns the plain text of the paragraph.
+func (p Paragraph) Text() string {
+	var b strings.Builder
+	for _, span := range p.Spans {
+		b.WriteString(span.Text)
+	}
+	return b.String()
+}
+
+// ReviewText returns the plain text of all review paragraphs separated by blank lines.
+func (r Record) ReviewText() string {
+	texts := make([]string, 0, len(r.Review))
+	for _, paragraph := range r.Review {
+		texts = append(texts, paragraph.Text())
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+var whitespacePattern = regexp.MustCompile(`[ \t\r\n\f]+`)
+
+// parseReviewBody collects the paragraphs and quotes that follow the review headline,
+// stopping at the next heading or rule like the description extraction does. Footer and
+// navigation blocks are skipped.
+func parseReviewBody(h2 *goquery.Selection, base *url.URL) []Paragraph {
+	var paragraphs []Paragraph
+	h2.NextUntil("h2, h3, h4, hr").Each(func(_ int, elem *goquery.Selection) {
+		kind := ParagraphText
+		switch goquery.NodeName(elem) {
+		case "p":
+		case "blockquote":
+			kind = ParagraphQuote
+		default:
+			return
+		}
+		text := elem.Text()
+		if strings.Contains(text, "Startseite") || strings.Contains(text, "Referenzen") {
+			return
+		}
+
+		var spans []Span
+		for _, node := range elem.Nodes {
+			spans = collectSpans(node, Span{}, base, spans)
+		}
+		spans = trimSpans(spans)
+		if len(spans) == 0 {
+			return
+		}
+		paragraphs = append(paragraphs, Paragraph{Kind: kind, Spans: spans})
+	})
+	return paragraphs
+}
+
+// collectSpans walks the children of n and appends their text with the formatting
+// inherited from style. Unknown elements are flattened to their text.
+func collectSpans(n *html.Node, style Span, base *url.URL, spans []Span) []Span {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.TextNode:
+			span := style
+			span.Text = whitespacePattern.ReplaceAllString(c.Data, " ")
+			spans = appendSpan(spans, span)
+		case html.ElementNode:
+			next := style
+			switch c.DataAtom {
+			case atom.Script, atom.Style:
+				continue
+			case atom.Br:
+				span := style
+				span.Text = "\n"
+				spans = appendSpan(spans, span)
+				continue
+			case atom.Em, atom.I:
+				next.Emphasis = true
+			case atom.Strong, atom.B:
+				next.Strong = true
+			case atom.A:
+				next.Link = safeReviewLink(attr(c, "href"), base)
+			}
+			spans = collectSpans(c, next, base, spans)
+		}
+	}
+	return spans
+}
+
+// appendSpan merges span into the previous one when both share the same formatting.
+func appendSpan(spans []Span, span Span) []Span {
+	if span.Text == "" {
+		return spans
+	}
+	if n := len(spans); n > 0 {
+		last := &spans[n-1]
+		if last.Emphasis == span.Emphasis && last.Strong == span.Strong && last.Link == span.Link {
+			last.Text += span.Text
+			return spans
+		}
+	}
+	return append(spans, span)
+}
+
+// trimSpans removes whitespace at the paragraph edges and around line breaks and drops
+// spans that end up empty.
+func trimSpans(spans []Span) []Span {
+	for i := range spans {
+		text := spans[i].Text
+		for strings.Contains(text, " \n") || strings.Contains(text, "\n ") {
+			text = strings.ReplaceAll(strings.ReplaceAll(text, " \n", "\n"), "\n ", "\n")
+		}
+		spans[i].Text = text
+	}
+	for i := 1; i < len(spans); i++ {
+		if strings.HasSuffix(spans[i-1].Text, "\n") {
+			spans[i].Text = strings.TrimLeft(spans[i].Text, " ")
+		}
+		if strings.HasPrefix(spans[i].Text, "\n") {
+			spans[i-1].Text = strings.TrimRight(spans[i-1].Text, " ")
+		}
+	}
+	if len(spans) > 0 {
+		spans[0].Text = strings.TrimLeft(spans[0].Text, " \n")
+		spans[len(spans)-1].Text = strings.TrimRight(spans[len(spans)-1].Text, " \n")
+	}
+
+	trimmed := spans[:0]
+	for _, span := range spans {
+		if span.Text != "" {
+			trimmed = append(trimmed, span)
+		}
+	}
+	return trimmed
+}
+
+// safeReviewLink resolves href against base and only keeps http(s) targets, so that
+// javascript: and similar schemes never reach the rendered page.
+func safeReviewLink(href string, base *url.URL) string {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return ""
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetHighlightsByRecordLink_ReviewParagraphs(t *testing.T) {
+	html := `<html><body>
+<h1>Review Band - Review Album</h1>
+<p>VÖ: 01.02.2024</p>
+<p class="bewertung"><strong>8/10</strong></p>
+<h2>Großes Kino</h2>
+<p>Kurz und <em>knapp</em>.</p>
+<blockquote>"Wir wollten es laut", sagt die Band.</blockquote>
+<p>Mehr dazu bei <a href="rezi.php?show=7">der <b>Vorgängerplatte</b></a>
+   und <a href="javascript:alert(1)">hier</a>.<br>
+   Neue Zeile.</p>
+<p>   </p>
+<p>Startseite | Referenzen</p>
+<h3>Tracklist</h3>
+<p>Nicht mehr Teil der Rezension.</p>
+</body></html>`
+
+	srv := startMockServer(t, html)
+	defer srv.Close()
+
+	rec := getHighlightsByRecordLink(srv.URL + "/rezi.php?show=1")
+
+	want := []Paragraph{
+		{Kind: ParagraphText, Spans: []Span{
+			{Text: "Kurz und "},
+			{Text: "knapp", Emphasis: true},
+			{Text: "."},
+		}},
+		{Kind: ParagraphQuote, Spans: []Span{
+			{Text: `"Wir wollten es laut", sagt die Band.`},
+		}},
+		{Kind: ParagraphText, Spans: []Span{
+			{Text: "Mehr dazu bei "},
+			{Text: "der ", Link: srv.URL + "/rezi.php?show=7"},
+			{Text: "Vorgängerplatte", Strong: true, Link: srv.URL + "/rezi.php?show=7"},
+			{Text: " und hier.\nNeue Zeile."},
+		}},
+	}
+
+	if !reflect.DeepEqual(rec.Review, want) {
+		t.Fatalf("Review =\n%#v\nwant\n%#v", rec.Review, want)
+	}
+	if rec.Description != "" {
+		t.Errorf("Description keeps the long-paragraph filter, got %q", rec.Description)
+	}
+
+	wantText := "Kurz und knapp.\n\n\"Wir wollten es laut\", sagt die Band.\n\nMehr dazu bei der Vorgängerplatte und hier.\nNeue Zeile."
+	if got := rec.ReviewText(); got != wantText {
+		t.Errorf("ReviewText() = %q, want %q", got, wantText)
+	}
+}
+
+func TestSafeReviewLink(t *testing.T) {
+	base := mustURL(t, baseurl+"rezi.php?show=1")
+	tests := []struct {
+		name string
+		href string
+		want string
+	}{
+		{name: "relative review link", href: "rezi.php?show=2", want: baseurl + "rezi.php?show=2"},
+		{name: "absolute https link", href: "https://example.com/a", want: "https://example.com/a"},
+		{name: "javascript scheme", href: "javascript:alert(1)", want: ""},
+		{name: "mailto scheme", href: "mailto:info@example.com", want: ""},
+		{name: "empty href", href: " ", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := safeReviewLink(tt.href, base); got != tt.want {
+				t.Errorf("safeReviewLink(%q) = %q, want %q", tt.href, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package creator
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+)
+
+// fullAlbumMinScore reads FULL_ALBUM_MIN_SCORE. Records scored at or above it contribute their
+// complete tracklist; 0, the default, adds only highlights.
+func fullAlbumMinScore() (int, error) {
+	value := strings.TrimSpace(os.Getenv("FULL_ALBUM_MIN_SCORE"))
+	if value == "" {
+		return 0, nil
+	}
+	score, err := strconv.Atoi(value)
+	if err != nil || score < 0 || score > 10 {
+		return 0, fmt.Errorf("FULL_ALBUM_MIN_SCORE must be a score between 0 and 10, got %q", value)
+	}
+	return score, nil
+}
+
+// isFullAlbum reports whether the complete tracklist of record goes into the playlist.
+func isFullAlbum(record crawler.Record, minScore int) bool {
+	return minScore > 0 && record.Score >= minScore
+}
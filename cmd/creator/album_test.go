@@ -0,0 +1,70 @@
+package creator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/zmb3/spotify/v2"
+)
+
+func TestCreatePlaylistAddsFullAlbums(t *testing.T) {
+	provider := newFakeProvider(
+		fakeTrack("a1", "Band A", "Intro", "Great Album", "album", "2024"),
+		fakeTrack("a2", "Band A", "Hit", "Great Album", "album", "2024"),
+		fakeTrack("a3", "Band A", "Outro", "Great Album", "album", "2024"),
+		fakeTrack("b1", "Band B", "Single", "Fine Album", "album", "2024"),
+		fakeTrack("b2", "Band B", "Filler", "Fine Album", "album", "2024"),
+	)
+	provider.playlists["test-playlist"] = nil
+	useFakeCreatorDependencies(t, provider, []crawler.Record{
+		{Band: "Band B", Recordname: "Fine Album", Score: 6, ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band B", Trackname: "Single", IsHighlight: true},
+			{Band: "Band B", Trackname: "Filler"},
+		}},
+		{Band: "Band A", Recordname: "Great Album", Score: 9, ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band A", Trackname: "Intro"},
+			{Band: "Band A", Trackname: "Hit", IsHighlight: true},
+			{Band: "Band A", Trackname: "Outro"},
+			{Band: "Band A", Trackname: "Bonus"},
+		}},
+	}, "")
+	t.Setenv("FULL_ALBUM_MIN_SCORE", "8")
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	if got, want := provider.playlists["test-playlist"], []spotify.ID{"a1", "a2", "a3", "b1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("playlist = %v, want %v", got, want)
+	}
+	if result.TotalTracks != 5 || result.FoundTracks != 4 || !reflect.DeepEqual(result.NotFound, []string{"Band A - Bonus"}) {
+		t.Errorf("result = %d/%d, not found %v", result.FoundTracks, result.TotalTracks, result.NotFound)
+	}
+	if !reflect.DeepEqual(result.FullAlbums, []string{"Band A - Great Album"}) {
+		t.Errorf("FullAlbums = %v", result.FullAlbums)
+	}
+
+	// Rules passed to the run override the environment.
+	preview, err := Run(Options{DryRun: true, Rules: &Rules{FullAlbumMinScore: 10}})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if preview.TotalTracks != 2 || len(preview.FullAlbums) != 0 {
+		t.Errorf("preview = %d tracks, full albums %v", preview.TotalTracks, preview.FullAlbums)
+	}
+}
+
+func TestFullAlbumMinScoreRejectsInvalidValues(t *testing.T) {
+	for _, value := range []string{"eight", "-1", "11"} {
+		t.Run(value, func(t *testing.T) {
+			useFakeCreatorDependencies(t, newFakeProvider(), nil, "")
+			t.Setenv("FULL_ALBUM_MIN_SCORE", value)
+
+			if _, err := CreatePlaylist(""); err == nil || !strings.Contains(err.Error(), "FULL_ALBUM_MIN_SCORE") {
+				t.Errorf("CreatePlaylist() error = %v, want FULL_ALBUM_MIN_SCORE error", err)
+			}
+		})
+	}
+}
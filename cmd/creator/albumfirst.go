@@ -0,0 +1,453 @@
+package creator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/jetzlstorfer/plattentests-go/internal/matchcache"
+	"github.com/jetzlstorfer/plattentests-go/internal/similarity"
+	"github.com/zmb3/spotify/v2"
+)
+
+// Matching strategies, see matchStrategy.
+const (
+	// MatchStrategyAlbum resolves the reviewed record to a Spotify album and picks the
+	// highlights from its tracklist, searching track by track only for what it cannot place.
+	MatchStrategyAlbum = "album"
+	// MatchStrategyTrack searches every highlight on its own.
+	MatchStrategyTrack = "track"
+)
+
+// MatchSourceAlbum marks a track taken from the tracklist of the resolved album.
+const MatchSourceAlbum = "album"
+
+// TrackCountWeight is how much a matching track count adds to the title similarity of an
+// album candidate; it separates the regular edition from deluxe versions and singles.
+const TrackCountWeight = 0.25
+
+// matchStrategy reads MATCH_STRATEGY; album-first is the default.
+func matchStrategy() (string, error) {
+	switch value := strings.ToLower(strings.TrimSpace(os.Getenv("MATCH_STRATEGY"))); value {
+	case "", MatchStrategyAlbum:
+		return MatchStrategyAlbum, nil
+	case MatchStrategyTrack:
+		return MatchStrategyTrack, nil
+	default:
+		return "", fmt.Errorf("MATCH_STRATEGY must be %q or %q, got %q", MatchStrategyAlbum, MatchStrategyTrack, value)
+	}
+}
+
+// AlbumResolution explains how a record was resolved to a Spotify album.
+type AlbumResolution struct {
+	// Queries are the album searches that were sent; none when the album came from the
+	// match cache.
+	Queries    []string
+	Candidates []AlbumCandidate
+	// ID and Name are the resolved album; ID is empty when none was found.
+	ID     spotify.ID
+	Name   string
+	Reason string
+	// Error is set when a search or the tracklist failed.
+	Error string
+	// LearnedAlias is the Spotify artist name the album taught as a new alias of the band.
+	LearnedAlias string
+}
+
+// AlbumCandidate is an album search result with the components of its score and the verdict.
+type AlbumCandidate struct {
+	ID          spotify.ID
+	Artists     string
+	Name        string
+	AlbumType   string
+	ReleaseDate string
+	TotalTracks int
+	// TitleSimilarity and ArtistSimilarity range from 0 to 1.
+	TitleSimilarity  float64
+	ArtistSimilarity float64
+	// ArtistAlias is the alias of the band the artist was compared with, see CandidateScore.
+	ArtistAlias string
+	// TrackCountScore is 1 when the album has as many tracks as the reviewed tracklist.
+	TrackCountScore float64
+	Score           float64
+	Accepted        bool
+	Reason          string
+}
+
+// albumLookup is a resolved album together with its tracklist.
+type albumLookup struct {
+	resolution AlbumResolution
+	tracks     []spotify.SimpleTrack
+	// names are the titles of tracks after albumTrackTitle, compared with every highlight
+	names []string
+	// bands are the names the artists of tracks are compared with
+	bands []bandName
+	// config compares the highlights with tracks
+	config matchConfig
+	// markets are the configured markets; details then hold the tracks as Spotify reports
+	// them there, by album track ID, to check playability and relinking.
+	markets []string
+	details map[spotify.ID]spotify.FullTrack
+}
+
+// found reports whether the record was resolved to an album.
+func (a *albumLookup) found() bool {
+	return a != nil && a.resolution.ID != ""
+}
+
+// resolveAlbum finds the Spotify album of record. A known album ID, taken from the match cache,
+// is reused; otherwise the band, record name and release year are searched, and once more
+// without the year when that finds nothing. Candidates need a similar artist and title; the best title
+// similarity wins, weighted by how close the track count is to the reviewed tracklist. When no
+// candidate is accepted, the aliases and the transliteration of the band are searched as well.
+func resolveAlbum(provider MusicProvider, config matchConfig, record crawler.Record, known spotify.ID) (*albumLookup, error) {
+	lookup := &albumLookup{bands: bandNames(config.aliases, record.Band), config: config}
+	res := &lookup.resolution
+
+	if known != "" {
+		res.ID = known
+		res.Name = record.Recordname
+		res.Reason = "album from the match cache"
+	} else {
+		if record.Recordname == "" {
+			res.Reason = "the record has no name to search for"
+			return lookup, nil
+		}
+		years := []string{record.ReleaseYear}
+		if record.ReleaseYear != "" {
+			years = append(years, "")
+		}
+	search:
+		for _, band := range searchBands(lookup.bands) {
+			for _, year := range years {
+				query := sanitizeTrackname(band + " " + record.Recordname)
+				if year != "" {
+					query += " year:" + year
+				}
+				log.Printf(" searching album: %s", query)
+				res.Queries = append(res.Queries, query)
+				albums, err := provider.SearchAlbums(context.Background(), query)
+				if err != nil {
+					res.Error = err.Error()
+					res.Reason = "the album search failed"
+					return lookup, fmt.Errorf("search album %q: %w", query, err)
+				}
+				if len(albums) == 0 {
+					continue
+				}
+				res.Candidates = scoreAlbumCandidates(albums, record, config, lookup.bands)
+				for _, candidate := range res.Candidates {
+					if !candidate.Accepted {
+						continue
+					}
+					res.ID, res.Name = candidate.ID, candidate.Name
+					res.Reason = candidate.Reason
+					for _, album := range albums {
+						if album.ID == candidate.ID {
+							res.LearnedAlias = config.learnAlias(lookup.bands, album.Artists)
+						}
+					}
+					break search
+				}
+				break
+			}
+		}
+		if res.ID == "" {
+			res.Reason = "no album with a similar artist and title"
+			if len(res.Candidates) == 0 {
+				res.Reason = "no album search results"
+			}
+			return lookup, nil
+		}
+	}
+
+	tracks, err := provider.AlbumTracks(context.Background(), res.ID)
+	if err != nil {
+		albumID := res.ID
+		res.ID, res.Name = "", ""
+		res.Error = err.Error()
+		res.Reason = "the tracklist of the album could not be read"
+		return lookup, fmt.Errorf("album tracks %s: %w", albumID, err)
+	}
+	if markets := provider.Markets(); len(markets) > 0 {
+		ids := make([]spotify.ID, len(tracks))
+		for i, track := range tracks {
+			ids[i] = track.ID
+		}
+		details, err := trackDetails(provider, ids)
+		if err != nil {
+			albumID := res.ID
+			res.ID, res.Name = "", ""
+			res.Error = err.Error()
+			res.Reason = "the tracks of the album could not be checked for " + strings.Join(markets, ", ")
+			return lookup, fmt.Errorf("album tracks %s: %w", albumID, err)
+		}
+		lookup.markets, lookup.details = markets, details
+	}
+	lookup.tracks = tracks
+	lookup.names = make([]string, len(tracks))
+	for i, track := range tracks {
+		lookup.names[i] = albumTrackTitle(track.Name)
+	}
+	log.Printf(" resolved %s - %s to album %s (%d tracks)", record.Band, record.Recordname, res.ID, len(tracks))
+	return lookup, nil
+}
+
+// scoreAlbumCandidates scores album search results, best first.
+func scoreAlbumCandidates(albums []spotify.SimpleAlbum, record crawler.Record, config matchConfig, names []bandName) []AlbumCandidate {
+	normalizedRecordName := normalizeForComparison(record.Recordname)
+	candidates := make([]AlbumCandidate, 0, len(albums))
+	for _, album := range albums {
+		candidate := AlbumCandidate{
+			ID:          album.ID,
+			Artists:     joinArtists(album.Artists),
+			Name:        album.Name,
+			AlbumType:   album.AlbumType,
+			ReleaseDate: album.ReleaseDate,
+			TotalTracks: int(album.TotalTracks),
+		}
+		// "Record (Deluxe Edition)" is still the reviewed record
+		candidate.TitleSimilarity = math.Max(
+			config.similarity(normalizeForComparison(album.Name), normalizedRecordName),
+			config.similarity(normalizeForComparison(strings.Split(album.Name, " (")[0]), normalizedRecordName),
+		)
+		candidate.ArtistSimilarity, candidate.ArtistAlias = config.artistSimilarity(album.Artists, names)
+		if reviewed := len(record.Tracks); reviewed > 0 && candidate.TotalTracks > 0 {
+			candidate.TrackCountScore = 1 - math.Abs(float64(candidate.TotalTracks-reviewed))/float64(maxInt(candidate.TotalTracks, reviewed))
+		}
+		candidate.Score = candidate.TitleSimilarity + TrackCountWeight*candidate.TrackCountScore
+
+		switch {
+		case candidate.ArtistSimilarity < config.minArtist():
+			candidate.Reason = fmt.Sprintf("artist similarity %.2f is below %.2f", candidate.ArtistSimilarity, config.minArtist())
+		case candidate.TitleSimilarity < config.minTitle():
+			candidate.Reason = fmt.Sprintf("title similarity %.2f is below %.2f", candidate.TitleSimilarity, config.minTitle())
+		default:
+			candidate.Accepted = true
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	// only the best acceptable album is used
+	var best *AlbumCandidate
+	for i := range candidates {
+		if !candidates[i].Accepted {
+			continue
+		}
+		if best == nil {
+			best = &candidates[i]
+			best.Reason = fmt.Sprintf("best score %.2f, artist and title match", best.Score)
+			if best.ArtistAlias != "" {
+				best.Reason += ", the artist as " + best.ArtistAlias
+			}
+			continue
+		}
+		candidates[i].Accepted = false
+		candidates[i].Reason = fmt.Sprintf("score %.2f is below the best score %.2f", candidates[i].Score, best.Score)
+	}
+	return candidates
+}
+
+// albumTrackTitle normalizes a track name for comparison with a tracklist. The version suffix
+// is stripped first, while the brackets and dashes that mark it are still there; sanitizing
+// then drops featured artists.
+func albumTrackTitle(name string) string {
+	return normalizeForComparison(sanitizeTrackname(similarity.StripVersion(name)))
+}
+
+// matchAlbumTrack picks track from the tracklist of a resolved album. The most similar title of
+// the band is accepted when it is similar enough and playable in the configured markets; songs
+// of other artists, as on compilations and split releases, are never taken. ok is false when
+// the track is not on the album or cannot be played, which also sets Unavailable.
+func matchAlbumTrack(album *albumLookup, track string, record crawler.Record) (MatchExplanation, bool) {
+	explanation := MatchExplanation{
+		Band:        record.Band,
+		Trackname:   track,
+		Recordname:  record.Recordname,
+		ReleaseYear: record.ReleaseYear,
+		Source:      MatchSourceAlbum,
+		Album:       &album.resolution,
+	}
+
+	normalizedTrackName := albumTrackTitle(track)
+	candidates := make([]CandidateScore, 0, len(album.tracks))
+	for i, item := range album.tracks {
+		candidate := CandidateScore{
+			ID:              item.ID,
+			Artists:         joinArtists(item.Artists),
+			Name:            item.Name,
+			Album:           album.resolution.Name,
+			AlbumType:       "album",
+			Rank:            i + 1,
+			TrackSimilarity: album.config.similarity(album.names[i], normalizedTrackName),
+		}
+		candidate.ArtistSimilarity, candidate.ArtistAlias = album.config.artistSimilarity(item.Artists, album.bands)
+		candidates = append(candidates, candidate)
+	}
+	// tracks of the band go first; earlier tracks win ties, like the first of two identical
+	// titles on a deluxe edition
+	minArtist := album.config.minArtist()
+	sort.SliceStable(candidates, func(i, j int) bool {
+		iBand, jBand := candidates[i].ArtistSimilarity >= minArtist, candidates[j].ArtistSimilarity >= minArtist
+		if iBand != jBand {
+			return iBand
+		}
+		return candidates[i].TrackSimilarity > candidates[j].TrackSimilarity
+	})
+	otherArtist := func(candidate *CandidateScore) bool {
+		if candidate.ArtistSimilarity >= minArtist {
+			return false
+		}
+		candidate.Reason = fmt.Sprintf("artist similarity %.2f is below %.2f", candidate.ArtistSimilarity, minArtist)
+		return true
+	}
+
+	attempt := SearchAttempt{Query: "tracklist of " + album.resolution.Name, Candidates: candidates}
+	if len(candidates) == 0 || candidates[0].ArtistSimilarity < minArtist || candidates[0].TrackSimilarity < album.config.minTitle() {
+		for i := range candidates {
+			if !otherArtist(&candidates[i]) {
+				candidates[i].Reason = fmt.Sprintf("title similarity %.2f is below %.2f", candidates[i].TrackSimilarity, album.config.minTitle())
+			}
+		}
+		attempt.Outcome = "not on the album"
+		explanation.Attempts = append(explanation.Attempts, attempt)
+		return explanation, false
+	}
+
+	best := &candidates[0]
+	best.Selected = true
+	for i := range candidates[1:] {
+		if other := &candidates[i+1]; !otherArtist(other) {
+			other.Reason = fmt.Sprintf("title similarity %.2f is below the best %.2f", other.TrackSimilarity, best.TrackSimilarity)
+		}
+	}
+	if album.markets != nil {
+		detail, ok := album.details[best.ID]
+		if !ok || !playable(detail) {
+			best.Unplayable = true
+			best.Reason = unplayableReason(album.markets)
+			attempt.Outcome = "on the album, but " + best.Reason
+			explanation.Attempts = append(explanation.Attempts, attempt)
+			explanation.Unavailable = true
+			return explanation, false
+		}
+		if detail.ID != best.ID {
+			best.ID, best.LinkedFrom = detail.ID, best.ID
+			explanation.Notes = append(explanation.Notes, relinkNote(best.LinkedFrom, album.markets))
+		}
+	}
+	best.Accepted = true
+	best.Reason = fmt.Sprintf("most similar title on the album (%.2f)", best.TrackSimilarity)
+	log.Printf(" using album track: %s - %s (%s)", best.Artists, best.Name, album.resolution.Name)
+	attempt.Outcome = "accepted " + best.Artists + " - " + best.Name
+	explanation.Attempts = append(explanation.Attempts, attempt)
+	explanation.TrackID = best.ID
+	explanation.Confidence = math.Min(best.ArtistSimilarity, best.TrackSimilarity)
+	explanation.Reason = fmt.Sprintf("track %d of the album %s", best.Rank, album.resolution.Name)
+	return explanation, true
+}
+
+// explainHighlight matches a highlight with the album-first strategy: from the tracklist of
+// album when the record was resolved to one, otherwise, or when the track is not on it, with
+// the per-track search. album is nil for the per-track strategy.
+func explainHighlight(provider MusicProvider, config matchConfig, track string, record crawler.Record, album *albumLookup) (MatchExplanation, error) {
+	unavailableOnAlbum := false
+	if album.found() {
+		explanation, ok := matchAlbumTrack(album, track, record)
+		if ok {
+			return explanation, nil
+		}
+		unavailableOnAlbum = explanation.Unavailable
+	}
+
+	explanation, err := explainMatch(provider, config, track, record)
+	if album != nil {
+		explanation.Album = &album.resolution
+		switch {
+		case unavailableOnAlbum:
+			explanation.Notes = append(explanation.Notes, "on the album "+album.resolution.Name+", but "+unplayableReason(album.markets)+", searched track by track")
+			if !explanation.Matched() {
+				explanation.Unavailable = true
+			}
+		case album.found():
+			explanation.Notes = append(explanation.Notes, "not on the album "+album.resolution.Name+", searched track by track")
+		default:
+			explanation.Notes = append(explanation.Notes, "no album found ("+album.resolution.Reason+"), searched track by track")
+		}
+	}
+	return explanation, err
+}
+
+// resolveAlbums resolves every record in needed to its album, concurrently, reusing the album
+// IDs in cache. Records that fail keep a lookup without an album and are searched track by
+// track.
+func resolveAlbums(provider MusicProvider, config matchConfig, cache *matchcache.Cache, records []crawler.Record, needed []bool) []*albumLookup {
+	albums := make([]*albumLookup, len(records))
+	sem := make(chan struct{}, MaxConcurrentSpotifySearches)
+	var wg sync.WaitGroup
+	for i := range records {
+		if !needed[i] {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			known := cachedAlbumID(cache, records[i], matchSettings(config, provider.Markets(), records[i].Band))
+			album, err := resolveAlbum(provider, config, records[i], known)
+			if err != nil {
+				log.Printf("could not resolve album of %s - %s: %v", records[i].Band, records[i].Recordname, err)
+			}
+			albums[i] = album
+		}(i)
+	}
+	wg.Wait()
+	return albums
+}
+
+// cachedAlbumID returns the album record was resolved to before with settings, or "" when
+// cache is nil or does not know it.
+func cachedAlbumID(cache *matchcache.Cache, record crawler.Record, settings string) spotify.ID {
+	if cache == nil {
+		return ""
+	}
+	entry, status := cachedEntry(cache, albumCacheKey(record.Band, record.Recordname), settings)
+	if status != matchcache.Hit {
+		return ""
+	}
+	return spotify.ID(entry.AlbumID)
+}
+
+// cacheAlbums puts the albums that had to be searched for into cache, with the settings they
+// were found with.
+func cacheAlbums(cache *matchcache.Cache, config matchConfig, markets []string, records []crawler.Record, albums []*albumLookup) {
+	for i, album := range albums {
+		if album.found() && len(album.resolution.Queries) > 0 {
+			cache.Put(albumCacheKey(records[i].Band, records[i].Recordname), matchcache.Entry{
+				AlbumID:  string(album.resolution.ID),
+				Settings: matchSettings(config, markets, records[i].Band),
+			})
+		}
+	}
+}
+
+// albumIDs returns the albums the records were resolved to by albumCacheKey.
+func albumIDs(records []crawler.Record, albums []*albumLookup) map[string]spotify.ID {
+	ids := make(map[string]spotify.ID)
+	for i, album := range albums {
+		if album.found() {
+			ids[albumCacheKey(records[i].Band, records[i].Recordname)] = album.resolution.ID
+		}
+	}
+	return ids
+}
@@ -0,0 +1,272 @@
+package creator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/jetzlstorfer/plattentests-go/internal/matchcache"
+	"github.com/zmb3/spotify/v2"
+)
+
+// deluxeCatalog has the reviewed record as regular and deluxe edition, the deluxe edition
+// first, and a single of the opener.
+var deluxeCatalog = []spotify.FullTrack{
+	fakeTrack("deluxe-1", "Band", "Opener", "Record (Deluxe Edition)", "album", "2024"),
+	fakeTrack("deluxe-2", "Band", "Closer", "Record (Deluxe Edition)", "album", "2024"),
+	fakeTrack("deluxe-3", "Band", "Demo", "Record (Deluxe Edition)", "album", "2024"),
+	fakeTrack("single", "Band", "Opener", "Opener", "single", "2024"),
+	fakeTrack("regular-1", "Band", "Opener", "Record", "album", "2024"),
+	fakeTrack("regular-2", "Band", "Closer", "Record", "album", "2024"),
+}
+
+var deluxeRecord = crawler.Record{Band: "Band", Recordname: "Record", ReleaseYear: "2024", Tracks: []crawler.Track{
+	{Band: "Band", Trackname: "Opener", IsHighlight: true},
+	{Band: "Band", Trackname: "Closer"},
+}}
+
+func TestResolveAlbum(t *testing.T) {
+	tests := []struct {
+		name     string
+		catalog  []spotify.FullTrack
+		albumErr error
+		record   crawler.Record
+		// known is the album ID from the match cache
+		known      spotify.ID
+		wantID     spotify.ID
+		wantQuery  []string
+		wantReason string
+		wantErr    bool
+	}{
+		{
+			name:       "track count separates regular and deluxe edition",
+			catalog:    deluxeCatalog,
+			record:     deluxeRecord,
+			wantID:     fakeAlbumID("Band", "Record", "album"),
+			wantQuery:  []string{"Band Record year:2024"},
+			wantReason: "best score 1.25, artist and title match",
+		},
+		{
+			name:       "deluxe edition when there is no other",
+			catalog:    deluxeCatalog[:3],
+			record:     deluxeRecord,
+			wantID:     fakeAlbumID("Band", "Record (Deluxe Edition)", "album"),
+			wantQuery:  []string{"Band Record year:2024"},
+			wantReason: "best score 1.17, artist and title match",
+		},
+		{
+			name:       "searched again without the year",
+			catalog:    []spotify.FullTrack{fakeTrack("reissue", "Band", "Opener", "Record", "album", "2015")},
+			record:     deluxeRecord,
+			wantID:     fakeAlbumID("Band", "Record", "album"),
+			wantQuery:  []string{"Band Record year:2024", "Band Record"},
+			wantReason: "best score 1.12, artist and title match",
+		},
+		{
+			name:       "other artist",
+			catalog:    []spotify.FullTrack{fakeTrack("t1", "Band", "Opener", "Record", "album", "2024")},
+			record:     crawler.Record{Band: "Band Of Horses", Recordname: "Record"},
+			wantQuery:  []string{"Band Of Horses Record"},
+			wantReason: "no album with a similar artist and title",
+		},
+		{
+			name:       "album from the match cache",
+			catalog:    deluxeCatalog,
+			record:     crawler.Record{Band: "Band", Recordname: "Record"},
+			known:      fakeAlbumID("Band", "Opener", "single"),
+			wantID:     fakeAlbumID("Band", "Opener", "single"),
+			wantReason: "album from the match cache",
+		},
+		{
+			name:       "no record name",
+			record:     crawler.Record{Band: "Band"},
+			wantReason: "the record has no name to search for",
+		},
+		{
+			name:       "search error",
+			albumErr:   errors.New("rate limited"),
+			record:     deluxeRecord,
+			wantQuery:  []string{"Band Record year:2024"},
+			wantReason: "the album search failed",
+			wantErr:    true,
+		},
+		{
+			name:       "tracklist error",
+			record:     crawler.Record{Band: "Band", Recordname: "Record"},
+			known:      "missing",
+			wantReason: "the tracklist of the album could not be read",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newFakeProvider(tt.catalog...)
+			provider.albumErr = tt.albumErr
+
+			album, err := resolveAlbum(provider, matchConfig{}, tt.record, tt.known)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveAlbum() error = %v, want error %v", err, tt.wantErr)
+			}
+			if album.resolution.ID != tt.wantID || album.resolution.Reason != tt.wantReason {
+				t.Errorf("resolveAlbum() = %q (%s), want %q (%s)", album.resolution.ID, album.resolution.Reason, tt.wantID, tt.wantReason)
+			}
+			if !reflect.DeepEqual(provider.albumQueries, tt.wantQuery) {
+				t.Errorf("album queries = %q, want %q", provider.albumQueries, tt.wantQuery)
+			}
+			if album.found() && len(album.tracks) == 0 {
+				t.Error("a resolved album must have its tracklist")
+			}
+		})
+	}
+}
+
+func TestMatchAlbumTrack(t *testing.T) {
+	provider := newFakeProvider(
+		fakeTrack("t1", "Band", "Opener", "Record", "album", "2024"),
+		fakeTrack("t2", "Band", "Closer (feat. Guest)", "Record", "album", "2024"),
+		fakeTrack("t3", "Band", "Opener", "Record", "album", "2024"),
+		fakeTrack("t4", "Band", "Middle - Remastered", "Record", "album", "2024"),
+		fakeTrack("t5", "Band", "Middle - Reprise", "Record", "album", "2024"),
+		fakeTrack("t6", "Split Partner", "Shared Title", "Record", "album", "2024"),
+		fakeTrack("t7", "Band", "Shared Title", "Record", "album", "2024"),
+		fakeTrack("t8", "Split Partner", "Partner Song", "Record", "album", "2024"),
+	)
+	album, err := resolveAlbum(provider, matchConfig{}, crawler.Record{Band: "Band", Recordname: "Record"}, "")
+	if err != nil || !album.found() {
+		t.Fatalf("resolveAlbum() = %+v, %v", album.resolution, err)
+	}
+
+	tests := []struct {
+		name           string
+		track          string
+		wantID         spotify.ID
+		wantOK         bool
+		wantConfidence float64
+	}{
+		{name: "first of identical titles", track: "Opener", wantID: "t1", wantOK: true, wantConfidence: 1},
+		{name: "featured artist is ignored", track: "Closer", wantID: "t2", wantOK: true, wantConfidence: 1},
+		{name: "remaster suffix is ignored", track: "Middle", wantID: "t4", wantOK: true, wantConfidence: 1},
+		{name: "remaster suffix of the highlight is ignored", track: "Middle (Remastered 2011)", wantID: "t4", wantOK: true, wantConfidence: 1},
+		{name: "reprise is another song", track: "Middle - Reprise", wantID: "t5", wantOK: true, wantConfidence: 1},
+		{name: "same title by another artist is skipped", track: "Shared Title", wantID: "t7", wantOK: true, wantConfidence: 1},
+		{name: "song of another artist", track: "Partner Song", wantOK: false},
+		{name: "not on the album", track: "Bonus Track", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			explanation, ok := matchAlbumTrack(album, tt.track, crawler.Record{Band: "Band", Recordname: "Record"})
+			if ok != tt.wantOK || explanation.TrackID != tt.wantID || explanation.Confidence != tt.wantConfidence {
+				t.Errorf("matchAlbumTrack(%q) = %q (%.2f), %v, want %q (%.2f), %v", tt.track, explanation.TrackID, explanation.Confidence, ok, tt.wantID, tt.wantConfidence, tt.wantOK)
+			}
+			if explanation.Source != MatchSourceAlbum || explanation.Album != &album.resolution {
+				t.Errorf("explanation = %+v", explanation)
+			}
+		})
+	}
+}
+
+func TestRunMatchesAlbumFirst(t *testing.T) {
+	tests := []struct {
+		name             string
+		strategy         string
+		wantPlanned      []spotify.ID
+		wantAlbumQueries int
+		wantAlbumID      spotify.ID
+	}{
+		{
+			name:             "album first",
+			wantPlanned:      []spotify.ID{"regular-1"},
+			wantAlbumQueries: 1,
+			wantAlbumID:      fakeAlbumID("Band", "Record", "album"),
+		},
+		{
+			name:        "track by track",
+			strategy:    MatchStrategyTrack,
+			wantPlanned: []spotify.ID{"deluxe-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newFakeProvider(deluxeCatalog...)
+			provider.playlists["test-playlist"] = nil
+			useFakeCreatorDependencies(t, provider, []crawler.Record{deluxeRecord}, "")
+			t.Setenv("MATCH_STRATEGY", tt.strategy)
+
+			result, err := CreatePlaylist("")
+			if err != nil {
+				t.Fatalf("CreatePlaylist() error: %v", err)
+			}
+			var planned []spotify.ID
+			for _, track := range result.Planned {
+				planned = append(planned, track.ID)
+			}
+			if !reflect.DeepEqual(planned, tt.wantPlanned) {
+				t.Errorf("Planned = %v, want %v", planned, tt.wantPlanned)
+			}
+			if len(provider.albumQueries) != tt.wantAlbumQueries {
+				t.Errorf("album queries = %q, want %d", provider.albumQueries, tt.wantAlbumQueries)
+			}
+			cache, _, _ := openMatchCache()
+			if got := cachedAlbumID(cache, deluxeRecord, matchSettings(matchConfig{}, nil, "Band")); got != tt.wantAlbumID {
+				t.Errorf("cached album = %q, want %q", got, tt.wantAlbumID)
+			}
+		})
+	}
+}
+
+func TestRunReusesCachedAlbum(t *testing.T) {
+	provider := newFakeProvider(deluxeCatalog...)
+	provider.playlists["test-playlist"] = nil
+	useFakeCreatorDependencies(t, provider, []crawler.Record{deluxeRecord}, "")
+	cache, _, _ := openMatchCache()
+	cache.Put(albumCacheKey("band", "RECORD"), matchcache.Entry{
+		AlbumID:  string(fakeAlbumID("Band", "Record", "album")),
+		Settings: matchSettings(matchConfig{}, nil, "Band"),
+	})
+
+	result, err := PreviewPlaylist("")
+	if err != nil {
+		t.Fatalf("PreviewPlaylist() error: %v", err)
+	}
+	if len(provider.albumQueries) != 0 {
+		t.Errorf("album queries = %q, want none", provider.albumQueries)
+	}
+	if len(result.Planned) != 1 || result.Planned[0].ID != "regular-1" {
+		t.Errorf("Planned = %+v, want the track of the cached album", result.Planned)
+	}
+	if album := result.Explanations[0].Album; album == nil || album.Reason != "album from the match cache" {
+		t.Errorf("album resolution = %+v", album)
+	}
+}
+
+func TestRunAlbumSearchErrorFallsBackToTracks(t *testing.T) {
+	provider := newFakeProvider(deluxeCatalog...)
+	provider.albumErr = errors.New("rate limited")
+	provider.playlists["test-playlist"] = nil
+	useFakeCreatorDependencies(t, provider, []crawler.Record{deluxeRecord}, "")
+
+	result, err := PreviewPlaylist("")
+	if err != nil {
+		t.Fatalf("PreviewPlaylist() error: %v", err)
+	}
+	if result.FoundTracks != 1 || !reflect.DeepEqual(provider.queries, []string{"Band Opener year:2024"}) {
+		t.Errorf("found %d, queries %q", result.FoundTracks, provider.queries)
+	}
+	explanation := result.Explanations[0]
+	if explanation.Album == nil || explanation.Album.Error != "rate limited" {
+		t.Errorf("album = %+v", explanation.Album)
+	}
+}
+
+func TestRunRejectsUnknownMatchStrategy(t *testing.T) {
+	useFakeCreatorDependencies(t, newFakeProvider(), nil, "")
+	t.Setenv("MATCH_STRATEGY", "fuzzy")
+
+	if _, err := PreviewPlaylist(""); err == nil {
+		t.Error("PreviewPlaylist() must reject an unknown MATCH_STRATEGY")
+	}
+}
@@ -0,0 +1,159 @@
+package creator
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/jetzlstorfer/plattentests-go/internal/aliases"
+	"github.com/zmb3/spotify/v2"
+)
+
+// openArtistAliases returns the table of band aliases; tests replace it with an empty one.
+var openArtistAliases = defaultOpenArtistAliases
+
+var (
+	memoryArtistAliasesOnce sync.Once
+	memoryArtistAliases     *aliases.Table
+)
+
+// defaultOpenArtistAliases loads the table from ARTIST_ALIASES_FILE. Without it learned aliases
+// are kept as long as the process runs.
+func defaultOpenArtistAliases() (*aliases.Table, error) {
+	if path := strings.TrimSpace(os.Getenv("ARTIST_ALIASES_FILE")); path != "" {
+		return aliases.Open(path)
+	}
+	memoryArtistAliasesOnce.Do(func() {
+		memoryArtistAliases, _ = aliases.Open("")
+	})
+	return memoryArtistAliases, nil
+}
+
+// SaveArtistAlias adds alias as a curated name of band to the table in ARTIST_ALIASES_FILE.
+func SaveArtistAlias(band, alias string) error {
+	if strings.TrimSpace(os.Getenv("ARTIST_ALIASES_FILE")) == "" {
+		return fmt.Errorf("ARTIST_ALIASES_FILE missing, it is required for curated aliases")
+	}
+	table, err := openArtistAliases()
+	if err != nil {
+		return err
+	}
+	log.Printf("alias %q added for %s", alias, band)
+	return table.Put(aliases.Alias{Band: band, Alias: alias, UpdatedAt: now()})
+}
+
+// ArtistAliases lists the curated and learned aliases.
+func ArtistAliases() ([]aliases.Alias, error) {
+	table, err := openArtistAliases()
+	if err != nil {
+		return nil, err
+	}
+	return table.Aliases(), nil
+}
+
+// saveArtistAliases adds the aliases learned by a run that updates the playlist to the table
+// and writes it; the playlist is not affected when that fails.
+func saveArtistAliases(table *aliases.Table, learned []aliases.Alias) {
+	for _, alias := range learned {
+		table.Learn(alias.Band, alias.Alias, now())
+	}
+	if err := table.Save(); err != nil {
+		log.Printf("could not save learned artist aliases: %v", err)
+	}
+}
+
+// normalizeArtist normalizes an artist for comparison like normalizeForComparison, after
+// writing it in Latin letters and without a leading "The".
+func normalizeArtist(name string) string {
+	return strings.TrimPrefix(normalizeForComparison(aliases.Transliterate(name)), "the ")
+}
+
+// bandName is a name a band is compared with.
+type bandName struct {
+	// name is the band name or an alias as written, normalized is it after normalizeArtist
+	name       string
+	normalized string
+}
+
+// bandNames are the band and its aliases, the band first.
+func bandNames(table *aliases.Table, band string) []bandName {
+	names := []bandName{{name: band, normalized: normalizeArtist(band)}}
+	for _, alias := range table.Lookup(band) {
+		names = append(names, bandName{name: alias, normalized: normalizeArtist(alias)})
+	}
+	return names
+}
+
+// artistSimilarity compares the artists of a search result with the band and its aliases. It
+// returns the best similarity and the alias that reached it, empty for the band name itself.
+func (c matchConfig) artistSimilarity(artists []spotify.SimpleArtist, names []bandName) (float64, string) {
+	artist := searchArtistName(artists)
+	best, alias := c.similarity(artist, names[0].normalized), ""
+	for _, name := range names[1:] {
+		if s := c.similarity(artist, name.normalized); s > best {
+			best, alias = s, name.name
+		}
+	}
+	return best, alias
+}
+
+// searchBands are the names a band is searched under: the band, its aliases and, for a band
+// written in another script, its transliteration.
+func searchBands(names []bandName) []string {
+	var bands []string
+	seen := make(map[string]bool)
+	add := func(band string) {
+		if !seen[aliases.Key(band)] {
+			seen[aliases.Key(band)] = true
+			bands = append(bands, band)
+		}
+	}
+	for _, name := range names {
+		add(name.name)
+	}
+	add(aliases.Transliterate(names[0].name))
+	return bands
+}
+
+// learnedAliases collects the aliases learned while matching the explained tracks.
+func learnedAliases(explanations []MatchExplanation) []aliases.Alias {
+	var learned []aliases.Alias
+	seen := make(map[string]bool)
+	add := func(band, alias string) {
+		key := aliases.Key(band) + "\x00" + aliases.Key(alias)
+		if alias != "" && !seen[key] {
+			seen[key] = true
+			learned = append(learned, aliases.Alias{Band: band, Alias: alias, Learned: true})
+		}
+	}
+	for _, explanation := range explanations {
+		if explanation.Album != nil {
+			add(explanation.Band, explanation.Album.LearnedAlias)
+		}
+		add(explanation.Band, explanation.LearnedAlias)
+	}
+	return learned
+}
+
+// learnAlias returns the artist of an accepted match as a new alias of the band when only a
+// transliteration or a leading "The" made them compare equal. Collaborations are not learned:
+// the alias has to be the first artist on its own. The table is left as it is; only runs that
+// update the playlist add what they learned, through saveArtistAliases.
+func (c matchConfig) learnAlias(names []bandName, artists []spotify.SimpleArtist) string {
+	if c.aliases == nil || len(artists) == 0 {
+		return ""
+	}
+	artist := artists[0].Name
+	if s, _ := c.artistSimilarity(artists[:1], names); s < c.minArtist() {
+		return ""
+	}
+	for _, name := range names {
+		if c.similarity(normalizeForComparison(artist), normalizeForComparison(name.name)) >= c.minArtist() {
+			return ""
+		}
+	}
+	log.Printf(" learned alias %q for %s", artist, names[0].name)
+	return artist
+}
@@ -0,0 +1,221 @@
+package creator
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/jetzlstorfer/plattentests-go/internal/aliases"
+	"github.com/zmb3/spotify/v2"
+)
+
+func TestArtistSimilarity(t *testing.T) {
+	tests := []struct {
+		name      string
+		band      string
+		aliases   []string
+		artist    string
+		want      float64
+		wantAlias string
+	}{
+		{name: "same name", band: "Band", artist: "Band", want: 1},
+		{name: "cyrillic artist", band: "Kino", artist: "Кино", want: 1},
+		{name: "cyrillic band", band: "Кино", artist: "Kino", want: 1},
+		{name: "leading the", band: "The Notwist", artist: "Notwist", want: 1},
+		{name: "renamed band", band: "Dinosaur", aliases: []string{"Dinosaur Jr."}, artist: "Dinosaur Jr.", want: 1, wantAlias: "Dinosaur Jr."},
+		{name: "band name beats alias", band: "Band", aliases: []string{"Other"}, artist: "Band", want: 1},
+		{name: "different band", band: "Kino", artist: "Аквариум", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, _ := aliases.Open("")
+			for _, alias := range tt.aliases {
+				if err := table.Put(aliases.Alias{Band: tt.band, Alias: alias}); err != nil {
+					t.Fatalf("Put() error: %v", err)
+				}
+			}
+			got, alias := matchConfig{}.artistSimilarity([]spotify.SimpleArtist{{Name: tt.artist}}, bandNames(table, tt.band))
+			if got != tt.want || alias != tt.wantAlias {
+				t.Errorf("artistSimilarity() = %.2f, %q, want %.2f, %q", got, alias, tt.want, tt.wantAlias)
+			}
+		})
+	}
+}
+
+func TestExplainMatchWithAliases(t *testing.T) {
+	tests := []struct {
+		name        string
+		band        string
+		curated     []string
+		learned     []string
+		catalog     spotify.FullTrack
+		wantID      spotify.ID
+		wantQueries []string
+		wantLearned string
+		wantReason  string
+	}{
+		{
+			name:        "curated alias of a renamed band",
+			band:        "Dinosaur",
+			curated:     []string{"Dinosaur Jr."},
+			catalog:     fakeTrack("d1", "Dinosaur Jr.", "Feel the Pain", "Without a Sound", "album", "1994"),
+			wantID:      "d1",
+			wantQueries: []string{"Dinosaur Feel the Pain", "Dinosaur Jr Feel the Pain"},
+			wantReason:  "best score, artist and title match, the artist as Dinosaur Jr.",
+		},
+		{
+			name:        "transliteration of a cyrillic band is searched and learned",
+			band:        "Кино",
+			catalog:     fakeTrack("k1", "Kino", "Gruppa Krovi", "Gruppa Krovi", "album", "1988"),
+			wantID:      "k1",
+			wantQueries: []string{"Кино Gruppa Krovi", "Kino Gruppa Krovi"},
+			wantLearned: "Kino",
+			wantReason:  "best score, artist and title match",
+		},
+		{
+			name:        "learned alias finds the cyrillic spelling",
+			band:        "Kino",
+			learned:     []string{"Кино"},
+			catalog:     fakeTrack("k1", "Кино", "Gruppa Krovi", "Gruppa Krovi", "album", "1988"),
+			wantID:      "k1",
+			wantQueries: []string{"Kino Gruppa Krovi", "Кино Gruppa Krovi"},
+			wantReason:  "best score, artist and title match",
+		},
+		{
+			name:        "without the alias the cyrillic spelling is not found",
+			band:        "Kino",
+			catalog:     fakeTrack("k1", "Кино", "Gruppa Krovi", "Gruppa Krovi", "album", "1988"),
+			wantQueries: []string{"Kino Gruppa Krovi"},
+			wantReason:  "no search results",
+		},
+		{
+			name:        "leading the",
+			band:        "The Notwist",
+			catalog:     fakeTrack("n1", "Notwist", "Pilot", "Neon Golden", "album", "2002"),
+			wantID:      "n1",
+			wantQueries: []string{"The Notwist Pilot"},
+			wantLearned: "Notwist",
+			wantReason:  "best score, artist and title match",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, _ := aliases.Open("")
+			for _, alias := range tt.curated {
+				if err := table.Put(aliases.Alias{Band: tt.band, Alias: alias}); err != nil {
+					t.Fatalf("Put() error: %v", err)
+				}
+			}
+			for _, alias := range tt.learned {
+				table.Learn(tt.band, alias, time.Now())
+			}
+			provider := newFakeProvider(tt.catalog)
+
+			explanation, err := explainMatch(provider, matchConfig{aliases: table}, tt.catalog.Name, crawler.Record{Band: tt.band})
+			if err != nil {
+				t.Fatalf("explainMatch() error: %v", err)
+			}
+			if explanation.TrackID != tt.wantID || explanation.Reason != tt.wantReason {
+				t.Errorf("explainMatch() = %q (%s), want %q (%s)", explanation.TrackID, explanation.Reason, tt.wantID, tt.wantReason)
+			}
+			if !reflect.DeepEqual(provider.queries, tt.wantQueries) {
+				t.Errorf("queries = %q, want %q", provider.queries, tt.wantQueries)
+			}
+			if explanation.LearnedAlias != tt.wantLearned {
+				t.Errorf("LearnedAlias = %q, want %q", explanation.LearnedAlias, tt.wantLearned)
+			}
+			if tt.wantLearned != "" && slices.Contains(table.Lookup(tt.band), tt.wantLearned) {
+				t.Errorf("Lookup(%q) = %q, an explanation must not add the learned alias", tt.band, table.Lookup(tt.band))
+			}
+		})
+	}
+}
+
+func TestExplainMatchDoesNotSaveLearnedAliases(t *testing.T) {
+	provider := newFakeProvider(fakeTrack("k1", "Kino", "Kukushka", "Gruppa Krovi", "album", "1988"))
+	useFakeCreatorDependencies(t, provider, nil, "")
+	path := filepath.Join(t.TempDir(), "aliases.json")
+	t.Setenv("ARTIST_ALIASES_FILE", path)
+	openArtistAliases = defaultOpenArtistAliases
+
+	explanation, err := ExplainMatch(crawler.Record{Band: "Кино", Recordname: "Gruppa Krovi", ReleaseYear: "1988"}, "Kukushka")
+	if err != nil {
+		t.Fatalf("ExplainMatch() error: %v", err)
+	}
+	if learned := learnedAliases([]MatchExplanation{explanation}); explanation.TrackID != "k1" || len(learned) != 1 || learned[0].Alias != "Kino" {
+		t.Errorf("explanation = %q, learned %+v", explanation.TrackID, learned)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("alias table written by an explanation: %v", err)
+	}
+}
+
+func TestRunLearnsArtistAliases(t *testing.T) {
+	provider := newFakeProvider(
+		fakeTrack("k1", "Kino", "Gruppa Krovi", "Gruppa Krovi", "album", "1988"),
+		fakeTrack("k2", "Kino", "Kukushka", "Gruppa Krovi", "album", "1988"),
+	)
+	provider.playlists["test-playlist"] = nil
+	useFakeCreatorDependencies(t, provider, []crawler.Record{
+		{Band: "Кино", Recordname: "Gruppa Krovi", Score: 9, ReleaseYear: "1988", Tracks: []crawler.Track{
+			{Band: "Кино", Trackname: "Gruppa Krovi", IsHighlight: true},
+			{Band: "Кино", Trackname: "Kukushka", IsHighlight: true},
+		}},
+	}, "")
+	path := filepath.Join(t.TempDir(), "aliases.json")
+	t.Setenv("ARTIST_ALIASES_FILE", path)
+	openArtistAliases = defaultOpenArtistAliases
+
+	preview, err := PreviewPlaylist("")
+	if err != nil {
+		t.Fatalf("PreviewPlaylist() error: %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("alias table written by a preview: %v", err)
+	}
+	if !reflect.DeepEqual(preview.LearnedAliases, []aliases.Alias{{Band: "Кино", Alias: "Kino", Learned: true}}) {
+		t.Errorf("preview LearnedAliases = %+v", preview.LearnedAliases)
+	}
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	if result.FoundTracks != 2 || len(result.NotFound) != 0 {
+		t.Errorf("found %d, not found %v", result.FoundTracks, result.NotFound)
+	}
+	want := []aliases.Alias{{Band: "Кино", Alias: "Kino", Learned: true}}
+	if !reflect.DeepEqual(result.LearnedAliases, want) {
+		t.Errorf("LearnedAliases = %+v, want %+v", result.LearnedAliases, want)
+	}
+	if album := result.Explanations[0].Album; album == nil || !strings.HasPrefix(album.Queries[len(album.Queries)-1], "Kino ") {
+		t.Errorf("album resolution = %+v, want the transliterated band searched", album)
+	}
+
+	if err := SaveArtistAlias("Кино", "Kino Band"); err != nil {
+		t.Fatalf("SaveArtistAlias() error: %v", err)
+	}
+	if err := SaveArtistAlias("Кино", "кино"); err == nil {
+		t.Error("SaveArtistAlias() of the band name must fail")
+	}
+	saved, err := ArtistAliases()
+	if err != nil {
+		t.Fatalf("ArtistAliases() error: %v", err)
+	}
+	if len(saved) != 2 || saved[0].Alias != "Kino" || !saved[0].Learned || saved[1].Alias != "Kino Band" || saved[1].Learned {
+		t.Errorf("ArtistAliases() = %+v, want the learned and the curated alias", saved)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("alias table not written: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package creator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// archiveSettings reads the optional archive playlist configuration. An empty or unset
+// ARCHIVE_PLAYLIST_WEEKS keeps every track.
+func archiveSettings() (spotify.ID, int, error) {
+	playlistID := spotify.ID(strings.TrimSpace(os.Getenv("ARCHIVE_PLAYLIST_ID")))
+	weeks := 0
+	if value := strings.TrimSpace(os.Getenv("ARCHIVE_PLAYLIST_WEEKS")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return "", 0, fmt.Errorf("ARCHIVE_PLAYLIST_WEEKS must be a number of weeks, got %q", value)
+		}
+		weeks = parsed
+	}
+	return playlistID, weeks, nil
+}
+
+// now is the creator's clock; tests replace it to trim the archive playlist deterministically.
+var now = time.Now
+
+// ArchiveResult summarises the update of the cumulative "all highlights" playlist.
+type ArchiveResult struct {
+	PlaylistID string
+	// Weeks is the rolling window; 0 keeps every track ever added.
+	Weeks int
+	// Added counts this week's tracks that were new to the archive playlist.
+	Added int
+	// AlreadyPresent counts this week's tracks the archive playlist already contained.
+	AlreadyPresent int
+	// Trimmed counts tracks removed because they were added before the rolling window.
+	Trimmed int
+	// Total is the number of tracks in the archive playlist after the run.
+	Total int
+	// Error is set when the archive playlist could not be updated; the weekly playlist is
+	// not affected by it.
+	Error string
+}
+
+// planArchive appends the week's tracks that are not yet in the archive playlist and, with a
+// rolling window, removes tracks added more than weeks weeks before at. This week's tracks are
+// never trimmed, and neither are items without an added date or items that are no tracks.
+func planArchive(items []PlaylistItem, week []spotify.ID, weeks int, at time.Time) (syncPlan, ArchiveResult) {
+	latest := make(map[spotify.ID]time.Time, len(items))
+	undated := make(map[spotify.ID]bool)
+	present := make(map[spotify.ID]bool, len(items))
+	var order []spotify.ID
+	for _, item := range items {
+		if item.ID == "" {
+			continue
+		}
+		if !present[item.ID] {
+			order = append(order, item.ID)
+		}
+		present[item.ID] = true
+		switch {
+		case item.AddedAt.IsZero():
+			undated[item.ID] = true
+		case item.AddedAt.After(latest[item.ID]):
+			latest[item.ID] = item.AddedAt
+		}
+	}
+
+	var plan syncPlan
+	result := ArchiveResult{Weeks: weeks}
+	inWeek := make(map[spotify.ID]bool, len(week))
+	for _, id := range removeDuplicates(week) {
+		inWeek[id] = true
+		if present[id] {
+			result.AlreadyPresent++
+			continue
+		}
+		plan.Add = append(plan.Add, id)
+	}
+
+	if weeks > 0 {
+		cutoff := at.AddDate(0, 0, -7*weeks)
+		for _, id := range order {
+			if !inWeek[id] && !undated[id] && latest[id].Before(cutoff) {
+				plan.Remove = append(plan.Remove, id)
+			}
+		}
+	}
+
+	result.Added = len(plan.Add)
+	result.Trimmed = len(plan.Remove)
+	result.Total = len(order) - len(plan.Remove) + len(plan.Add)
+	return plan, result
+}
+
+// updateArchivePlaylist appends the week's tracks to the archive playlist and trims it to the
+// rolling window. With dryRun the result only reports what would change.
+func updateArchivePlaylist(ctx context.Context, provider MusicProvider, playlistID spotify.ID, weeks int, week []spotify.ID, dryRun bool) (ArchiveResult, error) {
+	items, err := provider.PlaylistItems(ctx, playlistID)
+	if err != nil {
+		return ArchiveResult{}, fmt.Errorf("read archive playlist %s: %w", playlistID, err)
+	}
+
+	plan, result := planArchive(items, week, weeks, now())
+	result.PlaylistID = string(playlistID)
+	if dryRun || plan.empty() {
+		return result, nil
+	}
+
+	log.Printf("updating archive playlist: %d new track(s), %d trimmed", len(plan.Add), len(plan.Remove))
+	if err := applySync(ctx, provider, playlistID, plan); err != nil {
+		return result, fmt.Errorf("update archive playlist %s: %w", playlistID, err)
+	}
+	return result, nil
+}
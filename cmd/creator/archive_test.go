@@ -0,0 +1,192 @@
+package creator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/zmb3/spotify/v2"
+)
+
+func TestPlanArchive(t *testing.T) {
+	at := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	weeksAgo := func(n int) time.Time { return at.AddDate(0, 0, -7*n) }
+	items := []PlaylistItem{
+		{ID: "old", AddedAt: weeksAgo(10)},
+		{ID: "recent", AddedAt: weeksAgo(1)},
+		{ID: "undated"},
+		{ID: "again", AddedAt: weeksAgo(9)},
+		{ID: "again", AddedAt: weeksAgo(2)},
+		{ID: "featured", AddedAt: weeksAgo(20)},
+	}
+	week := ids("new", "featured", "new")
+
+	tests := []struct {
+		name       string
+		weeks      int
+		wantPlan   syncPlan
+		wantResult ArchiveResult
+	}{
+		{
+			name:       "keeps everything without window",
+			weeks:      0,
+			wantPlan:   syncPlan{Add: ids("new")},
+			wantResult: ArchiveResult{Weeks: 0, Added: 1, AlreadyPresent: 1, Total: 6},
+		},
+		{
+			name:       "trims tracks older than the window",
+			weeks:      4,
+			wantPlan:   syncPlan{Remove: ids("old"), Add: ids("new")},
+			wantResult: ArchiveResult{Weeks: 4, Added: 1, AlreadyPresent: 1, Trimmed: 1, Total: 5},
+		},
+		{
+			name:       "short window",
+			weeks:      1,
+			wantPlan:   syncPlan{Remove: ids("old", "again"), Add: ids("new")},
+			wantResult: ArchiveResult{Weeks: 1, Added: 1, AlreadyPresent: 1, Trimmed: 2, Total: 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, result := planArchive(items, week, tt.weeks, at)
+			if !reflect.DeepEqual(plan, tt.wantPlan) {
+				t.Errorf("plan = %+v, want %+v", plan, tt.wantPlan)
+			}
+			if result != tt.wantResult {
+				t.Errorf("result = %+v, want %+v", result, tt.wantResult)
+			}
+		})
+	}
+}
+
+func useArchivePlaylist(t *testing.T, provider *fakeProvider, weeks string) {
+	t.Helper()
+	records := []crawler.Record{
+		{Band: "Band A", Recordname: "First Album", ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band A", Trackname: "Opener", IsHighlight: true},
+			{Band: "Band A", Trackname: "Closer", IsHighlight: true},
+		}},
+	}
+	useFakeCreatorDependencies(t, provider, records, "")
+	t.Setenv("ARCHIVE_PLAYLIST_ID", "archive")
+	t.Setenv("ARCHIVE_PLAYLIST_WEEKS", weeks)
+
+	prevNow := now
+	now = func() time.Time { return time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { now = prevNow })
+}
+
+func TestCreatePlaylistUpdatesArchivePlaylist(t *testing.T) {
+	provider := newFakeProvider(
+		fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"),
+		fakeTrack("t2", "Band A", "Closer", "First Album", "album", "2024"),
+	)
+	provider.playlists["test-playlist"] = nil
+	provider.playlists["archive"] = ids("ancient", "t2")
+	useArchivePlaylist(t, provider, "8")
+	provider.setAddedAt("archive", "ancient", now().AddDate(-1, 0, 0))
+	provider.setAddedAt("archive", "t2", now().AddDate(0, 0, -7))
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+
+	if got, want := provider.playlists["archive"], ids("t2", "t1"); !reflect.DeepEqual(got, want) {
+		t.Errorf("archive playlist = %v, want %v", got, want)
+	}
+	want := &ArchiveResult{PlaylistID: "archive", Weeks: 8, Added: 1, AlreadyPresent: 1, Trimmed: 1, Total: 2}
+	if !reflect.DeepEqual(result.Archive, want) {
+		t.Errorf("Archive = %+v, want %+v", result.Archive, want)
+	}
+	if got := provider.playlists["test-playlist"]; !reflect.DeepEqual(got, ids("t1", "t2")) {
+		t.Errorf("weekly playlist = %v", got)
+	}
+
+	// A second run finds everything in place.
+	provider.calls = nil
+	if _, err := CreatePlaylist(""); err != nil {
+		t.Fatalf("second CreatePlaylist() error: %v", err)
+	}
+	if len(provider.calls) != 0 {
+		t.Errorf("second run changed playlists: %v", provider.calls)
+	}
+}
+
+func TestOnlyArchiveProfilesUpdateArchivePlaylist(t *testing.T) {
+	provider := newFakeProvider(
+		fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"),
+		fakeTrack("t2", "Band A", "Closer", "First Album", "album", "2024"),
+	)
+	provider.playlists["scratch-playlist"] = nil
+	provider.playlists["prod-playlist"] = nil
+	provider.playlists["archive"] = nil
+	useArchivePlaylist(t, provider, "")
+	writeProfiles(t, `{"profiles": [
+		{"name": "prod", "playlistId": "prod-playlist", "archive": true},
+		{"name": "scratch", "playlistId": "scratch-playlist"}
+	]}`)
+
+	scratch, err := Run(Options{Profile: "scratch"})
+	if err != nil {
+		t.Fatalf("Run(scratch) error: %v", err)
+	}
+	if got := provider.playlists["archive"]; len(got) != 0 || scratch.Archive != nil {
+		t.Errorf("scratch profile updated the archive playlist: %v, %+v", got, scratch.Archive)
+	}
+
+	prod, err := Run(Options{Profile: "prod"})
+	if err != nil {
+		t.Fatalf("Run(prod) error: %v", err)
+	}
+	if got, want := provider.playlists["archive"], ids("t1", "t2"); !reflect.DeepEqual(got, want) || prod.Archive == nil {
+		t.Errorf("archive playlist = %v (%+v), want %v", got, prod.Archive, want)
+	}
+}
+
+func TestPreviewPlaylistDoesNotTouchArchivePlaylist(t *testing.T) {
+	provider := newFakeProvider(fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"))
+	provider.playlists["test-playlist"] = nil
+	provider.playlists["archive"] = nil
+	useArchivePlaylist(t, provider, "")
+
+	result, err := PreviewPlaylist("")
+	if err != nil {
+		t.Fatalf("PreviewPlaylist() error: %v", err)
+	}
+	if len(provider.calls) != 0 {
+		t.Errorf("dry run changed playlists: %v", provider.calls)
+	}
+	if result.Archive == nil || result.Archive.Added != 1 || result.Archive.Total != 1 {
+		t.Errorf("Archive = %+v", result.Archive)
+	}
+}
+
+func TestArchivePlaylistErrorsDoNotFailTheRun(t *testing.T) {
+	provider := newFakeProvider(fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"))
+	provider.playlists["test-playlist"] = nil
+	useArchivePlaylist(t, provider, "")
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	if result.Archive == nil || result.Archive.Error == "" {
+		t.Errorf("Archive = %+v, want an error", result.Archive)
+	}
+	if got := provider.playlists["test-playlist"]; !reflect.DeepEqual(got, []spotify.ID{"t1"}) {
+		t.Errorf("weekly playlist = %v, want [t1]", got)
+	}
+}
+
+func TestArchiveSettingsRejectsInvalidWeeks(t *testing.T) {
+	useFakeCreatorDependencies(t, newFakeProvider(), nil, "")
+	t.Setenv("ARCHIVE_PLAYLIST_WEEKS", "four")
+
+	if _, err := CreatePlaylist(""); err == nil || !strings.Contains(err.Error(), "ARCHIVE_PLAYLIST_WEEKS") {
+		t.Errorf("CreatePlaylist() error = %v, want ARCHIVE_PLAYLIST_WEEKS error", err)
+	}
+}
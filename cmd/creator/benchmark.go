@@ -0,0 +1,254 @@
+package creator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/jetzlstorfer/plattentests-go/internal/aliases"
+	"github.com/jetzlstorfer/plattentests-go/internal/matchbench"
+	"github.com/zmb3/spotify/v2"
+)
+
+// BenchmarkStrategies are the matching strategies the benchmark compares.
+var BenchmarkStrategies = []string{MatchStrategyAlbum, MatchStrategyTrack}
+
+// errBenchmarkPlaylist is returned by every playlist change during a benchmark.
+var errBenchmarkPlaylist = errors.New("the matching benchmark does not change playlists")
+
+// BenchmarkMatching matches the corpus offline with every strategy, answering Spotify
+// requests from recording, and reports how many highlights were matched correctly. Scorer
+// and thresholds are configured as for playlist runs; aliases start empty so the corpus
+// alone decides the result.
+func BenchmarkMatching(corpus matchbench.Corpus, recording *matchbench.Recording) ([]matchbench.Report, error) {
+	matchCfg, err := loadMatchConfig()
+	if err != nil {
+		return nil, err
+	}
+	var reports []matchbench.Report
+	for _, strategy := range BenchmarkStrategies {
+		provider := &benchmarkProvider{recording: recording}
+		results, err := benchmarkStrategy(provider, matchCfg, corpus, strategy)
+		if err != nil {
+			return nil, err
+		}
+		report := matchbench.Summarize(strategy, results)
+		report.Unrecorded = provider.unrecorded
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// RecordBenchmark matches the corpus with every strategy against Spotify and adds the
+// responses recording lacks. Responses recorded before are replayed, so existing entries
+// keep their results.
+func RecordBenchmark(corpus matchbench.Corpus, recording *matchbench.Recording) error {
+	matchCfg, err := loadMatchConfig()
+	if err != nil {
+		return err
+	}
+	live, err := newMusicProvider()
+	if err != nil {
+		return err
+	}
+	if len(recording.Markets) == 0 {
+		recording.Markets = live.Markets()
+	}
+	for _, strategy := range BenchmarkStrategies {
+		provider := &benchmarkProvider{live: live, recording: recording}
+		if _, err := benchmarkStrategy(provider, matchCfg, corpus, strategy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// benchmarkStrategy matches every highlight of the corpus like ExplainMatch does.
+func benchmarkStrategy(provider MusicProvider, config matchConfig, corpus matchbench.Corpus, strategy string) ([]matchbench.Result, error) {
+	// learned aliases must not carry over from another strategy or run
+	config.aliases, _ = aliases.Open("")
+	results := make([]matchbench.Result, 0, corpus.Highlights())
+	for _, entry := range corpus.Records {
+		record := crawler.Record{
+			Band:        entry.Band,
+			Recordname:  entry.Recordname,
+			ReleaseYear: entry.ReleaseYear,
+			Tracks:      make([]crawler.Track, entry.TrackCount),
+		}
+		var album *albumLookup
+		if strategy == MatchStrategyAlbum {
+			var err error
+			if album, err = resolveAlbum(provider, config, record, ""); err != nil {
+				log.Printf("could not resolve album of %s - %s: %v", record.Band, record.Recordname, err)
+			}
+		}
+		for _, highlight := range entry.Highlights {
+			explanation, err := explainHighlight(provider, config, highlight.Track, record, album)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s - %s: %w", strategy, record.Band, highlight.Track, err)
+			}
+			results = append(results, matchbench.Result{
+				Band:     record.Band,
+				Track:    highlight.Track,
+				Expected: highlight.Expected,
+				Got:      explanation.TrackID,
+				Reason:   explanation.Reason,
+			})
+		}
+	}
+	return results, nil
+}
+
+// benchmarkProvider answers the reads of the matcher from a recording. With a live provider,
+// requests the recording lacks are sent to it and recorded; without one they get no results
+// and are listed in unrecorded.
+type benchmarkProvider struct {
+	live      MusicProvider
+	recording *matchbench.Recording
+
+	mu         sync.Mutex
+	unrecorded []string
+}
+
+// miss notes a request the recording has no response for.
+func (p *benchmarkProvider) miss(request string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unrecorded = append(p.unrecorded, request)
+}
+
+func (p *benchmarkProvider) Markets() []string {
+	return p.recording.Markets
+}
+
+func (p *benchmarkProvider) SearchTracks(ctx context.Context, query string) ([]spotify.FullTrack, error) {
+	p.mu.Lock()
+	tracks, ok := p.recording.Tracks[query]
+	p.mu.Unlock()
+	if ok {
+		return tracks, nil
+	}
+	if p.live == nil {
+		p.miss("track search " + query)
+		return nil, nil
+	}
+	tracks, err := p.live.SearchTracks(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.recording.Tracks[query] = tracks
+	return tracks, nil
+}
+
+func (p *benchmarkProvider) SearchAlbums(ctx context.Context, query string) ([]spotify.SimpleAlbum, error) {
+	p.mu.Lock()
+	albums, ok := p.recording.Albums[query]
+	p.mu.Unlock()
+	if ok {
+		return albums, nil
+	}
+	if p.live == nil {
+		p.miss("album search " + query)
+		return nil, nil
+	}
+	albums, err := p.live.SearchAlbums(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.recording.Albums[query] = albums
+	return albums, nil
+}
+
+func (p *benchmarkProvider) AlbumTracks(ctx context.Context, albumID spotify.ID) ([]spotify.SimpleTrack, error) {
+	p.mu.Lock()
+	tracks, ok := p.recording.AlbumTracks[albumID]
+	p.mu.Unlock()
+	if ok {
+		return tracks, nil
+	}
+	if p.live == nil {
+		p.miss("tracklist of album " + string(albumID))
+		return nil, fmt.Errorf("tracklist of album %s not recorded", albumID)
+	}
+	tracks, err := p.live.AlbumTracks(ctx, albumID)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.recording.AlbumTracks[albumID] = tracks
+	return tracks, nil
+}
+
+// Tracks records tracks under the requested ID, which differs from the ID of a relinked track.
+func (p *benchmarkProvider) Tracks(ctx context.Context, trackIDs ...spotify.ID) ([]spotify.FullTrack, error) {
+	var tracks []spotify.FullTrack
+	var missing []spotify.ID
+	p.mu.Lock()
+	for _, id := range trackIDs {
+		if track, ok := p.recording.Details[id]; ok {
+			tracks = append(tracks, track)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	p.mu.Unlock()
+	if len(missing) == 0 {
+		return tracks, nil
+	}
+	if p.live == nil {
+		for _, id := range missing {
+			p.miss("details of track " + string(id))
+		}
+		return tracks, nil
+	}
+	fetched, err := p.live.Tracks(ctx, missing...)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, track := range fetched {
+		p.recording.Details[originalID(track)] = track
+	}
+	return append(tracks, fetched...), nil
+}
+
+func (p *benchmarkProvider) ReplacePlaylistTracks(context.Context, spotify.ID, ...spotify.ID) error {
+	return errBenchmarkPlaylist
+}
+
+func (p *benchmarkProvider) AddTracksToPlaylist(context.Context, spotify.ID, ...spotify.ID) error {
+	return errBenchmarkPlaylist
+}
+
+func (p *benchmarkProvider) RemoveTracksFromPlaylist(context.Context, spotify.ID, ...spotify.ID) error {
+	return errBenchmarkPlaylist
+}
+
+func (p *benchmarkProvider) ReorderPlaylistTracks(context.Context, spotify.ID, int, int) error {
+	return errBenchmarkPlaylist
+}
+
+func (p *benchmarkProvider) CreatePlaylist(context.Context, string, string) (spotify.ID, error) {
+	return "", errBenchmarkPlaylist
+}
+
+func (p *benchmarkProvider) PlaylistItems(context.Context, spotify.ID) ([]PlaylistItem, error) {
+	return nil, errBenchmarkPlaylist
+}
+
+func (p *benchmarkProvider) PlaylistDetails(context.Context, spotify.ID) (string, string, error) {
+	return "", "", errBenchmarkPlaylist
+}
+
+func (p *benchmarkProvider) ChangePlaylistDetails(context.Context, spotify.ID, string, string) error {
+	return errBenchmarkPlaylist
+}
@@ -0,0 +1,125 @@
+package creator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jetzlstorfer/plattentests-go/internal/matchbench"
+	"github.com/zmb3/spotify/v2"
+)
+
+// Benchmark files, see README; update the baseline with `go run ./cmd/matcher benchmark -update`.
+const (
+	benchmarkCorpus    = "testdata/benchmark/corpus.json"
+	benchmarkRecording = "testdata/benchmark/recording.json"
+	benchmarkBaseline  = "testdata/benchmark/baseline.json"
+)
+
+func TestMatchingBenchmark(t *testing.T) {
+	useFakeCreatorDependencies(t, newFakeProvider(), nil, "")
+	corpus, err := matchbench.LoadCorpus(benchmarkCorpus)
+	if err != nil {
+		t.Fatalf("LoadCorpus() error: %v", err)
+	}
+	recording, err := matchbench.LoadRecording(benchmarkRecording)
+	if err != nil {
+		t.Fatalf("LoadRecording() error: %v", err)
+	}
+	baseline, err := matchbench.LoadBaseline(benchmarkBaseline)
+	if err != nil {
+		t.Fatalf("LoadBaseline() error: %v", err)
+	}
+
+	reports, err := BenchmarkMatching(corpus, recording)
+	if err != nil {
+		t.Fatalf("BenchmarkMatching() error: %v", err)
+	}
+	for _, report := range reports {
+		t.Logf("%s: precision %.3f, recall %.3f, not found %.3f", report.Strategy, report.Precision, report.Recall, report.NotFoundRate)
+		for _, mistake := range report.Mistakes {
+			t.Logf("  %s - %s: got %q, want %q (%s)", mistake.Band, mistake.Track, mistake.Got, mistake.Expected, mistake.Reason)
+		}
+		if report.Highlights != corpus.Highlights() {
+			t.Errorf("%s matched %d highlights, want %d", report.Strategy, report.Highlights, corpus.Highlights())
+		}
+		if len(report.Unrecorded) > 0 {
+			t.Errorf("%s sent requests that are not recorded, record them with `go run ./cmd/matcher benchmark -record`: %q", report.Strategy, report.Unrecorded)
+		}
+	}
+	for _, regression := range matchbench.Regressions(baseline, reports) {
+		t.Error(regression)
+	}
+}
+
+func TestRecordBenchmark(t *testing.T) {
+	provider := newFakeProvider(
+		fakeTrack("a1", "Band", "Song", "Record", "album", "2024"),
+		fakeTrack("a2", "Band", "Other Song", "Record", "album", "2024"),
+	)
+	useFakeCreatorDependencies(t, provider, nil, "")
+	corpus := matchbench.Corpus{Records: []matchbench.Record{{
+		Band: "Band", Recordname: "Record", ReleaseYear: "2024", TrackCount: 2,
+		Highlights: []matchbench.Highlight{
+			{Track: "Song", Expected: []spotify.ID{"a1"}},
+			{Track: "Missing"},
+		},
+	}}}
+
+	recording := matchbench.NewRecording()
+	if err := RecordBenchmark(corpus, recording); err != nil {
+		t.Fatalf("RecordBenchmark() error: %v", err)
+	}
+	wantQueries := []string{"Band Song year:2024", "Band Missing year:2024", "Band Missing"}
+	for _, query := range wantQueries {
+		if _, ok := recording.Tracks[query]; !ok {
+			t.Errorf("track search %q not recorded", query)
+		}
+	}
+	if len(recording.AlbumTracks["band-record-album"]) != 2 {
+		t.Errorf("AlbumTracks = %v, want the tracklist recorded", recording.AlbumTracks)
+	}
+
+	// replaying needs no provider and sends nothing
+	searches := len(provider.queries)
+	newMusicProvider = func() (MusicProvider, error) { return nil, errors.New("offline") }
+	reports, err := BenchmarkMatching(corpus, recording)
+	if err != nil {
+		t.Fatalf("BenchmarkMatching() error: %v", err)
+	}
+	if len(provider.queries) != searches {
+		t.Errorf("replay sent %d searches", len(provider.queries)-searches)
+	}
+	want := []matchbench.Report{
+		{Strategy: MatchStrategyAlbum, Highlights: 2, Correct: 1, NotFound: 1, Precision: 1, Recall: 1, NotFoundRate: 0.5},
+		{Strategy: MatchStrategyTrack, Highlights: 2, Correct: 1, NotFound: 1, Precision: 1, Recall: 1, NotFoundRate: 0.5},
+	}
+	if !reflect.DeepEqual(reports, want) {
+		t.Errorf("BenchmarkMatching() = %+v, want %+v", reports, want)
+	}
+
+	// requests that were not recorded are answered with nothing and reported
+	delete(recording.Tracks, "Band Song year:2024")
+	delete(recording.AlbumTracks, "band-record-album")
+	reports, err = BenchmarkMatching(corpus, recording)
+	if err != nil {
+		t.Fatalf("BenchmarkMatching() error: %v", err)
+	}
+	if got := reports[1]; got.Correct != 0 || got.Missed != 1 || !reflect.DeepEqual(got.Unrecorded, []string{"track search Band Song year:2024", "track search Band Song"}) {
+		t.Errorf("track report = %+v, want the unrecorded searches", got)
+	}
+	if len(reports[0].Unrecorded) == 0 || reports[0].Unrecorded[0] != "tracklist of album band-record-album" {
+		t.Errorf("album report Unrecorded = %q, want the tracklist first", reports[0].Unrecorded)
+	}
+}
+
+func TestBenchmarkProviderLeavesPlaylistsAlone(t *testing.T) {
+	provider := &benchmarkProvider{recording: matchbench.NewRecording()}
+	if err := provider.AddTracksToPlaylist(context.Background(), "playlist", "a1"); !errors.Is(err, errBenchmarkPlaylist) {
+		t.Errorf("AddTracksToPlaylist() error = %v, want errBenchmarkPlaylist", err)
+	}
+	if _, err := provider.CreatePlaylist(context.Background(), "name", ""); !errors.Is(err, errBenchmarkPlaylist) {
+		t.Errorf("CreatePlaylist() error = %v, want errBenchmarkPlaylist", err)
+	}
+}
@@ -0,0 +1,156 @@
+package creator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// maxTracksPerLookup is the Spotify limit for track details per request.
+const maxTracksPerLookup = 50
+
+// CollapsedDuplicate is a group of planned tracks that turned out to be the same recording or
+// song; only Kept goes into the playlist.
+type CollapsedDuplicate struct {
+	Kept    PlannedTrack
+	Dropped []PlannedTrack
+	// Reason tells why the tracks were considered the same.
+	Reason string
+}
+
+// trackVersion is a planned track with the details needed to tell versions apart.
+type trackVersion struct {
+	track PlannedTrack
+	// isrc identifies the recording; empty when Spotify does not know it or the details
+	// could not be fetched.
+	isrc string
+	// title is the band and the song title without version suffixes.
+	title string
+	// fromReviewedAlbum is set when the track is on the album of the reviewed record.
+	fromReviewedAlbum bool
+}
+
+// consolidateVersions collapses planned tracks that are the same recording, by ISRC, such as a
+// single and the album version. Tracks without an ISRC are compared by band and normalized
+// title instead, which keeps "(Part 2)" or "- Reprise" apart from the song itself; a group never
+// holds two ISRCs. Of each group the version from the reviewed album is kept, otherwise the
+// first; it takes the place of the first track of the group. albums holds the album IDs of the
+// reviewed records by albumCacheKey. Without track details only titles are compared.
+func consolidateVersions(provider MusicProvider, planned []PlannedTrack, albums map[string]spotify.ID) ([]PlannedTrack, []CollapsedDuplicate) {
+	if len(planned) < 2 {
+		return planned, nil
+	}
+
+	ids := make([]spotify.ID, len(planned))
+	for i, track := range planned {
+		ids[i] = track.ID
+	}
+	details, err := trackDetails(provider, ids)
+	if err != nil {
+		log.Printf("could not fetch track details, comparing titles only: %v", err)
+	}
+
+	versions := make([]trackVersion, len(planned))
+	for i, track := range planned {
+		version := trackVersion{track: track}
+		name := track.Trackname
+		if detail, ok := details[track.ID]; ok {
+			version.isrc = strings.ToUpper(detail.ExternalIDs["isrc"])
+			name = detail.Name
+			albumID, resolved := albums[albumCacheKey(track.Band, track.Recordname)]
+			version.fromReviewedAlbum = (resolved && detail.Album.ID == albumID) ||
+				normalizeForComparison(detail.Album.Name) == normalizeForComparison(track.Recordname)
+		}
+		version.title = normalizeForComparison(track.Band) + "\x00" + normalizeTitle(name)
+		versions[i] = version
+	}
+
+	// group by a shared key; groups keep the order of their first track. "title:" is the first
+	// group of a title, "no-isrc:" the first group of a title that has tracks without an ISRC.
+	groupOf := make(map[string]int)
+	var groups [][]int
+	var reasons, isrcs []string
+	for i, version := range versions {
+		group, reason := -1, ""
+		if version.isrc != "" {
+			if g, ok := groupOf["isrc:"+version.isrc]; ok {
+				group, reason = g, "same recording (ISRC "+version.isrc+")"
+			} else if g, ok := groupOf["no-isrc:"+version.title]; ok && isrcs[g] == "" {
+				// two ISRCs tell whether tracks are the same recording, so a track with one
+				// only joins tracks of the same title that all lack it
+				group, reason = g, "same title"
+			}
+		} else if g, ok := groupOf["title:"+version.title]; ok {
+			group, reason = g, "same title"
+		}
+		if group < 0 {
+			group = len(groups)
+			groups = append(groups, nil)
+			reasons = append(reasons, "")
+			isrcs = append(isrcs, "")
+		}
+		if reasons[group] == "" {
+			reasons[group] = reason
+		}
+		if isrcs[group] == "" {
+			isrcs[group] = version.isrc
+		}
+		groups[group] = append(groups[group], i)
+
+		keys := []string{"title:" + version.title, "isrc:" + version.isrc}
+		if version.isrc == "" {
+			keys[1] = "no-isrc:" + version.title
+		}
+		for _, key := range keys {
+			if _, ok := groupOf[key]; !ok {
+				groupOf[key] = group
+			}
+		}
+	}
+
+	unique := make([]PlannedTrack, 0, len(groups))
+	var collapsed []CollapsedDuplicate
+	for g, members := range groups {
+		kept := members[0]
+		for _, i := range members {
+			if versions[i].fromReviewedAlbum {
+				kept = i
+				break
+			}
+		}
+		unique = append(unique, versions[kept].track)
+		if len(members) == 1 {
+			continue
+		}
+
+		duplicate := CollapsedDuplicate{Kept: versions[kept].track, Reason: reasons[g]}
+		for _, i := range members {
+			if i != kept {
+				duplicate.Dropped = append(duplicate.Dropped, versions[i].track)
+			}
+		}
+		log.Printf("collapsed %d version(s) of %s - %s: %s", len(duplicate.Dropped), duplicate.Kept.Band, duplicate.Kept.Trackname, duplicate.Reason)
+		collapsed = append(collapsed, duplicate)
+	}
+	return unique, collapsed
+}
+
+// trackDetails fetches the full tracks for ids in batches Spotify accepts, keyed by the
+// requested ID also when Spotify relinked a track.
+func trackDetails(provider MusicProvider, ids []spotify.ID) (map[spotify.ID]spotify.FullTrack, error) {
+	details := make(map[spotify.ID]spotify.FullTrack, len(ids))
+	for start := 0; start < len(ids); start += maxTracksPerLookup {
+		end := min(start+maxTracksPerLookup, len(ids))
+		tracks, err := provider.Tracks(context.Background(), ids[start:end]...)
+		if err != nil {
+			return details, fmt.Errorf("track details: %w", err)
+		}
+		for _, track := range tracks {
+			details[originalID(track)] = track
+		}
+	}
+	return details, nil
+}
@@ -0,0 +1,188 @@
+package creator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/zmb3/spotify/v2"
+)
+
+// withISRC sets the ISRC Spotify reports for track.
+func withISRC(track spotify.FullTrack, isrc string) spotify.FullTrack {
+	track.ExternalIDs = map[string]string{"isrc": isrc}
+	return track
+}
+
+func TestConsolidateVersions(t *testing.T) {
+	catalog := []spotify.FullTrack{
+		withISRC(fakeTrack("single", "Band", "Song", "Song", "single", "2024"), "DEA012400001"),
+		withISRC(fakeTrack("album", "Band", "Song", "Record", "album", "2024"), "dea012400001"),
+		withISRC(fakeTrack("deluxe", "Band", "Song - 2024 Remaster", "Record (Deluxe)", "album", "2024"), "DEA012400099"),
+		withISRC(fakeTrack("other", "Band", "Other Song", "Record", "album", "2024"), "DEA012400002"),
+		withISRC(fakeTrack("edit", "Band", "Radio Edit", "Radio Edit", "single", "2024"), "DEA012400002"),
+		fakeTrack("remaster", "Band", "Song - 2024 Remaster", "Record (Deluxe)", "album", "2024"),
+		fakeTrack("part1", "Band", "Song (Part 1)", "Record", "album", "2024"),
+		fakeTrack("part2", "Band", "Song (Part 2)", "Record", "album", "2024"),
+		fakeTrack("reprise", "Band", "Song - Reprise", "Record", "album", "2024"),
+		fakeTrack("cover", "Other Band", "Song", "Covers", "album", "2024"),
+	}
+	planned := func(id spotify.ID, track string) PlannedTrack {
+		band, record := "Band", "Record"
+		if id == "cover" {
+			band, record = "Other Band", "Covers"
+		}
+		return PlannedTrack{ID: id, Band: band, Trackname: track, Recordname: record}
+	}
+
+	tests := []struct {
+		name      string
+		tracksErr error
+		// albums are the resolved albums of the reviewed records
+		albums      map[string]spotify.ID
+		planned     []PlannedTrack
+		want        []spotify.ID
+		wantReasons []string
+		wantDropped [][]spotify.ID
+	}{
+		{
+			name:        "same recording keeps the album version in place of the single",
+			planned:     []PlannedTrack{planned("single", "Song"), planned("other", "Other Song"), planned("album", "Song")},
+			want:        []spotify.ID{"album", "other"},
+			wantReasons: []string{"same recording (ISRC DEA012400001)"},
+			wantDropped: [][]spotify.ID{{"single"}},
+		},
+		{
+			name:        "same title without a version suffix when an ISRC is missing",
+			planned:     []PlannedTrack{planned("remaster", "Song"), planned("album", "Song")},
+			want:        []spotify.ID{"album"},
+			wantReasons: []string{"same title"},
+			wantDropped: [][]spotify.ID{{"remaster"}},
+		},
+		{
+			name:    "different recordings with the same title are kept",
+			planned: []PlannedTrack{planned("deluxe", "Song"), planned("album", "Song")},
+			want:    []spotify.ID{"deluxe", "album"},
+		},
+		{
+			name:        "a title without an ISRC does not join two recordings",
+			planned:     []PlannedTrack{planned("album", "Song"), planned("remaster", "Song"), planned("deluxe", "Song")},
+			want:        []spotify.ID{"album", "deluxe"},
+			wantReasons: []string{"same title"},
+			wantDropped: [][]spotify.ID{{"remaster"}},
+		},
+		{
+			name:        "a title without an ISRC does not join two recordings in reverse order",
+			planned:     []PlannedTrack{planned("deluxe", "Song"), planned("remaster", "Song"), planned("album", "Song")},
+			want:        []spotify.ID{"deluxe", "album"},
+			wantReasons: []string{"same title"},
+			wantDropped: [][]spotify.ID{{"remaster"}},
+		},
+		{
+			name:    "parts and reprises are other songs",
+			planned: []PlannedTrack{planned("part1", "Song (Part 1)"), planned("part2", "Song (Part 2)"), planned("reprise", "Song - Reprise"), planned("remaster", "Song")},
+			want:    []spotify.ID{"part1", "part2", "reprise", "remaster"},
+		},
+		{
+			name:        "the resolved album counts as the reviewed one",
+			albums:      map[string]spotify.ID{albumCacheKey("Band", "Record"): fakeAlbumID("Band", "Record (Deluxe)", "album")},
+			planned:     []PlannedTrack{planned("remaster", "Song"), planned("album", "Song")},
+			want:        []spotify.ID{"remaster"},
+			wantReasons: []string{"same title"},
+			wantDropped: [][]spotify.ID{{"album"}},
+		},
+		{
+			name:        "same recording under another title",
+			planned:     []PlannedTrack{planned("other", "Other Song"), planned("edit", "Other Song")},
+			want:        []spotify.ID{"other"},
+			wantReasons: []string{"same recording (ISRC DEA012400002)"},
+			wantDropped: [][]spotify.ID{{"edit"}},
+		},
+		{
+			name:    "same title of another band is kept",
+			planned: []PlannedTrack{planned("album", "Song"), planned("cover", "Song")},
+			want:    []spotify.ID{"album", "cover"},
+		},
+		{
+			name:        "titles only without track details",
+			tracksErr:   errors.New("rate limited"),
+			planned:     []PlannedTrack{planned("single", "Song"), planned("other", "Other Song"), planned("album", "Song")},
+			want:        []spotify.ID{"single", "other"},
+			wantReasons: []string{"same title"},
+			wantDropped: [][]spotify.ID{{"album"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newFakeProvider(catalog...)
+			provider.tracksErr = tt.tracksErr
+
+			unique, duplicates := consolidateVersions(provider, tt.planned, tt.albums)
+			var got []spotify.ID
+			for _, track := range unique {
+				got = append(got, track.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kept = %v, want %v", got, tt.want)
+			}
+
+			var reasons []string
+			var dropped [][]spotify.ID
+			for _, duplicate := range duplicates {
+				reasons = append(reasons, duplicate.Reason)
+				var ids []spotify.ID
+				for _, track := range duplicate.Dropped {
+					ids = append(ids, track.ID)
+				}
+				dropped = append(dropped, ids)
+			}
+			if !reflect.DeepEqual(reasons, tt.wantReasons) || !reflect.DeepEqual(dropped, tt.wantDropped) {
+				t.Errorf("duplicates = %q %v, want %q %v", reasons, dropped, tt.wantReasons, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestTrackDetailsBatches(t *testing.T) {
+	provider := newFakeProvider()
+	ids := make([]spotify.ID, 120)
+	for i := range ids {
+		ids[i] = spotify.ID(fmt.Sprintf("t%d", i))
+	}
+	if _, err := trackDetails(provider, ids); err != nil {
+		t.Fatalf("trackDetails() error: %v", err)
+	}
+	if provider.trackLookups != 3 {
+		t.Errorf("Tracks called %d times, want 3 batches of at most 50", provider.trackLookups)
+	}
+}
+
+func TestRunCollapsesDuplicateVersions(t *testing.T) {
+	provider := newFakeProvider(
+		withISRC(fakeTrack("ep", "Band", "Song", "Song EP", "single", "2024"), "DEA012400001"),
+		withISRC(fakeTrack("album", "Band", "Song", "Record", "album", "2024"), "DEA012400001"),
+	)
+	provider.playlists["test-playlist"] = nil
+	useFakeCreatorDependencies(t, provider, []crawler.Record{
+		{Band: "Band", Recordname: "Song EP", Score: 9, ReleaseYear: "2024", Tracks: []crawler.Track{{Band: "Band", Trackname: "Song", IsHighlight: true}}},
+		{Band: "Band", Recordname: "Record", Score: 8, ReleaseYear: "2024", Tracks: []crawler.Track{{Band: "Band", Trackname: "Song", IsHighlight: true}}},
+	}, "")
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	// both versions are from their reviewed record, so the first one stays
+	if got := provider.playlists["test-playlist"]; !reflect.DeepEqual(got, []spotify.ID{"ep"}) {
+		t.Errorf("playlist = %v, want [ep]", got)
+	}
+	if len(result.Duplicates) != 1 || result.Duplicates[0].Kept.ID != "ep" || result.Duplicates[0].Dropped[0].ID != "album" {
+		t.Errorf("Duplicates = %+v", result.Duplicates)
+	}
+	if len(result.Planned) != 1 {
+		t.Errorf("Planned = %+v", result.Planned)
+	}
+}
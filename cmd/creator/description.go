@@ -0,0 +1,169 @@
+package creator
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/zmb3/spotify/v2"
+)
+
+// Spotify limits for playlist details, counted in characters.
+const (
+	maxPlaylistNameLength        = 100
+	maxPlaylistDescriptionLength = 300
+)
+
+// topRecordsInDescription is the number of best-rated records passed to the templates.
+const topRecordsInDescription = 3
+
+// defaultDescriptionTemplate summarises the week when PLAYLIST_DESCRIPTION_TEMPLATE is unset.
+const defaultDescriptionTemplate = `Die Highlights der Woche von Plattentests.de, Stand {{.Date.Format "02.01.2006"}}: {{.TrackCount}} Songs.` +
+	`{{with .RecordOfTheWeek}} Platte der Woche: {{.Band}} - {{.Recordname}} ({{.Score}}/10).{{end}}` +
+	`{{if .TopRecords}} Top bewertet: {{range $i, $r := .TopRecords}}{{if $i}}, {{end}}{{$r.Band}} ({{$r.Score}}/10){{end}}.{{end}}`
+
+// descriptionData is what the name and description templates are executed with.
+type descriptionData struct {
+	// Result is the complete run result.
+	Result Result
+	// Date is the run date; Week is its ISO week number.
+	Date time.Time
+	Week int
+	// RecordOfTheWeek is nil when there is no record of the week among the highlights.
+	RecordOfTheWeek *crawler.Record
+	// TopRecords are the best-rated other records, highest score first.
+	TopRecords []crawler.Record
+	// TrackCount is the number of tracks in the playlist after the run.
+	TrackCount int
+}
+
+// PlaylistDetails is the title and description a run gives the playlist.
+type PlaylistDetails struct {
+	// Name is empty when the playlist keeps its name.
+	Name        string
+	Description string
+	// Updated is set when the run changed the details on the provider.
+	Updated bool
+	// Error is set when the details could not be rendered or updated; the tracks are not
+	// affected by it.
+	Error string
+}
+
+// detailsTemplates parses PLAYLIST_DESCRIPTION_TEMPLATE and the optional PLAYLIST_NAME_TEMPLATE.
+// The name template is nil when the playlist should keep its name.
+func detailsTemplates() (name, description *template.Template, err error) {
+	descriptionText := os.Getenv("PLAYLIST_DESCRIPTION_TEMPLATE")
+	if strings.TrimSpace(descriptionText) == "" {
+		descriptionText = defaultDescriptionTemplate
+	}
+	if description, err = template.New("description").Parse(descriptionText); err != nil {
+		return nil, nil, fmt.Errorf("PLAYLIST_DESCRIPTION_TEMPLATE is not a valid template: %w", err)
+	}
+	if nameText := os.Getenv("PLAYLIST_NAME_TEMPLATE"); strings.TrimSpace(nameText) != "" {
+		if name, err = template.New("name").Parse(nameText); err != nil {
+			return nil, nil, fmt.Errorf("PLAYLIST_NAME_TEMPLATE is not a valid template: %w", err)
+		}
+	}
+	return name, description, nil
+}
+
+// newDescriptionData derives the template data from a run result.
+func newDescriptionData(result Result, at time.Time) descriptionData {
+	_, week := at.ISOWeek()
+	data := descriptionData{Result: result, Date: at, Week: week, TrackCount: len(result.Planned)}
+
+	var others []crawler.Record
+	for i, record := range result.Records {
+		if record.IsRecordOfTheWeek && data.RecordOfTheWeek == nil {
+			data.RecordOfTheWeek = &result.Records[i]
+			continue
+		}
+		others = append(others, record)
+	}
+	sort.SliceStable(others, func(i, j int) bool {
+		return others[i].Score > others[j].Score
+	})
+	if len(others) > topRecordsInDescription {
+		others = others[:topRecordsInDescription]
+	}
+	data.TopRecords = others
+	return data
+}
+
+// renderPlaylistDetails executes the templates for result. A nil name template keeps the name.
+func renderPlaylistDetails(name, description *template.Template, result Result, at time.Time) (PlaylistDetails, error) {
+	data := newDescriptionData(result, at)
+	var details PlaylistDetails
+
+	var text strings.Builder
+	if err := description.Execute(&text, data); err != nil {
+		return details, fmt.Errorf("render playlist description: %w", err)
+	}
+	details.Description = sanitizePlaylistText(text.String(), maxPlaylistDescriptionLength)
+
+	if name != nil {
+		text.Reset()
+		if err := name.Execute(&text, data); err != nil {
+			return details, fmt.Errorf("render playlist name: %w", err)
+		}
+		details.Name = sanitizePlaylistText(text.String(), maxPlaylistNameLength)
+	}
+	return details, nil
+}
+
+// sanitizePlaylistText makes text acceptable to Spotify: it drops control characters and angle
+// brackets, which Spotify rejects or strips, collapses whitespace including line breaks, and
+// cuts the text to limit characters with an ellipsis.
+func sanitizePlaylistText(text string, limit int) string {
+	text = strings.Map(func(r rune) rune {
+		switch {
+		case r == '<' || r == '>':
+			return -1
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsControl(r) || r == unicode.ReplacementChar:
+			return -1
+		}
+		return r
+	}, text)
+	text = strings.Join(strings.Fields(text), " ")
+
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	cut := strings.TrimRightFunc(string(runes[:limit-1]), unicode.IsSpace)
+	return cut + "…"
+}
+
+// updatePlaylistDetails changes name and description of the playlist when they differ from
+// the current ones, so reruns with the same result leave the playlist alone.
+func updatePlaylistDetails(ctx context.Context, provider MusicProvider, playlistID spotify.ID, details PlaylistDetails) (bool, error) {
+	currentName, currentDescription, err := provider.PlaylistDetails(ctx, playlistID)
+	if err != nil {
+		return false, fmt.Errorf("read details of playlist %s: %w", playlistID, err)
+	}
+	// Spotify returns descriptions HTML-escaped.
+	nameChanged := details.Name != "" && details.Name != currentName
+	if !nameChanged && details.Description == html.UnescapeString(currentDescription) {
+		return false, nil
+	}
+
+	name := ""
+	if nameChanged {
+		name = details.Name
+	}
+	log.Printf("updating details of playlist %s", playlistID)
+	if err := provider.ChangePlaylistDetails(ctx, playlistID, name, details.Description); err != nil {
+		return false, fmt.Errorf("update details of playlist %s: %w", playlistID, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,188 @@
+package creator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+)
+
+func TestSanitizePlaylistText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  string
+	}{
+		{name: "unchanged", text: "Die Highlights der Woche", limit: 100, want: "Die Highlights der Woche"},
+		{name: "line breaks and tabs", text: "Zeile eins\n\tZeile  zwei\r\n", limit: 100, want: "Zeile eins Zeile zwei"},
+		{name: "angle brackets and control characters", text: "<b>Fett</b>\x00 & gut", limit: 100, want: "bFett/b & gut"},
+		{name: "cut with ellipsis", text: "Motörhead Motörhead", limit: 11, want: "Motörhead…"},
+		{name: "exactly at the limit", text: "Motörhead", limit: 9, want: "Motörhead"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sanitizePlaylistText(tt.text, tt.limit)
+			if got != tt.want {
+				t.Errorf("sanitizePlaylistText() = %q, want %q", got, tt.want)
+			}
+			if n := len([]rune(got)); n > tt.limit {
+				t.Errorf("result has %d characters, limit is %d", n, tt.limit)
+			}
+		})
+	}
+}
+
+func TestRenderPlaylistDetails(t *testing.T) {
+	result := Result{
+		Records: []crawler.Record{
+			{Band: "Band A", Recordname: "First Album", Score: 7, IsRecordOfTheWeek: true},
+			{Band: "Band B", Recordname: "Second Album", Score: 9},
+			{Band: "Band C", Recordname: "Third Album", Score: 6},
+			{Band: "Band D", Recordname: "Fourth Album", Score: 8},
+			{Band: "Band E", Recordname: "Fifth Album", Score: 5},
+		},
+		Planned: make([]PlannedTrack, 12),
+	}
+	at := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		nameTemplate    string
+		description     string
+		wantName        string
+		wantDescription string
+	}{
+		{
+			name:            "default description keeps the name",
+			wantDescription: "Die Highlights der Woche von Plattentests.de, Stand 16.10.2026: 12 Songs. Platte der Woche: Band A - First Album (7/10). Top bewertet: Band B (9/10), Band D (8/10), Band C (6/10).",
+		},
+		{
+			name:            "custom templates",
+			nameTemplate:    "Plattentests KW {{.Week}}",
+			description:     "{{.Result.FoundTracks}} von {{.Result.TotalTracks}} gefunden\n{{range .TopRecords}}<{{.Band}}>{{end}}",
+			wantName:        "Plattentests KW 42",
+			wantDescription: "0 von 0 gefunden Band BBand DBand C",
+		},
+		{
+			name:            "long descriptions are cut",
+			description:     `{{range .Result.Planned}}{{printf "%030d" 0}}{{end}}`,
+			wantDescription: strings.Repeat("0", maxPlaylistDescriptionLength-1) + "…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PLAYLIST_NAME_TEMPLATE", tt.nameTemplate)
+			t.Setenv("PLAYLIST_DESCRIPTION_TEMPLATE", tt.description)
+			name, description, err := detailsTemplates()
+			if err != nil {
+				t.Fatalf("detailsTemplates() error: %v", err)
+			}
+			got, err := renderPlaylistDetails(name, description, result, at)
+			if err != nil {
+				t.Fatalf("renderPlaylistDetails() error: %v", err)
+			}
+			if got.Name != tt.wantName || got.Description != tt.wantDescription {
+				t.Errorf("details = %q / %q, want %q / %q", got.Name, got.Description, tt.wantName, tt.wantDescription)
+			}
+		})
+	}
+}
+
+func TestRenderPlaylistDetailsWithoutRecordOfTheWeek(t *testing.T) {
+	_, description, err := detailsTemplates()
+	if err != nil {
+		t.Fatalf("detailsTemplates() error: %v", err)
+	}
+	got, err := renderPlaylistDetails(nil, description, Result{}, time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("renderPlaylistDetails() error: %v", err)
+	}
+	if want := "Die Highlights der Woche von Plattentests.de, Stand 16.10.2026: 0 Songs."; got.Description != want {
+		t.Errorf("Description = %q, want %q", got.Description, want)
+	}
+}
+
+func useDetailsTest(t *testing.T, provider *fakeProvider) {
+	t.Helper()
+	useFakeCreatorDependencies(t, provider, []crawler.Record{
+		{Band: "Band A", Recordname: "First Album", Score: 8, ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band A", Trackname: "Opener", IsHighlight: true},
+		}},
+	}, "Band A")
+	prevNow := now
+	now = func() time.Time { return time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { now = prevNow })
+}
+
+func TestCreatePlaylistUpdatesPlaylistDetails(t *testing.T) {
+	provider := newFakeProvider(fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"))
+	provider.playlists["test-playlist"] = nil
+	provider.details["test-playlist"] = [2]string{"Old name", "Old description"}
+	useDetailsTest(t, provider)
+	t.Setenv("PLAYLIST_NAME_TEMPLATE", "Plattentests: {{.RecordOfTheWeek.Band}}")
+
+	preview, err := PreviewPlaylist("")
+	if err != nil {
+		t.Fatalf("PreviewPlaylist() error: %v", err)
+	}
+	if len(provider.calls) != 0 {
+		t.Errorf("dry run changed the playlist: %v", provider.calls)
+	}
+	if preview.Details == nil || preview.Details.Name != "Plattentests: Band A" || preview.Details.Updated {
+		t.Errorf("preview Details = %+v", preview.Details)
+	}
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	want := "Die Highlights der Woche von Plattentests.de, Stand 16.10.2026: 1 Songs. Platte der Woche: Band A - First Album (8/10)."
+	if got := provider.details["test-playlist"]; got != [2]string{"Plattentests: Band A", want} {
+		t.Errorf("playlist details = %q", got)
+	}
+	if result.Details == nil || !result.Details.Updated || result.Details.Error != "" {
+		t.Errorf("Details = %+v", result.Details)
+	}
+
+	// Nothing changed since, so a rerun leaves the details alone.
+	provider.calls = nil
+	result, err = CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("second CreatePlaylist() error: %v", err)
+	}
+	if len(provider.calls) != 0 || result.Details.Updated {
+		t.Errorf("second run calls = %v, Details = %+v", provider.calls, result.Details)
+	}
+}
+
+func TestPlaylistDetailsErrorsDoNotFailTheRun(t *testing.T) {
+	provider := newFakeProvider(fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"))
+	provider.playlists["test-playlist"] = nil
+	useDetailsTest(t, provider)
+	// no record of the week in the template data fails at execution time
+	t.Setenv("PLAYLIST_DESCRIPTION_TEMPLATE", "{{.RecordOfTheWeek.Missing}}")
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	if result.Details == nil || result.Details.Error == "" {
+		t.Errorf("Details = %+v, want an error", result.Details)
+	}
+	if got := provider.playlists["test-playlist"]; len(got) != 1 {
+		t.Errorf("playlist = %v, want the matched track", got)
+	}
+}
+
+func TestInvalidDescriptionTemplateFailsEarly(t *testing.T) {
+	useFakeCreatorDependencies(t, newFakeProvider(), nil, "")
+	t.Setenv("PLAYLIST_DESCRIPTION_TEMPLATE", "{{.Date")
+
+	if _, err := CreatePlaylist(""); err == nil || !strings.Contains(err.Error(), "PLAYLIST_DESCRIPTION_TEMPLATE") {
+		t.Errorf("CreatePlaylist() error = %v, want PLAYLIST_DESCRIPTION_TEMPLATE error", err)
+	}
+}
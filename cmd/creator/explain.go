@@ -0,0 +1,423 @@
+package creator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/jetzlstorfer/plattentests-go/internal/matchcache"
+	"github.com/zmb3/spotify/v2"
+)
+
+// Score components of a candidate, see scoreCandidates.
+const (
+	albumNameMatchScore  = 1000
+	albumTypeAlbumScore  = 100
+	albumTypeSingleScore = 10
+)
+
+// How a track was decided, see MatchExplanation.Source.
+const (
+	MatchSourceSearch   = "search"
+	MatchSourceOverride = "override"
+	MatchSourceCache    = "cache"
+)
+
+// MatchExplanation records how the matcher decided on one highlight.
+type MatchExplanation struct {
+	Band      string
+	Trackname string
+	// Recordname and ReleaseYear are the record context of the first search.
+	Recordname  string
+	ReleaseYear string
+	// Source is one of the MatchSource constants; only searches have attempts.
+	Source   string
+	Attempts []SearchAttempt
+	// Album is how the record was resolved to an album; nil for the per-track strategy.
+	Album *AlbumResolution
+	// TrackID is the accepted match; it is empty when nothing was accepted.
+	TrackID    spotify.ID
+	Confidence float64
+	// Reason sums up the decision.
+	Reason string
+	// Unavailable is set when no track was accepted although one matched, because it cannot
+	// be played in the configured markets.
+	Unavailable bool
+	// LearnedAlias is the Spotify artist name the match taught as a new alias of the band.
+	LearnedAlias string
+	// Notes add what else is known about the track, e.g. an override or a cached match.
+	Notes []string
+}
+
+// Matched reports whether a track was accepted.
+func (e MatchExplanation) Matched() bool {
+	return e.TrackID != ""
+}
+
+// SearchAttempt is one query the matcher sent and what it made of the results.
+type SearchAttempt struct {
+	Query string
+	// Error is set when the search failed.
+	Error      string
+	Candidates []CandidateScore
+	// Outcome says how the attempt ended, e.g. why the matcher tried another query.
+	Outcome string
+}
+
+// CandidateScore is a search result with the components of its score and the verdict.
+type CandidateScore struct {
+	ID        spotify.ID
+	Artists   string
+	Name      string
+	Album     string
+	AlbumType string
+	// Rank is the 1-based position in the search results.
+	Rank int
+	// AlbumNameMatch is set when the track is the title track of the reviewed record.
+	AlbumNameMatch int
+	AlbumTypeScore int
+	// RankScore prefers earlier results when everything else is equal.
+	RankScore int
+	Score     int
+	// ArtistSimilarity and TrackSimilarity range from 0 to 1.
+	ArtistSimilarity float64
+	TrackSimilarity  float64
+	// ArtistAlias is the alias of the band the artist was compared with; empty when the band
+	// name itself compared best.
+	ArtistAlias string
+	// Unplayable is set when the track cannot be played in the configured markets.
+	Unplayable bool
+	// LinkedFrom is the track Spotify relinked to ID, the version playable in the first market.
+	LinkedFrom spotify.ID
+	Selected   bool
+	Accepted   bool
+	Reason     string
+}
+
+// scoreCandidates scores search results, best first; tracks that cannot be played in the
+// configured markets come last. Priority:
+// 1. If track name matches record name, prioritize that
+// 2. Prefer album versions over singles/EPs
+// 3. Use first result as fallback
+func scoreCandidates(tracks []spotify.FullTrack, trackName string, record crawler.Record, config matchConfig, names []bandName) []CandidateScore {
+	normalizedTrackName := normalizeForComparison(trackName)
+	normalizedTitle := normalizeTitle(trackName)
+	normalizedRecordName := normalizeForComparison(record.Recordname)
+
+	candidates := make([]CandidateScore, 0, len(tracks))
+	for i := range tracks {
+		track := &tracks[i]
+		candidate := CandidateScore{
+			ID:        track.ID,
+			Artists:   joinArtists(track.Artists),
+			Name:      track.Name,
+			Album:     track.Album.Name,
+			AlbumType: track.Album.AlbumType,
+			Rank:      i + 1,
+			RankScore: len(tracks) - i,
+		}
+
+		if normalizedTrackName == normalizedRecordName && normalizeForComparison(track.Album.Name) == normalizedRecordName {
+			candidate.AlbumNameMatch = albumNameMatchScore
+			log.Printf(" [Priority] Track name '%s' matches record name '%s' on album '%s'", trackName, record.Recordname, track.Album.Name)
+		}
+		// EP gets no bonus
+		switch track.Album.AlbumType {
+		case "album":
+			candidate.AlbumTypeScore = albumTypeAlbumScore
+		case "single":
+			candidate.AlbumTypeScore = albumTypeSingleScore
+		}
+		candidate.Score = candidate.AlbumNameMatch + candidate.AlbumTypeScore + candidate.RankScore
+		candidate.ArtistSimilarity, candidate.ArtistAlias = config.artistSimilarity(track.Artists, names)
+		candidate.TrackSimilarity = config.similarity(normalizeTitle(track.Name), normalizedTitle)
+		candidate.Unplayable = !playable(*track)
+		if id := originalID(*track); id != track.ID {
+			candidate.LinkedFrom = id
+		}
+
+		candidates = append(candidates, candidate)
+		log.Printf(" [Score %d] %s - %s (%s) [%s]", candidate.Score, candidate.Artists, track.Name, track.Album.Name, track.Album.AlbumType)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Unplayable != candidates[j].Unplayable {
+			return !candidates[i].Unplayable
+		}
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates
+}
+
+// mismatches lists why a candidate's artist or title is not similar enough for config.
+func (c CandidateScore) mismatches(config matchConfig) []string {
+	var mismatches []string
+	if c.ArtistSimilarity < config.minArtist() {
+		mismatches = append(mismatches, fmt.Sprintf("artist similarity %.2f is below %.2f", c.ArtistSimilarity, config.minArtist()))
+	}
+	if c.TrackSimilarity < config.minTitle() {
+		mismatches = append(mismatches, fmt.Sprintf("title similarity %.2f is below %.2f", c.TrackSimilarity, config.minTitle()))
+	}
+	return mismatches
+}
+
+// explainMatch searches a highlight and records every step. The band is searched under its
+// name first; when nothing is accepted, its aliases and its transliteration are searched the
+// same way. See searchTrack for a single band name.
+func explainMatch(provider MusicProvider, config matchConfig, track string, record crawler.Record) (MatchExplanation, error) {
+	explanation := MatchExplanation{
+		Band:        record.Band,
+		Trackname:   track,
+		Recordname:  record.Recordname,
+		ReleaseYear: record.ReleaseYear,
+		Source:      MatchSourceSearch,
+	}
+
+	names := bandNames(config.aliases, record.Band)
+	bands := searchBands(names)
+	for i, band := range bands {
+		if i > 0 {
+			log.Printf(" nothing accepted, searching again as %s", band)
+			explanation.Attempts[len(explanation.Attempts)-1].Outcome += ", searching again as " + band
+		}
+		err := searchTrack(provider, config, &explanation, band, names, track, record)
+		if err != nil || explanation.Matched() {
+			return explanation, err
+		}
+	}
+	if len(bands) > 1 && explanation.Unavailable {
+		explanation.Reason = "found, but " + unplayableReason(provider.Markets())
+	}
+	return explanation, nil
+}
+
+// searchTrack searches a highlight under one band name and adds the attempts to explanation.
+// The record name and year narrow the first search; without results, or without results
+// playable in the configured markets, the search is repeated without them. The best-scored
+// playable candidate is accepted when artist and title are similar enough, or regardless when
+// the search was restricted to the release year. A matching candidate that is not playable
+// makes the track Unavailable.
+func searchTrack(provider MusicProvider, config matchConfig, explanation *MatchExplanation, band string, names []bandName, track string, record crawler.Record) error {
+	for {
+		searchTerm := sanitizeTrackname(band + " " + track)
+		// if record has a year, append it to the search
+		if record.ReleaseYear != "" {
+			searchTerm += " year:" + record.ReleaseYear
+		}
+
+		log.Printf(" searching term: %s", searchTerm)
+		attempt := SearchAttempt{Query: searchTerm}
+		tracks, err := provider.SearchTracks(context.Background(), searchTerm)
+		if err != nil {
+			attempt.Error = err.Error()
+			attempt.Outcome = "search failed"
+			explanation.Attempts = append(explanation.Attempts, attempt)
+			explanation.Reason = "the search failed"
+			return fmt.Errorf("search %q: %w", searchTerm, err)
+		}
+
+		if len(tracks) == 0 {
+			if record.Recordname == "" {
+				log.Printf(" nothing found for %s", searchTerm)
+				attempt.Outcome = "no results"
+				explanation.Attempts = append(explanation.Attempts, attempt)
+				explanation.Reason = "no search results"
+				return nil
+			}
+			log.Println(" nothing found, removing recordname and year from search query")
+			attempt.Outcome = "no results, searching again without record name and year"
+			explanation.Attempts = append(explanation.Attempts, attempt)
+			record.ReleaseYear = ""
+			record.Recordname = ""
+			continue
+		}
+
+		for i, item := range tracks {
+			log.Printf(" found item: %s - %s  (%s) [%s]", item.Artists[0].Name, item.Name, item.Album.Name, item.Album.AlbumType)
+			// only get MAX_SEARCH_RESULTS results
+			if i >= MaxSearchResults-1 {
+				break
+			}
+		}
+
+		attempt.Candidates = scoreCandidates(tracks, track, record, config, names)
+		yearRestricted := record.ReleaseYear != ""
+		best := &attempt.Candidates[0]
+		for i := range attempt.Candidates {
+			if candidate := &attempt.Candidates[i]; candidate.Unplayable {
+				candidate.Reason = unplayableReason(provider.Markets())
+			}
+		}
+		if best.Unplayable {
+			// a match that only exists where our listeners are not
+			if len(best.mismatches(config)) == 0 || yearRestricted {
+				explanation.Unavailable = true
+			}
+			attempt.Outcome = "no playable results"
+			if record.Recordname != "" {
+				log.Println(" nothing playable found, removing recordname and year from search query")
+				attempt.Outcome += ", searching again without record name and year"
+				explanation.Attempts = append(explanation.Attempts, attempt)
+				record.ReleaseYear = ""
+				record.Recordname = ""
+				continue
+			}
+			explanation.Attempts = append(explanation.Attempts, attempt)
+			explanation.Reason = "no playable search results"
+			if explanation.Unavailable {
+				explanation.Reason = "found, but " + unplayableReason(provider.Markets())
+			}
+			return nil
+		}
+
+		best.Selected = true
+		for i := range attempt.Candidates[1:] {
+			if other := &attempt.Candidates[i+1]; !other.Unplayable {
+				other.Reason = fmt.Sprintf("score %d is below the best score %d", other.Score, best.Score)
+			}
+		}
+
+		mismatches := best.mismatches(config)
+		if best.ArtistSimilarity < config.minArtist() {
+			log.Printf(" not adding item %s - %s (%s) since artists don't match (%s)", best.Artists, best.Name, best.Album, record.Band)
+		}
+		if best.TrackSimilarity < config.minTitle() {
+			log.Printf(" not adding item %s - %s (%s) since tracknames don't match (%s)", best.Artists, best.Name, best.Album, track)
+		}
+
+		switch {
+		case len(mismatches) == 0:
+			best.Accepted = true
+			best.Reason = "best score, artist and title match"
+			if best.ArtistAlias != "" {
+				best.Reason += ", the artist as " + best.ArtistAlias
+			}
+		case yearRestricted:
+			best.Accepted = true
+			best.Reason = "best score; " + strings.Join(mismatches, ", ") + ", accepted because the search was restricted to the release year"
+		default:
+			best.Reason = "best score, but " + strings.Join(mismatches, ", ")
+		}
+
+		if !best.Accepted {
+			attempt.Outcome = "best candidate rejected"
+			explanation.Attempts = append(explanation.Attempts, attempt)
+			explanation.Reason = "the best candidate was rejected: " + strings.Join(mismatches, ", ")
+			note := "a matching track was found before, but it is " + unplayableReason(provider.Markets())
+			if explanation.Unavailable && !slices.Contains(explanation.Notes, note) {
+				explanation.Notes = append(explanation.Notes, note)
+			}
+			return nil
+		}
+
+		log.Printf(" using item: %s - %s (%s) [%s]", best.Artists, best.Name, best.Album, best.AlbumType)
+		attempt.Outcome = "accepted " + best.Artists + " - " + best.Name
+		explanation.Attempts = append(explanation.Attempts, attempt)
+		explanation.TrackID = best.ID
+		explanation.Confidence = math.Min(best.ArtistSimilarity, best.TrackSimilarity)
+		explanation.Reason = best.Reason
+		explanation.Unavailable = false
+		if best.LinkedFrom != "" {
+			explanation.Notes = append(explanation.Notes, relinkNote(best.LinkedFrom, provider.Markets()))
+		}
+		if alias := config.learnAlias(names, tracks[best.Rank-1].Artists); alias != "" {
+			explanation.LearnedAlias = alias
+			explanation.Notes = append(explanation.Notes, fmt.Sprintf("learned %s as an alias of %s", alias, record.Band))
+		}
+		return nil
+	}
+}
+
+// relinkNote explains that Spotify replaced track by another version; it relinks for the
+// first market, if any.
+func relinkNote(track spotify.ID, markets []string) string {
+	if len(markets) == 0 {
+		return fmt.Sprintf("Spotify relinked %s to another version", track)
+	}
+	return fmt.Sprintf("Spotify relinked %s to the version playable in %s", track, markets[0])
+}
+
+// searchArtistName is the normalized artist of a search result; a second artist is included
+// because collaborations are often credited to both.
+func searchArtistName(artists []spotify.SimpleArtist) string {
+	if len(artists) == 0 {
+		return ""
+	}
+	name := normalizeArtist(artists[0].Name)
+	if len(artists) > 1 {
+		name += " " + normalizeArtist(artists[1].Name)
+	}
+	return name
+}
+
+func joinArtists(artists []spotify.SimpleArtist) string {
+	names := make([]string, len(artists))
+	for i, artist := range artists {
+		names[i] = artist.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// ExplainMatch searches a track the way a playlist run does and explains the decision. record
+// gives the context of the search, at least the band; with a record name the album-first
+// strategy resolves the album first, or takes it from the match cache. A manual override or a
+// cached match for the track is reported in the notes but does not skip the search.
+func ExplainMatch(record crawler.Record, track string) (MatchExplanation, error) {
+	strategy, err := matchStrategy()
+	if err != nil {
+		return MatchExplanation{}, err
+	}
+	provider, err := newMusicProvider()
+	if err != nil {
+		return MatchExplanation{}, err
+	}
+	matchCfg, err := loadMatchConfig()
+	if err != nil {
+		return MatchExplanation{}, err
+	}
+	matchCache, storage, err := openMatchCache()
+	if err != nil {
+		log.Printf("could not open match cache: %v", err)
+		matchCache = nil
+	}
+	var album *albumLookup
+	if strategy == MatchStrategyAlbum {
+		known := cachedAlbumID(matchCache, record, matchSettings(matchCfg, provider.Markets(), record.Band))
+		if album, err = resolveAlbum(provider, matchCfg, record, known); err != nil {
+			log.Printf("could not resolve album of %s - %s: %v", record.Band, record.Recordname, err)
+		}
+	}
+	// an explanation only reports a learned alias; playlist runs save it
+	explanation, err := explainHighlight(provider, matchCfg, track, record, album)
+	if err != nil {
+		return explanation, err
+	}
+
+	matchOverrides, err := openMatchOverrides()
+	if err != nil {
+		return explanation, err
+	}
+	if override, ok := lookupOverride(matchOverrides, record.Band, track); ok {
+		if override.Skipped() {
+			explanation.Notes = append(explanation.Notes, "a manual override leaves this track out of playlists")
+		} else {
+			explanation.Notes = append(explanation.Notes, "a manual override matches this track to "+override.TrackID+", which playlist runs use instead")
+		}
+	}
+	if matchCache == nil {
+		return explanation, nil
+	}
+	if entry, status := cachedEntry(matchCache, foundCacheKey(record.Band, track), matchSettings(matchCfg, provider.Markets(), record.Band)); status == matchcache.Hit {
+		cached := "a miss"
+		if !entry.Negative() {
+			cached = "match " + entry.TrackID
+		}
+		explanation.Notes = append(explanation.Notes, fmt.Sprintf("the %s match cache holds %s from %s", storage, cached, entry.CachedAt.Format("2006-01-02 15:04")))
+	}
+	return explanation, nil
+}
@@ -0,0 +1,231 @@
+package creator
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/jetzlstorfer/plattentests-go/internal/overrides"
+	"github.com/zmb3/spotify/v2"
+)
+
+func TestExplainMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		catalog     []spotify.FullTrack
+		searchErr   error
+		track       string
+		record      crawler.Record
+		wantID      spotify.ID
+		wantQueries []string
+		wantReason  string
+		// wantCandidates are the IDs of the last attempt, best first
+		wantCandidates []spotify.ID
+		wantErr        bool
+	}{
+		{
+			name: "album version preferred over single",
+			catalog: []spotify.FullTrack{
+				fakeTrack("single", "Band", "Song", "Song", "single", "2024"),
+				fakeTrack("album", "Band", "Song", "Record", "album", "2024"),
+			},
+			track:          "Song",
+			record:         crawler.Record{Band: "Band", Recordname: "Record", ReleaseYear: "2024"},
+			wantID:         "album",
+			wantQueries:    []string{"Band Song year:2024"},
+			wantReason:     "best score, artist and title match",
+			wantCandidates: []spotify.ID{"album", "single"},
+		},
+		{
+			name:           "artist mismatch rejected without a year",
+			catalog:        []spotify.FullTrack{fakeTrack("other", "Band", "Song", "Record", "album", "2024")},
+			track:          "Song",
+			record:         crawler.Record{Band: "Band Of Horses"},
+			wantQueries:    []string{"Band Of Horses Song"},
+			wantReason:     "the best candidate was rejected: artist similarity 0.43 is below 0.80",
+			wantCandidates: []spotify.ID{"other"},
+		},
+		{
+			name:           "artist mismatch accepted within the release year",
+			catalog:        []spotify.FullTrack{fakeTrack("other", "Band", "Song", "Record", "album", "2024")},
+			track:          "Song",
+			record:         crawler.Record{Band: "Band Of Horses", Recordname: "Record", ReleaseYear: "2024"},
+			wantID:         "other",
+			wantQueries:    []string{"Band Of Horses Song year:2024"},
+			wantReason:     "best score; artist similarity 0.43 is below 0.80, accepted because the search was restricted to the release year",
+			wantCandidates: []spotify.ID{"other"},
+		},
+		{
+			name:           "retry without record and year",
+			catalog:        []spotify.FullTrack{fakeTrack("remaster", "Band", "Song", "Record (Remastered)", "album", "2015")},
+			track:          "Song",
+			record:         crawler.Record{Band: "Band", Recordname: "Record", ReleaseYear: "1999"},
+			wantID:         "remaster",
+			wantQueries:    []string{"Band Song year:1999", "Band Song"},
+			wantReason:     "best score, artist and title match",
+			wantCandidates: []spotify.ID{"remaster"},
+		},
+		{
+			name:           "version suffix of the title ignored",
+			catalog:        []spotify.FullTrack{fakeTrack("live", "Band", "Song - Live at Wembley", "Live at Wembley", "album", "2010")},
+			track:          "Song",
+			record:         crawler.Record{Band: "Band"},
+			wantID:         "live",
+			wantQueries:    []string{"Band Song"},
+			wantReason:     "best score, artist and title match",
+			wantCandidates: []spotify.ID{"live"},
+		},
+		{
+			name:        "nothing found",
+			track:       "Song",
+			record:      crawler.Record{Band: "Band", Recordname: "Record", ReleaseYear: "1999"},
+			wantQueries: []string{"Band Song year:1999", "Band Song"},
+			wantReason:  "no search results",
+		},
+		{
+			name:        "search error",
+			searchErr:   errors.New("rate limited"),
+			track:       "Song",
+			record:      crawler.Record{Band: "Band"},
+			wantQueries: []string{"Band Song"},
+			wantReason:  "the search failed",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newFakeProvider(tt.catalog...)
+			provider.searchErr = tt.searchErr
+
+			explanation, err := explainMatch(provider, matchConfig{}, tt.track, tt.record)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("explainMatch() error = %v, want error %v", err, tt.wantErr)
+			}
+			if explanation.TrackID != tt.wantID || explanation.Reason != tt.wantReason {
+				t.Errorf("explainMatch() = %q (%s), want %q (%s)", explanation.TrackID, explanation.Reason, tt.wantID, tt.wantReason)
+			}
+
+			var queries []string
+			for _, attempt := range explanation.Attempts {
+				queries = append(queries, attempt.Query)
+			}
+			if !reflect.DeepEqual(queries, tt.wantQueries) || !reflect.DeepEqual(provider.queries, tt.wantQueries) {
+				t.Errorf("attempts = %q, searched %q, want %q", queries, provider.queries, tt.wantQueries)
+			}
+
+			var candidates []spotify.ID
+			if n := len(explanation.Attempts); n > 0 {
+				for _, candidate := range explanation.Attempts[n-1].Candidates {
+					candidates = append(candidates, candidate.ID)
+				}
+			}
+			if !reflect.DeepEqual(candidates, tt.wantCandidates) {
+				t.Errorf("candidates = %q, want %q", candidates, tt.wantCandidates)
+			}
+		})
+	}
+}
+
+func TestExplainMatchScoreComponents(t *testing.T) {
+	provider := newFakeProvider(
+		fakeTrack("single", "Band", "Record", "Record", "single", "2024"),
+		fakeTrack("compilation", "Band", "Record", "Best Of", "compilation", "2024"),
+		fakeTrack("title-track", "Band", "Record", "Record", "album", "2024"),
+	)
+
+	explanation, err := explainMatch(provider, matchConfig{}, "Record", crawler.Record{Band: "Band", Recordname: "Record", ReleaseYear: "2024"})
+	if err != nil {
+		t.Fatalf("explainMatch() error: %v", err)
+	}
+	got := explanation.Attempts[0].Candidates
+	want := []CandidateScore{
+		{ID: "title-track", Artists: "Band", Name: "Record", Album: "Record", AlbumType: "album", Rank: 3, AlbumNameMatch: 1000, AlbumTypeScore: 100, RankScore: 1, Score: 1101, ArtistSimilarity: 1, TrackSimilarity: 1, Selected: true, Accepted: true, Reason: "best score, artist and title match"},
+		{ID: "single", Artists: "Band", Name: "Record", Album: "Record", AlbumType: "single", Rank: 1, AlbumNameMatch: 1000, AlbumTypeScore: 10, RankScore: 3, Score: 1013, ArtistSimilarity: 1, TrackSimilarity: 1, Reason: "score 1013 is below the best score 1101"},
+		{ID: "compilation", Artists: "Band", Name: "Record", Album: "Best Of", AlbumType: "compilation", Rank: 2, RankScore: 2, Score: 2, ArtistSimilarity: 1, TrackSimilarity: 1, Reason: "score 2 is below the best score 1101"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("candidates =\n%+v\nwant\n%+v", got, want)
+	}
+	if explanation.Confidence != 1 || explanation.Source != MatchSourceSearch {
+		t.Errorf("explanation = %+v", explanation)
+	}
+}
+
+func TestRunExplainsEveryTrack(t *testing.T) {
+	provider := newFakeProvider(fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"))
+	provider.playlists["test-playlist"] = nil
+	useFakeCreatorDependencies(t, provider, []crawler.Record{
+		{Band: "Band A", Recordname: "First Album", Score: 9, ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band A", Trackname: "Opener", IsHighlight: true},
+			{Band: "Band A", Trackname: "Unreleased", IsHighlight: true},
+			{Band: "Band A", Trackname: "Interlude", IsHighlight: true},
+			{Band: "Band A", Trackname: "Manual", IsHighlight: true},
+		}},
+	}, "")
+	t.Setenv("MATCH_OVERRIDES_FILE", filepath.Join(t.TempDir(), "overrides.json"))
+	if err := SaveMatchOverride("Band A", "Interlude", overrides.Skip, ""); err != nil {
+		t.Fatalf("SaveMatchOverride() error: %v", err)
+	}
+	if err := SaveMatchOverride("Band A", "Manual", "m1", "jane@example.com"); err != nil {
+		t.Fatalf("SaveMatchOverride() error: %v", err)
+	}
+
+	if _, err := CreatePlaylist(""); err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	// the second run answers Opener and Unreleased from the match cache
+	result, err := PreviewPlaylist("")
+	if err != nil {
+		t.Fatalf("PreviewPlaylist() error: %v", err)
+	}
+
+	var got []string
+	for _, explanation := range result.Explanations {
+		got = append(got, explanation.Trackname+" "+explanation.Source+" "+string(explanation.TrackID)+": "+explanation.Reason)
+	}
+	want := []string{
+		"Opener cache t1: cached match from",
+		"Unreleased cache : cached miss from",
+		"Interlude override : a manual override leaves this track out",
+		"Manual override m1: matched manually by jane@example.com",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("explanations = %q, want %q", got, want)
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("explanation %d = %q, want prefix %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRunExplainsSearches(t *testing.T) {
+	provider := newFakeProvider(fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"))
+	provider.playlists["test-playlist"] = nil
+	useFakeCreatorDependencies(t, provider, matchCacheRecords, "")
+
+	result, err := PreviewPlaylist("")
+	if err != nil {
+		t.Fatalf("PreviewPlaylist() error: %v", err)
+	}
+	if len(result.Explanations) != 2 {
+		t.Fatalf("explanations = %+v", result.Explanations)
+	}
+	opener, unreleased := result.Explanations[0], result.Explanations[1]
+	if !opener.Matched() || opener.Source != MatchSourceAlbum || len(opener.Attempts) != 1 || len(opener.Attempts[0].Candidates) != 1 {
+		t.Errorf("Opener explanation = %+v", opener)
+	}
+	if opener.Album == nil || opener.Album.Name != "First Album" {
+		t.Errorf("Opener album = %+v", opener.Album)
+	}
+	if unreleased.Matched() || unreleased.Source != MatchSourceSearch || len(unreleased.Attempts) != 2 || unreleased.Reason != "no search results" {
+		t.Errorf("Unreleased explanation = %+v", unreleased)
+	}
+	if want := []string{"not on the album First Album, searched track by track"}; !reflect.DeepEqual(unreleased.Notes, want) {
+		t.Errorf("Unreleased notes = %q, want %q", unreleased.Notes, want)
+	}
+}
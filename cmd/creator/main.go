@@ -2,7 +2,6 @@ package creator
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -13,15 +12,16 @@ import (
 	"unicode"
 
 	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
-	myauth "github.com/jetzlstorfer/plattentests-go/internal/auth"
+	"github.com/jetzlstorfer/plattentests-go/internal/aliases"
+	"github.com/jetzlstorfer/plattentests-go/internal/matchcache"
+	"github.com/jetzlstorfer/plattentests-go/internal/ratelimit"
+	"github.com/jetzlstorfer/plattentests-go/internal/weekly"
 	"github.com/zmb3/spotify/v2"
 	"golang.org/x/text/runes"
 	"golang.org/x/text/transform"
 	"golang.org/x/text/unicode/norm"
 
 	"github.com/kelseyhightower/envconfig"
-
-	"github.com/agnivade/levenshtein"
 )
 
 // MaxSearchResults is the maximum number of search results to return
@@ -30,7 +30,9 @@ const MaxSearchResults = 3
 // MaxRecordsOfTheWeek is the maximum number of records of the week to be considered
 const MaxRecordsOfTheWeek = 25
 
-// MaxConcurrentSpotifySearches limits concurrent Spotify track lookups during playlist creation.
+// MaxConcurrentSpotifySearches limits concurrent Spotify track lookups during playlist creation
+// and found-status checks. It is an upper bound: the request governor of internal/auth lets
+// fewer requests through while Spotify throttles.
 const MaxConcurrentSpotifySearches = 6
 
 var (
@@ -47,9 +49,19 @@ var (
 
 // Result contains playlist creation output records and unmatched tracks.
 type Result struct {
-	Records                 []crawler.Record
-	NotFound                []string
-	PlaylistID              string
+	Records  []crawler.Record
+	NotFound []string
+	// FailedSearches lists the tracks ("Band - Track") whose search failed, e.g. because
+	// Spotify kept throttling it; they are not counted as not found and not cached.
+	FailedSearches []string
+	// Unavailable lists the tracks ("Band - Track") found on Spotify but not playable in the
+	// markets of SPOTIFY_MARKETS; they are not counted as not found either.
+	Unavailable []string
+	PlaylistID  string
+	// PlaylistName is the title of the dated weekly playlist; empty for fixed playlists.
+	PlaylistName string
+	// PlaylistCreated is set when this run created the dated weekly playlist.
+	PlaylistCreated         bool
 	ShowFoundStatus         bool
 	TotalTracks             int
 	FoundTracks             int
@@ -57,98 +69,307 @@ type Result struct {
 	ComparedToProd          bool
 	NewTracksComparedToProd int
 	AlreadyInProdTracks     int
+
+	// DryRun is set when the playlist was left untouched and the result is only a preview.
+	DryRun bool
+	// PlanToken identifies the changes of the run; pass it as Options.Confirm to apply
+	// exactly the changes a preview showed.
+	PlanToken string
+	// Stale is set when a confirmed preview no longer matched the playlist or the matches, so
+	// nothing was applied and the result is a new preview.
+	Stale bool
+	// Planned is the tracklist the playlist consists of after the run, in playlist order.
+	Planned []PlannedTrack
+	// ComparedToCurrent reports whether the playlist could be read before the run; Added,
+	// Removed and Unchanged are only meaningful when it is set.
+	ComparedToCurrent bool
+	Added             []PlannedTrack
+	Removed           []spotify.ID
+	Unchanged         int
+	// Moved counts the reorders needed to bring the remaining tracks into playlist order.
+	Moved int
+
+	// Profile is the playlist profile of the run; nil when the run was not started for one.
+	Profile *Profile
+	// Rules are the selection rules the run used; SkippedRecords the records they left out.
+	Rules          Rules
+	SkippedRecords []SkippedRecord
+	// SkippedTracks lists the tracks ("Band - Track") left out by a manual override;
+	// ManualMatches counts the tracks matched by one instead of a search.
+	SkippedTracks []string
+	ManualMatches int
+	// Unmatched details NotFound with candidates for a manual match; it is only set when
+	// MATCH_OVERRIDES_FILE is configured.
+	Unmatched []UnmatchedTrack
+	// MatchCache reports how many searches the match cache saved.
+	MatchCache *MatchCacheStats
+	// Explanations tell for every searched highlight, in record order, how it was matched
+	// or why it was not.
+	Explanations []MatchExplanation
+	// Duplicates lists the planned tracks collapsed into one version, e.g. a single and the
+	// album version of the same recording.
+	Duplicates []CollapsedDuplicate
+	// LearnedAliases are the Spotify artist names the run learned as aliases of bands.
+	LearnedAliases []aliases.Alias
+	// RateLimit counts the Spotify requests of the run that were throttled, retried or failed
+	// anyway. Runs and lookups at the same time in the same process share the counts.
+	RateLimit ratelimit.Stats
+	// FullAlbums lists the records ("Band - Record") whose complete tracklist was added.
+	FullAlbums []string
+
+	// Archive is set when ARCHIVE_PLAYLIST_ID configures a cumulative playlist.
+	Archive *ArchiveResult
+	// Details is the rendered playlist title and description.
+	Details *PlaylistDetails
+}
+
+// PlannedTrack is a matched highlight together with the Plattentests.de names it was found for.
+type PlannedTrack struct {
+	ID         spotify.ID
+	Band       string
+	Trackname  string
+	Recordname string
 }
 
-var playlistID spotify.ID
+// Options control a playlist run.
+type Options struct {
+	// PlaylistID is the target playlist; empty means PLAYLIST_ID.
+	PlaylistID string
+	// DryRun runs the crawl and the matching but does not modify the playlist.
+	DryRun bool
+	// Confirm is the PlanToken of the preview being applied. When set, the playlist is only
+	// changed if the run arrives at the same changes; otherwise the run turns into a preview
+	// with Result.Stale set.
+	Confirm string
+	// Weekly targets the dated playlist of the current week instead of PlaylistID and creates
+	// it on the first run of the week.
+	Weekly bool
+	// Rules select and order records and tracks; nil uses PLAYLIST_RULES or the defaults.
+	Rules *Rules
+	// Profile names a playlist profile (see LoadProfiles); it replaces PlaylistID, Weekly
+	// and, when the profile defines them, Rules.
+	Profile string
+}
+
+// Crawler dependencies of the creator; tests replace them to avoid hitting Plattentests.de.
+var (
+	getRecordsOfTheWeek        = crawler.GetRecordsOfTheWeekSafe
+	getRecordOfTheWeekBandName = crawler.GetRecordOfTheWeekBandNameSafe
+)
 
 // CreatePlaylist builds the target Spotify playlist from Plattentests highlights.
 func CreatePlaylist(pid string) (Result, error) {
+	return Run(Options{PlaylistID: pid})
+}
+
+// PreviewPlaylist runs the same crawl and matching as CreatePlaylist without touching the
+// playlist and reports what applying it would change.
+func PreviewPlaylist(pid string) (Result, error) {
+	return Run(Options{PlaylistID: pid, DryRun: true})
+}
+
+// Run matches the highlights of the week and, unless opts.DryRun is set, syncs the playlist to
+// the matched tracks. The playlist is only modified once all searches are done.
+func Run(opts Options) (Result, error) {
 	err := envconfig.Process("", &config)
 	if err != nil {
 		return Result{}, fmt.Errorf("load creator config: %w", err)
 	}
 
+	opts, profile, err := applyProfile(opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	nameTemplate, descriptionTemplate, err := detailsTemplates()
+	if err != nil {
+		return Result{}, err
+	}
+	if opts.Weekly {
+		// dated weekly playlists keep their dated name
+		nameTemplate = nil
+	}
+
+	rules, err := runRules(opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	archivePlaylistID, archiveWeeks, err := archiveSettings()
+	if err != nil {
+		return Result{}, err
+	}
+	if profile != nil && !profile.Archive {
+		// scratch and staging profiles must not write into the archive
+		archivePlaylistID = ""
+	}
+
+	matchOverrides, err := openMatchOverrides()
+	if err != nil {
+		return Result{}, err
+	}
+	matchCache, cacheStorage, err := openMatchCache()
+	if err != nil {
+		return Result{}, err
+	}
+	matchCfg, err := loadMatchConfig()
+	if err != nil {
+		return Result{}, err
+	}
+	cacheStats := MatchCacheStats{Storage: cacheStorage}
+	strategy, err := matchStrategy()
+	if err != nil {
+		return Result{}, err
+	}
+
+	pid := opts.PlaylistID
+	playlistID := spotify.ID(pid)
 	if pid == "" {
 		playlistID = spotify.ID(os.Getenv("PLAYLIST_ID"))
+	}
 
-	} else {
-		playlistID = spotify.ID(pid)
+	var weeklyStore *weekly.Store
+	var weeklyPlaylist weekly.Playlist
+	var playlistCreated bool
+	if opts.Weekly {
+		if weeklyStore, err = openWeeklyPlaylists(); err != nil {
+			return Result{}, err
+		}
+		weeklyPlaylist = currentWeeklyPlaylist(weeklyStore)
+		// empty until the playlist of this week has been created
+		playlistID = spotify.ID(weeklyPlaylist.PlaylistID)
 	}
 
 	log.Println("Plattentests.de Highlights of the week playlist generator")
 	log.Println()
 
-	if playlistID == "" || os.Getenv("SPOTIFY_ID") == "" || os.Getenv("SPOTIFY_SECRET") == "" {
+	if (playlistID == "" && !opts.Weekly) || os.Getenv("SPOTIFY_ID") == "" || os.Getenv("SPOTIFY_SECRET") == "" {
 		return Result{}, fmt.Errorf("PLAYLIST_ID, SPOTIFY_ID, or SPOTIFY_SECRET missing")
 	}
 
 	log.Println("Getting tracks of the week...")
-	highlights, err := crawler.GetRecordsOfTheWeekSafe()
+	highlights, err := getRecordsOfTheWeek()
 	if err != nil {
 		return Result{}, fmt.Errorf("get records of the week: %w", err)
 	}
 
-	// only use the first records up to MAX_RECORDS_OF_THE_WEEK
-	// this is mainly for debugging purposes
-	if len(highlights) > MaxRecordsOfTheWeek {
-		highlights = highlights[0:MaxRecordsOfTheWeek]
-	}
-
 	log.Println("Size of records of the week: ", len(highlights))
 
 	log.Println("---")
 	log.Println("Connecting to Spotify")
 	log.Println("---")
 
-	client, err := myauth.VerifyLogin()
+	requestsBefore := spotifyRequestStats()
+	provider, err := newMusicProvider()
 	if err != nil {
-		return Result{}, fmt.Errorf("spotify login failed: %w", err)
-	}
-	ctx := context.Background()
-
-	log.Println("Emptying playlist...")
-	err = client.ReplacePlaylistTracks(ctx, playlistID)
-	if err != nil {
-		return Result{}, fmt.Errorf("empty playlist: %w", err)
+		return Result{}, err
 	}
 
-	// put record of the week first, preserve original order for remaining records
-	recordOfTheWeek, rotweErr := crawler.GetRecordOfTheWeekBandNameSafe()
+	// apply the rules; by default the record of the week goes first, then by score
+	recordOfTheWeek, rotweErr := getRecordOfTheWeekBandName()
 	if rotweErr != nil {
 		log.Printf("could not determine record of the week: %v", rotweErr)
 	}
-	highlights = orderRecordsForPlaylist(highlights, recordOfTheWeek)
+	highlights, skipped := selectRecords(highlights, rules, recordOfTheWeek)
+	for _, record := range skipped {
+		log.Printf("skipping %s: %s", record.Record, record.Reason)
+	}
 
 	log.Println("Adding highlights of the week to playlist...")
 	type highlightSearchJob struct {
 		recordIdx int
 		trackIdx  int
+		// known is set when a manual override or the match cache already decided the
+		// track; knownID is empty for a cached miss
+		known   bool
+		knownID spotify.ID
+		// explanationIdx is the position of the track in explanations
+		explanationIdx int
 	}
 	type highlightSearchResult struct {
-		itemID spotify.ID
-		err    error
+		itemID      spotify.ID
+		confidence  float64
+		searched    bool
+		err         error
+		explanation MatchExplanation
 	}
 
 	jobs := make([]highlightSearchJob, 0)
+	var fullAlbums, skippedTracks []string
+	var explanations []MatchExplanation
+	manualMatches := 0
 	for i := range highlights {
-		for j := range highlights[i].Tracks {
-			if !highlights[i].Tracks[j].IsHighlight {
-				continue
+		if isFullAlbum(highlights[i], rules.FullAlbumMinScore) {
+			fullAlbums = append(fullAlbums, highlights[i].Band+" - "+highlights[i].Recordname)
+		}
+		for _, j := range tracksToSearch(highlights[i], rules) {
+			job := highlightSearchJob{recordIdx: i, trackIdx: j, explanationIdx: len(explanations)}
+			explanation := MatchExplanation{
+				Band:        highlights[i].Band,
+				Trackname:   highlights[i].Tracks[j].Trackname,
+				Recordname:  highlights[i].Recordname,
+				ReleaseYear: highlights[i].ReleaseYear,
+				Source:      MatchSourceSearch,
+			}
+			if override, ok := lookupOverride(matchOverrides, highlights[i].Band, highlights[i].Tracks[j].Trackname); ok {
+				explanation.Source = MatchSourceOverride
+				if override.Skipped() {
+					skippedTracks = append(skippedTracks, highlights[i].Band+" - "+highlights[i].Tracks[j].Trackname)
+					explanation.Reason = "a manual override leaves this track out"
+					explanations = append(explanations, explanation)
+					continue
+				}
+				job.known, job.knownID = true, spotify.ID(override.TrackID)
+				explanation.TrackID, explanation.Confidence = job.knownID, 1
+				explanation.Reason = "matched manually"
+				if override.UpdatedBy != "" {
+					explanation.Reason += " by " + override.UpdatedBy
+				}
+				manualMatches++
+			} else if entry, ok := cachedMatch(matchCache, foundCacheKey(highlights[i].Band, highlights[i].Tracks[j].Trackname), matchSettings(matchCfg, provider.Markets(), highlights[i].Band), &cacheStats); ok {
+				job.known, job.knownID = true, spotify.ID(entry.TrackID)
+				explanation.Source = MatchSourceCache
+				explanation.TrackID, explanation.Confidence = job.knownID, entry.Confidence
+				explanation.Reason = "cached match from " + entry.CachedAt.Format("2006-01-02 15:04")
+				if entry.Negative() {
+					explanation.Reason = "cached miss from " + entry.CachedAt.Format("2006-01-02 15:04")
+				}
+				if entry.Unavailable {
+					explanation.Unavailable = true
+					explanation.Reason = "cached as unavailable from " + entry.CachedAt.Format("2006-01-02 15:04")
+				}
 			}
-			jobs = append(jobs, highlightSearchJob{recordIdx: i, trackIdx: j})
+			explanations = append(explanations, explanation)
+			jobs = append(jobs, job)
 		}
 	}
 
 	total := len(jobs)
-	var notFound []string
+	var notFound, failedSearches, unavailable []string
+	var unmatched []UnmatchedTrack
+	// searched holds the fresh search results for the match cache, stored once the run is
+	// known to update the playlist
+	var searched []cachedSearch
 
 	// collect track IDs record by record, preserving within-record track order
-	var itemsToAddIDs []spotify.ID
+	var planned []PlannedTrack
 	for i := range highlights {
 		record := &highlights[i]
 		log.Println(record.Band + " - " + record.Recordname + ": " + record.Link)
 	}
 
+	// resolve the albums of the records that still need a search
+	var albums []*albumLookup
+	if strategy == MatchStrategyAlbum {
+		needed := make([]bool, len(highlights))
+		for _, job := range jobs {
+			if !job.known {
+				needed[job.recordIdx] = true
+			}
+		}
+		albums = resolveAlbums(provider, matchCfg, matchCache, highlights, needed)
+	}
+
 	if len(jobs) > 0 {
 		results := make([]highlightSearchResult, len(jobs))
 		jobIndexes := make(chan int)
@@ -165,10 +386,18 @@ func CreatePlaylist(pid string) (Result, error) {
 				defer wg.Done()
 				for jobIdx := range jobIndexes {
 					job := jobs[jobIdx]
+					if job.known {
+						results[jobIdx] = highlightSearchResult{itemID: job.knownID}
+						continue
+					}
 					record := highlights[job.recordIdx]
 					track := record.Tracks[job.trackIdx]
-					itemID, searchErr := searchSong(client, track.Trackname, record)
-					results[jobIdx] = highlightSearchResult{itemID: itemID, err: searchErr}
+					var album *albumLookup
+					if albums != nil {
+						album = albums[job.recordIdx]
+					}
+					explanation, searchErr := explainHighlight(provider, matchCfg, track.Trackname, record, album)
+					results[jobIdx] = highlightSearchResult{itemID: explanation.TrackID, confidence: explanation.Confidence, searched: true, err: searchErr, explanation: explanation}
 				}
 			}()
 		}
@@ -183,43 +412,130 @@ func CreatePlaylist(pid string) (Result, error) {
 			record := &highlights[job.recordIdx]
 			track := &record.Tracks[job.trackIdx]
 			result := results[i]
+			if result.searched {
+				explanations[job.explanationIdx] = result.explanation
+			}
 
 			if result.err != nil {
 				log.Printf("search failed for %s - %s: %v", track.Band, track.Trackname, result.err)
-				notFound = append(notFound, track.Band+" - "+track.Trackname)
+				failedSearches = append(failedSearches, track.Band+" - "+track.Trackname)
 				continue
 			}
+			if result.searched {
+				searched = append(searched, cachedSearch{key: foundCacheKey(record.Band, track.Trackname), entry: matchcache.Entry{
+					TrackID:     string(result.itemID),
+					Confidence:  result.confidence,
+					Unavailable: result.explanation.Unavailable,
+					Settings:    matchSettings(matchCfg, provider.Markets(), record.Band),
+				}})
+			}
 
 			if result.itemID != "" {
 				log.Println("adding item to collection to be added: " + result.itemID)
 				track.Found = true
-				itemsToAddIDs = append(itemsToAddIDs, result.itemID)
+				planned = append(planned, PlannedTrack{ID: result.itemID, Band: track.Band, Trackname: track.Trackname, Recordname: record.Recordname})
 				continue
 			}
 
+			if explanations[job.explanationIdx].Unavailable {
+				unavailable = append(unavailable, track.Band+" - "+track.Trackname)
+				continue
+			}
 			notFound = append(notFound, track.Band+" - "+track.Trackname)
+			if matchOverrides != nil {
+				unmatched = append(unmatched, UnmatchedTrack{Band: record.Band, Trackname: track.Trackname, Recordname: record.Recordname})
+			}
 		}
 	}
 
+	// offer candidates for a manual match; without an override store they could not be saved
+	for i := range unmatched {
+		candidates, candidatesErr := searchCandidates(provider, unmatched[i].Band, unmatched[i].Trackname)
+		if candidatesErr != nil {
+			log.Printf("could not search candidates for %s - %s: %v", unmatched[i].Band, unmatched[i].Trackname, candidatesErr)
+			continue
+		}
+		unmatched[i].Candidates = candidates
+	}
+	sort.Slice(unmatched, func(i, j int) bool {
+		return unmatched[i].Band+" - "+unmatched[i].Trackname < unmatched[j].Band+" - "+unmatched[j].Trackname
+	})
+
 	// remove duplicates
 	log.Println("removing duplicates...")
-	noDuplicateTracks := removeDuplicates(itemsToAddIDs)
+	planned = removeDuplicatePlannedTracks(planned)
+	planned, duplicates := consolidateVersions(provider, planned, albumIDs(highlights, albums))
+	noDuplicateTracks := make([]spotify.ID, len(planned))
+	for i, track := range planned {
+		noDuplicateTracks[i] = track.ID
+	}
 
 	// sort notfound tracks
 	sort.Strings(notFound)
 
-	// now add tracks to playlist
-	log.Println("adding tracks to playlist...")
-	if err := addTracks(client, noDuplicateTracks...); err != nil {
-		return Result{}, err
+	var currentTrackIDs []spotify.ID
+	var currentErr error
+	if playlistID != "" {
+		currentTrackIDs, currentErr = playlistTrackIDs(context.Background(), provider, playlistID)
+	}
+	if currentErr != nil {
+		if !opts.DryRun {
+			return Result{}, fmt.Errorf("read playlist %s: %w", playlistID, currentErr)
+		}
+		log.Printf("could not read current playlist %s: %v", playlistID, currentErr)
+	}
+
+	// only touch what differs, so tracks that stay keep their position and "added at" date
+	plan := planSync(currentTrackIDs, noDuplicateTracks)
+	token := planToken(playlistID, currentTrackIDs, noDuplicateTracks)
+	stale := !opts.DryRun && opts.Confirm != "" && opts.Confirm != token
+	if stale {
+		// the playlist, the crawl or a match changed since the preview
+		opts.DryRun = true
+	}
+
+	// search results are worth keeping even when the sync fails, but a preview and a stale
+	// confirmation leave the cache and the aliases as they were
+	learned := learnedAliases(explanations)
+	if !opts.DryRun {
+		for _, search := range searched {
+			matchCache.Put(search.key, search.entry)
+			cacheStats.Stored++
+		}
+		cacheAlbums(matchCache, matchCfg, provider.Markets(), highlights, albums)
+		flushMatchCache(matchCache, &cacheStats)
+		saveArtistAliases(matchCfg.aliases, learned)
+	}
+
+	switch {
+	case stale:
+		log.Println("the changes differ from the confirmed preview, leaving playlist untouched")
+	case opts.DryRun:
+		log.Println("dry run, leaving playlist untouched")
+	case plan.empty():
+		log.Println("playlist is already up to date")
+	default:
+		if playlistID == "" {
+			playlistCreated = true
+			if weeklyPlaylist, err = createWeeklyPlaylist(context.Background(), provider, weeklyStore, weeklyPlaylist); err != nil {
+				return Result{}, err
+			}
+			playlistID = spotify.ID(weeklyPlaylist.PlaylistID)
+		}
+		log.Printf("syncing playlist: %d removal(s), %d addition(s), %d move(s)", len(plan.Remove), len(plan.Add), len(plan.Moves))
+		if err := applySync(context.Background(), provider, playlistID, plan); err != nil {
+			return Result{}, err
+		}
 	}
 
 	log.Println()
 	log.Println("--- RESULTS ---")
 	log.Println()
 	log.Println("total tracks:     ", total)
-	log.Println("found tracks:     ", total-len(notFound))
+	log.Println("found tracks:     ", total-len(notFound)-len(failedSearches)-len(unavailable))
 	log.Println("not found tracks: ", len(notFound))
+	log.Println("unavailable:      ", len(unavailable))
+	log.Println("failed searches:  ", len(failedSearches))
 	log.Println()
 	log.Println("Not found items: ")
 
@@ -232,20 +548,44 @@ func CreatePlaylist(pid string) (Result, error) {
 	outputJSON["highlights"] = highlights
 	outputJSON["notFound"] = notFound
 
-	foundTracks := total - len(notFound)
+	foundTracks := total - len(notFound) - len(failedSearches) - len(unavailable)
 	result := Result{
 		Records:           highlights,
 		NotFound:          notFound,
+		FailedSearches:    failedSearches,
+		Unavailable:       unavailable,
 		PlaylistID:        string(playlistID),
 		ShowFoundStatus:   true,
 		TotalTracks:       total,
 		FoundTracks:       foundTracks,
 		SearchSuccessRate: calculateSearchSuccessRate(foundTracks, total),
+		DryRun:            opts.DryRun,
+		PlanToken:         token,
+		Stale:             stale,
+		Planned:           planned,
+		PlaylistName:      weeklyPlaylist.Name,
+		PlaylistCreated:   playlistCreated,
+		FullAlbums:        fullAlbums,
+		Rules:             rules,
+		Profile:           profile,
+		SkippedRecords:    skipped,
+		SkippedTracks:     skippedTracks,
+		ManualMatches:     manualMatches,
+		Unmatched:         unmatched,
+		MatchCache:        &cacheStats,
+		Explanations:      explanations,
+		Duplicates:        duplicates,
+		LearnedAliases:    learned,
+	}
+	if currentErr == nil {
+		result.ComparedToCurrent = true
+		result.Added, result.Removed, result.Unchanged = diffPlaylist(planned, currentTrackIDs)
+		result.Moved = len(plan.Moves)
 	}
 
 	prodPlaylistID := strings.TrimSpace(os.Getenv("PLAYLIST_ID_PROD"))
 	if prodPlaylistID != "" && spotify.ID(prodPlaylistID) != playlistID {
-		prodTrackIDs, prodErr := getPlaylistTrackIDs(client, spotify.ID(prodPlaylistID))
+		prodTrackIDs, prodErr := getPlaylistTrackIDs(provider, spotify.ID(prodPlaylistID))
 		if prodErr != nil {
 			log.Printf("could not compare against production playlist %s: %v", prodPlaylistID, prodErr)
 		} else {
@@ -256,9 +596,75 @@ func CreatePlaylist(pid string) (Result, error) {
 		}
 	}
 
+	if archivePlaylistID != "" && archivePlaylistID != playlistID {
+		archive, archiveErr := updateArchivePlaylist(context.Background(), provider, archivePlaylistID, archiveWeeks, noDuplicateTracks, opts.DryRun)
+		if archiveErr != nil {
+			log.Printf("could not update archive playlist %s: %v", archivePlaylistID, archiveErr)
+			archive = ArchiveResult{PlaylistID: string(archivePlaylistID), Weeks: archiveWeeks, Error: "The archive playlist could not be updated; see the logs for details."}
+		}
+		result.Archive = &archive
+	}
+
+	details, detailsErr := renderPlaylistDetails(nameTemplate, descriptionTemplate, result, now())
+	if detailsErr == nil && playlistID != "" && !opts.DryRun {
+		details.Updated, detailsErr = updatePlaylistDetails(context.Background(), provider, playlistID, details)
+	}
+	if detailsErr != nil {
+		log.Printf("could not update playlist details: %v", detailsErr)
+		details.Error = "The playlist title and description could not be updated; see the logs for details."
+	}
+	result.Details = &details
+	result.RateLimit = spotifyRequestStats().Sub(requestsBefore)
+	if result.RateLimit.Throttled > 0 {
+		log.Printf("spotify throttled %d request(s), %d retried, %d failed; %d request(s) in flight allowed", result.RateLimit.Throttled, result.RateLimit.Retries, result.RateLimit.Failed, result.RateLimit.Limit)
+	}
+
 	return result, nil
 }
 
+// cachedSearch is a search result waiting to be stored in the match cache.
+type cachedSearch struct {
+	key   string
+	entry matchcache.Entry
+}
+
+// diffPlaylist compares the planned tracklist with the current playlist items.
+func diffPlaylist(planned []PlannedTrack, current []spotify.ID) (added []PlannedTrack, removed []spotify.ID, unchanged int) {
+	inCurrent := make(map[spotify.ID]struct{}, len(current))
+	for _, id := range current {
+		inCurrent[id] = struct{}{}
+	}
+	inPlanned := make(map[spotify.ID]struct{}, len(planned))
+	for _, track := range planned {
+		inPlanned[track.ID] = struct{}{}
+		if _, ok := inCurrent[track.ID]; ok {
+			unchanged++
+			continue
+		}
+		added = append(added, track)
+	}
+	for _, id := range removeDuplicates(current) {
+		// items that are no tracks are never removed
+		if _, ok := inPlanned[id]; !ok && id != "" {
+			removed = append(removed, id)
+		}
+	}
+	return added, removed, unchanged
+}
+
+func removeDuplicatePlannedTracks(tracks []PlannedTrack) []PlannedTrack {
+	seen := make(map[spotify.ID]bool, len(tracks))
+	var unique []PlannedTrack
+	for _, track := range tracks {
+		if seen[track.ID] {
+			continue
+		}
+		seen[track.ID] = true
+		unique = append(unique, track)
+	}
+	return unique
+}
+
 func orderRecordsForPlaylist(records []crawler.Record, recordOfTheWeek string) []crawler.Record {
 	ordered := append([]crawler.Record(nil), records...)
 
@@ -293,189 +699,72 @@ func orderRecordsForPlaylist(records []crawler.Record, recordOfTheWeek string) [
 // 2. Prefer album versions over singles/EPs
 // 3. Use first result as fallback
 func selectBestTrack(tracks []spotify.FullTrack, trackName string, record crawler.Record) *spotify.FullTrack {
-	if len(tracks) == 0 {
+	candidates := scoreCandidates(tracks, trackName, record, matchConfig{}, bandNames(nil, record.Band))
+	if len(candidates) == 0 {
 		return nil
 	}
-
-	normalizedTrackName := normalizeForComparison(trackName)
-	normalizedRecordName := normalizeForComparison(record.Recordname)
-
-	type scoredTrack struct {
-		track *spotify.FullTrack
-		score int
-	}
-
-	var scored []scoredTrack
-
-	for i := range tracks {
-		track := &tracks[i]
-		score := 0
-
-		// Priority 1: Track name matches record name
-		normalizedAlbumName := normalizeForComparison(track.Album.Name)
-		if normalizedTrackName == normalizedRecordName && normalizedAlbumName == normalizedRecordName {
-			score += 1000
-			log.Printf(" [Priority] Track name '%s' matches record name '%s' on album '%s'", trackName, record.Recordname, track.Album.Name)
-		}
-
-		// Priority 2: Prefer album over single/EP
-		switch track.Album.AlbumType {
-		case "album":
-			score += 100
-		case "single":
-			score += 10
-		}
-		// EP gets no bonus (score += 0)
-
-		// Priority 3: Earlier results get slight tiebreaker preference (all else being equal)
-		score += (len(tracks) - i)
-
-		scored = append(scored, scoredTrack{track: track, score: score})
-		log.Printf(" [Score %d] %s - %s (%s) [%s]", score, track.Artists[0].Name, track.Name, track.Album.Name, track.Album.AlbumType)
-	}
-
-	// Sort by score descending
-	sort.Slice(scored, func(i, j int) bool {
-		return scored[i].score > scored[j].score
-	})
-
-	return scored[0].track
+	return &tracks[candidates[0].Rank-1]
 }
 
-// searches a song given by the track and record name and returns spotify.ID if successful
-func searchSong(client spotify.Client, track string, record crawler.Record) (spotify.ID, error) {
-	searchTerm := sanitizeTrackname(record.Band + " " + track)
-	// POTENTIAL FIX - do not include recordname in search
-	//searchTerm = searchTerm + " " + record.Recordname
-
-	// if record has a year, append it to the search
-	if record.ReleaseYear != "" {
-		searchTerm += " year:" + record.ReleaseYear
-	}
-
-	log.Printf(" searching term: %s", searchTerm)
-	results, err := client.Search(context.Background(), searchTerm, spotify.SearchTypeTrack)
-	if err != nil {
-		return "", fmt.Errorf("search %q: %w", searchTerm, err)
-	}
-	// handle track results only if tracks are available
-	if results.Tracks != nil && results.Tracks.Tracks != nil && len(results.Tracks.Tracks) > 0 {
-		for i, item := range results.Tracks.Tracks {
-			log.Printf(" found item: %s - %s  (%s) [%s]", item.Artists[0].Name, item.Name, item.Album.Name, item.Album.AlbumType)
-			// only get MAX_SEARCH_RESULTS results
-			if i >= MaxSearchResults-1 {
-				break
-			}
-		}
-
-		// Select best match from results with prioritization
-		item := selectBestTrack(results.Tracks.Tracks, track, record)
-		if item == nil {
-			log.Printf(" no suitable match found after filtering")
-			if record.Recordname == "" {
-				return "", nil
-			}
-			log.Println(" nothing found, removing recordname and year from search query")
-			newRecord := record
-			newRecord.ReleaseYear = ""
-			newRecord.Recordname = ""
-			return searchSong(client, track, newRecord)
-		}
-
-		bandnameFromSearch := normalizeForComparison(item.Artists[0].Name)
-		if len(item.Artists) > 1 {
-			bandnameFromSearch += " " + normalizeForComparison(item.Artists[1].Name)
-		}
-
-		bandnameFromPlattentests := normalizeForComparison(record.Band)
-		distance := levenshtein.ComputeDistance(bandnameFromSearch, bandnameFromPlattentests)
-		log.Println(" Levenshtein distance between", bandnameFromSearch, "and", bandnameFromPlattentests, ":", distance)
-		threshold := 0.8
-
-		calculatedThreshold := 1 - float64(distance)/float64(maxInt(len(bandnameFromSearch), len(bandnameFromPlattentests)))
-		if (calculatedThreshold) < threshold {
-			log.Println(" Levenshtein distance too large")
-			log.Printf(" not adding item %s - %s (%s) since artists don't match (%s != %s)", bandnameFromSearch, item.Name, item.Album.Name, bandnameFromPlattentests, bandnameFromSearch)
-			if record.ReleaseYear == "" {
-				return "", nil
-			}
-		}
-
-		// calculate the levenshtein distance between the trackname from the search and the trackname from the record
-		tracknameFromSearch := normalizeForComparison(item.Name)
-		tracknameFromPlattentests := normalizeForComparison(track)
-		distance = levenshtein.ComputeDistance(tracknameFromSearch, tracknameFromPlattentests)
-
-		calculatedThreshold = 1 - float64(distance)/float64(maxInt(len(tracknameFromSearch), len(tracknameFromPlattentests)))
-		if (calculatedThreshold) < threshold {
-			log.Println(" Levenshtein distance too large")
-			log.Printf(" not adding item %s - %s (%s) since tracknames don't match (%s != %s)", bandnameFromSearch, item.Name, item.Album.Name, tracknameFromPlattentests, tracknameFromSearch)
-			if record.ReleaseYear == "" {
-				return "", nil
-			}
-		}
-
-		log.Printf(" using item: %s - %s (%s) [%s]", bandnameFromSearch, item.Name, item.Album.Name, item.Album.AlbumType)
-		return item.ID, nil
-	}
+// trackMatch is the result of a search; an empty ID means nothing matched.
+type trackMatch struct {
+	ID spotify.ID
+	// Confidence is the lower of the artist and title similarity, from 0 to 1.
+	Confidence float64
+	// Unavailable is set when a matching track cannot be played in the configured markets.
+	Unavailable bool
+}
 
-	if record.Recordname == "" {
-		log.Printf(" nothing found for %s", searchTerm)
-		return "", nil
-	}
-	log.Println(" nothing found, removing recordname and year from search query")
-	newRecord := record
-	newRecord.ReleaseYear = ""
-	newRecord.Recordname = ""
-	return searchSong(client, track, newRecord)
+// searches a song given by the track and record name, without aliases and with the default
+// similarity scorer, and returns spotify.ID if successful
+func searchSong(provider MusicProvider, track string, record crawler.Record) (spotify.ID, error) {
+	match, err := matchSong(provider, matchConfig{}, track, record)
+	return match.ID, err
+}
 
+// matchSong searches a song like searchSong, compared as config says, and reports how
+// confident the match is.
+func matchSong(provider MusicProvider, config matchConfig, track string, record crawler.Record) (trackMatch, error) {
+	explanation, err := explainMatch(provider, config, track, record)
+	return trackMatch{ID: explanation.TrackID, Confidence: explanation.Confidence, Unavailable: explanation.Unavailable}, err
 }
 
-// adds tracks to global playlist ID
-func addTracks(client spotify.Client, trackids ...spotify.ID) error {
+// adds tracks to the given playlist
+func addTracks(provider MusicProvider, playlistID spotify.ID, trackids ...spotify.ID) error {
 	if len(trackids) == 0 {
 		log.Println("no tracks to add")
 		return nil
 	}
-	_, err := client.AddTracksToPlaylist(context.Background(), playlistID, trackids...)
-	if err != nil {
-		return fmt.Errorf("could not add tracks to playlist: %w", err)
+	for _, chunk := range chunkIDs(trackids, maxTracksPerRequest) {
+		err := provider.AddTracksToPlaylist(context.Background(), playlistID, chunk...)
+		if err != nil {
+			return fmt.Errorf("could not add tracks to playlist: %w", err)
+		}
 	}
 	return nil
 
 }
 
-func getPlaylistTrackIDs(client spotify.Client, id spotify.ID) (map[spotify.ID]struct{}, error) {
-	trackIDs := make(map[spotify.ID]struct{})
-	page, err := client.GetPlaylistItems(context.Background(), id, spotify.Limit(100))
+func getPlaylistTrackIDs(provider MusicProvider, id spotify.ID) (map[spotify.ID]struct{}, error) {
+	ids, err := playlistTrackIDs(context.Background(), provider, id)
 	if err != nil {
 		return nil, fmt.Errorf("get playlist items for %s: %w", id, err)
 	}
 
-	for {
-		for _, item := range page.Items {
-			track := item.Track.Track
-			if track == nil || track.ID == "" {
-				continue
-			}
-			trackIDs[track.ID] = struct{}{}
-		}
-
-		if err := client.NextPage(context.Background(), page); err != nil {
-			if errors.Is(err, spotify.ErrNoMorePages) {
-				break
-			}
-			return nil, fmt.Errorf("paginate playlist %s: %w", id, err)
+	trackIDs := make(map[spotify.ID]struct{}, len(ids))
+	for _, trackID := range ids {
+		if trackID != "" {
+			trackIDs[trackID] = struct{}{}
 		}
 	}
-
 	return trackIDs, nil
 }
 
 // MarkFoundTracks marks each highlight track as found when it can be located on Spotify, using
-// the same search and fuzzy-matching logic as playlist creation. Lookups run concurrently and are
-// cached in-memory so listing pages can show a found indicator without repeating the searches on
-// every request.
+// the same search and fuzzy-matching logic as playlist creation. Lookups run concurrently and
+// read the match cache of playlist runs, so listing pages can show a found indicator without
+// repeating their searches. Like a preview it updates no playlist, so it writes neither the
+// cache nor learned aliases.
 func MarkFoundTracks(records []crawler.Record) error {
 	if len(records) == 0 {
 		return nil
@@ -485,14 +774,26 @@ func MarkFoundTracks(records []crawler.Record) error {
 		return fmt.Errorf("load creator config: %w", err)
 	}
 
-	client, err := myauth.VerifyLogin()
+	provider, err := newMusicProvider()
+	if err != nil {
+		return err
+	}
+	matchOverrides, err := openMatchOverrides()
+	if err != nil {
+		return err
+	}
+	matchCache, _, err := openMatchCache()
+	if err != nil {
+		return err
+	}
+	matchCfg, err := loadMatchConfig()
 	if err != nil {
-		return fmt.Errorf("spotify login failed: %w", err)
+		return err
 	}
 
-	const maxConcurrentLookups = 8
-	sem := make(chan struct{}, maxConcurrentLookups)
+	sem := make(chan struct{}, MaxConcurrentSpotifySearches)
 	var wg sync.WaitGroup
+	var stats MatchCacheStats
 
 	for i := range records {
 		record := records[i]
@@ -501,29 +802,29 @@ func MarkFoundTracks(records []crawler.Record) error {
 			if !track.IsHighlight {
 				continue
 			}
+			if override, ok := lookupOverride(matchOverrides, record.Band, track.Trackname); ok {
+				track.Found = !override.Skipped()
+				continue
+			}
 			key := foundCacheKey(record.Band, track.Trackname)
-
-			if found, ok := lookupFoundCache(key); ok {
-				track.Found = found
+			if entry, ok := cachedMatch(matchCache, key, matchSettings(matchCfg, provider.Markets(), record.Band), &stats); ok {
+				track.Found = !entry.Negative()
 				continue
 			}
 
 			wg.Add(1)
 			sem <- struct{}{}
-			go func(track *crawler.Track, record crawler.Record, key string) {
+			go func(track *crawler.Track, record crawler.Record) {
 				defer wg.Done()
 				defer func() { <-sem }()
 
-				id, searchErr := searchSong(client, track.Trackname, record)
+				match, searchErr := matchSong(provider, matchCfg, track.Trackname, record)
 				if searchErr != nil {
 					log.Printf("found-status search failed for %s - %s: %v", record.Band, track.Trackname, searchErr)
 					return
 				}
-
-				found := id != ""
-				storeFoundCache(key, found)
-				track.Found = found
-			}(track, record, key)
+				track.Found = match.ID != ""
+			}(track, record)
 		}
 	}
 
@@ -531,26 +832,16 @@ func MarkFoundTracks(records []crawler.Record) error {
 	return nil
 }
 
-var (
-	foundCacheMu sync.RWMutex
-	foundCache   = make(map[string]bool)
-)
-
+// foundCacheKey is the match cache key of a track. It identifies a track independent of case
+// and punctuation, which is also how the rules exclude tracks.
 func foundCacheKey(band, trackName string) string {
 	return normalizeForComparison(band) + "\x00" + normalizeForComparison(trackName)
 }
 
-func lookupFoundCache(key string) (bool, bool) {
-	foundCacheMu.RLock()
-	defer foundCacheMu.RUnlock()
-	found, ok := foundCache[key]
-	return found, ok
-}
-
-func storeFoundCache(key string, found bool) {
-	foundCacheMu.Lock()
-	defer foundCacheMu.Unlock()
-	foundCache[key] = found
+// albumCacheKey is the match cache key of the album a record was resolved to; the prefix
+// keeps it apart from the track keys of foundCacheKey.
+func albumCacheKey(band, recordname string) string {
+	return "album\x00" + normalizeForComparison(band) + "\x00" + normalizeForComparison(recordname)
 }
 
 func calculateSearchSuccessRate(found, total int) float64 {
@@ -0,0 +1,54 @@
+package creator
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// spotifyMarkets reads SPOTIFY_MARKETS, a comma-separated list of ISO 3166-1 alpha-2 country
+// codes such as "AT,DE". Matches must be playable in every listed market; the first one is
+// passed to the searches, so Spotify relinks tracks to the version available there. Empty
+// means matches are chosen without a market.
+func spotifyMarkets() ([]string, error) {
+	value := strings.TrimSpace(os.Getenv("SPOTIFY_MARKETS"))
+	if value == "" {
+		return nil, nil
+	}
+
+	var markets []string
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(value, ",") {
+		market := strings.ToUpper(strings.TrimSpace(field))
+		if len(market) != 2 || market[0] < 'A' || market[0] > 'Z' || market[1] < 'A' || market[1] > 'Z' {
+			return nil, fmt.Errorf("SPOTIFY_MARKETS must list two-letter country codes like AT,DE, got %q", field)
+		}
+		if !seen[market] {
+			seen[market] = true
+			markets = append(markets, market)
+		}
+	}
+	return markets, nil
+}
+
+// playable reports whether Spotify reported a track as playable in the configured markets;
+// tracks looked up without a market count as playable.
+func playable(track spotify.FullTrack) bool {
+	return track.IsPlayable == nil || *track.IsPlayable
+}
+
+// originalID is the ID a track was requested by; Spotify relinks a track that is not
+// available in the market to another version and reports the original in LinkedFrom.
+func originalID(track spotify.FullTrack) spotify.ID {
+	if track.LinkedFrom != nil && track.LinkedFrom.ID != "" {
+		return track.LinkedFrom.ID
+	}
+	return track.ID
+}
+
+// unplayableReason names the markets a track cannot be played in.
+func unplayableReason(markets []string) string {
+	return "not playable in " + strings.Join(markets, ", ")
+}
@@ -0,0 +1,210 @@
+package creator
+
+import (
+	"reflect"
+	"testing"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/jetzlstorfer/plattentests-go/internal/matchcache"
+	"github.com/zmb3/spotify/v2"
+)
+
+// inMarkets limits where track can be played.
+func inMarkets(track spotify.FullTrack, markets ...string) spotify.FullTrack {
+	track.AvailableMarkets = markets
+	return track
+}
+
+func TestSpotifyMarkets(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: " at, de ", want: []string{"AT", "DE"}},
+		{value: "DE,AT,DE", want: []string{"DE", "AT"}},
+		{value: "AUT", wantErr: true},
+		{value: "A1", wantErr: true},
+		{value: "AT,", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("SPOTIFY_MARKETS", tt.value)
+			got, err := spotifyMarkets()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("spotifyMarkets() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("spotifyMarkets() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExplainMatchInMarkets(t *testing.T) {
+	record := crawler.Record{Band: "Band", Recordname: "Record", ReleaseYear: "2024"}
+	tests := []struct {
+		name            string
+		catalog         []spotify.FullTrack
+		relinks         map[spotify.ID]spotify.FullTrack
+		wantID          spotify.ID
+		wantReason      string
+		wantUnavailable bool
+		wantQueries     int
+		wantNotes       []string
+	}{
+		{
+			name: "playable single instead of the album version",
+			catalog: []spotify.FullTrack{
+				inMarkets(fakeTrack("album", "Band", "Song", "Record", "album", "2024"), "US"),
+				fakeTrack("single", "Band", "Song", "Song", "single", "2024"),
+			},
+			wantID:      "single",
+			wantReason:  "best score, artist and title match",
+			wantQueries: 1,
+		},
+		{
+			name: "playable in only one of the markets",
+			catalog: []spotify.FullTrack{
+				inMarkets(fakeTrack("album", "Band", "Song", "Record", "album", "2024"), "AT"),
+				inMarkets(fakeTrack("single", "Band", "Song", "Song", "single", "2024"), "AT", "DE", "CH"),
+			},
+			wantID:      "single",
+			wantReason:  "best score, artist and title match",
+			wantQueries: 1,
+		},
+		{
+			name: "searched again for a playable release",
+			catalog: []spotify.FullTrack{
+				inMarkets(fakeTrack("original", "Band", "Song", "Record", "album", "2024"), "US"),
+				fakeTrack("reissue", "Band", "Song", "Record (Reissue)", "album", "2015"),
+			},
+			wantID:      "reissue",
+			wantReason:  "best score, artist and title match",
+			wantQueries: 2,
+		},
+		{
+			name:            "found but not playable",
+			catalog:         []spotify.FullTrack{inMarkets(fakeTrack("album", "Band", "Song", "Record", "album", "2024"), "US")},
+			wantReason:      "found, but not playable in AT, DE",
+			wantUnavailable: true,
+			wantQueries:     2,
+		},
+		{
+			name:        "relinked version",
+			catalog:     []spotify.FullTrack{fakeTrack("album", "Band", "Song", "Record", "album", "2024")},
+			relinks:     map[spotify.ID]spotify.FullTrack{"album": fakeTrack("album-at", "Band", "Song", "Record", "album", "2024")},
+			wantID:      "album-at",
+			wantReason:  "best score, artist and title match",
+			wantQueries: 1,
+			wantNotes:   []string{"Spotify relinked album to the version playable in AT"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newFakeProvider(tt.catalog...)
+			provider.markets = []string{"AT", "DE"}
+			provider.relinks = tt.relinks
+
+			explanation, err := explainMatch(provider, matchConfig{}, "Song", record)
+			if err != nil {
+				t.Fatalf("explainMatch() error: %v", err)
+			}
+			if explanation.TrackID != tt.wantID || explanation.Reason != tt.wantReason || explanation.Unavailable != tt.wantUnavailable {
+				t.Errorf("explainMatch() = %q (%s, unavailable %v), want %q (%s, unavailable %v)",
+					explanation.TrackID, explanation.Reason, explanation.Unavailable, tt.wantID, tt.wantReason, tt.wantUnavailable)
+			}
+			if len(provider.queries) != tt.wantQueries {
+				t.Errorf("queries = %q, want %d", provider.queries, tt.wantQueries)
+			}
+			if !reflect.DeepEqual(explanation.Notes, tt.wantNotes) {
+				t.Errorf("notes = %q, want %q", explanation.Notes, tt.wantNotes)
+			}
+			for _, attempt := range explanation.Attempts {
+				for _, candidate := range attempt.Candidates {
+					if candidate.Unplayable && (candidate.Accepted || candidate.Reason != "not playable in AT, DE") {
+						t.Errorf("unplayable candidate %+v", candidate)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestExplainMatchRelinkedWithoutMarkets(t *testing.T) {
+	relinked := fakeTrack("album-at", "Band", "Song", "Record", "album", "2024")
+	relinked.LinkedFrom = &spotify.LinkedFromInfo{ID: "album"}
+	provider := newFakeProvider(relinked)
+
+	explanation, err := explainMatch(provider, matchConfig{}, "Song", crawler.Record{Band: "Band", Recordname: "Record", ReleaseYear: "2024"})
+	if err != nil {
+		t.Fatalf("explainMatch() error: %v", err)
+	}
+	if want := []string{"Spotify relinked album to another version"}; explanation.TrackID != "album-at" || !reflect.DeepEqual(explanation.Notes, want) {
+		t.Errorf("explainMatch() = %q, notes %q, want album-at, notes %q", explanation.TrackID, explanation.Notes, want)
+	}
+}
+
+func TestMatchAlbumTrackInMarkets(t *testing.T) {
+	provider := newFakeProvider(
+		fakeTrack("t1", "Band", "Opener", "Record", "album", "2024"),
+		inMarkets(fakeTrack("t2", "Band", "Closer", "Record", "album", "2024"), "US"),
+	)
+	provider.markets = []string{"AT"}
+	provider.relinks = map[spotify.ID]spotify.FullTrack{"t1": fakeTrack("t1-at", "Band", "Opener", "Record", "album", "2024")}
+	record := crawler.Record{Band: "Band", Recordname: "Record"}
+	album, err := resolveAlbum(provider, matchConfig{}, record, "")
+	if err != nil || !album.found() {
+		t.Fatalf("resolveAlbum() = %+v, %v", album.resolution, err)
+	}
+
+	opener, ok := matchAlbumTrack(album, "Opener", record)
+	if !ok || opener.TrackID != "t1-at" || opener.Attempts[0].Candidates[0].LinkedFrom != "t1" {
+		t.Errorf("Opener = %q, %v, %+v", opener.TrackID, ok, opener.Attempts)
+	}
+	closer, ok := matchAlbumTrack(album, "Closer", record)
+	if ok || closer.TrackID != "" || !closer.Unavailable {
+		t.Errorf("Closer = %q, %v, unavailable %v", closer.TrackID, ok, closer.Unavailable)
+	}
+}
+
+func TestRunReportsUnavailableTracks(t *testing.T) {
+	provider := newFakeProvider(
+		fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"),
+		inMarkets(fakeTrack("t2", "Band A", "Closer", "First Album", "album", "2024"), "US"),
+	)
+	provider.markets = []string{"AT", "DE"}
+	provider.playlists["test-playlist"] = nil
+	useFakeCreatorDependencies(t, provider, []crawler.Record{
+		{Band: "Band A", Recordname: "First Album", Score: 8, ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band A", Trackname: "Opener", IsHighlight: true},
+			{Band: "Band A", Trackname: "Closer", IsHighlight: true},
+			{Band: "Band A", Trackname: "Unreleased", IsHighlight: true},
+		}},
+	}, "")
+
+	// the second run answers every track from the match cache
+	for run := 1; run <= 2; run++ {
+		result, err := CreatePlaylist("")
+		if err != nil {
+			t.Fatalf("run %d: CreatePlaylist() error: %v", run, err)
+		}
+		if !reflect.DeepEqual(result.Unavailable, []string{"Band A - Closer"}) || !reflect.DeepEqual(result.NotFound, []string{"Band A - Unreleased"}) {
+			t.Errorf("run %d: unavailable %v, not found %v", run, result.Unavailable, result.NotFound)
+		}
+		if result.FoundTracks != 1 || len(result.Planned) != 1 || result.Planned[0].ID != "t1" {
+			t.Errorf("run %d: found %d, planned %+v", run, result.FoundTracks, result.Planned)
+		}
+		if closer := result.Explanations[1]; !closer.Unavailable {
+			t.Errorf("run %d: Closer explanation = %+v", run, closer)
+		}
+	}
+
+	cache, _, _ := openMatchCache()
+	if entry, status := cache.Get(foundCacheKey("Band A", "Closer")); status != matchcache.Hit || !entry.Unavailable || !entry.Negative() {
+		t.Errorf("cached Closer = %+v (%v)", entry, status)
+	}
+}
@@ -0,0 +1,144 @@
+package creator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jetzlstorfer/plattentests-go/internal/matchcache"
+)
+
+// Default lifetimes of cached matches and of cached misses.
+const (
+	defaultMatchCacheTTL         = 30 * 24 * time.Hour
+	defaultMatchCacheNegativeTTL = 24 * time.Hour
+)
+
+// MatchCacheStats counts how the match cache served the searches of a run.
+type MatchCacheStats struct {
+	// Storage is "file", "blob" or "memory" for the process-local fallback.
+	Storage string
+	// Hits were served from the cache; NegativeHits are the hits that recorded a miss.
+	Hits         int
+	NegativeHits int
+	// Misses and Expired were searched; Expired had an entry that was too old.
+	Misses  int
+	Expired int
+	// Stored counts the search results written to the cache.
+	Stored int
+	// Error is set when the cache could not be saved; the playlist is not affected by it.
+	Error string
+}
+
+// Searched counts the tracks the cache could not answer.
+func (s MatchCacheStats) Searched() int {
+	return s.Misses + s.Expired
+}
+
+// openMatchCache returns the cache of search results and the name of its storage; tests
+// replace it with an empty in-memory cache.
+var openMatchCache = defaultOpenMatchCache
+
+var (
+	memoryMatchCacheOnce sync.Once
+	memoryMatchCache     *matchcache.Cache
+)
+
+// defaultOpenMatchCache loads the cache from MATCH_CACHE_FILE or, in the Azure Storage
+// container, from the blob MATCH_CACHE_BLOB. Without either it falls back to a cache that lives
+// as long as the process.
+func defaultOpenMatchCache() (*matchcache.Cache, string, error) {
+	ttl, negativeTTL, err := matchCacheTTLs()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if path := strings.TrimSpace(os.Getenv("MATCH_CACHE_FILE")); path != "" {
+		cache, err := matchcache.Open(matchcache.FileStorage{Path: path}, ttl, negativeTTL)
+		return cache, "file", err
+	}
+	if name := strings.TrimSpace(os.Getenv("MATCH_CACHE_BLOB")); name != "" {
+		cache, err := matchcache.Open(matchcache.BlobStorage{Name: name}, ttl, negativeTTL)
+		return cache, "blob", err
+	}
+
+	memoryMatchCacheOnce.Do(func() {
+		memoryMatchCache, _ = matchcache.Open(nil, ttl, negativeTTL)
+	})
+	return memoryMatchCache, "memory", nil
+}
+
+// matchCacheTTLs reads MATCH_CACHE_TTL and MATCH_CACHE_NEGATIVE_TTL, Go durations such as
+// "720h"; "0" keeps entries forever.
+func matchCacheTTLs() (time.Duration, time.Duration, error) {
+	ttls := []time.Duration{defaultMatchCacheTTL, defaultMatchCacheNegativeTTL}
+	for i, name := range []string{"MATCH_CACHE_TTL", "MATCH_CACHE_NEGATIVE_TTL"} {
+		value := strings.TrimSpace(os.Getenv(name))
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return 0, 0, fmt.Errorf("%s must be a duration like 720h, got %q", name, value)
+		}
+		ttls[i] = parsed
+	}
+	return ttls[0], ttls[1], nil
+}
+
+// matchSettings fingerprints what a match for band depends on besides the Spotify catalog: the
+// markets, the scorer, the similarities a match needs and the aliases of the band. Cached
+// entries stored with other settings are searched again.
+func matchSettings(config matchConfig, markets []string, band string) string {
+	parts := []string{
+		strings.Join(markets, ","),
+		config.scorerName,
+		strconv.FormatFloat(config.minArtist(), 'f', -1, 64),
+		strconv.FormatFloat(config.minTitle(), 'f', -1, 64),
+	}
+	parts = append(parts, config.aliases.Lookup(band)...)
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// cachedEntry looks up key and treats an entry stored with other settings as expired.
+func cachedEntry(cache *matchcache.Cache, key, settings string) (matchcache.Entry, matchcache.Status) {
+	entry, status := cache.Get(key)
+	if status == matchcache.Hit && entry.Settings != settings {
+		return matchcache.Entry{}, matchcache.Expired
+	}
+	return entry, status
+}
+
+// cachedMatch looks up a track matched with settings and counts the outcome in stats. It
+// reports whether the search can be skipped.
+func cachedMatch(cache *matchcache.Cache, key, settings string, stats *MatchCacheStats) (matchcache.Entry, bool) {
+	entry, status := cachedEntry(cache, key, settings)
+	switch status {
+	case matchcache.Hit:
+		stats.Hits++
+		if entry.Negative() {
+			stats.NegativeHits++
+		}
+		return entry, true
+	case matchcache.Expired:
+		stats.Expired++
+	default:
+		stats.Misses++
+	}
+	return entry, false
+}
+
+// flushMatchCache saves the cache and records a failure in stats.
+func flushMatchCache(cache *matchcache.Cache, stats *MatchCacheStats) {
+	if err := cache.Flush(); err != nil {
+		log.Printf("could not save match cache: %v", err)
+		stats.Error = "The match cache could not be saved; see the logs for details."
+	}
+}
@@ -0,0 +1,262 @@
+package creator
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/jetzlstorfer/plattentests-go/internal/aliases"
+	"github.com/jetzlstorfer/plattentests-go/internal/matchcache"
+	"github.com/zmb3/spotify/v2"
+)
+
+// useFileMatchCache switches the creator to the file-backed match cache at a temporary path.
+func useFileMatchCache(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "matches.json")
+	t.Setenv("MATCH_CACHE_FILE", path)
+	openMatchCache = defaultOpenMatchCache
+	return path
+}
+
+var matchCacheRecords = []crawler.Record{
+	{Band: "Band A", Recordname: "First Album", Score: 9, ReleaseYear: "2024", Tracks: []crawler.Track{
+		{Band: "Band A", Trackname: "Opener", IsHighlight: true},
+		{Band: "Band A", Trackname: "Unreleased", IsHighlight: true},
+	}},
+}
+
+func TestRunUsesMatchCache(t *testing.T) {
+	provider := newFakeProvider(fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"))
+	provider.playlists["test-playlist"] = nil
+	useFakeCreatorDependencies(t, provider, matchCacheRecords, "")
+	path := useFileMatchCache(t)
+
+	first, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	if want := (MatchCacheStats{Storage: "file", Misses: 2, Stored: 2}); *first.MatchCache != want {
+		t.Errorf("first run cache stats = %+v, want %+v", *first.MatchCache, want)
+	}
+
+	cache, err := matchcache.Open(matchcache.FileStorage{Path: path}, 0, 0)
+	if err != nil {
+		t.Fatalf("matchcache.Open() error: %v", err)
+	}
+	if entry, status := cache.Get(foundCacheKey("Band A", "Opener")); status != matchcache.Hit || entry.TrackID != "t1" || entry.Confidence != 1 {
+		t.Errorf("cached match = %+v, %d", entry, status)
+	}
+	if entry, status := cache.Get(foundCacheKey("Band A", "Unreleased")); status != matchcache.Hit || !entry.Negative() {
+		t.Errorf("cached miss = %+v, %d", entry, status)
+	}
+
+	// a restarted instance answers from the file without searching
+	provider.searchErr = errors.New("search must not be called")
+	provider.queries = nil
+	second, err := PreviewPlaylist("")
+	if err != nil {
+		t.Fatalf("PreviewPlaylist() error: %v", err)
+	}
+	if len(provider.queries) != 0 {
+		t.Errorf("queries = %q, want none", provider.queries)
+	}
+	if want := (MatchCacheStats{Storage: "file", Hits: 2, NegativeHits: 1}); *second.MatchCache != want {
+		t.Errorf("second run cache stats = %+v, want %+v", *second.MatchCache, want)
+	}
+	if second.FoundTracks != 1 || !reflect.DeepEqual(second.NotFound, []string{"Band A - Unreleased"}) {
+		t.Errorf("second run = %d found, not found %v", second.FoundTracks, second.NotFound)
+	}
+	if len(second.Planned) != 1 || second.Planned[0].ID != spotify.ID("t1") {
+		t.Errorf("Planned = %+v", second.Planned)
+	}
+}
+
+func TestPreviewLeavesMatchCacheUntouched(t *testing.T) {
+	provider := newFakeProvider(fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"))
+	provider.playlists["test-playlist"] = nil
+	useFakeCreatorDependencies(t, provider, matchCacheRecords, "")
+	path := useFileMatchCache(t)
+
+	result, err := PreviewPlaylist("")
+	if err != nil {
+		t.Fatalf("PreviewPlaylist() error: %v", err)
+	}
+	if want := (MatchCacheStats{Storage: "file", Misses: 2}); *result.MatchCache != want {
+		t.Errorf("cache stats = %+v, want %+v", *result.MatchCache, want)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("match cache written by a preview: %v", err)
+	}
+}
+
+func TestMatchSettings(t *testing.T) {
+	withAlias, err := aliases.Open("")
+	if err != nil {
+		t.Fatalf("aliases.Open() error: %v", err)
+	}
+	if err := withAlias.Put(aliases.Alias{Band: "Band", Alias: "The Band"}); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+
+	// every case is compared with the default config in the market AT
+	tests := []struct {
+		name     string
+		config   matchConfig
+		markets  []string
+		band     string
+		wantSame bool
+	}{
+		{name: "same settings", markets: []string{"AT"}, band: "Band", wantSame: true},
+		{name: "explicit default thresholds", config: matchConfig{minArtistSimilarity: minSimilarity, minTitleSimilarity: minSimilarity}, markets: []string{"AT"}, band: "Band", wantSame: true},
+		{name: "alias of another band", config: matchConfig{aliases: withAlias}, markets: []string{"AT"}, band: "Other Band", wantSame: true},
+		{name: "other markets", markets: []string{"AT", "DE"}, band: "Band"},
+		{name: "other scorer", config: matchConfig{scorerName: "levenshtein"}, markets: []string{"AT"}, band: "Band"},
+		{name: "other title similarity", config: matchConfig{minTitleSimilarity: 0.9}, markets: []string{"AT"}, band: "Band"},
+		{name: "other artist similarity", config: matchConfig{minArtistSimilarity: 0.7}, markets: []string{"AT"}, band: "Band"},
+		{name: "alias of the band", config: matchConfig{aliases: withAlias}, markets: []string{"AT"}, band: "Band"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := matchSettings(matchConfig{}, []string{"AT"}, tt.band)
+			if got := matchSettings(tt.config, tt.markets, tt.band); (got == base) != tt.wantSame {
+				t.Errorf("matchSettings() = %q, default %q, want same %v", got, base, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestRunSearchesAgainWhenSettingsChange(t *testing.T) {
+	provider := newFakeProvider(fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"))
+	provider.playlists["test-playlist"] = nil
+	useFakeCreatorDependencies(t, provider, matchCacheRecords, "")
+	useFileMatchCache(t)
+
+	if _, err := CreatePlaylist(""); err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	t.Setenv("MATCH_MIN_TITLE_SIMILARITY", "0.9")
+	provider.queries = nil
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	if want := (MatchCacheStats{Storage: "file", Expired: 2, Stored: 2}); *result.MatchCache != want {
+		t.Errorf("cache stats after changing a threshold = %+v, want %+v", *result.MatchCache, want)
+	}
+	if result.FoundTracks != 1 {
+		t.Errorf("FoundTracks = %d, want 1", result.FoundTracks)
+	}
+
+	again, err := PreviewPlaylist("")
+	if err != nil {
+		t.Fatalf("PreviewPlaylist() error: %v", err)
+	}
+	if want := (MatchCacheStats{Storage: "file", Hits: 2, NegativeHits: 1}); *again.MatchCache != want {
+		t.Errorf("cache stats with the new settings = %+v, want %+v", *again.MatchCache, want)
+	}
+}
+
+func TestRunRetriesExpiredMisses(t *testing.T) {
+	provider := newFakeProvider(fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"))
+	provider.playlists["test-playlist"] = nil
+	useFakeCreatorDependencies(t, provider, matchCacheRecords, "")
+	useFileMatchCache(t)
+	t.Setenv("MATCH_CACHE_NEGATIVE_TTL", "1ns")
+
+	if _, err := CreatePlaylist(""); err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	// the track has been released in the meantime
+	provider.catalog = append(provider.catalog, fakeTrack("t2", "Band A", "Unreleased", "First Album", "album", "2024"))
+	provider.queries, provider.albumQueries = nil, nil
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	// the retry finds the track on the tracklist of the album the first run cached
+	if len(provider.albumQueries) != 0 || len(provider.queries) != 0 {
+		t.Errorf("album queries = %q, queries = %q, want none", provider.albumQueries, provider.queries)
+	}
+	if want := (MatchCacheStats{Storage: "file", Hits: 1, Misses: 1, Stored: 1}); *result.MatchCache != want {
+		t.Errorf("cache stats = %+v, want %+v", *result.MatchCache, want)
+	}
+	if result.FoundTracks != 2 {
+		t.Errorf("FoundTracks = %d, want 2", result.FoundTracks)
+	}
+}
+
+func TestMarkFoundTracksSharesMatchCache(t *testing.T) {
+	provider := newFakeProvider(fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"))
+	provider.playlists["test-playlist"] = nil
+	useFakeCreatorDependencies(t, provider, matchCacheRecords, "")
+	useFileMatchCache(t)
+
+	if _, err := CreatePlaylist(""); err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	provider.searchErr = errors.New("search must not be called")
+
+	records := append([]crawler.Record(nil), matchCacheRecords...)
+	records[0].Tracks = append([]crawler.Track(nil), matchCacheRecords[0].Tracks...)
+	if err := MarkFoundTracks(records); err != nil {
+		t.Fatalf("MarkFoundTracks() error: %v", err)
+	}
+	if !records[0].Tracks[0].Found || records[0].Tracks[1].Found {
+		t.Errorf("found = %v, %v, want true, false", records[0].Tracks[0].Found, records[0].Tracks[1].Found)
+	}
+}
+
+func TestMarkFoundTracksWritesNothing(t *testing.T) {
+	provider := newFakeProvider(fakeTrack("k1", "Kino", "Kukushka", "Gruppa Krovi", "album", "1988"))
+	useFakeCreatorDependencies(t, provider, nil, "")
+	cachePath := useFileMatchCache(t)
+	aliasesPath := filepath.Join(t.TempDir(), "aliases.json")
+	t.Setenv("ARTIST_ALIASES_FILE", aliasesPath)
+	openArtistAliases = defaultOpenArtistAliases
+
+	records := []crawler.Record{{Band: "Кино", Recordname: "Gruppa Krovi", ReleaseYear: "1988", Tracks: []crawler.Track{
+		{Band: "Кино", Trackname: "Kukushka", IsHighlight: true},
+	}}}
+	if err := MarkFoundTracks(records); err != nil {
+		t.Fatalf("MarkFoundTracks() error: %v", err)
+	}
+	if !records[0].Tracks[0].Found {
+		t.Error("track not marked as found")
+	}
+	for _, path := range []string{cachePath, aliasesPath} {
+		if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s written by MarkFoundTracks: %v", filepath.Base(path), err)
+		}
+	}
+}
+
+func TestMatchCacheTTLsRejectInvalidValues(t *testing.T) {
+	for _, tt := range []struct{ name, value string }{
+		{name: "MATCH_CACHE_TTL", value: "30 days"},
+		{name: "MATCH_CACHE_NEGATIVE_TTL", value: "-1h"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MATCH_CACHE_TTL", "")
+			t.Setenv("MATCH_CACHE_NEGATIVE_TTL", "")
+			t.Setenv(tt.name, tt.value)
+			if _, _, err := matchCacheTTLs(); err == nil {
+				t.Errorf("matchCacheTTLs() with %s=%q must fail", tt.name, tt.value)
+			}
+		})
+	}
+
+	t.Setenv("MATCH_CACHE_TTL", "0")
+	t.Setenv("MATCH_CACHE_NEGATIVE_TTL", "")
+	ttl, negativeTTL, err := matchCacheTTLs()
+	if err != nil || ttl != 0 || negativeTTL != defaultMatchCacheNegativeTTL {
+		t.Errorf("matchCacheTTLs() = %v, %v, %v", ttl, negativeTTL, err)
+	}
+}
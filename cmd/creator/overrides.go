@@ -0,0 +1,107 @@
+package creator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/jetzlstorfer/plattentests-go/internal/overrides"
+	"github.com/zmb3/spotify/v2"
+)
+
+// MaxCandidates is the number of Spotify tracks offered for an unmatched track.
+const MaxCandidates = 5
+
+// Candidate is a Spotify track offered when the search could not match a highlight.
+type Candidate struct {
+	ID          spotify.ID
+	Artists     string
+	Name        string
+	Album       string
+	AlbumType   string
+	ReleaseDate string
+}
+
+// UnmatchedTrack is a highlight without a match, together with the tracks that could be
+// picked as its override.
+type UnmatchedTrack struct {
+	Band       string
+	Trackname  string
+	Recordname string
+	Candidates []Candidate
+}
+
+// openMatchOverrides opens the store named by MATCH_OVERRIDES_FILE; it returns nil when manual
+// overrides are not configured.
+func openMatchOverrides() (*overrides.Store, error) {
+	path := strings.TrimSpace(os.Getenv("MATCH_OVERRIDES_FILE"))
+	if path == "" {
+		return nil, nil
+	}
+	return overrides.Open(path)
+}
+
+// lookupOverride returns the manual match of a track; a nil store has none.
+func lookupOverride(store *overrides.Store, band, track string) (overrides.Override, bool) {
+	if store == nil {
+		return overrides.Override{}, false
+	}
+	return store.Lookup(band, track)
+}
+
+// saveOverrideMu serializes SaveMatchOverride, so concurrent saves from the web UI do not
+// load the same file and overwrite each other's override.
+var saveOverrideMu sync.Mutex
+
+// SaveMatchOverride remembers choice for the track: a Spotify track ID, URI or link, or
+// overrides.Skip to leave the track out. user is recorded as the author.
+func SaveMatchOverride(band, track, choice, user string) error {
+	saveOverrideMu.Lock()
+	defer saveOverrideMu.Unlock()
+
+	store, err := openMatchOverrides()
+	if err != nil {
+		return err
+	}
+	if store == nil {
+		return fmt.Errorf("MATCH_OVERRIDES_FILE missing, it is required for manual matches")
+	}
+	trackID, err := overrides.ParseTrackID(choice)
+	if err != nil {
+		return err
+	}
+	log.Printf("override for %s - %s set to %s by %q", band, track, trackID, user)
+	return store.Put(overrides.Override{Band: band, Track: track, TrackID: trackID, UpdatedAt: now(), UpdatedBy: user})
+}
+
+// searchCandidates returns the top Spotify tracks for a loose "band track" query, without the
+// filters and checks searchSong applies.
+func searchCandidates(provider MusicProvider, band, track string) ([]Candidate, error) {
+	query := sanitizeTrackname(band + " " + track)
+	tracks, err := provider.SearchTracks(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("search candidates %q: %w", query, err)
+	}
+	if len(tracks) > MaxCandidates {
+		tracks = tracks[:MaxCandidates]
+	}
+	candidates := make([]Candidate, 0, len(tracks))
+	for _, item := range tracks {
+		artists := make([]string, len(item.Artists))
+		for i, artist := range item.Artists {
+			artists[i] = artist.Name
+		}
+		candidates = append(candidates, Candidate{
+			ID:          item.ID,
+			Artists:     strings.Join(artists, ", "),
+			Name:        item.Name,
+			Album:       item.Album.Name,
+			AlbumType:   item.Album.AlbumType,
+			ReleaseDate: item.Album.ReleaseDate,
+		})
+	}
+	return candidates, nil
+}
@@ -0,0 +1,160 @@
+package creator
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/jetzlstorfer/plattentests-go/internal/overrides"
+	"github.com/zmb3/spotify/v2"
+)
+
+func TestRunUsesMatchOverrides(t *testing.T) {
+	provider := newFakeProvider(
+		fakeTrack("wrong", "Band A", "Opener", "Single Version", "single", "2024"),
+		fakeTrack("b1", "Band B", "Hit", "Second Album", "album", "2024"),
+		fakeTrack("c1", "Band C", "Song", "Third Album", "album", "2024"),
+	)
+	provider.playlists["test-playlist"] = nil
+	useFakeCreatorDependencies(t, provider, []crawler.Record{
+		{Band: "Band A", Recordname: "First Album", Score: 9, Tracks: []crawler.Track{
+			{Band: "Band A", Trackname: "Opener", IsHighlight: true},
+		}},
+		{Band: "Band B", Recordname: "Second Album", Score: 8, Tracks: []crawler.Track{
+			{Band: "Band B", Trackname: "Hit", IsHighlight: true},
+			{Band: "Band B", Trackname: "Interlude", IsHighlight: true},
+		}},
+		{Band: "Band C", Recordname: "Third Album", Score: 7, Tracks: []crawler.Track{
+			{Band: "Band C", Trackname: "Song Live", IsHighlight: true},
+		}},
+	}, "")
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	t.Setenv("MATCH_OVERRIDES_FILE", path)
+	if err := SaveMatchOverride("band a", "OPENER", "https://open.spotify.com/track/right?si=x", "jane@example.com"); err != nil {
+		t.Fatalf("SaveMatchOverride() error: %v", err)
+	}
+	if err := SaveMatchOverride("Band B", "Interlude", overrides.Skip, ""); err != nil {
+		t.Fatalf("SaveMatchOverride() error: %v", err)
+	}
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	if got, want := provider.playlists["test-playlist"], []spotify.ID{"right", "b1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("playlist = %v, want %v", got, want)
+	}
+	for _, query := range provider.queries {
+		if strings.Contains(query, "Opener") || strings.Contains(query, "Interlude") {
+			t.Errorf("overridden track was searched: %q", query)
+		}
+	}
+	if result.TotalTracks != 3 || result.FoundTracks != 2 || result.ManualMatches != 1 {
+		t.Errorf("result = %d/%d with %d manual match(es)", result.FoundTracks, result.TotalTracks, result.ManualMatches)
+	}
+	if !reflect.DeepEqual(result.SkippedTracks, []string{"Band B - Interlude"}) {
+		t.Errorf("SkippedTracks = %v", result.SkippedTracks)
+	}
+	want := []UnmatchedTrack{{Band: "Band C", Trackname: "Song Live", Recordname: "Third Album", Candidates: []Candidate{
+		{ID: "c1", Artists: "Band C", Name: "Song", Album: "Third Album", AlbumType: "album", ReleaseDate: "2024-01-01"},
+	}}}
+	if !reflect.DeepEqual(result.Unmatched, want) {
+		t.Errorf("Unmatched = %+v, want %+v", result.Unmatched, want)
+	}
+
+	// picking the candidate fixes the track for the next run
+	if err := SaveMatchOverride("Band C", "Song Live", "c1", ""); err != nil {
+		t.Fatalf("SaveMatchOverride() error: %v", err)
+	}
+	result, err = CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	if got, want := provider.playlists["test-playlist"], []spotify.ID{"right", "b1", "c1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("playlist = %v, want %v", got, want)
+	}
+	if len(result.NotFound) != 0 || len(result.Unmatched) != 0 {
+		t.Errorf("NotFound = %v, Unmatched = %+v, want none", result.NotFound, result.Unmatched)
+	}
+}
+
+func TestRunWithoutMatchOverridesHasNoCandidates(t *testing.T) {
+	provider := newFakeProvider(fakeTrack("c1", "Band C", "Song", "Third Album", "album", "2024"))
+	provider.playlists["test-playlist"] = nil
+	useFakeCreatorDependencies(t, provider, []crawler.Record{
+		{Band: "Band C", Recordname: "Third Album", Score: 7, Tracks: []crawler.Track{
+			{Band: "Band C", Trackname: "Song Live", IsHighlight: true},
+		}},
+	}, "")
+
+	result, err := PreviewPlaylist("")
+	if err != nil {
+		t.Fatalf("PreviewPlaylist() error: %v", err)
+	}
+	if !reflect.DeepEqual(result.NotFound, []string{"Band C - Song Live"}) || result.Unmatched != nil {
+		t.Errorf("NotFound = %v, Unmatched = %+v", result.NotFound, result.Unmatched)
+	}
+	if len(provider.queries) != 1 {
+		t.Errorf("queries = %q, want only the match search", provider.queries)
+	}
+}
+
+func TestSaveMatchOverrideConcurrently(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	t.Setenv("MATCH_OVERRIDES_FILE", path)
+
+	const saves = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, saves)
+	for i := 0; i < saves; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- SaveMatchOverride("Band", fmt.Sprintf("Song %d", i), fmt.Sprintf("t%d", i), "")
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("SaveMatchOverride() error: %v", err)
+		}
+	}
+
+	store, err := overrides.Open(path)
+	if err != nil {
+		t.Fatalf("overrides.Open() error: %v", err)
+	}
+	if got := len(store.Overrides()); got != saves {
+		t.Errorf("saved %d overrides, want %d", got, saves)
+	}
+}
+
+func TestSaveMatchOverrideErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		choice string
+	}{
+		{name: "no store configured", choice: "abc"},
+		{name: "invalid choice", file: "overrides.json", choice: "https://example.com/track/abc"},
+		{name: "album instead of track", file: "overrides.json", choice: "spotify:album:abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := ""
+			if tt.file != "" {
+				path = filepath.Join(t.TempDir(), tt.file)
+			}
+			t.Setenv("MATCH_OVERRIDES_FILE", path)
+			if err := SaveMatchOverride("Band", "Song", tt.choice, ""); err == nil {
+				t.Error("SaveMatchOverride() must fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package creator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Profile is a named playlist the creator maintains with its own selection rules.
+type Profile struct {
+	// Name identifies the profile in URLs and results, e.g. "prod".
+	Name string `json:"name"`
+	// Title is shown in the web UI; it defaults to Name.
+	Title string `json:"title,omitempty"`
+	// PlaylistID is the target playlist. Environment variables like $PLAYLIST_ID are
+	// expanded, so the file does not need to contain the IDs.
+	PlaylistID string `json:"playlistId,omitempty"`
+	// Weekly targets the dated playlist of the current week instead of PlaylistID.
+	Weekly bool `json:"weekly,omitempty"`
+	// Rules select the tracks; nil uses PLAYLIST_RULES or the defaults.
+	Rules *Rules `json:"rules,omitempty"`
+	// Archive adds the profile's tracks to ARCHIVE_PLAYLIST_ID; runs of other profiles leave
+	// the archive playlist alone.
+	Archive bool `json:"archive,omitempty"`
+}
+
+// profileNamePattern keeps profile names usable in URLs without escaping.
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// LoadProfiles returns the profiles defined in PLAYLIST_PROFILES_FILE. Without that file the
+// classic playlists become profiles: "test" for PLAYLIST_ID, "prod" for PLAYLIST_ID_PROD and
+// "weekly" when WEEKLY_PLAYLISTS_FILE is set; "prod", or "test" without it, feeds the archive
+// playlist. The first profile is the default.
+func LoadProfiles() ([]Profile, error) {
+	path := strings.TrimSpace(os.Getenv("PLAYLIST_PROFILES_FILE"))
+	if path == "" {
+		return environmentProfiles(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read playlist profiles %s: %w", path, err)
+	}
+	var file struct {
+		Profiles []Profile `json:"profiles"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("decode playlist profiles %s: %w", path, err)
+	}
+
+	for i := range file.Profiles {
+		profile := &file.Profiles[i]
+		profile.PlaylistID = strings.TrimSpace(os.ExpandEnv(profile.PlaylistID))
+		if profile.Title == "" {
+			profile.Title = profile.Name
+		}
+	}
+	if err := validateProfiles(file.Profiles); err != nil {
+		return nil, fmt.Errorf("playlist profiles %s: %w", path, err)
+	}
+	return file.Profiles, nil
+}
+
+func environmentProfiles() []Profile {
+	var profiles []Profile
+	if id := strings.TrimSpace(os.Getenv("PLAYLIST_ID")); id != "" {
+		profiles = append(profiles, Profile{Name: "test", Title: "test", PlaylistID: id})
+	}
+	if id := strings.TrimSpace(os.Getenv("PLAYLIST_ID_PROD")); id != "" {
+		profiles = append(profiles, Profile{Name: "prod", Title: "production", PlaylistID: id})
+	}
+	if len(profiles) > 0 {
+		profiles[len(profiles)-1].Archive = true
+	}
+	if strings.TrimSpace(os.Getenv("WEEKLY_PLAYLISTS_FILE")) != "" {
+		profiles = append(profiles, Profile{Name: "weekly", Title: "dated weekly", Weekly: true})
+	}
+	return profiles
+}
+
+func validateProfiles(profiles []Profile) error {
+	if len(profiles) == 0 {
+		return errors.New("no profiles defined")
+	}
+	var errs []error
+	seen := make(map[string]bool, len(profiles))
+	for _, profile := range profiles {
+		if !profileNamePattern.MatchString(profile.Name) {
+			errs = append(errs, fmt.Errorf("profile name %q must consist of lowercase letters, digits and dashes", profile.Name))
+		}
+		if seen[profile.Name] {
+			errs = append(errs, fmt.Errorf("profile %q is defined twice", profile.Name))
+		}
+		seen[profile.Name] = true
+		if profile.PlaylistID == "" && !profile.Weekly {
+			errs = append(errs, fmt.Errorf("profile %q needs a playlistId", profile.Name))
+		}
+		if profile.PlaylistID != "" && profile.Weekly {
+			errs = append(errs, fmt.Errorf("profile %q is weekly and must not set a playlistId", profile.Name))
+		}
+		if profile.Rules != nil {
+			if err := profile.Rules.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("profile %q: %w", profile.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FindProfile returns the profile called name; an empty name selects the first profile.
+func FindProfile(profiles []Profile, name string) (Profile, bool) {
+	if name == "" && len(profiles) > 0 {
+		return profiles[0], true
+	}
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+	return Profile{}, false
+}
+
+// applyProfile resolves opts.Profile into the target playlist and rules of the run.
+func applyProfile(opts Options) (Options, *Profile, error) {
+	if opts.Profile == "" {
+		return opts, nil, nil
+	}
+	profiles, err := LoadProfiles()
+	if err != nil {
+		return opts, nil, err
+	}
+	profile, ok := FindProfile(profiles, opts.Profile)
+	if !ok {
+		return opts, nil, fmt.Errorf("unknown playlist profile %q", opts.Profile)
+	}
+	opts.PlaylistID = profile.PlaylistID
+	opts.Weekly = profile.Weekly
+	if profile.Rules != nil {
+		opts.Rules = profile.Rules
+	}
+	return opts, &profile, nil
+}
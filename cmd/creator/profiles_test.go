@@ -0,0 +1,155 @@
+package creator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/zmb3/spotify/v2"
+)
+
+func writeProfiles(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	t.Setenv("PLAYLIST_PROFILES_FILE", path)
+}
+
+func TestLoadProfilesFromEnvironment(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantNames   []string
+		wantArchive []string
+	}{
+		{name: "test only", env: map[string]string{"PLAYLIST_ID": "t"}, wantNames: []string{"test"}, wantArchive: []string{"test"}},
+		{name: "test and prod", env: map[string]string{"PLAYLIST_ID": "t", "PLAYLIST_ID_PROD": "p"}, wantNames: []string{"test", "prod"}, wantArchive: []string{"prod"}},
+		{name: "with weekly", env: map[string]string{"PLAYLIST_ID": "t", "WEEKLY_PLAYLISTS_FILE": "weekly.json"}, wantNames: []string{"test", "weekly"}, wantArchive: []string{"test"}},
+		{name: "nothing configured"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"PLAYLIST_PROFILES_FILE", "PLAYLIST_ID", "PLAYLIST_ID_PROD", "WEEKLY_PLAYLISTS_FILE"} {
+				t.Setenv(key, tt.env[key])
+			}
+			profiles, err := LoadProfiles()
+			if err != nil {
+				t.Fatalf("LoadProfiles() error: %v", err)
+			}
+			var names, archive []string
+			for _, profile := range profiles {
+				names = append(names, profile.Name)
+				if profile.Archive {
+					archive = append(archive, profile.Name)
+				}
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("profiles = %v, want %v", names, tt.wantNames)
+			}
+			if !reflect.DeepEqual(archive, tt.wantArchive) {
+				t.Errorf("archive profiles = %v, want %v", archive, tt.wantArchive)
+			}
+		})
+	}
+}
+
+func TestLoadProfilesFromFile(t *testing.T) {
+	t.Setenv("EIGHT_PLUS_ID", "eight-playlist")
+	writeProfiles(t, `{"profiles": [
+		{"name": "prod", "title": "production", "playlistId": "prod-playlist"},
+		{"name": "eight-plus", "title": "8+ only", "playlistId": "$EIGHT_PLUS_ID", "rules": {"minScore": 8}},
+		{"name": "new", "playlistId": "new-playlist", "rules": {"releasedOnly": true}},
+		{"name": "weekly", "weekly": true}
+	]}`)
+
+	profiles, err := LoadProfiles()
+	if err != nil {
+		t.Fatalf("LoadProfiles() error: %v", err)
+	}
+	want := []Profile{
+		{Name: "prod", Title: "production", PlaylistID: "prod-playlist"},
+		{Name: "eight-plus", Title: "8+ only", PlaylistID: "eight-playlist", Rules: &Rules{MinScore: 8}},
+		{Name: "new", Title: "new", PlaylistID: "new-playlist", Rules: &Rules{ReleasedOnly: true}},
+		{Name: "weekly", Title: "weekly", Weekly: true},
+	}
+	if !reflect.DeepEqual(profiles, want) {
+		t.Errorf("LoadProfiles() = %+v, want %+v", profiles, want)
+	}
+
+	if got, ok := FindProfile(profiles, ""); !ok || got.Name != "prod" {
+		t.Errorf("FindProfile(\"\") = %+v, %v, want the first profile", got, ok)
+	}
+	if _, ok := FindProfile(profiles, "missing"); ok {
+		t.Error("FindProfile() found an unknown profile")
+	}
+}
+
+func TestLoadProfilesRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "not json", content: `{`, wantErr: "decode"},
+		{name: "unknown field", content: `{"profiles": [{"name": "a", "playlist": "x"}]}`, wantErr: "unknown field"},
+		{name: "no profiles", content: `{"profiles": []}`, wantErr: "no profiles"},
+		{name: "invalid name", content: `{"profiles": [{"name": "Eight Plus", "playlistId": "x"}]}`, wantErr: "lowercase"},
+		{name: "duplicate name", content: `{"profiles": [{"name": "a", "playlistId": "x"}, {"name": "a", "playlistId": "y"}]}`, wantErr: "defined twice"},
+		{name: "missing playlist", content: `{"profiles": [{"name": "a", "playlistId": "$UNSET_PROFILE_ID"}]}`, wantErr: "needs a playlistId"},
+		{name: "weekly with playlist", content: `{"profiles": [{"name": "a", "playlistId": "x", "weekly": true}]}`, wantErr: "must not set a playlistId"},
+		{name: "invalid rules", content: `{"profiles": [{"name": "a", "playlistId": "x", "rules": {"minScore": 20}}]}`, wantErr: "minScore"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeProfiles(t, tt.content)
+			if _, err := LoadProfiles(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadProfiles() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunProfile(t *testing.T) {
+	provider := newFakeProvider(
+		fakeTrack("a1", "Band A", "Opener", "First Album", "album", "2024"),
+		fakeTrack("b1", "Band B", "Hit", "Second Album", "album", "2024"),
+	)
+	provider.playlists["eight-playlist"] = nil
+	useFakeCreatorDependencies(t, provider, []crawler.Record{
+		{Band: "Band A", Recordname: "First Album", Score: 7, ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band A", Trackname: "Opener", IsHighlight: true},
+		}},
+		{Band: "Band B", Recordname: "Second Album", Score: 8, ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band B", Trackname: "Hit", IsHighlight: true},
+		}},
+	}, "Band A")
+	writeProfiles(t, `{"profiles": [
+		{"name": "test", "playlistId": "test-playlist"},
+		{"name": "eight-plus", "title": "8+ only", "playlistId": "eight-playlist", "rules": {"minScore": 8}}
+	]}`)
+
+	result, err := Run(Options{Profile: "eight-plus"})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if got := provider.playlists["eight-playlist"]; !reflect.DeepEqual(got, []spotify.ID{"b1"}) {
+		t.Errorf("profile playlist = %v, want [b1]", got)
+	}
+	if _, touched := provider.playlists["test-playlist"]; touched {
+		t.Error("the default playlist must not be touched")
+	}
+	if result.Profile == nil || result.Profile.Name != "eight-plus" || result.PlaylistID != "eight-playlist" || result.Rules.MinScore != 8 {
+		t.Errorf("result profile = %+v, playlist %s, rules %+v", result.Profile, result.PlaylistID, result.Rules)
+	}
+
+	if _, err := Run(Options{Profile: "nine-plus"}); err == nil || !strings.Contains(err.Error(), "unknown playlist profile") {
+		t.Errorf("Run() of an unknown profile error = %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+package creator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	myauth "github.com/jetzlstorfer/plattentests-go/internal/auth"
+	"github.com/jetzlstorfer/plattentests-go/internal/ratelimit"
+	"github.com/zmb3/spotify/v2"
+)
+
+// MusicProvider is the part of a streaming service the creator needs to search tracks and
+// maintain playlists. Spotify is the production implementation; tests use an in-memory fake.
+type MusicProvider interface {
+	// Markets are the countries matches must be playable in; empty when the provider looks
+	// tracks up without a market.
+	Markets() []string
+	// SearchTracks returns the tracks matching a Spotify search query, best match first. With
+	// markets, IsPlayable is false for tracks that cannot be played in one of them, and tracks
+	// relinked to another version carry the original in LinkedFrom.
+	SearchTracks(ctx context.Context, query string) ([]spotify.FullTrack, error)
+	// SearchAlbums returns the albums matching a Spotify search query, best match first.
+	SearchAlbums(ctx context.Context, query string) ([]spotify.SimpleAlbum, error)
+	// AlbumTracks lists the tracks of an album in tracklist order.
+	AlbumTracks(ctx context.Context, albumID spotify.ID) ([]spotify.SimpleTrack, error)
+	// Tracks returns the full details, including the ISRC, of up to 50 tracks; unknown IDs
+	// are left out. Playability and relinking are reported as for SearchTracks.
+	Tracks(ctx context.Context, trackIDs ...spotify.ID) ([]spotify.FullTrack, error)
+	// ReplacePlaylistTracks replaces all items of a playlist; without track IDs it empties it.
+	ReplacePlaylistTracks(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) error
+	// AddTracksToPlaylist appends tracks to a playlist.
+	AddTracksToPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) error
+	// RemoveTracksFromPlaylist removes every occurrence of the tracks from a playlist.
+	RemoveTracksFromPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) error
+	// ReorderPlaylistTracks moves the item at rangeStart so it is inserted before the item
+	// currently at insertBefore.
+	ReorderPlaylistTracks(ctx context.Context, playlistID spotify.ID, rangeStart, insertBefore int) error
+	// CreatePlaylist creates a new public playlist for the logged-in user.
+	CreatePlaylist(ctx context.Context, name, description string) (spotify.ID, error)
+	// PlaylistItems lists the tracks of a playlist in playlist order.
+	PlaylistItems(ctx context.Context, playlistID spotify.ID) ([]PlaylistItem, error)
+	// PlaylistDetails returns the name and description of a playlist.
+	PlaylistDetails(ctx context.Context, playlistID spotify.ID) (name, description string, err error)
+	// ChangePlaylistDetails sets name and description of a playlist; an empty value is left
+	// unchanged.
+	ChangePlaylistDetails(ctx context.Context, playlistID spotify.ID, name, description string) error
+}
+
+// PlaylistItem is a track in a playlist. AddedAt is zero when the provider does not know it,
+// which Spotify documents for very old playlists.
+type PlaylistItem struct {
+	// ID is empty for items that are no Spotify tracks, such as local files and podcast
+	// episodes; they are listed anyway so positions match the playlist.
+	ID      spotify.ID
+	AddedAt time.Time
+}
+
+// playlistTrackIDs lists the track IDs of a playlist in playlist order, with an empty ID for
+// every item that is no Spotify track.
+func playlistTrackIDs(ctx context.Context, provider MusicProvider, playlistID spotify.ID) ([]spotify.ID, error) {
+	items, err := provider.PlaylistItems(ctx, playlistID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]spotify.ID, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids, nil
+}
+
+// newMusicProvider logs in to the configured provider; tests replace it with a fake.
+var newMusicProvider = newSpotifyProvider
+
+// spotifyRequestStats reports what the request governor shared by all Spotify clients did so
+// far; a run reports the difference.
+func spotifyRequestStats() ratelimit.Stats {
+	return myauth.Governor.Stats()
+}
+
+// spotifyProvider implements MusicProvider with the Spotify Web API.
+type spotifyProvider struct {
+	client *spotify.Client
+	// markets come from SPOTIFY_MARKETS; the first one is passed to the lookups
+	markets []string
+}
+
+func newSpotifyProvider() (MusicProvider, error) {
+	markets, err := spotifyMarkets()
+	if err != nil {
+		return nil, err
+	}
+	client, err := myauth.VerifyLogin()
+	if err != nil {
+		return nil, fmt.Errorf("spotify login failed: %w", err)
+	}
+	return &spotifyProvider{client: &client, markets: markets}, nil
+}
+
+func (p *spotifyProvider) Markets() []string {
+	return p.markets
+}
+
+// marketOptions passes the first market, which makes Spotify report playability and relink
+// tracks for it.
+func (p *spotifyProvider) marketOptions() []spotify.RequestOption {
+	if len(p.markets) == 0 {
+		return nil
+	}
+	return []spotify.RequestOption{spotify.Market(p.markets[0])}
+}
+
+// checkOtherMarkets marks tracks that cannot be played in one of the markets after the first,
+// which Spotify already checked, as not playable.
+func (p *spotifyProvider) checkOtherMarkets(ctx context.Context, tracks []spotify.FullTrack) error {
+	if len(p.markets) < 2 || len(tracks) == 0 {
+		return nil
+	}
+	ids := make([]spotify.ID, len(tracks))
+	for i, track := range tracks {
+		ids[i] = originalID(track)
+	}
+	for _, market := range p.markets[1:] {
+		for start := 0; start < len(ids); start += maxTracksPerLookup {
+			end := min(start+maxTracksPerLookup, len(ids))
+			results, err := p.client.GetTracks(ctx, ids[start:end], spotify.Market(market))
+			if err != nil {
+				return fmt.Errorf("check playability in %s: %w", market, err)
+			}
+			for i, result := range results {
+				if result == nil || !playable(*result) {
+					unplayable := false
+					tracks[start+i].IsPlayable = &unplayable
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (p *spotifyProvider) SearchTracks(ctx context.Context, query string) ([]spotify.FullTrack, error) {
+	results, err := p.client.Search(ctx, query, spotify.SearchTypeTrack, p.marketOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	if results.Tracks == nil {
+		return nil, nil
+	}
+	tracks := results.Tracks.Tracks
+	if err := p.checkOtherMarkets(ctx, tracks); err != nil {
+		return nil, err
+	}
+	return tracks, nil
+}
+
+func (p *spotifyProvider) SearchAlbums(ctx context.Context, query string) ([]spotify.SimpleAlbum, error) {
+	results, err := p.client.Search(ctx, query, spotify.SearchTypeAlbum)
+	if err != nil {
+		return nil, err
+	}
+	if results.Albums == nil {
+		return nil, nil
+	}
+	return results.Albums.Albums, nil
+}
+
+func (p *spotifyProvider) AlbumTracks(ctx context.Context, albumID spotify.ID) ([]spotify.SimpleTrack, error) {
+	page, err := p.client.GetAlbumTracks(ctx, albumID, spotify.Limit(50))
+	if err != nil {
+		return nil, err
+	}
+
+	var tracks []spotify.SimpleTrack
+	for {
+		tracks = append(tracks, page.Tracks...)
+		if err := p.client.NextPage(ctx, page); err != nil {
+			if errors.Is(err, spotify.ErrNoMorePages) {
+				break
+			}
+			return nil, fmt.Errorf("paginate album %s: %w", albumID, err)
+		}
+	}
+	return tracks, nil
+}
+
+func (p *spotifyProvider) Tracks(ctx context.Context, trackIDs ...spotify.ID) ([]spotify.FullTrack, error) {
+	results, err := p.client.GetTracks(ctx, trackIDs, p.marketOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	tracks := make([]spotify.FullTrack, 0, len(results))
+	for _, track := range results {
+		if track != nil {
+			tracks = append(tracks, *track)
+		}
+	}
+	if err := p.checkOtherMarkets(ctx, tracks); err != nil {
+		return nil, err
+	}
+	return tracks, nil
+}
+
+func (p *spotifyProvider) ReplacePlaylistTracks(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) error {
+	return p.client.ReplacePlaylistTracks(ctx, playlistID, trackIDs...)
+}
+
+func (p *spotifyProvider) AddTracksToPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) error {
+	_, err := p.client.AddTracksToPlaylist(ctx, playlistID, trackIDs...)
+	return err
+}
+
+func (p *spotifyProvider) RemoveTracksFromPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) error {
+	_, err := p.client.RemoveTracksFromPlaylist(ctx, playlistID, trackIDs...)
+	return err
+}
+
+func (p *spotifyProvider) ReorderPlaylistTracks(ctx context.Context, playlistID spotify.ID, rangeStart, insertBefore int) error {
+	_, err := p.client.ReorderPlaylistTracks(ctx, playlistID, spotify.PlaylistReorderOptions{
+		RangeStart:   spotify.Numeric(rangeStart),
+		InsertBefore: spotify.Numeric(insertBefore),
+	})
+	return err
+}
+
+func (p *spotifyProvider) CreatePlaylist(ctx context.Context, name, description string) (spotify.ID, error) {
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return "", fmt.Errorf("identify current user: %w", err)
+	}
+	playlist, err := p.client.CreatePlaylistForUser(ctx, user.ID, name, description, true, false)
+	if err != nil {
+		return "", err
+	}
+	return playlist.ID, nil
+}
+
+func (p *spotifyProvider) PlaylistItems(ctx context.Context, playlistID spotify.ID) ([]PlaylistItem, error) {
+	page, err := p.client.GetPlaylistItems(ctx, playlistID, spotify.Limit(100))
+	if err != nil {
+		return nil, err
+	}
+
+	var items []PlaylistItem
+	for {
+		for _, item := range page.Items {
+			addedAt, _ := time.Parse(spotify.TimestampLayout, item.AddedAt)
+			var id spotify.ID
+			if track := item.Track.Track; track != nil {
+				id = track.ID
+			}
+			items = append(items, PlaylistItem{ID: id, AddedAt: addedAt})
+		}
+
+		if err := p.client.NextPage(ctx, page); err != nil {
+			if errors.Is(err, spotify.ErrNoMorePages) {
+				break
+			}
+			return nil, fmt.Errorf("paginate playlist %s: %w", playlistID, err)
+		}
+	}
+	return items, nil
+}
+
+func (p *spotifyProvider) PlaylistDetails(ctx context.Context, playlistID spotify.ID) (string, string, error) {
+	playlist, err := p.client.GetPlaylist(ctx, playlistID, spotify.Fields("name,description"))
+	if err != nil {
+		return "", "", err
+	}
+	return playlist.Name, playlist.Description, nil
+}
+
+func (p *spotifyProvider) ChangePlaylistDetails(ctx context.Context, playlistID spotify.ID, name, description string) error {
+	// the library only combines name and description with the access flag, which must stay as is
+	if name != "" {
+		if err := p.client.ChangePlaylistName(ctx, playlistID, name); err != nil {
+			return err
+		}
+	}
+	if description != "" {
+		return p.client.ChangePlaylistDescription(ctx, playlistID, description)
+	}
+	return nil
+}
@@ -0,0 +1,544 @@
+package creator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/jetzlstorfer/plattentests-go/internal/aliases"
+	"github.com/jetzlstorfer/plattentests-go/internal/matchcache"
+	"github.com/zmb3/spotify/v2"
+)
+
+// fakeProvider is an in-memory MusicProvider. Searches match catalog tracks whose artist and
+// title both occur in the query and honour a "year:" filter like Spotify does; album searches
+// do the same with the album title, ignoring an edition in parentheses.
+type fakeProvider struct {
+	mu        sync.Mutex
+	catalog   []spotify.FullTrack
+	playlists map[spotify.ID][]spotify.ID
+	queries   []string
+	searchErr error
+	// albumQueries records album searches, which fail with albumErr when it is set.
+	albumQueries []string
+	albumErr     error
+	// trackLookups counts Tracks calls, which fail with tracksErr when it is set.
+	trackLookups int
+	tracksErr    error
+	// markets are the countries tracks are looked up for; catalog tracks are playable where
+	// their AvailableMarkets say, everywhere when they have none. relinks holds the version
+	// a catalog track is relinked to in the markets, by catalog ID.
+	markets []string
+	relinks map[spotify.ID]spotify.FullTrack
+	calls   []string
+	// addedAt records when a track was added to a playlist; seeded items have no date.
+	addedAt map[spotify.ID]map[spotify.ID]time.Time
+	// details holds name and description per playlist.
+	details map[spotify.ID][2]string
+}
+
+func newFakeProvider(catalog ...spotify.FullTrack) *fakeProvider {
+	return &fakeProvider{
+		catalog:   catalog,
+		playlists: make(map[spotify.ID][]spotify.ID),
+		addedAt:   make(map[spotify.ID]map[spotify.ID]time.Time),
+		details:   make(map[spotify.ID][2]string),
+	}
+}
+
+// fakeTrack builds a catalog entry released in year. Tracks of the same artist and album
+// share an album ID.
+func fakeTrack(id, artist, name, album, albumType, year string) spotify.FullTrack {
+	return spotify.FullTrack{
+		SimpleTrack: spotify.SimpleTrack{
+			ID:      spotify.ID(id),
+			Name:    name,
+			Artists: []spotify.SimpleArtist{{Name: artist}},
+		},
+		Album: spotify.SimpleAlbum{
+			ID:          fakeAlbumID(artist, album, albumType),
+			Name:        album,
+			AlbumType:   albumType,
+			ReleaseDate: year + "-01-01",
+			Artists:     []spotify.SimpleArtist{{Name: artist}},
+		},
+	}
+}
+
+func fakeAlbumID(artist, album, albumType string) spotify.ID {
+	return spotify.ID(strings.ReplaceAll(normalizeForComparison(artist+" "+album+" "+albumType), " ", "-"))
+}
+
+var fakeYearFilter = regexp.MustCompile(`\s*year:(\d{4})`)
+
+func (f *fakeProvider) Markets() []string {
+	return f.markets
+}
+
+// inMarkets returns track as Spotify reports it for the markets.
+func (f *fakeProvider) inMarkets(track spotify.FullTrack) spotify.FullTrack {
+	if len(f.markets) == 0 {
+		return track
+	}
+	isPlayable := true
+	if relinked, ok := f.relinks[track.ID]; ok {
+		relinked.LinkedFrom = &spotify.LinkedFromInfo{ID: track.ID}
+		relinked.IsPlayable = &isPlayable
+		return relinked
+	}
+	if len(track.AvailableMarkets) > 0 {
+		for _, market := range f.markets {
+			isPlayable = isPlayable && slices.Contains(track.AvailableMarkets, market)
+		}
+	}
+	track.IsPlayable = &isPlayable
+	track.AvailableMarkets = nil
+	return track
+}
+
+func (f *fakeProvider) SearchTracks(_ context.Context, query string) ([]spotify.FullTrack, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = append(f.queries, query)
+	if f.searchErr != nil {
+		return nil, f.searchErr
+	}
+
+	year := ""
+	if match := fakeYearFilter.FindStringSubmatch(query); match != nil {
+		year = match[1]
+	}
+	text := normalizeForComparison(fakeYearFilter.ReplaceAllString(query, ""))
+
+	var results []spotify.FullTrack
+	for _, track := range f.catalog {
+		if year != "" && !strings.HasPrefix(track.Album.ReleaseDate, year) {
+			continue
+		}
+		if strings.Contains(text, normalizeForComparison(track.Artists[0].Name)) &&
+			strings.Contains(text, normalizeTitle(track.Name)) {
+			results = append(results, f.inMarkets(track))
+		}
+	}
+	return results, nil
+}
+
+func (f *fakeProvider) SearchAlbums(_ context.Context, query string) ([]spotify.SimpleAlbum, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.albumQueries = append(f.albumQueries, query)
+	if f.albumErr != nil {
+		return nil, f.albumErr
+	}
+
+	year := ""
+	if match := fakeYearFilter.FindStringSubmatch(query); match != nil {
+		year = match[1]
+	}
+	text := normalizeForComparison(fakeYearFilter.ReplaceAllString(query, ""))
+
+	var results []spotify.SimpleAlbum
+	seen := make(map[spotify.ID]int)
+	for _, track := range f.catalog {
+		album := track.Album
+		if i, ok := seen[album.ID]; ok {
+			results[i].TotalTracks++
+			continue
+		}
+		if year != "" && !strings.HasPrefix(album.ReleaseDate, year) {
+			continue
+		}
+		if strings.Contains(text, normalizeForComparison(album.Artists[0].Name)) &&
+			strings.Contains(text, normalizeForComparison(strings.Split(album.Name, " (")[0])) {
+			album.TotalTracks = 1
+			seen[album.ID] = len(results)
+			results = append(results, album)
+		}
+	}
+	return results, nil
+}
+
+func (f *fakeProvider) AlbumTracks(_ context.Context, albumID spotify.ID) ([]spotify.SimpleTrack, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var tracks []spotify.SimpleTrack
+	for _, track := range f.catalog {
+		if track.Album.ID == albumID {
+			tracks = append(tracks, track.SimpleTrack)
+		}
+	}
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("album %s not found", albumID)
+	}
+	return tracks, nil
+}
+
+func (f *fakeProvider) Tracks(_ context.Context, trackIDs ...spotify.ID) ([]spotify.FullTrack, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.trackLookups++
+	if f.tracksErr != nil {
+		return nil, f.tracksErr
+	}
+	if len(trackIDs) > 50 {
+		return nil, fmt.Errorf("%d tracks requested, at most 50 are allowed", len(trackIDs))
+	}
+	var tracks []spotify.FullTrack
+	for _, id := range trackIDs {
+		for _, track := range f.catalog {
+			if track.ID == id {
+				tracks = append(tracks, f.inMarkets(track))
+				break
+			}
+		}
+		for _, relinked := range f.relinks {
+			if relinked.ID == id {
+				tracks = append(tracks, f.inMarkets(relinked))
+				break
+			}
+		}
+	}
+	return tracks, nil
+}
+
+func (f *fakeProvider) ReplacePlaylistTracks(_ context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, fmt.Sprintf("replace %s %d", playlistID, len(trackIDs)))
+	f.playlists[playlistID] = append([]spotify.ID(nil), trackIDs...)
+	return nil
+}
+
+func (f *fakeProvider) AddTracksToPlaylist(_ context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, fmt.Sprintf("add %s %d", playlistID, len(trackIDs)))
+	f.playlists[playlistID] = append(f.playlists[playlistID], trackIDs...)
+	for _, id := range trackIDs {
+		f.setAddedAt(playlistID, id, now())
+	}
+	return nil
+}
+
+// setAddedAt must be called with f.mu held or before the provider is shared.
+func (f *fakeProvider) setAddedAt(playlistID, trackID spotify.ID, at time.Time) {
+	if f.addedAt[playlistID] == nil {
+		f.addedAt[playlistID] = make(map[spotify.ID]time.Time)
+	}
+	f.addedAt[playlistID][trackID] = at
+}
+
+func (f *fakeProvider) RemoveTracksFromPlaylist(_ context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, fmt.Sprintf("remove %s %d", playlistID, len(trackIDs)))
+	remove := make(map[spotify.ID]bool, len(trackIDs))
+	for _, id := range trackIDs {
+		remove[id] = true
+	}
+	var kept []spotify.ID
+	for _, id := range f.playlists[playlistID] {
+		if !remove[id] {
+			kept = append(kept, id)
+		}
+	}
+	f.playlists[playlistID] = kept
+	return nil
+}
+
+func (f *fakeProvider) ReorderPlaylistTracks(_ context.Context, playlistID spotify.ID, rangeStart, insertBefore int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, fmt.Sprintf("move %s %d %d", playlistID, rangeStart, insertBefore))
+	items := f.playlists[playlistID]
+	id := items[rangeStart]
+	items = append(items[:rangeStart:rangeStart], items[rangeStart+1:]...)
+	if insertBefore > rangeStart {
+		insertBefore--
+	}
+	items = append(items[:insertBefore], append([]spotify.ID{id}, items[insertBefore:]...)...)
+	f.playlists[playlistID] = items
+	return nil
+}
+
+func (f *fakeProvider) CreatePlaylist(_ context.Context, name, description string) (spotify.ID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := spotify.ID(fmt.Sprintf("created-%d", len(f.playlists)+1))
+	f.calls = append(f.calls, fmt.Sprintf("create %s %q", id, name))
+	f.playlists[id] = nil
+	f.details[id] = [2]string{name, description}
+	return id, nil
+}
+
+func (f *fakeProvider) PlaylistDetails(_ context.Context, playlistID spotify.ID) (string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.playlists[playlistID]; !ok {
+		return "", "", fmt.Errorf("playlist %s not found", playlistID)
+	}
+	details := f.details[playlistID]
+	return details[0], details[1], nil
+}
+
+func (f *fakeProvider) ChangePlaylistDetails(_ context.Context, playlistID spotify.ID, name, description string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, fmt.Sprintf("details %s", playlistID))
+	details := f.details[playlistID]
+	if name != "" {
+		details[0] = name
+	}
+	if description != "" {
+		details[1] = description
+	}
+	f.details[playlistID] = details
+	return nil
+}
+
+func (f *fakeProvider) PlaylistItems(_ context.Context, playlistID spotify.ID) ([]PlaylistItem, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ids, ok := f.playlists[playlistID]
+	if !ok {
+		return nil, fmt.Errorf("playlist %s not found", playlistID)
+	}
+	items := make([]PlaylistItem, len(ids))
+	for i, id := range ids {
+		items[i] = PlaylistItem{ID: id, AddedAt: f.addedAt[playlistID][id]}
+	}
+	return items, nil
+}
+
+// useFakeCreatorDependencies configures the environment and replaces crawler and provider
+// so CreatePlaylist runs without network access.
+func useFakeCreatorDependencies(t *testing.T, provider *fakeProvider, records []crawler.Record, recordOfTheWeek string) {
+	t.Helper()
+	for key, value := range map[string]string{
+		"PLAYLIST_ID":      "test-playlist",
+		"PLAYLIST_ID_PROD": "prod-playlist",
+		"SPOTIFY_ID":       "id",
+		"SPOTIFY_SECRET":   "secret",
+		"TOKEN_FILE":       "token.txt",
+		"AZ_ACCOUNT":       "account",
+		"AZ_KEY":           "key",
+		"AZ_CONTAINER":     "container",
+		// archive playlist is off unless a test enables it
+		"ARCHIVE_PLAYLIST_ID":    "",
+		"ARCHIVE_PLAYLIST_WEEKS": "",
+		"WEEKLY_PLAYLISTS_FILE":  "",
+		// default description, playlists keep their name
+		"PLAYLIST_DESCRIPTION_TEMPLATE": "",
+		"PLAYLIST_NAME_TEMPLATE":        "",
+		"FULL_ALBUM_MIN_SCORE":          "",
+		"PLAYLIST_RULES":                "",
+		"PLAYLIST_PROFILES_FILE":        "",
+		"MATCH_OVERRIDES_FILE":          "",
+		"MATCH_CACHE_FILE":              "",
+		"MATCH_CACHE_BLOB":              "",
+		"MATCH_CACHE_TTL":               "",
+		"MATCH_CACHE_NEGATIVE_TTL":      "",
+		"MATCH_STRATEGY":                "",
+		"SPOTIFY_MARKETS":               "",
+		"ARTIST_ALIASES_FILE":           "",
+		"MATCH_SCORER":                  "",
+		"MATCH_MIN_ARTIST_SIMILARITY":   "",
+		"MATCH_MIN_TITLE_SIMILARITY":    "",
+	} {
+		t.Setenv(key, value)
+	}
+
+	prevRecords, prevBand, prevProvider, prevCache, prevAliases := getRecordsOfTheWeek, getRecordOfTheWeekBandName, newMusicProvider, openMatchCache, openArtistAliases
+	getRecordsOfTheWeek = func() ([]crawler.Record, error) {
+		return append([]crawler.Record(nil), records...), nil
+	}
+	getRecordOfTheWeekBandName = func() (string, error) { return recordOfTheWeek, nil }
+	newMusicProvider = func() (MusicProvider, error) { return provider, nil }
+	// every test starts with an empty process-local match cache
+	cache, err := matchcache.Open(nil, defaultMatchCacheTTL, defaultMatchCacheNegativeTTL)
+	if err != nil {
+		t.Fatalf("matchcache.Open() error: %v", err)
+	}
+	openMatchCache = func() (*matchcache.Cache, string, error) { return cache, "memory", nil }
+	// and without aliases
+	table, err := aliases.Open("")
+	if err != nil {
+		t.Fatalf("aliases.Open() error: %v", err)
+	}
+	openArtistAliases = func() (*aliases.Table, error) { return table, nil }
+	t.Cleanup(func() {
+		getRecordsOfTheWeek, getRecordOfTheWeekBandName, newMusicProvider, openMatchCache, openArtistAliases = prevRecords, prevBand, prevProvider, prevCache, prevAliases
+	})
+}
+
+func TestCreatePlaylistEndToEnd(t *testing.T) {
+	provider := newFakeProvider(
+		fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"),
+		fakeTrack("t1-single", "Band A", "Opener", "Opener", "single", "2024"),
+		fakeTrack("t2", "Band B", "Hit Song", "Second Album", "album", "2023"),
+		fakeTrack("t3", "Band B", "Deep Cut", "Second Album", "album", "2023"),
+	)
+	provider.playlists["test-playlist"] = []spotify.ID{"stale"}
+	provider.playlists["prod-playlist"] = []spotify.ID{"t3", "old"}
+
+	useFakeCreatorDependencies(t, provider, []crawler.Record{
+		{Band: "Band A", Recordname: "First Album", Score: 7, ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band A", Trackname: "Opener", IsHighlight: true},
+			{Band: "Band A", Trackname: "Filler"},
+		}},
+		{Band: "Band B", Recordname: "Second Album", Score: 9, ReleaseYear: "2023", Tracks: []crawler.Track{
+			{Band: "Band B", Trackname: "Hit Song", IsHighlight: true},
+			{Band: "Band B", Trackname: "Deep Cut", IsHighlight: true},
+			{Band: "Band B", Trackname: "Unreleased", IsHighlight: true},
+		}},
+	}, "Band A")
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+
+	// Record of the week first, then by score; the album version wins over the single.
+	wantPlaylist := []spotify.ID{"t1", "t2", "t3"}
+	if got := provider.playlists["test-playlist"]; !reflect.DeepEqual(got, wantPlaylist) {
+		t.Errorf("playlist = %v, want %v", got, wantPlaylist)
+	}
+	if wantCalls := []string{"remove test-playlist 1", "add test-playlist 3", "details test-playlist"}; !reflect.DeepEqual(provider.calls, wantCalls) {
+		t.Errorf("provider calls = %v, want %v", provider.calls, wantCalls)
+	}
+
+	if result.PlaylistID != "test-playlist" || result.TotalTracks != 4 || result.FoundTracks != 3 {
+		t.Errorf("result = %+v", result)
+	}
+	if !reflect.DeepEqual(result.NotFound, []string{"Band B - Unreleased"}) {
+		t.Errorf("NotFound = %v", result.NotFound)
+	}
+	if !result.ComparedToProd || result.NewTracksComparedToProd != 2 || result.AlreadyInProdTracks != 1 {
+		t.Errorf("prod comparison = %+v", result)
+	}
+	if !result.Records[0].IsRecordOfTheWeek || !result.Records[0].Tracks[0].Found {
+		t.Errorf("record of the week must be first and marked found: %+v", result.Records[0])
+	}
+}
+
+func TestCreatePlaylistReportsFailedSearchesSeparately(t *testing.T) {
+	provider := newFakeProvider()
+	provider.searchErr = errors.New("rate limited")
+	provider.playlists["explicit"] = nil
+	useFakeCreatorDependencies(t, provider, []crawler.Record{
+		{Band: "Band", Recordname: "Album", Tracks: []crawler.Track{{Band: "Band", Trackname: "Song", IsHighlight: true}}},
+	}, "")
+
+	result, err := CreatePlaylist("explicit")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	if result.PlaylistID != "explicit" || result.FoundTracks != 0 || len(result.NotFound) != 0 || len(result.Unmatched) != 0 {
+		t.Errorf("result = %+v", result)
+	}
+	if !reflect.DeepEqual(result.FailedSearches, []string{"Band - Song"}) {
+		t.Errorf("FailedSearches = %v, want [Band - Song]", result.FailedSearches)
+	}
+	if len(provider.playlists["explicit"]) != 0 {
+		t.Errorf("nothing must be added, got %v", provider.playlists["explicit"])
+	}
+}
+
+func TestCreatePlaylistProviderLoginError(t *testing.T) {
+	useFakeCreatorDependencies(t, newFakeProvider(), nil, "")
+	newMusicProvider = func() (MusicProvider, error) { return nil, errors.New("spotify login failed: boom") }
+
+	if _, err := CreatePlaylist(""); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("CreatePlaylist() error = %v, want login error", err)
+	}
+}
+
+func TestSearchSongRetriesWithoutYear(t *testing.T) {
+	provider := newFakeProvider(fakeTrack("remaster", "Band", "Song", "Best Of", "compilation", "2010"))
+
+	id, err := searchSong(provider, "Song", crawler.Record{Band: "Band", Recordname: "Album", ReleaseYear: "1999"})
+	if err != nil {
+		t.Fatalf("searchSong() error: %v", err)
+	}
+	if id != "remaster" {
+		t.Errorf("searchSong() = %q, want remaster", id)
+	}
+	if want := []string{"Band Song year:1999", "Band Song"}; !reflect.DeepEqual(provider.queries, want) {
+		t.Errorf("queries = %q, want %q", provider.queries, want)
+	}
+}
+
+func TestPreviewPlaylistLeavesPlaylistUntouched(t *testing.T) {
+	provider := newFakeProvider(
+		fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"),
+		fakeTrack("t2", "Band A", "Closer", "First Album", "album", "2024"),
+	)
+	provider.playlists["test-playlist"] = []spotify.ID{"t2", "old", "old"}
+	useFakeCreatorDependencies(t, provider, []crawler.Record{
+		{Band: "Band A", Recordname: "First Album", ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band A", Trackname: "Opener", IsHighlight: true},
+			{Band: "Band A", Trackname: "Closer", IsHighlight: true},
+			{Band: "Band A", Trackname: "Interlude", IsHighlight: true},
+		}},
+	}, "")
+
+	result, err := PreviewPlaylist("")
+	if err != nil {
+		t.Fatalf("PreviewPlaylist() error: %v", err)
+	}
+
+	if len(provider.calls) != 0 {
+		t.Errorf("dry run modified the playlist: %v", provider.calls)
+	}
+	if got, want := provider.playlists["test-playlist"], []spotify.ID{"t2", "old", "old"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("playlist = %v, want %v", got, want)
+	}
+
+	wantPlanned := []PlannedTrack{
+		{ID: "t1", Band: "Band A", Trackname: "Opener", Recordname: "First Album"},
+		{ID: "t2", Band: "Band A", Trackname: "Closer", Recordname: "First Album"},
+	}
+	if !result.DryRun || !reflect.DeepEqual(result.Planned, wantPlanned) {
+		t.Errorf("planned = %+v (dry run %v), want %+v", result.Planned, result.DryRun, wantPlanned)
+	}
+	if !result.ComparedToCurrent || !reflect.DeepEqual(result.Added, wantPlanned[:1]) ||
+		!reflect.DeepEqual(result.Removed, []spotify.ID{"old"}) || result.Unchanged != 1 {
+		t.Errorf("diff = added %v, removed %v, unchanged %d", result.Added, result.Removed, result.Unchanged)
+	}
+	if !reflect.DeepEqual(result.NotFound, []string{"Band A - Interlude"}) {
+		t.Errorf("NotFound = %v", result.NotFound)
+	}
+}
+
+func TestDiffPlaylist(t *testing.T) {
+	planned := []PlannedTrack{{ID: "a"}, {ID: "b"}}
+
+	tests := []struct {
+		name          string
+		current       []spotify.ID
+		wantAdded     []PlannedTrack
+		wantRemoved   []spotify.ID
+		wantUnchanged int
+	}{
+		{name: "empty playlist", current: nil, wantAdded: planned},
+		{name: "identical", current: []spotify.ID{"b", "a"}, wantUnchanged: 2},
+		{name: "replaced", current: []spotify.ID{"c", "a", "c"}, wantAdded: planned[1:], wantRemoved: []spotify.ID{"c"}, wantUnchanged: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed, unchanged := diffPlaylist(planned, tt.current)
+			if !reflect.DeepEqual(added, tt.wantAdded) || !reflect.DeepEqual(removed, tt.wantRemoved) || unchanged != tt.wantUnchanged {
+				t.Errorf("diffPlaylist() = %v, %v, %d; want %v, %v, %d", added, removed, unchanged, tt.wantAdded, tt.wantRemoved, tt.wantUnchanged)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package creator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+)
+
+// How the record of the week is treated by Rules.RecordOfTheWeek.
+const (
+	// RecordOfTheWeekFirst puts the record of the week at the top of the playlist.
+	RecordOfTheWeekFirst = "first"
+	// RecordOfTheWeekByScore orders the record of the week by score like every other record.
+	RecordOfTheWeekByScore = "score"
+	// RecordOfTheWeekExclude leaves the record of the week out.
+	RecordOfTheWeekExclude = "exclude"
+)
+
+// Rules select and order the records and tracks of a run. The zero value reproduces the
+// classic behaviour: the first MaxRecordsOfTheWeek records, highlights only, record of the
+// week first, then by score.
+type Rules struct {
+	// MaxRecords limits how many records of the week are considered; 0 means
+	// MaxRecordsOfTheWeek.
+	MaxRecords int `json:"maxRecords,omitempty"`
+	// MinScore skips records scored below it; 0 keeps every record.
+	MinScore int `json:"minScore,omitempty"`
+	// MaxTracksPerRecord caps the tracks taken from one record, in tracklist order; 0 means
+	// no limit.
+	MaxTracksPerRecord int `json:"maxTracksPerRecord,omitempty"`
+	// FullAlbumMinScore adds the complete tracklist of records scored at least this high;
+	// 0 adds only highlights.
+	FullAlbumMinScore int `json:"fullAlbumMinScore,omitempty"`
+	// ExcludeBands skips every record of these bands.
+	ExcludeBands []string `json:"excludeBands,omitempty"`
+	// ExcludeTracks skips single tracks, written "Band - Track" like the not-found list.
+	ExcludeTracks []string `json:"excludeTracks,omitempty"`
+	// ReleasedOnly skips records whose release date is still in the future.
+	ReleasedOnly bool `json:"releasedOnly,omitempty"`
+	// RecordOfTheWeek is one of the RecordOfTheWeek constants; empty means
+	// RecordOfTheWeekFirst.
+	RecordOfTheWeek string `json:"recordOfTheWeek,omitempty"`
+}
+
+// SkippedRecord is a record the rules left out of the playlist.
+type SkippedRecord struct {
+	Record string
+	Reason string
+}
+
+// Validate reports every problem with the rules at once.
+func (r Rules) Validate() error {
+	var errs []error
+	if r.MaxRecords < 0 {
+		errs = append(errs, fmt.Errorf("maxRecords must not be negative, got %d", r.MaxRecords))
+	}
+	if r.MinScore < 0 || r.MinScore > 10 {
+		errs = append(errs, fmt.Errorf("minScore must be between 0 and 10, got %d", r.MinScore))
+	}
+	if r.MaxTracksPerRecord < 0 {
+		errs = append(errs, fmt.Errorf("maxTracksPerRecord must not be negative, got %d", r.MaxTracksPerRecord))
+	}
+	if r.FullAlbumMinScore < 0 || r.FullAlbumMinScore > 10 {
+		errs = append(errs, fmt.Errorf("fullAlbumMinScore must be between 0 and 10, got %d", r.FullAlbumMinScore))
+	}
+	for _, band := range r.ExcludeBands {
+		if strings.TrimSpace(band) == "" {
+			errs = append(errs, errors.New("excludeBands must not contain empty names"))
+			break
+		}
+	}
+	for _, track := range r.ExcludeTracks {
+		band, name, ok := strings.Cut(track, " - ")
+		if !ok || strings.TrimSpace(band) == "" || strings.TrimSpace(name) == "" {
+			errs = append(errs, fmt.Errorf("excludeTracks entries must look like \"Band - Track\", got %q", track))
+		}
+	}
+	switch r.RecordOfTheWeek {
+	case "", RecordOfTheWeekFirst, RecordOfTheWeekByScore, RecordOfTheWeekExclude:
+	default:
+		errs = append(errs, fmt.Errorf("recordOfTheWeek must be %q, %q or %q, got %q",
+			RecordOfTheWeekFirst, RecordOfTheWeekByScore, RecordOfTheWeekExclude, r.RecordOfTheWeek))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid playlist rules: %w", err)
+	}
+	return nil
+}
+
+// Describe lists the rules in words for the run summary.
+func (r Rules) Describe() []string {
+	maxRecords := r.MaxRecords
+	if maxRecords == 0 {
+		maxRecords = MaxRecordsOfTheWeek
+	}
+	lines := []string{fmt.Sprintf("first %d records of the week", maxRecords)}
+	if r.MinScore > 0 {
+		lines = append(lines, fmt.Sprintf("records scored %d/10 or better", r.MinScore))
+	}
+	if r.ReleasedOnly {
+		lines = append(lines, "released records only")
+	}
+	if r.FullAlbumMinScore > 0 {
+		lines = append(lines, fmt.Sprintf("complete tracklist from %d/10, highlights otherwise", r.FullAlbumMinScore))
+	} else {
+		lines = append(lines, "highlights only")
+	}
+	if r.MaxTracksPerRecord > 0 {
+		lines = append(lines, fmt.Sprintf("at most %d track(s) per record", r.MaxTracksPerRecord))
+	}
+	if len(r.ExcludeBands) > 0 {
+		lines = append(lines, "excluding bands: "+strings.Join(r.ExcludeBands, ", "))
+	}
+	if len(r.ExcludeTracks) > 0 {
+		lines = append(lines, "excluding tracks: "+strings.Join(r.ExcludeTracks, ", "))
+	}
+	switch r.RecordOfTheWeek {
+	case RecordOfTheWeekByScore:
+		lines = append(lines, "record of the week ordered by score")
+	case RecordOfTheWeekExclude:
+		lines = append(lines, "record of the week excluded")
+	default:
+		lines = append(lines, "record of the week first, then by score")
+	}
+	return lines
+}
+
+// runRules returns opts.Rules, the JSON in PLAYLIST_RULES or the default rules. A missing
+// fullAlbumMinScore falls back to FULL_ALBUM_MIN_SCORE.
+func runRules(opts Options) (Rules, error) {
+	var rules Rules
+	switch {
+	case opts.Rules != nil:
+		rules = *opts.Rules
+	case strings.TrimSpace(os.Getenv("PLAYLIST_RULES")) != "":
+		decoder := json.NewDecoder(bytes.NewReader([]byte(os.Getenv("PLAYLIST_RULES"))))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&rules); err != nil {
+			return Rules{}, fmt.Errorf("PLAYLIST_RULES is not valid: %w", err)
+		}
+	}
+	if rules.FullAlbumMinScore == 0 {
+		minScore, err := fullAlbumMinScore()
+		if err != nil {
+			return Rules{}, err
+		}
+		rules.FullAlbumMinScore = minScore
+	}
+	return rules, rules.Validate()
+}
+
+// selectRecords applies the record rules and orders the remaining records for the playlist.
+// It returns the records that were left out together with the reason.
+func selectRecords(records []crawler.Record, rules Rules, recordOfTheWeek string) ([]crawler.Record, []SkippedRecord) {
+	maxRecords := rules.MaxRecords
+	if maxRecords == 0 {
+		maxRecords = MaxRecordsOfTheWeek
+	}
+	if len(records) > maxRecords {
+		records = records[:maxRecords]
+	}
+
+	excludedBands := make(map[string]bool, len(rules.ExcludeBands))
+	for _, band := range rules.ExcludeBands {
+		excludedBands[normalizeForComparison(band)] = true
+	}
+	recordOfTheWeek = strings.TrimSpace(recordOfTheWeek)
+
+	var selected []crawler.Record
+	var skipped []SkippedRecord
+	for _, record := range records {
+		reason := ""
+		switch {
+		case recordOfTheWeek != "" && record.Band == recordOfTheWeek && rules.RecordOfTheWeek == RecordOfTheWeekExclude:
+			reason = "record of the week excluded"
+		case excludedBands[normalizeForComparison(record.Band)]:
+			reason = "band excluded"
+		case rules.MinScore > 0 && record.Score < rules.MinScore:
+			reason = fmt.Sprintf("score %d/10 below %d/10", record.Score, rules.MinScore)
+		case rules.ReleasedOnly && record.HasFutureReleaseDate():
+			reason = "not released yet (" + record.ReleaseDate + ")"
+		}
+		if reason != "" {
+			skipped = append(skipped, SkippedRecord{Record: record.Band + " - " + record.Recordname, Reason: reason})
+			continue
+		}
+		selected = append(selected, record)
+	}
+
+	if rules.RecordOfTheWeek == RecordOfTheWeekByScore {
+		// still marked, so the web UI can highlight it
+		ordered := orderRecordsForPlaylist(selected, "")
+		for i := range ordered {
+			if recordOfTheWeek != "" && ordered[i].Band == recordOfTheWeek {
+				ordered[i].IsRecordOfTheWeek = true
+				break
+			}
+		}
+		return ordered, skipped
+	}
+	return orderRecordsForPlaylist(selected, recordOfTheWeek), skipped
+}
+
+// tracksToSearch returns the indexes of the record's tracks that go into the playlist, in
+// tracklist order: every track of a full album, otherwise only the highlights, without
+// excluded tracks and capped at MaxTracksPerRecord.
+func tracksToSearch(record crawler.Record, rules Rules) []int {
+	fullAlbum := isFullAlbum(record, rules.FullAlbumMinScore)
+	excluded := make(map[string]bool, len(rules.ExcludeTracks))
+	for _, track := range rules.ExcludeTracks {
+		band, name, _ := strings.Cut(track, " - ")
+		excluded[foundCacheKey(band, name)] = true
+	}
+
+	var indexes []int
+	for i, track := range record.Tracks {
+		if rules.MaxTracksPerRecord > 0 && len(indexes) == rules.MaxTracksPerRecord {
+			break
+		}
+		if !fullAlbum && !track.IsHighlight {
+			continue
+		}
+		if excluded[foundCacheKey(record.Band, track.Trackname)] {
+			continue
+		}
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
@@ -0,0 +1,219 @@
+package creator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/zmb3/spotify/v2"
+)
+
+func TestRulesValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   Rules
+		wantErr []string
+	}{
+		{name: "defaults", rules: Rules{}},
+		{name: "everything set", rules: Rules{
+			MaxRecords: 10, MinScore: 8, MaxTracksPerRecord: 2, FullAlbumMinScore: 9,
+			ExcludeBands: []string{"Band X"}, ExcludeTracks: []string{"Band A - Intro"},
+			ReleasedOnly: true, RecordOfTheWeek: RecordOfTheWeekByScore,
+		}},
+		{
+			name:    "all problems are reported",
+			rules:   Rules{MaxRecords: -1, MinScore: 11, MaxTracksPerRecord: -2, FullAlbumMinScore: -1, ExcludeBands: []string{" "}, ExcludeTracks: []string{"Intro"}, RecordOfTheWeek: "last"},
+			wantErr: []string{"maxRecords", "minScore", "maxTracksPerRecord", "fullAlbumMinScore", "excludeBands", `"Intro"`, `"last"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rules.Validate()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("Validate() error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Validate() must fail")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectRecords(t *testing.T) {
+	future := time.Now().AddDate(0, 1, 0).Format("02.01.2006")
+	records := []crawler.Record{
+		{Band: "Band A", Recordname: "A", Score: 7},
+		{Band: "Band B", Recordname: "B", Score: 9},
+		{Band: "Band C", Recordname: "C", Score: 8, ReleaseDate: future},
+		{Band: "Band D", Recordname: "D", Score: 6},
+	}
+
+	// Band A is the record of the week; by default it goes first, then by score.
+	tests := []struct {
+		name        string
+		rules       Rules
+		wantBands   []string
+		wantSkipped []SkippedRecord
+	}{
+		{name: "defaults", wantBands: []string{"Band A", "Band B", "Band C", "Band D"}},
+		{name: "max records", rules: Rules{MaxRecords: 2}, wantBands: []string{"Band A", "Band B"}},
+		{
+			name:        "min score",
+			rules:       Rules{MinScore: 7},
+			wantBands:   []string{"Band A", "Band B", "Band C"},
+			wantSkipped: []SkippedRecord{{Record: "Band D - D", Reason: "score 6/10 below 7/10"}},
+		},
+		{
+			name:        "released only",
+			rules:       Rules{ReleasedOnly: true},
+			wantBands:   []string{"Band A", "Band B", "Band D"},
+			wantSkipped: []SkippedRecord{{Record: "Band C - C", Reason: "not released yet (" + future + ")"}},
+		},
+		{
+			name:        "excluded band ignores case",
+			rules:       Rules{ExcludeBands: []string{"band b"}},
+			wantBands:   []string{"Band A", "Band C", "Band D"},
+			wantSkipped: []SkippedRecord{{Record: "Band B - B", Reason: "band excluded"}},
+		},
+		{name: "record of the week by score", rules: Rules{RecordOfTheWeek: RecordOfTheWeekByScore}, wantBands: []string{"Band B", "Band C", "Band A", "Band D"}},
+		{
+			name:        "record of the week excluded",
+			rules:       Rules{RecordOfTheWeek: RecordOfTheWeekExclude},
+			wantBands:   []string{"Band B", "Band C", "Band D"},
+			wantSkipped: []SkippedRecord{{Record: "Band A - A", Reason: "record of the week excluded"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, skipped := selectRecords(records, tt.rules, "Band A")
+			var bands []string
+			for _, record := range selected {
+				bands = append(bands, record.Band)
+			}
+			if !reflect.DeepEqual(bands, tt.wantBands) {
+				t.Errorf("selected = %v, want %v", bands, tt.wantBands)
+			}
+			if !reflect.DeepEqual(skipped, tt.wantSkipped) {
+				t.Errorf("skipped = %+v, want %+v", skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestTracksToSearch(t *testing.T) {
+	record := crawler.Record{Band: "Band A", Score: 8, Tracks: []crawler.Track{
+		{Trackname: "One"},
+		{Trackname: "Two", IsHighlight: true},
+		{Trackname: "Three"},
+		{Trackname: "Four", IsHighlight: true},
+		{Trackname: "Five", IsHighlight: true},
+	}}
+
+	tests := []struct {
+		name  string
+		rules Rules
+		want  []int
+	}{
+		{name: "highlights", want: []int{1, 3, 4}},
+		{name: "score below full album threshold", rules: Rules{FullAlbumMinScore: 9}, want: []int{1, 3, 4}},
+		{name: "score at full album threshold", rules: Rules{FullAlbumMinScore: 8}, want: []int{0, 1, 2, 3, 4}},
+		{name: "max tracks per record", rules: Rules{MaxTracksPerRecord: 2}, want: []int{1, 3}},
+		{name: "full album capped", rules: Rules{FullAlbumMinScore: 7, MaxTracksPerRecord: 2}, want: []int{0, 1}},
+		{name: "excluded track", rules: Rules{ExcludeTracks: []string{"band a - FOUR"}}, want: []int{1, 4}},
+		{name: "excluded track of another band", rules: Rules{ExcludeTracks: []string{"Band B - Four"}}, want: []int{1, 3, 4}},
+		{name: "excluded tracks do not count towards the cap", rules: Rules{ExcludeTracks: []string{"Band A - Two"}, MaxTracksPerRecord: 2}, want: []int{3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tracksToSearch(record, tt.rules); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tracksToSearch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreatePlaylistAppliesRules(t *testing.T) {
+	provider := newFakeProvider(
+		fakeTrack("a1", "Band A", "Opener", "First Album", "album", "2024"),
+		fakeTrack("b1", "Band B", "Hit", "Second Album", "album", "2024"),
+		fakeTrack("b2", "Band B", "Deep Cut", "Second Album", "album", "2024"),
+		fakeTrack("b3", "Band B", "Encore", "Second Album", "album", "2024"),
+	)
+	provider.playlists["test-playlist"] = nil
+	useFakeCreatorDependencies(t, provider, []crawler.Record{
+		{Band: "Band A", Recordname: "First Album", Score: 6, ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band A", Trackname: "Opener", IsHighlight: true},
+		}},
+		{Band: "Band B", Recordname: "Second Album", Score: 9, ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band B", Trackname: "Hit", IsHighlight: true},
+			{Band: "Band B", Trackname: "Deep Cut", IsHighlight: true},
+			{Band: "Band B", Trackname: "Encore", IsHighlight: true},
+		}},
+	}, "Band A")
+	t.Setenv("PLAYLIST_RULES", `{"minScore": 7, "maxTracksPerRecord": 2, "excludeTracks": ["Band B - Hit"]}`)
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	if got, want := provider.playlists["test-playlist"], []spotify.ID{"b2", "b3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("playlist = %v, want %v", got, want)
+	}
+	wantRules := Rules{MinScore: 7, MaxTracksPerRecord: 2, ExcludeTracks: []string{"Band B - Hit"}}
+	if !reflect.DeepEqual(result.Rules, wantRules) {
+		t.Errorf("Rules = %+v, want %+v", result.Rules, wantRules)
+	}
+	wantSkipped := []SkippedRecord{{Record: "Band A - First Album", Reason: "score 6/10 below 7/10"}}
+	if !reflect.DeepEqual(result.SkippedRecords, wantSkipped) {
+		t.Errorf("SkippedRecords = %+v, want %+v", result.SkippedRecords, wantSkipped)
+	}
+	if result.TotalTracks != 2 {
+		t.Errorf("TotalTracks = %d, want 2", result.TotalTracks)
+	}
+}
+
+func TestInvalidRulesFailBeforeCrawling(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		rules   *Rules
+		wantErr string
+	}{
+		{name: "unknown field", env: `{"minScroe": 8}`, wantErr: "PLAYLIST_RULES"},
+		{name: "invalid value", env: `{"minScore": 12}`, wantErr: "minScore"},
+		{name: "options", rules: &Rules{RecordOfTheWeek: "middle"}, wantErr: "recordOfTheWeek"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeCreatorDependencies(t, newFakeProvider(), nil, "")
+			t.Setenv("PLAYLIST_RULES", tt.env)
+			crawled := false
+			getRecordsOfTheWeek = func() ([]crawler.Record, error) {
+				crawled = true
+				return nil, nil
+			}
+
+			_, err := Run(Options{Rules: tt.rules})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Run() error = %v, want %s error", err, tt.wantErr)
+			}
+			if crawled {
+				t.Error("rules must be validated before crawling")
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package creator
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jetzlstorfer/plattentests-go/internal/aliases"
+	"github.com/jetzlstorfer/plattentests-go/internal/similarity"
+)
+
+// minSimilarity is the artist and title similarity a match needs unless the search was
+// restricted to the release year or MATCH_MIN_ARTIST_SIMILARITY and
+// MATCH_MIN_TITLE_SIMILARITY say otherwise.
+const minSimilarity = 0.8
+
+// matchConfig is how highlights are compared with what Spotify returns: the aliases of the
+// bands, the similarity scorer and the similarity a match needs. The zero value uses the
+// default scorer and thresholds and knows no aliases.
+type matchConfig struct {
+	aliases *aliases.Table
+	scorer  similarity.Scorer
+	// scorerName is MATCH_SCORER as configured, empty for the default scorer
+	scorerName string
+	// minArtistSimilarity and minTitleSimilarity are minSimilarity when zero
+	minArtistSimilarity float64
+	minTitleSimilarity  float64
+}
+
+// loadMatchConfig reads MATCH_SCORER, MATCH_MIN_ARTIST_SIMILARITY and
+// MATCH_MIN_TITLE_SIMILARITY and opens the artist aliases.
+func loadMatchConfig() (matchConfig, error) {
+	var config matchConfig
+	scorer, err := similarity.ByName(os.Getenv("MATCH_SCORER"))
+	if err != nil {
+		return matchConfig{}, fmt.Errorf("MATCH_SCORER: %w", err)
+	}
+	config.scorer = scorer
+	config.scorerName = strings.ToLower(strings.TrimSpace(os.Getenv("MATCH_SCORER")))
+	if config.minArtistSimilarity, err = similarityThreshold("MATCH_MIN_ARTIST_SIMILARITY"); err != nil {
+		return matchConfig{}, err
+	}
+	if config.minTitleSimilarity, err = similarityThreshold("MATCH_MIN_TITLE_SIMILARITY"); err != nil {
+		return matchConfig{}, err
+	}
+	if config.aliases, err = openArtistAliases(); err != nil {
+		return matchConfig{}, err
+	}
+	return config, nil
+}
+
+// similarityThreshold reads a similarity between 0 and 1 from the environment variable name;
+// unset is minSimilarity.
+func similarityThreshold(name string) (float64, error) {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return minSimilarity, nil
+	}
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil || threshold <= 0 || threshold > 1 {
+		return 0, fmt.Errorf("%s must be a number above 0 and at most 1, got %q", name, value)
+	}
+	return threshold, nil
+}
+
+// similarity rates two normalized names with the configured scorer.
+func (c matchConfig) similarity(a, b string) float64 {
+	if c.scorer == nil {
+		return similarity.Default.Similarity(a, b)
+	}
+	return c.scorer.Similarity(a, b)
+}
+
+// minArtist is the artist similarity a match needs.
+func (c matchConfig) minArtist() float64 {
+	if c.minArtistSimilarity == 0 {
+		return minSimilarity
+	}
+	return c.minArtistSimilarity
+}
+
+// minTitle is the track and album title similarity a match needs.
+func (c matchConfig) minTitle() float64 {
+	if c.minTitleSimilarity == 0 {
+		return minSimilarity
+	}
+	return c.minTitleSimilarity
+}
+
+// normalizeTitle normalizes a track title for comparison without its version suffix, so
+// "Song (Remastered 2011)" and "Song - Live at Wembley" compare equal to "Song".
+func normalizeTitle(name string) string {
+	return normalizeForComparison(similarity.StripVersion(name))
+}
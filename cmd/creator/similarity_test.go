@@ -0,0 +1,142 @@
+package creator
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/jetzlstorfer/plattentests-go/internal/similarity"
+)
+
+// similarityPair is a labelled comparison of testdata/similarity.json: a name as reviewed on
+// Plattentests.de and a name Spotify returned for it, and whether they are the same.
+type similarityPair struct {
+	// Kind is "title" or "artist".
+	Kind      string
+	Reviewed  string
+	Candidate string
+	Match     bool
+}
+
+func loadSimilarityPairs(tb testing.TB) []similarityPair {
+	tb.Helper()
+	data, err := os.ReadFile("testdata/similarity.json")
+	if err != nil {
+		tb.Fatalf("read similarity dataset: %v", err)
+	}
+	var pairs []similarityPair
+	if err := json.Unmarshal(data, &pairs); err != nil {
+		tb.Fatalf("decode similarity dataset: %v", err)
+	}
+	return pairs
+}
+
+// accuracy is the share of pairs config accepts or rejects like the label says, comparing
+// titles with normalizeTitle, or with normalizeForComparison when stripVersion is false.
+func accuracy(pairs []similarityPair, config matchConfig, stripVersion bool) (float64, []similarityPair) {
+	var wrong []similarityPair
+	for _, pair := range pairs {
+		var accepted bool
+		switch pair.Kind {
+		case "artist":
+			accepted = config.similarity(normalizeArtist(pair.Reviewed), normalizeArtist(pair.Candidate)) >= config.minArtist()
+		default:
+			normalize := normalizeTitle
+			if !stripVersion {
+				normalize = normalizeForComparison
+			}
+			accepted = config.similarity(normalize(pair.Reviewed), normalize(pair.Candidate)) >= config.minTitle()
+		}
+		if accepted != pair.Match {
+			wrong = append(wrong, pair)
+		}
+	}
+	return 1 - float64(len(wrong))/float64(len(pairs)), wrong
+}
+
+func TestSimilarityDataset(t *testing.T) {
+	pairs := loadSimilarityPairs(t)
+	// the matcher before configurable scorers: Levenshtein on the full names
+	baseline, _ := accuracy(pairs, matchConfig{scorer: similarity.Levenshtein}, false)
+	t.Logf("%-28s %.2f", "levenshtein, full names", baseline)
+
+	best := ""
+	results := make(map[string]float64)
+	for _, name := range similarity.Names() {
+		scorer, err := similarity.ByName(name)
+		if err != nil {
+			t.Fatalf("ByName(%q) error: %v", name, err)
+		}
+		got, wrong := accuracy(pairs, matchConfig{scorer: scorer}, true)
+		results[name] = got
+		t.Logf("%-28s %.2f", name, got)
+		for _, pair := range wrong {
+			t.Logf("  %s: %q vs %q, want match %v", pair.Kind, pair.Reviewed, pair.Candidate, pair.Match)
+		}
+		if best == "" || got > results[best] {
+			best = name
+		}
+	}
+
+	if results[similarity.NameCombined] <= baseline {
+		t.Errorf("default scorer accuracy %.2f, want above the Levenshtein baseline %.2f", results[similarity.NameCombined], baseline)
+	}
+	if results[best] > results[similarity.NameCombined] {
+		t.Errorf("%s scores %.2f on the dataset, better than the default %.2f", best, results[best], results[similarity.NameCombined])
+	}
+}
+
+func TestLoadMatchConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		scorer     string
+		minArtist  string
+		minTitle   string
+		wantArtist float64
+		wantTitle  float64
+		wantErr    bool
+	}{
+		{name: "defaults", wantArtist: minSimilarity, wantTitle: minSimilarity},
+		{name: "configured", scorer: "Levenshtein", minArtist: "0.9", minTitle: " 0.7 ", wantArtist: 0.9, wantTitle: 0.7},
+		{name: "unknown scorer", scorer: "soundex", wantErr: true},
+		{name: "threshold above one", minTitle: "1.5", wantErr: true},
+		{name: "zero threshold", minArtist: "0", wantErr: true},
+		{name: "threshold not a number", minArtist: "high", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeCreatorDependencies(t, newFakeProvider(), nil, "")
+			t.Setenv("MATCH_SCORER", tt.scorer)
+			t.Setenv("MATCH_MIN_ARTIST_SIMILARITY", tt.minArtist)
+			t.Setenv("MATCH_MIN_TITLE_SIMILARITY", tt.minTitle)
+
+			matchCfg, err := loadMatchConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadMatchConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if matchCfg.minArtist() != tt.wantArtist || matchCfg.minTitle() != tt.wantTitle {
+				t.Errorf("thresholds = %.2f, %.2f, want %.2f, %.2f", matchCfg.minArtist(), matchCfg.minTitle(), tt.wantArtist, tt.wantTitle)
+			}
+			if matchCfg.aliases == nil {
+				t.Error("loadMatchConfig() must open the artist aliases")
+			}
+		})
+	}
+}
+
+func BenchmarkSimilarityDataset(b *testing.B) {
+	pairs := loadSimilarityPairs(b)
+	for _, name := range similarity.Names() {
+		scorer, _ := similarity.ByName(name)
+		config := matchConfig{scorer: scorer}
+		b.Run(name, func(b *testing.B) {
+			for b.Loop() {
+				accuracy(pairs, config, true)
+			}
+		})
+	}
+}
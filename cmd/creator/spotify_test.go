@@ -0,0 +1,303 @@
+package creator
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	myauth "github.com/jetzlstorfer/plattentests-go/internal/auth"
+	"github.com/jetzlstorfer/plattentests-go/internal/ratelimit"
+	"github.com/jetzlstorfer/plattentests-go/internal/spotifytest"
+	"github.com/zmb3/spotify/v2"
+)
+
+// useSpotifyServer runs the creator against the fake Spotify API seeded from
+// testdata/spotify.json, going through internal/auth and the real spotifyProvider.
+func useSpotifyServer(t *testing.T, records []crawler.Record, recordOfTheWeek string) *spotifytest.Server {
+	t.Helper()
+	return useSpotifyServerWithFixture(t, spotifytest.LoadFixture(t, "testdata/spotify.json"), records, recordOfTheWeek)
+}
+
+// useSpotifyServerWithFixture is useSpotifyServer for tests that extend the fixture.
+func useSpotifyServerWithFixture(t *testing.T, fixture spotifytest.Fixture, records []crawler.Record, recordOfTheWeek string) *spotifytest.Server {
+	t.Helper()
+	srv := spotifytest.NewServer(t, fixture)
+	useFakeCreatorDependencies(t, nil, records, recordOfTheWeek)
+	newMusicProvider = newSpotifyProvider
+	t.Setenv("SPOTIFY_API_URL", srv.APIURL())
+	t.Setenv("SPOTIFY_ACCOUNTS_URL", srv.AccountsURL())
+
+	prevStore := myauth.Store
+	myauth.Store = spotifytest.NewTokenStore(t, srv.Token())
+	t.Cleanup(func() { myauth.Store = prevStore })
+	return srv
+}
+
+func TestCreatePlaylistWithSpotifyServer(t *testing.T) {
+	srv := useSpotifyServer(t, []crawler.Record{
+		{Band: "Band A", Recordname: "First Album", Score: 7, ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band A", Trackname: "Opener", IsHighlight: true},
+		}},
+		{Band: "Band B", Recordname: "Second Album", Score: 9, ReleaseYear: "2023", Tracks: []crawler.Track{
+			{Band: "Band B", Trackname: "Hit Song", IsHighlight: true},
+			{Band: "Band B", Trackname: "Deep Cut", IsHighlight: true},
+			{Band: "Band B", Trackname: "Unreleased", IsHighlight: true},
+		}},
+	}, "Band A")
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+
+	if got, want := srv.PlaylistTrackIDs("test-playlist"), []spotify.ID{"t1", "t2", "t3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("playlist = %v, want %v", got, want)
+	}
+	if result.FoundTracks != 3 || !reflect.DeepEqual(result.NotFound, []string{"Band B - Unreleased"}) {
+		t.Errorf("result = %+v", result)
+	}
+	if !result.ComparedToProd || result.AlreadyInProdTracks != 1 || result.NewTracksComparedToProd != 2 {
+		t.Errorf("prod comparison = %+v", result)
+	}
+	if details := srv.Details("test-playlist"); !strings.Contains(details.Description, "Platte der Woche: Band A - First Album (7/10)") {
+		t.Errorf("playlist description = %q", details.Description)
+	}
+	if srv.RefreshCount() != 1 {
+		t.Errorf("token refreshed %d times, want once", srv.RefreshCount())
+	}
+}
+
+func TestCreatePlaylistWithThrottlingSpotifyServer(t *testing.T) {
+	srv := useSpotifyServer(t, []crawler.Record{
+		{Band: "Band A", Recordname: "First Album", Score: 7, ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band A", Trackname: "Opener", IsHighlight: true},
+		}},
+		{Band: "Band B", Recordname: "Second Album", Score: 9, ReleaseYear: "2023", Tracks: []crawler.Track{
+			{Band: "Band B", Trackname: "Hit Song", IsHighlight: true},
+			{Band: "Band B", Trackname: "Deep Cut", IsHighlight: true},
+			{Band: "Band B", Trackname: "Unreleased", IsHighlight: true},
+		}},
+	}, "Band A")
+	prevGovernor := myauth.Governor
+	myauth.Governor = ratelimit.New(ratelimit.Options{MaxRetries: 20, Backoff: time.Millisecond})
+	t.Cleanup(func() { myauth.Governor = prevGovernor })
+	// one request at a time, after a burst that throttles everything
+	srv.SetThrottle(spotifytest.Throttle{MaxInFlight: 1, Burst: 3, Latency: 2 * time.Millisecond})
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+
+	if got, want := srv.PlaylistTrackIDs("test-playlist"), []spotify.ID{"t1", "t2", "t3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("playlist = %v, want %v", got, want)
+	}
+	if result.FoundTracks != 3 || result.FailedSearches != nil || !reflect.DeepEqual(result.NotFound, []string{"Band B - Unreleased"}) {
+		t.Errorf("found %d, failed %v, not found %v", result.FoundTracks, result.FailedSearches, result.NotFound)
+	}
+	if got := result.RateLimit; got.Throttled < 3 || got.Retries < got.Throttled || got.Failed != 0 || got.Throttled > srv.ThrottledCount() {
+		t.Errorf("RateLimit = %+v, server throttled %d", got, srv.ThrottledCount())
+	}
+}
+
+func TestCreatePlaylistWithSpotifyServerInMarkets(t *testing.T) {
+	fixture := spotifytest.LoadFixture(t, "testdata/spotify.json")
+	for i := range fixture.Tracks {
+		switch fixture.Tracks[i].ID {
+		case "t1":
+			// playable in the first market only, which the search already reports
+			fixture.Tracks[i].AvailableMarkets = []string{"AT"}
+		case "t3":
+			// not playable in the second market, which needs a lookup
+			fixture.Tracks[i].AvailableMarkets = []string{"DE"}
+		}
+	}
+	srv := useSpotifyServerWithFixture(t, fixture, []crawler.Record{
+		{Band: "Band A", Recordname: "First Album", Score: 7, ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band A", Trackname: "Opener", IsHighlight: true},
+		}},
+		{Band: "Band B", Recordname: "Second Album", Score: 9, ReleaseYear: "2023", Tracks: []crawler.Track{
+			{Band: "Band B", Trackname: "Hit Song", IsHighlight: true},
+			{Band: "Band B", Trackname: "Deep Cut", IsHighlight: true},
+		}},
+	}, "")
+	t.Setenv("SPOTIFY_MARKETS", "DE,AT")
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+
+	// the single of the opener is playable everywhere
+	if got, want := srv.PlaylistTrackIDs("test-playlist"), []spotify.ID{"t2", "t1-single"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("playlist = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(result.Unavailable, []string{"Band B - Deep Cut"}) || result.NotFound != nil {
+		t.Errorf("unavailable %v, not found %v", result.Unavailable, result.NotFound)
+	}
+}
+
+func TestCreatePlaylistRejectsInvalidMarkets(t *testing.T) {
+	useSpotifyServer(t, nil, "")
+	t.Setenv("SPOTIFY_MARKETS", "Austria")
+
+	if _, err := CreatePlaylist(""); err == nil || !strings.Contains(err.Error(), "SPOTIFY_MARKETS") {
+		t.Errorf("CreatePlaylist() error = %v, want a SPOTIFY_MARKETS error", err)
+	}
+}
+
+func TestMarkFoundTracksWithSpotifyServer(t *testing.T) {
+	records := []crawler.Record{
+		{Band: "Band B", Recordname: "Second Album", ReleaseYear: "2023", Tracks: []crawler.Track{
+			{Band: "Band B", Trackname: "Hit Song", IsHighlight: true},
+			{Band: "Band B", Trackname: "Unreleased", IsHighlight: true},
+			{Band: "Band B", Trackname: "Not A Highlight"},
+		}},
+	}
+	srv := useSpotifyServer(t, nil, "")
+
+	if err := MarkFoundTracks(records); err != nil {
+		t.Fatalf("MarkFoundTracks() error: %v", err)
+	}
+
+	var found []bool
+	for _, track := range records[0].Tracks {
+		found = append(found, track.Found)
+	}
+	if want := []bool{true, false, false}; !reflect.DeepEqual(found, want) {
+		t.Errorf("found = %v, want %v", found, want)
+	}
+	for _, query := range srv.Queries() {
+		if query == "Band B Not A Highlight year:2023" {
+			t.Errorf("searched for a track that is not a highlight")
+		}
+	}
+}
+
+func TestSearchSongRetriesWithoutYearWithSpotifyServer(t *testing.T) {
+	srv := useSpotifyServer(t, nil, "")
+	provider, err := newMusicProvider()
+	if err != nil {
+		t.Fatalf("newMusicProvider() error: %v", err)
+	}
+
+	id, err := searchSong(provider, "Old Song", crawler.Record{Band: "Band C", Recordname: "Album", ReleaseYear: "1999"})
+	if err != nil {
+		t.Fatalf("searchSong() error: %v", err)
+	}
+	if id != "remaster" {
+		t.Errorf("searchSong() = %q, want remaster", id)
+	}
+	if want := []string{"Band C Old Song year:1999", "Band C Old Song"}; !reflect.DeepEqual(srv.Queries(), want) {
+		t.Errorf("queries = %q, want %q", srv.Queries(), want)
+	}
+}
+
+func TestCreatePlaylistKeepsLocalFilesWithSpotifyServer(t *testing.T) {
+	fixture := spotifytest.LoadFixture(t, "testdata/spotify.json")
+	fixture.Playlists["test-playlist"] = []spotify.ID{"t2", "", "t1"}
+	srv := useSpotifyServerWithFixture(t, fixture, []crawler.Record{
+		{Band: "Band A", Recordname: "First Album", ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band A", Trackname: "Opener", IsHighlight: true},
+		}},
+		{Band: "Band B", Recordname: "Second Album", ReleaseYear: "2023", Tracks: []crawler.Track{
+			{Band: "Band B", Trackname: "Hit Song", IsHighlight: true},
+			{Band: "Band B", Trackname: "Deep Cut", IsHighlight: true},
+		}},
+	}, "")
+
+	if _, err := CreatePlaylist(""); err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+
+	// the local file cannot be removed, so t2 moves behind t1 and the local file stays first
+	if got, want := srv.PlaylistTrackIDs("test-playlist"), []spotify.ID{"", "t1", "t2", "t3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("playlist = %v, want %v", got, want)
+	}
+}
+
+func TestArchivePlaylistWithSpotifyServer(t *testing.T) {
+	srv := useSpotifyServer(t, []crawler.Record{
+		{Band: "Band B", Recordname: "Second Album", ReleaseYear: "2023", Tracks: []crawler.Track{
+			{Band: "Band B", Trackname: "Hit Song", IsHighlight: true},
+			{Band: "Band B", Trackname: "Deep Cut", IsHighlight: true},
+		}},
+	}, "")
+	t.Setenv("ARCHIVE_PLAYLIST_ID", "archive-playlist")
+	t.Setenv("ARCHIVE_PLAYLIST_WEEKS", "4")
+	srv.SetAddedAt("archive-playlist", "ancient", time.Now().AddDate(0, -3, 0))
+	srv.SetAddedAt("archive-playlist", "t3", time.Now().AddDate(0, -3, 0))
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+
+	// t3 is featured again this week, so only the ancient track falls out of the window.
+	if got, want := srv.PlaylistTrackIDs("archive-playlist"), []spotify.ID{"t3", "t2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("archive playlist = %v, want %v", got, want)
+	}
+	if result.Archive == nil || result.Archive.Trimmed != 1 || result.Archive.Added != 1 || result.Archive.Error != "" {
+		t.Errorf("Archive = %+v", result.Archive)
+	}
+}
+
+func TestWeeklyPlaylistWithSpotifyServer(t *testing.T) {
+	srv := useSpotifyServer(t, []crawler.Record{
+		{Band: "Band B", Recordname: "Second Album", ReleaseYear: "2023", Tracks: []crawler.Track{
+			{Band: "Band B", Trackname: "Hit Song", IsHighlight: true},
+		}},
+	}, "")
+	t.Setenv("WEEKLY_PLAYLISTS_FILE", filepath.Join(t.TempDir(), "weekly.json"))
+	prevNow := now
+	now = func() time.Time { return time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { now = prevNow })
+
+	for run := 1; run <= 2; run++ {
+		result, err := Run(Options{Weekly: true})
+		if err != nil {
+			t.Fatalf("run %d: Run() error: %v", run, err)
+		}
+		if got := srv.PlaylistTrackIDs(spotify.ID(result.PlaylistID)); !reflect.DeepEqual(got, []spotify.ID{"t2"}) {
+			t.Errorf("run %d: playlist %s = %v, want [t2]", run, result.PlaylistID, got)
+		}
+	}
+	if got, want := srv.CreatedPlaylists(), []string{"Plattentests KW 42/2026"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("created playlists = %v, want %v", got, want)
+	}
+}
+
+func TestFullAlbumWithSpotifyServer(t *testing.T) {
+	fixture := spotifytest.LoadFixture(t, "testdata/spotify.json")
+	record := crawler.Record{Band: "Band Z", Recordname: "Box Set", Score: 9, ReleaseYear: "2024"}
+	var want []spotify.ID
+	for i := 1; i <= 120; i++ {
+		name := fmt.Sprintf("Song %s", strings.Repeat("x", i))
+		id := fmt.Sprintf("box-%03d", i)
+		fixture.Tracks = append(fixture.Tracks, fakeTrack(id, "Band Z", name, "Box Set", "album", "2024"))
+		record.Tracks = append(record.Tracks, crawler.Track{Band: "Band Z", Trackname: name, IsHighlight: i == 60})
+		want = append(want, spotify.ID(id))
+	}
+	srv := useSpotifyServerWithFixture(t, fixture, []crawler.Record{record}, "")
+	t.Setenv("FULL_ALBUM_MIN_SCORE", "8")
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	// the fake rejects requests with more than 100 tracks, like Spotify
+	if got := srv.PlaylistTrackIDs("test-playlist"); !reflect.DeepEqual(got, want) {
+		t.Errorf("playlist has %d tracks, want the 120 album tracks in tracklist order: %v", len(got), got)
+	}
+	if result.FoundTracks != 120 || !reflect.DeepEqual(result.FullAlbums, []string{"Band Z - Box Set"}) {
+		t.Errorf("result = %d found, full albums %v", result.FoundTracks, result.FullAlbums)
+	}
+	// the tracks come from the paged tracklist of the album instead of 120 searches
+	if got := srv.Queries(); !reflect.DeepEqual(got, []string{"Band Z Box Set year:2024"}) {
+		t.Errorf("queries = %q, want only the album search", got)
+	}
+}
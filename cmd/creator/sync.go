@@ -0,0 +1,188 @@
+package creator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// maxTracksPerRequest is the Spotify limit for adding or removing playlist items in one call.
+const maxTracksPerRequest = 100
+
+// playlistMove moves the item at From so that it is inserted before the item at To; both are
+// positions before the move, as Spotify's range_start and insert_before.
+type playlistMove struct {
+	From int
+	To   int
+}
+
+// syncPlan is the set of changes planSync found to turn a playlist into the desired tracklist:
+// removals first, then additions at the end, then moves into place.
+type syncPlan struct {
+	Remove []spotify.ID
+	Add    []spotify.ID
+	Moves  []playlistMove
+}
+
+func (p syncPlan) empty() bool {
+	return len(p.Remove) == 0 && len(p.Add) == 0 && len(p.Moves) == 0
+}
+
+// planSync compares the current playlist items with the desired tracklist. Tracks that are
+// already present stay, so Spotify keeps their "added at" date. Spotify removes every occurrence
+// of a track, so tracks listed more than once are removed and added again. Items with an empty
+// ID, such as local files or podcast episodes, cannot be removed by track ID; they stay where
+// they are and only count for the positions of the moves.
+//
+// The moves are as few as possible: the longest run of tracks that already is in desired order
+// stays in place, and every other track is moved once, right behind its predecessor.
+func planSync(current, desired []spotify.ID) syncPlan {
+	wanted := make(map[spotify.ID]bool, len(desired))
+	for _, id := range desired {
+		wanted[id] = true
+	}
+	occurrences := make(map[spotify.ID]int, len(current))
+	for _, id := range current {
+		occurrences[id]++
+	}
+
+	var plan syncPlan
+	var list []spotify.ID
+	kept := make(map[spotify.ID]bool, len(current))
+	for _, id := range current {
+		switch {
+		case id == "":
+			list = append(list, id)
+		case wanted[id] && occurrences[id] == 1:
+			list = append(list, id)
+			kept[id] = true
+		case occurrences[id] > 0:
+			plan.Remove = append(plan.Remove, id)
+			occurrences[id] = 0
+		}
+	}
+	for _, id := range desired {
+		if !kept[id] {
+			plan.Add = append(plan.Add, id)
+			list = append(list, id)
+		}
+	}
+
+	rank := make(map[spotify.ID]int, len(desired))
+	for i, id := range desired {
+		rank[id] = i
+	}
+	var ranks []int
+	for _, id := range list {
+		if id != "" {
+			ranks = append(ranks, rank[id])
+		}
+	}
+	stay := make(map[spotify.ID]bool, len(desired))
+	for _, r := range longestIncreasing(ranks) {
+		stay[desired[r]] = true
+	}
+
+	// Moving each track right behind its predecessor in desired order keeps the tracks handled
+	// so far, and the ones that stay, in desired order.
+	for i, id := range desired {
+		if stay[id] {
+			continue
+		}
+		from := slices.Index(list, id)
+		to := 0
+		if i > 0 {
+			to = slices.Index(list, desired[i-1]) + 1
+		}
+		if from == to {
+			continue
+		}
+		plan.Moves = append(plan.Moves, playlistMove{From: from, To: to})
+		list = slices.Delete(list, from, from+1)
+		if to > from {
+			to--
+		}
+		list = slices.Insert(list, to, id)
+	}
+	return plan
+}
+
+// longestIncreasing returns the values of a longest strictly increasing subsequence of values.
+func longestIncreasing(values []int) []int {
+	// tails[k] is the index of the smallest value ending an increasing subsequence of length k+1
+	var tails []int
+	prev := make([]int, len(values))
+	for i, v := range values {
+		k, _ := slices.BinarySearchFunc(tails, v, func(t, v int) int { return values[t] - v })
+		if k > 0 {
+			prev[i] = tails[k-1]
+		} else {
+			prev[i] = -1
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+	result := make([]int, len(tails))
+	if len(tails) == 0 {
+		return result
+	}
+	for i, k := tails[len(tails)-1], len(tails)-1; k >= 0; k-- {
+		result[k] = values[i]
+		i = prev[i]
+	}
+	return result
+}
+
+// planToken identifies a sync by the target playlist, its items before the sync and the
+// desired tracklist. A preview hands it out; confirming it only applies the same sync.
+func planToken(playlistID spotify.ID, current, desired []spotify.ID) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", playlistID)
+	for _, id := range current {
+		fmt.Fprintf(h, "%s,", id)
+	}
+	fmt.Fprint(h, "\n")
+	for _, id := range desired {
+		fmt.Fprintf(h, "%s,", id)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// applySync executes plan against the playlist in chunks Spotify accepts.
+func applySync(ctx context.Context, provider MusicProvider, playlistID spotify.ID, plan syncPlan) error {
+	for _, chunk := range chunkIDs(plan.Remove, maxTracksPerRequest) {
+		log.Printf("removing %d track(s) from playlist...", len(chunk))
+		if err := provider.RemoveTracksFromPlaylist(ctx, playlistID, chunk...); err != nil {
+			return fmt.Errorf("remove tracks from playlist: %w", err)
+		}
+	}
+	if err := addTracks(provider, playlistID, plan.Add...); err != nil {
+		return err
+	}
+	for _, move := range plan.Moves {
+		if err := provider.ReorderPlaylistTracks(ctx, playlistID, move.From, move.To); err != nil {
+			return fmt.Errorf("move playlist item %d to %d: %w", move.From, move.To, err)
+		}
+	}
+	return nil
+}
+
+func chunkIDs(ids []spotify.ID, size int) [][]spotify.ID {
+	var chunks [][]spotify.ID
+	for len(ids) > size {
+		chunks = append(chunks, ids[:size])
+		ids = ids[size:]
+	}
+	if len(ids) > 0 {
+		chunks = append(chunks, ids)
+	}
+	return chunks
+}
@@ -0,0 +1,172 @@
+package creator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"testing"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/zmb3/spotify/v2"
+)
+
+func ids(values ...string) []spotify.ID {
+	out := make([]spotify.ID, len(values))
+	for i, v := range values {
+		out[i] = spotify.ID(v)
+	}
+	return out
+}
+
+func TestPlanSync(t *testing.T) {
+	tests := []struct {
+		name    string
+		current []spotify.ID
+		desired []spotify.ID
+		want    syncPlan
+	}{
+		{name: "unchanged", current: ids("a", "b"), desired: ids("a", "b"), want: syncPlan{}},
+		{name: "empty playlist", current: nil, desired: ids("a", "b"), want: syncPlan{Add: ids("a", "b")}},
+		{name: "clear playlist", current: ids("a", "b"), desired: nil, want: syncPlan{Remove: ids("a", "b")}},
+		{name: "keeps present tracks", current: ids("x", "a", "y"), desired: ids("a", "b"), want: syncPlan{Remove: ids("x", "y"), Add: ids("b")}},
+		{name: "new track in front", current: ids("a", "b"), desired: ids("c", "a", "b"), want: syncPlan{Add: ids("c"), Moves: []playlistMove{{From: 2, To: 0}}}},
+		{name: "swap", current: ids("a", "b", "c"), desired: ids("c", "b", "a"), want: syncPlan{Moves: []playlistMove{{From: 1, To: 3}, {From: 0, To: 3}}}},
+		{name: "duplicates are re-added", current: ids("a", "a", "b"), desired: ids("a", "b"), want: syncPlan{Remove: ids("a"), Add: ids("a"), Moves: []playlistMove{{From: 0, To: 2}}}},
+		{name: "rotation moves one track", current: ids("a", "b", "c", "d"), desired: ids("b", "c", "d", "a"), want: syncPlan{Moves: []playlistMove{{From: 0, To: 4}}}},
+		{name: "only the track out of order moves", current: ids("a", "e", "b", "c", "d"), desired: ids("a", "b", "c", "d", "e"), want: syncPlan{Moves: []playlistMove{{From: 1, To: 5}}}},
+		{name: "local file keeps its position", current: ids("a", "", "c", "b"), desired: ids("a", "b", "c"), want: syncPlan{Moves: []playlistMove{{From: 2, To: 4}}}},
+		{name: "local file is not removed", current: ids("x", "", "a"), desired: ids("a"), want: syncPlan{Remove: ids("x")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := planSync(tt.current, tt.desired)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planSync() = %+v, want %+v", got, tt.want)
+			}
+
+			// Applying the plan must always produce the desired tracklist.
+			provider := newFakeProvider()
+			provider.playlists["p"] = append([]spotify.ID(nil), tt.current...)
+			if err := applySync(context.Background(), provider, "p", got); err != nil {
+				t.Fatalf("applySync() error: %v", err)
+			}
+			var tracks []spotify.ID
+			for _, id := range provider.playlists["p"] {
+				if id != "" {
+					tracks = append(tracks, id)
+				}
+			}
+			if len(tracks) != len(tt.desired) || (len(tracks) > 0 && !reflect.DeepEqual(tracks, tt.desired)) {
+				t.Errorf("playlist after sync = %v, want %v", provider.playlists["p"], tt.desired)
+			}
+		})
+	}
+}
+
+func TestApplySyncChunksLargeChanges(t *testing.T) {
+	var current, desired []spotify.ID
+	for i := 0; i < 150; i++ {
+		current = append(current, spotify.ID(fmt.Sprintf("old-%d", i)))
+		desired = append(desired, spotify.ID(fmt.Sprintf("new-%d", i)))
+	}
+	provider := newFakeProvider()
+	provider.playlists["p"] = current
+
+	if err := applySync(context.Background(), provider, "p", planSync(current, desired)); err != nil {
+		t.Fatalf("applySync() error: %v", err)
+	}
+	want := []string{"remove p 100", "remove p 50", "add p 100", "add p 50"}
+	if !reflect.DeepEqual(provider.calls, want) {
+		t.Errorf("calls = %v, want %v", provider.calls, want)
+	}
+}
+
+func TestCreatePlaylistKeepsExistingTracks(t *testing.T) {
+	provider := newFakeProvider(
+		fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"),
+		fakeTrack("t2", "Band A", "Closer", "First Album", "album", "2024"),
+	)
+	provider.playlists["test-playlist"] = ids("t2", "t1")
+	useFakeCreatorDependencies(t, provider, []crawler.Record{
+		{Band: "Band A", Recordname: "First Album", ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band A", Trackname: "Opener", IsHighlight: true},
+			{Band: "Band A", Trackname: "Closer", IsHighlight: true},
+		}},
+	}, "")
+
+	result, err := CreatePlaylist("")
+	if err != nil {
+		t.Fatalf("CreatePlaylist() error: %v", err)
+	}
+	if want := []string{"move test-playlist 0 2", "details test-playlist"}; !reflect.DeepEqual(provider.calls, want) {
+		t.Errorf("calls = %v, want %v", provider.calls, want)
+	}
+	if result.Unchanged != 2 || result.Moved != 1 || len(result.Added) != 0 || len(result.Removed) != 0 {
+		t.Errorf("result diff = %+v", result)
+	}
+
+	provider.calls = nil
+	if _, err := CreatePlaylist(""); err != nil {
+		t.Fatalf("second CreatePlaylist() error: %v", err)
+	}
+	if len(provider.calls) != 0 {
+		t.Errorf("up-to-date playlist was modified: %v", provider.calls)
+	}
+}
+
+func TestConfirmAppliesOnlyThePreviewedPlan(t *testing.T) {
+	provider := newFakeProvider(
+		fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"),
+		fakeTrack("t2", "Band A", "Closer", "First Album", "album", "2024"),
+	)
+	provider.playlists["test-playlist"] = ids("t2")
+	useFakeCreatorDependencies(t, provider, []crawler.Record{
+		{Band: "Band A", Recordname: "First Album", ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band A", Trackname: "Opener", IsHighlight: true},
+			{Band: "Band A", Trackname: "Closer", IsHighlight: true},
+		}},
+	}, "")
+	cachePath := useFileMatchCache(t)
+
+	preview, err := PreviewPlaylist("")
+	if err != nil {
+		t.Fatalf("PreviewPlaylist() error: %v", err)
+	}
+	if preview.PlanToken == "" {
+		t.Fatal("preview must hand out a plan token")
+	}
+
+	// someone edits the playlist between preview and confirmation
+	provider.playlists["test-playlist"] = ids("t2", "other")
+	stale, err := Run(Options{Confirm: preview.PlanToken})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if !stale.Stale || !stale.DryRun || len(provider.calls) != 0 {
+		t.Fatalf("stale confirmation = stale %v, dry run %v, calls %v; want nothing applied", stale.Stale, stale.DryRun, provider.calls)
+	}
+	if stale.PlanToken == preview.PlanToken {
+		t.Error("a stale confirmation must hand out the token of the new plan")
+	}
+	if _, err := os.Stat(cachePath); !errors.Is(err, fs.ErrNotExist) || stale.MatchCache.Stored != 0 {
+		t.Errorf("stale confirmation stored %d match(es) in the cache: %v", stale.MatchCache.Stored, err)
+	}
+
+	applied, err := Run(Options{Confirm: stale.PlanToken})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if applied.Stale || applied.DryRun {
+		t.Errorf("confirmation of the current plan = stale %v, dry run %v", applied.Stale, applied.DryRun)
+	}
+	if got := provider.playlists["test-playlist"]; !reflect.DeepEqual(got, ids("t1", "t2")) {
+		t.Errorf("playlist = %v, want the confirmed plan", got)
+	}
+	if _, err := os.Stat(cachePath); err != nil || applied.MatchCache.Stored != 2 {
+		t.Errorf("confirmed run stored %d match(es) in the cache: %v", applied.MatchCache.Stored, err)
+	}
+}
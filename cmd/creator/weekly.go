@@ -0,0 +1,49 @@
+package creator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jetzlstorfer/plattentests-go/internal/weekly"
+)
+
+// weeklyPlaylistDescription is the description of newly created dated playlists.
+const weeklyPlaylistDescription = "Die Highlights der Woche von Plattentests.de"
+
+// openWeeklyPlaylists opens the week to playlist mapping named by WEEKLY_PLAYLISTS_FILE.
+func openWeeklyPlaylists() (*weekly.Store, error) {
+	path := strings.TrimSpace(os.Getenv("WEEKLY_PLAYLISTS_FILE"))
+	if path == "" {
+		return nil, fmt.Errorf("WEEKLY_PLAYLISTS_FILE missing, it is required for dated weekly playlists")
+	}
+	return weekly.Open(path)
+}
+
+// currentWeeklyPlaylist returns the stored playlist of the current week, or one without a
+// playlist ID when it has not been created yet.
+func currentWeeklyPlaylist(store *weekly.Store) weekly.Playlist {
+	at := now()
+	if playlist, ok := store.Lookup(weekly.WeekKey(at)); ok {
+		return playlist
+	}
+	return weekly.Playlist{Week: weekly.WeekKey(at), Name: weekly.Name(at)}
+}
+
+// createWeeklyPlaylist creates the dated playlist and remembers it, so reruns in the same week
+// reuse it.
+func createWeeklyPlaylist(ctx context.Context, provider MusicProvider, store *weekly.Store, playlist weekly.Playlist) (weekly.Playlist, error) {
+	log.Printf("creating weekly playlist %q", playlist.Name)
+	id, err := provider.CreatePlaylist(ctx, playlist.Name, weeklyPlaylistDescription)
+	if err != nil {
+		return playlist, fmt.Errorf("create weekly playlist %q: %w", playlist.Name, err)
+	}
+	playlist.PlaylistID = string(id)
+	playlist.CreatedAt = now()
+	if err := store.Put(playlist); err != nil {
+		return playlist, fmt.Errorf("remember weekly playlist %s for %s: %w", id, playlist.Week, err)
+	}
+	return playlist, nil
+}
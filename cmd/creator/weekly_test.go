@@ -0,0 +1,98 @@
+package creator
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/jetzlstorfer/plattentests-go/internal/weekly"
+)
+
+func useWeeklyPlaylists(t *testing.T, provider *fakeProvider) string {
+	t.Helper()
+	records := []crawler.Record{
+		{Band: "Band A", Recordname: "First Album", ReleaseYear: "2024", Tracks: []crawler.Track{
+			{Band: "Band A", Trackname: "Opener", IsHighlight: true},
+		}},
+	}
+	useFakeCreatorDependencies(t, provider, records, "")
+	path := filepath.Join(t.TempDir(), "weekly.json")
+	t.Setenv("WEEKLY_PLAYLISTS_FILE", path)
+
+	prevNow := now
+	now = func() time.Time { return time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { now = prevNow })
+	return path
+}
+
+func TestWeeklyPlaylistIsCreatedOnceAWeek(t *testing.T) {
+	provider := newFakeProvider(fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"))
+	path := useWeeklyPlaylists(t, provider)
+
+	result, err := Run(Options{Weekly: true})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	wantCalls := []string{`create created-1 "Plattentests KW 42/2026"`, "add created-1 1", "details created-1"}
+	if !reflect.DeepEqual(provider.calls, wantCalls) {
+		t.Errorf("provider calls = %v, want %v", provider.calls, wantCalls)
+	}
+	if result.PlaylistID != "created-1" || result.PlaylistName != "Plattentests KW 42/2026" || !result.PlaylistCreated {
+		t.Errorf("result = %+v", result)
+	}
+
+	store, err := weekly.Open(path)
+	if err != nil {
+		t.Fatalf("weekly.Open() error: %v", err)
+	}
+	if got, ok := store.Lookup("2026-W42"); !ok || got.PlaylistID != "created-1" || !got.CreatedAt.Equal(now()) {
+		t.Errorf("stored playlist = %+v, %v", got, ok)
+	}
+
+	// A rerun in the same week reuses the playlist.
+	provider.calls = nil
+	result, err = Run(Options{Weekly: true})
+	if err != nil {
+		t.Fatalf("second Run() error: %v", err)
+	}
+	if len(provider.calls) != 0 {
+		t.Errorf("second run changed playlists: %v", provider.calls)
+	}
+	if result.PlaylistID != "created-1" || result.PlaylistCreated {
+		t.Errorf("second result = %+v", result)
+	}
+}
+
+func TestWeeklyPlaylistPreviewCreatesNothing(t *testing.T) {
+	provider := newFakeProvider(fakeTrack("t1", "Band A", "Opener", "First Album", "album", "2024"))
+	path := useWeeklyPlaylists(t, provider)
+
+	result, err := Run(Options{Weekly: true, DryRun: true})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if len(provider.calls) != 0 {
+		t.Errorf("dry run changed playlists: %v", provider.calls)
+	}
+	if result.PlaylistID != "" || result.PlaylistName != "Plattentests KW 42/2026" || len(result.Added) != 1 {
+		t.Errorf("result = %+v", result)
+	}
+	store, err := weekly.Open(path)
+	if err != nil {
+		t.Fatalf("weekly.Open() error: %v", err)
+	}
+	if len(store.Playlists()) != 0 {
+		t.Errorf("dry run stored playlists: %+v", store.Playlists())
+	}
+}
+
+func TestWeeklyPlaylistRequiresStoreFile(t *testing.T) {
+	useFakeCreatorDependencies(t, newFakeProvider(), nil, "")
+
+	if _, err := Run(Options{Weekly: true}); err == nil || !strings.Contains(err.Error(), "WEEKLY_PLAYLISTS_FILE") {
+		t.Errorf("Run() error = %v, want WEEKLY_PLAYLISTS_FILE error", err)
+	}
+}
@@ -0,0 +1,298 @@
+// Command matcher explains how the playlist creator matches a highlight to a Spotify track,
+// edits the aliases bands are searched under and benchmarks matching against a labelled corpus.
+//
+//	go run ./cmd/matcher explain [-record NAME] [-year YYYY] [-json] "<band>" "<track>"
+//	go run ./cmd/matcher alias "<band>" "<alias>"
+//	go run ./cmd/matcher aliases
+//	go run ./cmd/matcher benchmark [-dir DIR] [-record] [-update] [-json]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	creator "github.com/jetzlstorfer/plattentests-go/cmd/creator"
+	"github.com/jetzlstorfer/plattentests-go/internal/matchbench"
+)
+
+const usage = `usage: matcher explain [-record NAME] [-year YYYY] [-json] "<band>" "<track>"
+       matcher alias "<band>" "<alias>"
+       matcher aliases
+       matcher benchmark [-dir DIR] [-record] [-update] [-json]`
+
+// defaultBenchmarkDir holds corpus.json, recording.json and baseline.json of the benchmark.
+const defaultBenchmarkDir = "cmd/creator/testdata/benchmark"
+
+// explainMatch is creator.ExplainMatch; tests replace it to avoid Spotify. saveArtistAlias and
+// artistAliases are replaced to avoid ARTIST_ALIASES_FILE, recordBenchmark to avoid Spotify.
+var (
+	explainMatch      = creator.ExplainMatch
+	saveArtistAlias   = creator.SaveArtistAlias
+	artistAliases     = creator.ArtistAliases
+	benchmarkMatching = creator.BenchmarkMatching
+	recordBenchmark   = creator.RecordBenchmark
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	switch {
+	case len(args) > 0 && args[0] == "explain":
+		return runExplain(args[1:], stdout, stderr)
+	case len(args) > 0 && args[0] == "benchmark":
+		return runBenchmark(args[1:], stdout, stderr)
+	case len(args) == 3 && args[0] == "alias":
+		if err := saveArtistAlias(args[1], args[2]); err != nil {
+			fmt.Fprintln(stderr, "alias failed:", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "%s is now also searched as %s\n", args[1], args[2])
+		return 0
+	case len(args) == 1 && args[0] == "aliases":
+		aliases, err := artistAliases()
+		if err != nil {
+			fmt.Fprintln(stderr, "aliases failed:", err)
+			return 1
+		}
+		for _, alias := range aliases {
+			learned := ""
+			if alias.Learned {
+				learned = " (learned)"
+			}
+			fmt.Fprintf(stdout, "%s: %s%s\n", alias.Band, alias.Alias, learned)
+		}
+		return 0
+	default:
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+}
+
+// runExplain runs the explain command with the arguments after its name.
+func runExplain(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("explain", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	recordName := flags.String("record", "", "name of the reviewed record, narrows the first search")
+	year := flags.String("year", "", "release year of the record, narrows the first search")
+	asJSON := flags.Bool("json", false, "print the explanation as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	// the matcher logs every step; keep stdout for the explanation
+	log.SetOutput(io.Discard)
+	record := crawler.Record{Band: flags.Arg(0), Recordname: *recordName, ReleaseYear: *year}
+	explanation, err := explainMatch(record, flags.Arg(1))
+	if err != nil && len(explanation.Attempts) == 0 {
+		fmt.Fprintln(stderr, "explain failed:", err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if encodeErr := encoder.Encode(explanation); encodeErr != nil {
+			fmt.Fprintln(stderr, "explain failed:", encodeErr)
+			return 1
+		}
+	} else {
+		writeExplanation(stdout, explanation)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "explain failed:", err)
+		return 1
+	}
+	return 0
+}
+
+// runBenchmark replays the corpus of the benchmark with the Spotify responses of the recording
+// and compares the reports with the baseline; it fails when a metric got worse. -record adds
+// the responses the recording lacks from Spotify first, -update writes the reports as the new
+// baseline instead of comparing. Synthetic fixtures are pointed out, since their metrics do not
+// describe matching against the real catalog.
+func runBenchmark(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("benchmark", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dir := flags.String("dir", defaultBenchmarkDir, "directory of corpus.json, recording.json and baseline.json")
+	record := flags.Bool("record", false, "record missing Spotify responses before replaying")
+	update := flags.Bool("update", false, "write the results as the new baseline")
+	asJSON := flags.Bool("json", false, "print the reports as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+	recordingPath := filepath.Join(*dir, "recording.json")
+	baselinePath := filepath.Join(*dir, "baseline.json")
+
+	log.SetOutput(io.Discard)
+	corpus, err := matchbench.LoadCorpus(filepath.Join(*dir, "corpus.json"))
+	if err != nil {
+		fmt.Fprintln(stderr, "benchmark failed:", err)
+		return 1
+	}
+	recording, err := matchbench.LoadRecording(recordingPath)
+	if err != nil {
+		fmt.Fprintln(stderr, "benchmark failed:", err)
+		return 1
+	}
+	if *record {
+		if err := recordBenchmark(corpus, recording); err != nil {
+			fmt.Fprintln(stderr, "benchmark failed:", err)
+			return 1
+		}
+		if err := recording.Save(recordingPath); err != nil {
+			fmt.Fprintln(stderr, "benchmark failed:", err)
+			return 1
+		}
+	}
+
+	if corpus.Synthetic || recording.Synthetic {
+		fmt.Fprintln(stderr, "note: the corpus or recording is synthetic, not recorded from Spotify")
+	}
+	reports, err := benchmarkMatching(corpus, recording)
+	if err != nil {
+		fmt.Fprintln(stderr, "benchmark failed:", err)
+		return 1
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			fmt.Fprintln(stderr, "benchmark failed:", err)
+			return 1
+		}
+	} else {
+		writeReports(stdout, reports)
+	}
+	for _, report := range reports {
+		if len(report.Unrecorded) > 0 {
+			fmt.Fprintf(stderr, "%s: %d requests are not recorded, run with -record\n", report.Strategy, len(report.Unrecorded))
+		}
+	}
+
+	if *update {
+		if err := matchbench.SaveBaseline(baselinePath, reports); err != nil {
+			fmt.Fprintln(stderr, "benchmark failed:", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, "baseline updated")
+		return 0
+	}
+	baseline, err := matchbench.LoadBaseline(baselinePath)
+	if err != nil {
+		fmt.Fprintln(stderr, "benchmark failed:", err)
+		return 1
+	}
+	regressions := matchbench.Regressions(baseline, reports)
+	for _, regression := range regressions {
+		fmt.Fprintln(stderr, "regression:", regression)
+	}
+	if len(regressions) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// writeReports prints the metrics of every strategy as a table, followed by the mistakes.
+func writeReports(w io.Writer, reports []matchbench.Report) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "strategy\thighlights\tcorrect\twrong\tmissed\tprecision\trecall\tnot found\t")
+	for _, r := range reports {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%d\t%d\t%.3f\t%.3f\t%.3f\t\n", r.Strategy, r.Highlights, r.Correct, r.Wrong, r.Missed, r.Precision, r.Recall, r.NotFoundRate)
+	}
+	_ = table.Flush()
+
+	for _, r := range reports {
+		for _, m := range r.Mistakes {
+			got := "nothing"
+			if m.Got != "" {
+				got = string(m.Got)
+			}
+			want := "nothing"
+			if len(m.Expected) > 0 {
+				want = fmt.Sprint(m.Expected)
+			}
+			fmt.Fprintf(w, "%s: %s - %s: matched %s, want %s (%s)\n", r.Strategy, m.Band, m.Track, got, want, m.Reason)
+		}
+	}
+}
+
+// writeExplanation prints the search attempts and the scored candidates as plain text.
+func writeExplanation(w io.Writer, e creator.MatchExplanation) {
+	fmt.Fprintf(w, "%s - %s\n", e.Band, e.Trackname)
+	if e.Matched() {
+		fmt.Fprintf(w, "matched %s (confidence %.2f): %s\n", e.TrackID, e.Confidence, e.Reason)
+	} else if e.Unavailable {
+		fmt.Fprintf(w, "unavailable: %s\n", e.Reason)
+	} else {
+		fmt.Fprintf(w, "not matched: %s\n", e.Reason)
+	}
+	for _, note := range e.Notes {
+		fmt.Fprintf(w, "note: %s\n", note)
+	}
+
+	if album := e.Album; album != nil {
+		fmt.Fprintf(w, "\nalbum: %s\n", album.Reason)
+		for _, query := range album.Queries {
+			fmt.Fprintf(w, "  search: %s\n", query)
+		}
+		if album.Error != "" {
+			fmt.Fprintf(w, "  error: %s\n", album.Error)
+		}
+		for _, c := range album.Candidates {
+			verdict := "rejected"
+			if c.Accepted {
+				verdict = "accepted"
+			}
+			fmt.Fprintf(w, "  %s - %s [%s, %d tracks, %s] %s\n", c.Artists, c.Name, c.AlbumType, c.TotalTracks, c.ReleaseDate, c.ID)
+			fmt.Fprintf(w, "     score %.2f = title %.2f + track count %.2f x %.2f; artist %.2f%s\n",
+				c.Score, c.TitleSimilarity, c.TrackCountScore, creator.TrackCountWeight, c.ArtistSimilarity, asAlias(c.ArtistAlias))
+			fmt.Fprintf(w, "     %s: %s\n", verdict, c.Reason)
+		}
+	}
+
+	for i, attempt := range e.Attempts {
+		fmt.Fprintf(w, "\nsearch %d: %s\n", i+1, attempt.Query)
+		if attempt.Error != "" {
+			fmt.Fprintf(w, "  error: %s\n", attempt.Error)
+		}
+		for _, c := range attempt.Candidates {
+			verdict := "rejected"
+			if c.Accepted {
+				verdict = "accepted"
+			}
+			fmt.Fprintf(w, "  #%d %s - %s (%s) [%s] %s\n", c.Rank, c.Artists, c.Name, c.Album, c.AlbumType, c.ID)
+			if c.LinkedFrom != "" {
+				fmt.Fprintf(w, "     relinked from %s\n", c.LinkedFrom)
+			}
+			fmt.Fprintf(w, "     score %d = album name %d + album type %d + rank %d; artist %.2f%s, title %.2f\n",
+				c.Score, c.AlbumNameMatch, c.AlbumTypeScore, c.RankScore, c.ArtistSimilarity, asAlias(c.ArtistAlias), c.TrackSimilarity)
+			fmt.Fprintf(w, "     %s: %s\n", verdict, c.Reason)
+		}
+		fmt.Fprintf(w, "  => %s\n", attempt.Outcome)
+	}
+}
+
+// asAlias names the alias an artist was compared with.
+func asAlias(alias string) string {
+	if alias == "" {
+		return ""
+	}
+	return " as " + alias
+}
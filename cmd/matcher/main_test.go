@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	creator "github.com/jetzlstorfer/plattentests-go/cmd/creator"
+	"github.com/jetzlstorfer/plattentests-go/internal/aliases"
+	"github.com/jetzlstorfer/plattentests-go/internal/matchbench"
+)
+
+func TestRunExplain(t *testing.T) {
+	explanation := creator.MatchExplanation{
+		Band:       "Band",
+		Trackname:  "Song",
+		Source:     creator.MatchSourceSearch,
+		TrackID:    "t1",
+		Confidence: 1,
+		Reason:     "best score, artist and title match",
+		Album: &creator.AlbumResolution{
+			Queries: []string{"Band Album year:2024"},
+			Reason:  "no album with a similar artist and title",
+			Candidates: []creator.AlbumCandidate{
+				{ID: "a1", Artists: "Other Band", Name: "Album", AlbumType: "album", TotalTracks: 10, ReleaseDate: "2024-01-01", TitleSimilarity: 1, ArtistSimilarity: 0.5, TrackCountScore: 0.8, Score: 1.2, Reason: "artist similarity 0.50 is below 0.80"},
+			},
+		},
+		Attempts: []creator.SearchAttempt{{
+			Query: "Band Song year:2024",
+			Candidates: []creator.CandidateScore{
+				{ID: "t1", LinkedFrom: "t0", Artists: "Band", Name: "Song", Album: "Album", AlbumType: "album", Rank: 2, AlbumTypeScore: 100, RankScore: 1, Score: 101, ArtistSimilarity: 1, TrackSimilarity: 1, Selected: true, Accepted: true, Reason: "best score, artist and title match"},
+				{ID: "t2", Artists: "Old Name", Name: "Song", Album: "Song", AlbumType: "single", Rank: 1, AlbumTypeScore: 10, RankScore: 2, Score: 12, ArtistSimilarity: 1, ArtistAlias: "Old Name", TrackSimilarity: 1, Reason: "score 12 is below the best score 101"},
+			},
+			Outcome: "accepted Band - Song",
+		}},
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		err      error
+		wantCode int
+		wantOut  []string
+		wantErr  string
+	}{
+		{
+			name:     "text",
+			args:     []string{"explain", "-record", "Album", "-year", "2024", "Band", "Song"},
+			wantCode: 0,
+			wantOut: []string{
+				"matched t1 (confidence 1.00)",
+				"search 1: Band Song year:2024",
+				"score 101 = album name 0 + album type 100 + rank 1",
+				"rejected: score 12 is below the best score 101",
+				"relinked from t0",
+				"rank 2; artist 1.00 as Old Name, title 1.00",
+				"album: no album with a similar artist and title",
+				"score 1.20 = title 1.00 + track count 0.80 x 0.25; artist 0.50",
+			},
+		},
+		{
+			name:     "json",
+			args:     []string{"explain", "-json", "Band", "Song"},
+			wantCode: 0,
+			wantOut:  []string{`"AlbumTypeScore": 100`, `"Query": "Band Song year:2024"`},
+		},
+		{
+			name:     "missing track",
+			args:     []string{"explain", "Band"},
+			wantCode: 2,
+			wantErr:  "usage:",
+		},
+		{
+			name:     "unknown command",
+			args:     []string{"match", "Band", "Song"},
+			wantCode: 2,
+			wantErr:  "usage:",
+		},
+		{
+			name:     "search error",
+			args:     []string{"explain", "Band", "Song"},
+			err:      errors.New("rate limited"),
+			wantCode: 1,
+			wantErr:  "rate limited",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRecord crawler.Record
+			prev := explainMatch
+			explainMatch = func(record crawler.Record, track string) (creator.MatchExplanation, error) {
+				gotRecord = record
+				if tt.err != nil {
+					return creator.MatchExplanation{}, tt.err
+				}
+				return explanation, nil
+			}
+			t.Cleanup(func() { explainMatch = prev })
+
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, &stdout, &stderr); code != tt.wantCode {
+				t.Fatalf("run() = %d, want %d; stderr: %s", code, tt.wantCode, stderr.String())
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("output misses %q:\n%s", want, stdout.String())
+				}
+			}
+			if tt.wantErr != "" && !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.wantErr)
+			}
+			if tt.name == "text" && (gotRecord.Recordname != "Album" || gotRecord.ReleaseYear != "2024") {
+				t.Errorf("record = %+v, want the -record and -year flags", gotRecord)
+			}
+		})
+	}
+}
+
+func TestRunAliases(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		err      error
+		wantCode int
+		wantOut  []string
+		wantErr  string
+	}{
+		{
+			name:     "add alias",
+			args:     []string{"alias", "Dinosaur", "Dinosaur Jr."},
+			wantCode: 0,
+			wantOut:  []string{"Dinosaur is now also searched as Dinosaur Jr."},
+		},
+		{
+			name:     "add alias fails",
+			args:     []string{"alias", "Dinosaur", "Dinosaur Jr."},
+			err:      errors.New("ARTIST_ALIASES_FILE missing"),
+			wantCode: 1,
+			wantErr:  "ARTIST_ALIASES_FILE missing",
+		},
+		{
+			name:     "alias without a name",
+			args:     []string{"alias", "Dinosaur"},
+			wantCode: 2,
+			wantErr:  "usage:",
+		},
+		{
+			name:     "list aliases",
+			args:     []string{"aliases"},
+			wantCode: 0,
+			wantOut:  []string{"Dinosaur: Dinosaur Jr.\n", "Кино: Kino (learned)\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prevSave, prevList := saveArtistAlias, artistAliases
+			saveArtistAlias = func(band, alias string) error { return tt.err }
+			artistAliases = func() ([]aliases.Alias, error) {
+				return []aliases.Alias{{Band: "Dinosaur", Alias: "Dinosaur Jr."}, {Band: "Кино", Alias: "Kino", Learned: true}}, tt.err
+			}
+			t.Cleanup(func() { saveArtistAlias, artistAliases = prevSave, prevList })
+
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, &stdout, &stderr); code != tt.wantCode {
+				t.Fatalf("run() = %d, want %d; stderr: %s", code, tt.wantCode, stderr.String())
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("output misses %q:\n%s", want, stdout.String())
+				}
+			}
+			if tt.wantErr != "" && !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunBenchmark(t *testing.T) {
+	baseline := []matchbench.Report{{Strategy: "album", Highlights: 2, Correct: 2, Precision: 1, Recall: 1}}
+	worse := matchbench.Report{
+		Strategy: "album", Highlights: 2, Correct: 1, Wrong: 1, Precision: 0.5, Recall: 0.5,
+		Mistakes: []matchbench.Result{{Band: "Band", Track: "Lover", Got: "loser", Reason: "best score"}},
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		reports    []matchbench.Report
+		wantCode   int
+		wantOut    []string
+		wantErr    string
+		wantRecord bool
+		// wantBaseline is the precision of the baseline file after the run
+		wantBaseline float64
+	}{
+		{
+			name:         "baseline kept",
+			args:         []string{"benchmark"},
+			reports:      baseline,
+			wantCode:     0,
+			wantOut:      []string{"strategy", "album", "1.000"},
+			wantBaseline: 1,
+		},
+		{
+			name:         "regression fails",
+			args:         []string{"benchmark"},
+			reports:      []matchbench.Report{worse},
+			wantCode:     1,
+			wantOut:      []string{"album: Band - Lover: matched loser, want nothing (best score)"},
+			wantErr:      "regression: album: precision 0.500 is below the baseline 1.000",
+			wantBaseline: 1,
+		},
+		{
+			name:         "update the baseline",
+			args:         []string{"benchmark", "-update"},
+			reports:      []matchbench.Report{worse},
+			wantCode:     0,
+			wantOut:      []string{"baseline updated"},
+			wantBaseline: 0.5,
+		},
+		{
+			name:         "record missing responses",
+			args:         []string{"benchmark", "-record"},
+			reports:      baseline,
+			wantCode:     0,
+			wantRecord:   true,
+			wantBaseline: 1,
+		},
+		{
+			name:         "unrecorded requests",
+			args:         []string{"benchmark", "-json"},
+			reports:      []matchbench.Report{{Strategy: "album", Highlights: 2, Correct: 2, Precision: 1, Recall: 1, Unrecorded: []string{"track search Band Song"}}},
+			wantCode:     0,
+			wantOut:      []string{`"Strategy": "album"`, `"track search Band Song"`},
+			wantErr:      "album: 1 requests are not recorded, run with -record",
+			wantBaseline: 1,
+		},
+		{
+			name:         "unexpected argument",
+			args:         []string{"benchmark", "Band"},
+			wantCode:     2,
+			wantErr:      "usage:",
+			wantBaseline: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "corpus.json"), []byte(`{"Records": []}`), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := matchbench.SaveBaseline(filepath.Join(dir, "baseline.json"), baseline); err != nil {
+				t.Fatal(err)
+			}
+
+			recorded := false
+			prevMatch, prevRecord := benchmarkMatching, recordBenchmark
+			benchmarkMatching = func(matchbench.Corpus, *matchbench.Recording) ([]matchbench.Report, error) {
+				return tt.reports, nil
+			}
+			recordBenchmark = func(_ matchbench.Corpus, recording *matchbench.Recording) error {
+				recorded = true
+				recording.Markets = []string{"AT"}
+				return nil
+			}
+			t.Cleanup(func() { benchmarkMatching, recordBenchmark = prevMatch, prevRecord })
+
+			var stdout, stderr bytes.Buffer
+			args := append([]string{tt.args[0], "-dir", dir}, tt.args[1:]...)
+			if code := run(args, &stdout, &stderr); code != tt.wantCode {
+				t.Fatalf("run() = %d, want %d; stderr: %s", code, tt.wantCode, stderr.String())
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("output misses %q:\n%s", want, stdout.String())
+				}
+			}
+			if tt.wantErr != "" && !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.wantErr)
+			}
+
+			if recorded != tt.wantRecord {
+				t.Errorf("recorded = %v, want %v", recorded, tt.wantRecord)
+			}
+			if tt.wantRecord {
+				recording, err := matchbench.LoadRecording(filepath.Join(dir, "recording.json"))
+				if err != nil || len(recording.Markets) != 1 {
+					t.Errorf("recording = %+v (%v), want it saved", recording, err)
+				}
+			}
+			saved, err := matchbench.LoadBaseline(filepath.Join(dir, "baseline.json"))
+			if err != nil || len(saved) != 1 || saved[0].Precision != tt.wantBaseline || saved[0].Mistakes != nil {
+				t.Errorf("baseline = %+v (%v), want precision %.1f without mistakes", saved, err, tt.wantBaseline)
+			}
+		})
+	}
+}
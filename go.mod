@@ -5,6 +5,9 @@ go 1.26.6
 require (
 	// Azure services
 	github.com/Azure/azure-sdk-for-go/sdk/storage/azblob v1.8.0
+
+	// Image processing for the cover cache
+	github.com/HugoSmits86/nativewebp v0.9.3
 	github.com/PuerkitoBio/goquery v1.12.0
 
 	// String algorithms
@@ -18,12 +21,13 @@ require (
 
 	// Spotify API
 	github.com/zmb3/spotify/v2 v2.4.3
+	golang.org/x/image v0.46.0
 	// HTML parsing and HTTP utilities
 	golang.org/x/net v0.58.0
 
 	// OAuth2 and text processing
 	golang.org/x/oauth2 v0.36.0
-	golang.org/x/text v0.41.0
+	golang.org/x/text v0.42.0
 )
 
 require (
@@ -55,6 +59,6 @@ require (
 	go.mongodb.org/mongo-driver/v2 v2.8.0 // indirect
 	golang.org/x/arch v0.30.0 // indirect
 	golang.org/x/crypto v0.55.0 // indirect
-	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/sys v0.48.0 // indirect
 	google.golang.org/protobuf v1.36.12 // indirect
 )
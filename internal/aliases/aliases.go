@@ -0,0 +1,211 @@
+// Package aliases keeps the other names a band is known by on Spotify, so matching does not
+// depend on Plattentests.de and Spotify spelling it the same way. Curated aliases, such as a
+// former band name or the Latin spelling of a Cyrillic one, are edited by hand; learned
+// aliases are the Spotify artist names of past matches that only an alias or a
+// transliteration could place.
+package aliases
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jetzlstorfer/plattentests-go/internal/fsutil"
+)
+
+// Alias is another name of a band.
+type Alias struct {
+	Band  string
+	Alias string
+	// Learned is set for aliases taken from a match; curated aliases are edited by hand and
+	// win over learned ones.
+	Learned   bool `json:",omitempty"`
+	UpdatedAt time.Time
+}
+
+// Key identifies a name independent of case and spacing. The script is kept: "Кино" is a
+// different search term than "Kino", even though both compare equal.
+func Key(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// Table is a JSON file backed set of aliases. It is safe for concurrent use.
+type Table struct {
+	mu   sync.RWMutex
+	path string
+	// aliases holds every alias under the key of its band
+	aliases map[string][]Alias
+	// dirty is set when learned aliases have not been saved yet
+	dirty bool
+}
+
+// Open loads the table at path. A missing file yields an empty table that is created on the
+// first save; an empty path yields a table that lives as long as the process.
+func Open(path string) (*Table, error) {
+	t := &Table{path: path, aliases: make(map[string][]Alias)}
+	if path == "" {
+		return t, nil
+	}
+
+	var aliases []Alias
+	if err := fsutil.ReadJSON(path, &aliases); err != nil {
+		return nil, fmt.Errorf("load artist aliases: %w", err)
+	}
+	for _, alias := range aliases {
+		if strings.TrimSpace(alias.Band) != "" && strings.TrimSpace(alias.Alias) != "" {
+			t.add(alias)
+		}
+	}
+	return t, nil
+}
+
+// Lookup returns the other names of band, curated aliases first. Aliases apply both ways: a
+// band is also found under the name it is an alias of. A nil table has none.
+func (t *Table) Lookup(band string) []string {
+	if t == nil {
+		return nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	key := Key(band)
+	var curated, learned []string
+	seen := map[string]bool{key: true}
+	collect := func(alias Alias, name string) {
+		if seen[Key(name)] {
+			return
+		}
+		seen[Key(name)] = true
+		if alias.Learned {
+			learned = append(learned, name)
+		} else {
+			curated = append(curated, name)
+		}
+	}
+	for _, alias := range t.aliases[key] {
+		collect(alias, alias.Alias)
+	}
+	for _, alias := range t.sorted() {
+		if Key(alias.Alias) == key {
+			collect(alias, alias.Band)
+		}
+	}
+	return append(curated, learned...)
+}
+
+// Put stores a curated alias, replacing a learned one for the same names, and writes the
+// table to disk.
+func (t *Table) Put(alias Alias) error {
+	if strings.TrimSpace(alias.Band) == "" || strings.TrimSpace(alias.Alias) == "" {
+		return fmt.Errorf("artist alias needs a band and an alias, got %+v", alias)
+	}
+	if Key(alias.Band) == Key(alias.Alias) {
+		return fmt.Errorf("artist alias %q is the band name itself", alias.Alias)
+	}
+	alias.Learned = false
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := Key(alias.Band)
+	prev := t.aliases[key]
+	t.aliases[key] = append([]Alias(nil), prev...)
+	t.add(alias)
+	if err := t.save(); err != nil {
+		t.aliases[key] = prev
+		return err
+	}
+	return nil
+}
+
+// Learn remembers alias as a learned name of band unless it is known already and reports
+// whether it was new. Learned aliases are kept in memory until Save.
+func (t *Table) Learn(band, alias string, at time.Time) bool {
+	if t == nil || strings.TrimSpace(band) == "" || strings.TrimSpace(alias) == "" || Key(band) == Key(alias) {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, known := range t.aliases[Key(band)] {
+		if Key(known.Alias) == Key(alias) {
+			return false
+		}
+	}
+	t.add(Alias{Band: band, Alias: alias, Learned: true, UpdatedAt: at})
+	t.dirty = true
+	return true
+}
+
+// Save writes learned aliases to disk; it does nothing when there are none or the table
+// has no file.
+func (t *Table) Save() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.dirty {
+		return nil
+	}
+	if err := t.save(); err != nil {
+		return err
+	}
+	t.dirty = false
+	return nil
+}
+
+// Aliases returns all aliases ordered by band and alias.
+func (t *Table) Aliases() []Alias {
+	if t == nil {
+		return nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.sorted()
+}
+
+// add stores alias, replacing an alias with the same names. The caller must hold the write
+// lock or own the table.
+func (t *Table) add(alias Alias) {
+	key := Key(alias.Band)
+	for i, known := range t.aliases[key] {
+		if Key(known.Alias) == Key(alias.Alias) {
+			t.aliases[key][i] = alias
+			return
+		}
+	}
+	t.aliases[key] = append(t.aliases[key], alias)
+}
+
+// sorted lists the aliases by band and alias. The caller must hold a lock.
+func (t *Table) sorted() []Alias {
+	var aliases []Alias
+	for _, band := range t.aliases {
+		aliases = append(aliases, band...)
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		if a, b := Key(aliases[i].Band), Key(aliases[j].Band); a != b {
+			return a < b
+		}
+		return Key(aliases[i].Alias) < Key(aliases[j].Alias)
+	})
+	return aliases
+}
+
+// save writes the table atomically. The caller must hold the write lock.
+func (t *Table) save() error {
+	if t.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(t.sorted(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode artist aliases: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(t.path, data); err != nil {
+		return fmt.Errorf("save artist aliases: %w", err)
+	}
+	return nil
+}
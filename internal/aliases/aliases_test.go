@@ -0,0 +1,136 @@
+package aliases
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTableLookupLearnAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "aliases.json")
+	table, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if got := table.Lookup("Kino"); got != nil {
+		t.Fatalf("new table must be empty, got %v", got)
+	}
+
+	updated := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	if !table.Learn("Kino", "Кино", updated) {
+		t.Error("Learn() of a new alias must report it")
+	}
+	if table.Learn("KINO", "кино", updated) || table.Learn("Kino", "kino", updated) {
+		t.Error("Learn() of a known alias or of the band name must not report it")
+	}
+	for _, alias := range []Alias{
+		{Band: "Dinosaur", Alias: "Dinosaur Jr.", UpdatedAt: updated},
+		{Band: "Kino", Alias: "Kino (band)", UpdatedAt: updated},
+	} {
+		if err := table.Put(alias); err != nil {
+			t.Fatalf("Put() error: %v", err)
+		}
+	}
+	for _, invalid := range []Alias{
+		{Band: "Kino"},
+		{Alias: "Kino"},
+		{Band: "Kino", Alias: " KINO "},
+	} {
+		if err := table.Put(invalid); err == nil {
+			t.Errorf("Put(%+v) must fail", invalid)
+		}
+	}
+	if err := table.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen error: %v", err)
+	}
+	if got, want := reopened.Lookup("kino"), []string{"Kino (band)", "Кино"}; !slices.Equal(got, want) {
+		t.Errorf("Lookup() = %v, want curated before learned %v", got, want)
+	}
+	if got, want := reopened.Lookup("Dinosaur Jr."), []string{"Dinosaur"}; !slices.Equal(got, want) {
+		t.Errorf("Lookup() of an alias = %v, want the band %v", got, want)
+	}
+	all := reopened.Aliases()
+	if len(all) != 3 || all[0].Band != "Dinosaur" || !all[2].Learned || !all[2].UpdatedAt.Equal(updated) {
+		t.Errorf("Aliases() = %+v, want three aliases ordered by band", all)
+	}
+
+	// a curated alias replaces the learned one
+	if err := reopened.Put(Alias{Band: "Kino", Alias: "Кино", UpdatedAt: updated}); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	if all := reopened.Aliases(); len(all) != 3 || all[2].Learned {
+		t.Errorf("Aliases() = %+v, want the learned alias curated", all)
+	}
+
+	files, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("ReadDir() error: %v", err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected only the table file, found %d entries", len(files))
+	}
+}
+
+func TestMemoryTable(t *testing.T) {
+	table, err := Open("")
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	table.Learn("Kino", "Кино", time.Now())
+	if err := table.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if got := table.Lookup("Kino"); len(got) != 1 {
+		t.Errorf("Lookup() = %v, want the learned alias", got)
+	}
+
+	var none *Table
+	if none.Lookup("Kino") != nil || none.Learn("Kino", "Кино", time.Now()) || none.Save() != nil {
+		t.Error("a nil table must have no aliases")
+	}
+}
+
+func TestOpenCorruptTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aliases.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Error("Open() of a corrupt file must fail")
+	}
+}
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "russian", in: "Кино", want: "Kino"},
+		{name: "russian with short i", in: "Ляпис Трубецкой", want: "Lyapis Trubetskoy"},
+		{name: "ukrainian", in: "Океан Ельзи", want: "Okean Elzi"},
+		{name: "greek with accents", in: "Πυξ Λαξ", want: "Pyx Lax"},
+		{name: "katakana", in: "ボリス", want: "borisu"},
+		{name: "katakana with small vowel and long vowel", in: "ティーンエイジ・ファンクラブ", want: "tineiji fankurabu"},
+		{name: "hiragana digraphs", in: "きゃりーぱみゅぱみゅ", want: "kyaripamyupamyu"},
+		{name: "sokuon", in: "がっこう まっちゃ", want: "gakkou matcha"},
+		{name: "hangul", in: "방탄소년단", want: "bangtansonyeondan"},
+		{name: "kanji stays", in: "ゆら帝国", want: "yura帝国"},
+		{name: "latin stays", in: "Sigur Rós", want: "Sigur Rós"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Transliterate(tt.in); got != tt.want {
+				t.Errorf("Transliterate(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package aliases
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// cyrillic romanizes Russian, Ukrainian, Belarusian and Serbian letters, roughly as
+// Spotify spells such bands in Latin script.
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g", 'ў': "u", 'ђ': "dj", 'ј': "j",
+	'љ': "lj", 'њ': "nj", 'ћ': "c", 'џ': "dz",
+}
+
+var greek = map[rune]string{
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+	'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+	'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o",
+}
+
+// hiragana romanizes kana in Hepburn; katakana is looked up as the matching hiragana.
+var hiragana = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko",
+	'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so",
+	'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to",
+	'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho",
+	'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "i", 'ゑ': "e", 'を': "o", 'ん': "n", 'ゔ': "vu",
+}
+
+// Small kana change the kana before them: き+ゃ is "kya", フ+ァ is "fa".
+var (
+	smallY     = map[rune]string{'ゃ': "a", 'ゅ': "u", 'ょ': "o"}
+	smallVowel = map[rune]string{'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o", 'ゎ': "a"}
+)
+
+// sokuon doubles the consonant of the next kana.
+const sokuon = 'っ'
+
+// Hangul syllables are composed of an initial, a medial and an optional final jamo; they
+// are romanized in the Revised Romanization, syllable by syllable.
+var (
+	hangulInitials = []string{"g", "kk", "n", "d", "tt", "r", "m", "b", "pp", "s", "ss", "", "j", "jj", "ch", "k", "t", "p", "h"}
+	hangulMedials  = []string{"a", "ae", "ya", "yae", "eo", "e", "yeo", "ye", "o", "wa", "wae", "oe", "yo", "u", "wo", "we", "wi", "yu", "eu", "ui", "i"}
+	hangulFinals   = []string{"", "k", "k", "k", "n", "n", "n", "t", "l", "k", "m", "l", "l", "l", "p", "l", "m", "p", "p", "t", "t", "ng", "t", "t", "k", "t", "p", "t"}
+)
+
+const (
+	hangulFirst = 0xAC00
+	hangulLast  = 0xD7A3
+	// katakanaOffset is the distance of a katakana from the matching hiragana.
+	katakanaOffset = 'ア' - 'あ'
+)
+
+// Transliterate writes Cyrillic, Greek, Japanese kana and Korean Hangul in Latin letters and
+// leaves everything else as it is. Chinese characters and kanji have no reading without a
+// dictionary; bands written in them need a curated alias.
+func Transliterate(s string) string {
+	var b strings.Builder
+	// last is the romanization of the previous kana, double is set after a sokuon
+	last, double := "", false
+	for _, r := range s {
+		if r >= 'ァ' && r <= 'ヶ' {
+			r -= katakanaOffset
+		}
+		kana, isKana := hiragana[r]
+		switch {
+		case isKana:
+			if double && kana != "" && !strings.ContainsRune("aeiou", rune(kana[0])) {
+				if strings.HasPrefix(kana, "ch") {
+					b.WriteByte('t')
+				} else {
+					b.WriteByte(kana[0])
+				}
+			}
+			b.WriteString(kana)
+			last, double = kana, false
+			continue
+		case smallY[r] != "" && strings.HasSuffix(last, "i") && len(last) > 1:
+			// きゃ is "kya", but しゃ is "sha" and じゃ is "ja"
+			trimmed := strings.TrimSuffix(last, "i")
+			out := "y" + smallY[r]
+			if strings.HasSuffix(trimmed, "h") || trimmed == "j" {
+				out = smallY[r]
+			}
+			rewrite(&b, last, trimmed+out)
+			last = trimmed + out
+			continue
+		case smallVowel[r] != "" && last != "" && strings.ContainsRune("aeiou", rune(last[len(last)-1])) && len(last) > 1:
+			// ティ is "ti", ファ is "fa"
+			replaced := last[:len(last)-1] + smallVowel[r]
+			rewrite(&b, last, replaced)
+			last = replaced
+			continue
+		case r == sokuon:
+			double = true
+			continue
+		case r == 'ー':
+			// long vowels are not marked
+			continue
+		case r == '・':
+			b.WriteByte(' ')
+		case r >= hangulFirst && r <= hangulLast:
+			index := int(r - hangulFirst)
+			b.WriteString(hangulInitials[index/588] + hangulMedials[index%588/28] + hangulFinals[index%28])
+		default:
+			if latin, ok := transliterateLetter(r); ok {
+				b.WriteString(latin)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+		last, double = "", false
+	}
+	return b.String()
+}
+
+// transliterateLetter romanizes a Cyrillic or Greek letter, keeping upper case on the first
+// Latin letter.
+func transliterateLetter(r rune) (string, bool) {
+	lower := unicode.ToLower(r)
+	latin, ok := cyrillic[lower]
+	if !ok && unicode.Is(unicode.Greek, lower) {
+		// accented letters such as ά are looked up without the accent
+		base := []rune(norm.NFD.String(string(lower)))[0]
+		latin, ok = greek[base]
+	}
+	if !ok {
+		return "", false
+	}
+	if lower != r && latin != "" {
+		latin = strings.ToUpper(latin[:1]) + latin[1:]
+	}
+	return latin, true
+}
+
+// rewrite replaces the romanization last at the end of b with replacement.
+func rewrite(b *strings.Builder, last, replacement string) {
+	written := b.String()
+	b.Reset()
+	b.WriteString(strings.TrimSuffix(written, last))
+	b.WriteString(replacement)
+}
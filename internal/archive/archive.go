@@ -0,0 +1,170 @@
+// Package archive persists crawled Plattentests records so that features like offline
+// search and statistics can work without re-crawling the site.
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/jetzlstorfer/plattentests-go/internal/fsutil"
+)
+
+// Entry is one archived review together with when it was first and last crawled.
+type Entry struct {
+	Record    crawler.Record
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// Archive is a JSON file backed collection of records keyed by review ID. It is safe for
+// concurrent use.
+type Archive struct {
+	mu      sync.RWMutex
+	path    string
+	entries map[string]Entry
+}
+
+// Open loads the archive at path. A missing file yields an empty archive that is created
+// on the first Add.
+func Open(path string) (*Archive, error) {
+	a := &Archive{path: path, entries: make(map[string]Entry)}
+
+	var entries []Entry
+	if err := fsutil.ReadJSON(path, &entries); err != nil {
+		return nil, fmt.Errorf("load archive: %w", err)
+	}
+	for _, entry := range entries {
+		if key := entryKey(entry.Record); key != "" {
+			a.entries[key] = entry
+		}
+	}
+	return a, nil
+}
+
+// Add stores records seen at the given time. Existing entries are replaced by the newer
+// crawl but keep their FirstSeen time and their record of the week flag. It reports how
+// many records were new and how many known reviews changed, and writes the archive to disk
+// only when either happened; a LastSeen time alone is written with the next change.
+func (a *Archive) Add(records []crawler.Record, seen time.Time) (added, changed int, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, record := range records {
+		key := entryKey(record)
+		if key == "" {
+			continue
+		}
+		entry, exists := a.entries[key]
+		record.IsRecordOfTheWeek = record.IsRecordOfTheWeek || entry.Record.IsRecordOfTheWeek
+		// Found is a per-run Spotify flag and not part of the review.
+		record.Tracks = append([]crawler.Track(nil), record.Tracks...)
+		for i := range record.Tracks {
+			record.Tracks[i].Found = false
+		}
+		switch {
+		case !exists:
+			added++
+			entry.FirstSeen = seen
+		case !reflect.DeepEqual(entry.Record, record):
+			changed++
+		}
+		entry.Record = record
+		entry.LastSeen = seen
+		a.entries[key] = entry
+	}
+
+	if added == 0 && changed == 0 {
+		return 0, 0, nil
+	}
+	if err := a.save(); err != nil {
+		return added, changed, err
+	}
+	return added, changed, nil
+}
+
+// Entries returns all archived entries ordered by review ID, newest review first.
+func (a *Archive) Entries() []Entry {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(a.entries))
+	for _, entry := range a.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		ni, nj := reviewNumber(entries[i].Record), reviewNumber(entries[j].Record)
+		if ni != nj {
+			return ni > nj
+		}
+		return entryKey(entries[i].Record) < entryKey(entries[j].Record)
+	})
+	return entries
+}
+
+// Entry returns the archived entry of record, if there is one.
+func (a *Archive) Entry(record crawler.Record) (Entry, bool) {
+	key := entryKey(record)
+	if key == "" {
+		return Entry{}, false
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	entry, ok := a.entries[key]
+	return entry, ok
+}
+
+// Records returns the archived records in the order of Entries.
+func (a *Archive) Records() []crawler.Record {
+	entries := a.Entries()
+	records := make([]crawler.Record, len(entries))
+	for i, entry := range entries {
+		records[i] = entry.Record
+	}
+	return records
+}
+
+// Len returns the number of archived reviews.
+func (a *Archive) Len() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.entries)
+}
+
+// save writes the archive atomically so a crash never leaves a truncated file behind.
+// The caller must hold the write lock.
+func (a *Archive) save() error {
+	entries := make([]Entry, 0, len(a.entries))
+	for _, entry := range a.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entryKey(entries[i].Record) < entryKey(entries[j].Record)
+	})
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("encode archive: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(a.path, data); err != nil {
+		return fmt.Errorf("save archive: %w", err)
+	}
+	return nil
+}
+
+func entryKey(record crawler.Record) string {
+	if id := record.ReviewID(); id != "" {
+		return id
+	}
+	return record.Link
+}
+
+func reviewNumber(record crawler.Record) int {
+	id, _ := strconv.Atoi(record.ReviewID())
+	return id
+}
@@ -0,0 +1,125 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+)
+
+func TestArchiveAddAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "archive.json")
+	a, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if a.Len() != 0 {
+		t.Fatalf("new archive must be empty, got %d entries", a.Len())
+	}
+
+	week1 := time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)
+	week2 := week1.Add(7 * 24 * time.Hour)
+
+	tracks := []crawler.Track{{Trackname: "Hit", IsHighlight: true, Found: true}}
+	added, _, err := a.Add([]crawler.Record{
+		{Band: "Band A", Link: "https://www.plattentests.de/rezi.php?show=10", Score: 7, IsRecordOfTheWeek: true, Tracks: tracks},
+		{Band: "Band B", Link: "https://www.plattentests.de/rezi.php?show=12", Score: 8},
+		{Band: "No Link"},
+	}, week1)
+	if err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+	if added != 2 {
+		t.Errorf("Add() added = %d, want 2", added)
+	}
+	if !tracks[0].Found {
+		t.Errorf("Add() must not modify the caller's tracks")
+	}
+
+	added, changed, err := a.Add([]crawler.Record{
+		{Band: "Band A", Link: "https://www.plattentests.de/rezi.php?show=10", Score: 8},
+	}, week2)
+	if err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+	if added != 0 || changed != 1 {
+		t.Errorf("re-adding a changed review = %d added, %d changed, want 0 and 1", added, changed)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen error: %v", err)
+	}
+	entries := reopened.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("len(Entries()) = %d, want 2", len(entries))
+	}
+	if entries[0].Record.Band != "Band B" || entries[1].Record.Band != "Band A" {
+		t.Errorf("entries must be ordered newest review first, got %s, %s", entries[0].Record.Band, entries[1].Record.Band)
+	}
+
+	bandA := entries[1]
+	if bandA.Record.Score != 8 {
+		t.Errorf("newer crawl must replace the record, score = %d", bandA.Record.Score)
+	}
+	if !bandA.Record.IsRecordOfTheWeek {
+		t.Errorf("record of the week flag must be kept")
+	}
+	if !bandA.FirstSeen.Equal(week1) || !bandA.LastSeen.Equal(week2) {
+		t.Errorf("FirstSeen/LastSeen = %s/%s, want %s/%s", bandA.FirstSeen, bandA.LastSeen, week1, week2)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp"))
+	if err != nil || len(matches) != 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
+
+func TestArchiveAddUnchangedDoesNotWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.json")
+	a, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	record := crawler.Record{Band: "Band A", Link: "https://www.plattentests.de/rezi.php?show=10", Score: 7, Tracks: []crawler.Track{{Trackname: "Hit", IsHighlight: true}}}
+	seen := time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)
+	if _, _, err := a.Add([]crawler.Record{record}, seen); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+
+	// the same review again, only with a per-run Spotify flag set
+	record.Tracks = []crawler.Track{{Trackname: "Hit", IsHighlight: true, Found: true}}
+	added, changed, err := a.Add([]crawler.Record{record}, seen.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+	if added != 0 || changed != 0 {
+		t.Errorf("Add() = %d added, %d changed, want nothing", added, changed)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("an unchanged crawl must not write the archive, Stat() = %v", err)
+	}
+
+	record.IsRecordOfTheWeek = true
+	if _, changed, err := a.Add([]crawler.Record{record}, seen.Add(2*time.Hour)); err != nil || changed != 1 {
+		t.Fatalf("Add() = %d changed, %v, want the record of the week flag as a change", changed, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("a changed review must be written: %v", err)
+	}
+}
+
+func TestOpenInvalidArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Fatalf("Open() must fail for a corrupt archive")
+	}
+}
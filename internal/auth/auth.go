@@ -9,10 +9,13 @@ import (
 	"io"
 	"log"
 	"net"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/jetzlstorfer/plattentests-go/internal/ratelimit"
 	"github.com/kelseyhightower/envconfig"
 	"github.com/zmb3/spotify/v2"
 	"golang.org/x/oauth2"
@@ -34,9 +37,39 @@ var (
 		AzAccountName   string `envconfig:"AZ_ACCOUNT" required:"true"`
 		AzAccountKey    string `envconfig:"AZ_KEY" required:"true"`
 		AzContainerName string `envconfig:"AZ_CONTAINER" required:"true"`
+		// SpotifyAPIURL and SpotifyAccountsURL point the client at another Spotify Web API
+		// and accounts service, e.g. the fake in internal/spotifytest. Empty means Spotify.
+		SpotifyAPIURL      string `envconfig:"SPOTIFY_API_URL"`
+		SpotifyAccountsURL string `envconfig:"SPOTIFY_ACCOUNTS_URL"`
 	}
 )
 
+// Governor paces the Web API requests of every client VerifyLogin returns. It is shared, so
+// playlist runs and found-status lookups in one process back off together when Spotify
+// answers 429 Too Many Requests.
+var Governor = ratelimit.New(ratelimit.Options{MaxConcurrency: 8})
+
+// TokenStore persists the Spotify OAuth token between runs.
+type TokenStore interface {
+	Load() ([]byte, error)
+	Save([]byte) error
+}
+
+// Store is where VerifyLogin reads and writes the token. It defaults to the Azure blob
+// configured via AZ_* and TOKEN_FILE; tests replace it to stay offline.
+var Store TokenStore = azureTokenStore{}
+
+type azureTokenStore struct{}
+
+func (azureTokenStore) Load() ([]byte, error) {
+	return DownloadBlobToBytes("")
+}
+
+func (azureTokenStore) Save(b []byte) error {
+	_, err := UploadBytesToBlob(b)
+	return err
+}
+
 // VerifyLogin downloads the persisted token, refreshes it if needed, uploads it back, and returns an authenticated Spotify client.
 func VerifyLogin() (spotify.Client, error) {
 	err := envconfig.Process("", &config)
@@ -46,7 +79,7 @@ func VerifyLogin() (spotify.Client, error) {
 
 	log.Println("Connecting to Azure to download token")
 
-	buff, err := DownloadBlobToBytes("")
+	buff, err := Store.Load()
 	if err != nil {
 		return spotify.Client{}, fmt.Errorf("download token from Azure: %w", err)
 	}
@@ -62,8 +95,16 @@ func VerifyLogin() (spotify.Client, error) {
 	// so the new token is checked against the old one to see if it should be updated.
 	log.Println("Creating Spotify Authenticator")
 	ctx := context.Background()
-	httpClient := spotifyauth.New().Client(ctx, token)
-	client := spotify.New(httpClient)
+	httpClient := oauthConfig().Client(ctx, token)
+	// the governor goes below the oauth2 transport, which client.Token() needs on top
+	if transport, ok := httpClient.Transport.(*oauth2.Transport); ok {
+		transport.Base = Governor.Transport(transport.Base)
+	}
+	var opts []spotify.ClientOption
+	if config.SpotifyAPIURL != "" {
+		opts = append(opts, spotify.WithBaseURL(strings.TrimSuffix(config.SpotifyAPIURL, "/")+"/"))
+	}
+	client := spotify.New(httpClient, opts...)
 
 	log.Println("Creating new Client Token")
 	newToken, err := retrieveTokenWithRetry(client)
@@ -79,7 +120,7 @@ func VerifyLogin() (spotify.Client, error) {
 		return spotify.Client{}, fmt.Errorf("marshal refreshed token: %w", err)
 	}
 
-	_, err = UploadBytesToBlob(persistedToken)
+	err = Store.Save(persistedToken)
 	if err != nil {
 		return spotify.Client{}, fmt.Errorf("upload token to Azure: %w", err)
 	}
@@ -96,6 +137,20 @@ func VerifyLogin() (spotify.Client, error) {
 	return *client, nil
 }
 
+// oauthConfig mirrors spotifyauth.New() but honours SPOTIFY_ACCOUNTS_URL, which the
+// spotifyauth package offers no option for.
+func oauthConfig() *oauth2.Config {
+	endpoint := oauth2.Endpoint{AuthURL: spotifyauth.AuthURL, TokenURL: spotifyauth.TokenURL}
+	if accounts := strings.TrimSuffix(config.SpotifyAccountsURL, "/"); accounts != "" {
+		endpoint = oauth2.Endpoint{AuthURL: accounts + "/authorize", TokenURL: accounts + "/api/token"}
+	}
+	return &oauth2.Config{
+		ClientID:     os.Getenv("SPOTIFY_ID"),
+		ClientSecret: os.Getenv("SPOTIFY_SECRET"),
+		Endpoint:     endpoint,
+	}
+}
+
 func retrieveTokenWithRetry(client *spotify.Client) (*oauth2.Token, error) {
 	const maxAttempts = 4
 	const baseDelay = 2 * time.Second
@@ -161,10 +216,28 @@ func retryDelay(attempt int, baseDelay time.Duration) time.Duration {
 
 // DownloadBlobToBytes reads the configured token blob from Azure Storage.
 func DownloadBlobToBytes(string) ([]byte, error) {
+	return DownloadBlob("")
+}
+
+// UploadBytesToBlob writes bytes to the configured token blob in Azure Storage.
+func UploadBytesToBlob(b []byte) (string, error) {
+	return UploadBlob("", b)
+}
+
+// IsBlobNotFound reports whether err means that the blob does not exist yet.
+func IsBlobNotFound(err error) bool {
+	return bloberror.HasCode(err, bloberror.BlobNotFound)
+}
+
+// DownloadBlob reads a blob of the configured container; an empty name reads the token blob.
+func DownloadBlob(name string) ([]byte, error) {
 	const maxAttempts = 4
 	const baseDelay = 2 * time.Second
 
 	azrKey, accountName, _, container := GetAccountInfo()
+	if name == "" {
+		name = config.TokenFile
+	}
 	serviceURL := fmt.Sprintf("https://%s.blob.core.windows.net/", accountName)
 
 	cred, err := azblob.NewSharedKeyCredential(accountName, azrKey)
@@ -180,7 +253,7 @@ func DownloadBlobToBytes(string) ([]byte, error) {
 	ctx := context.Background()
 	var lastErr error
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		response, err := client.DownloadStream(ctx, container, config.TokenFile, nil)
+		response, err := client.DownloadStream(ctx, container, name, nil)
 		if err == nil {
 			blobData, readErr := io.ReadAll(response.Body)
 			closeErr := response.Body.Close()
@@ -199,19 +272,23 @@ func DownloadBlobToBytes(string) ([]byte, error) {
 		}
 
 		delay := retryDelay(attempt, baseDelay)
-		log.Printf("temporary Azure Blob error while downloading token (attempt %d/%d): %v; retrying in %s", attempt, maxAttempts, err, delay)
+		log.Printf("temporary Azure Blob error while downloading %s (attempt %d/%d): %v; retrying in %s", name, attempt, maxAttempts, err, delay)
 		time.Sleep(delay)
 	}
 
 	return nil, lastErr
 }
 
-// UploadBytesToBlob writes bytes to the configured token blob in Azure Storage.
-func UploadBytesToBlob(b []byte) (string, error) {
+// UploadBlob writes bytes to a blob of the configured container and returns its URL; an empty
+// name writes the token blob.
+func UploadBlob(name string, b []byte) (string, error) {
 	const maxAttempts = 4
 	const baseDelay = 2 * time.Second
 
 	azrKey, accountName, _, container := GetAccountInfo()
+	if name == "" {
+		name = config.TokenFile
+	}
 	serviceURL := fmt.Sprintf("https://%s.blob.core.windows.net/", accountName)
 
 	cred, err := azblob.NewSharedKeyCredential(accountName, azrKey)
@@ -225,12 +302,12 @@ func UploadBytesToBlob(b []byte) (string, error) {
 	}
 
 	ctx := context.Background()
-	blobURL := fmt.Sprintf("%s/%s/%s", serviceURL, container, config.TokenFile)
+	blobURL := fmt.Sprintf("%s/%s/%s", serviceURL, container, name)
 
 	var lastErr error
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
 		reader := bytes.NewReader(b)
-		_, err = client.UploadStream(ctx, container, config.TokenFile, reader, nil)
+		_, err = client.UploadStream(ctx, container, name, reader, nil)
 		if err == nil {
 			return blobURL, nil
 		}
@@ -241,7 +318,7 @@ func UploadBytesToBlob(b []byte) (string, error) {
 		}
 
 		delay := retryDelay(attempt, baseDelay)
-		log.Printf("temporary Azure Blob error while uploading token (attempt %d/%d): %v; retrying in %s", attempt, maxAttempts, err, delay)
+		log.Printf("temporary Azure Blob error while uploading %s (attempt %d/%d): %v; retrying in %s", name, attempt, maxAttempts, err, delay)
 		time.Sleep(delay)
 	}
 
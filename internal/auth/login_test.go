@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jetzlstorfer/plattentests-go/internal/spotifytest"
+)
+
+// useFakeSpotify points VerifyLogin at srv and keeps the token in memory.
+func useFakeSpotify(t *testing.T, srv *spotifytest.Server) *spotifytest.TokenStore {
+	t.Helper()
+	for key, value := range map[string]string{
+		"SPOTIFY_ID":           "id",
+		"SPOTIFY_SECRET":       "secret",
+		"SPOTIFY_API_URL":      srv.APIURL(),
+		"SPOTIFY_ACCOUNTS_URL": srv.AccountsURL(),
+		"TOKEN_FILE":           "token.txt",
+		"AZ_ACCOUNT":           "account",
+		"AZ_KEY":               "key",
+		"AZ_CONTAINER":         "container",
+	} {
+		t.Setenv(key, value)
+	}
+
+	store := spotifytest.NewTokenStore(t, srv.Token())
+	prev := Store
+	Store = store
+	t.Cleanup(func() { Store = prev })
+	return store
+}
+
+func TestVerifyLoginRefreshesAndPersistsToken(t *testing.T) {
+	srv := spotifytest.NewServer(t, spotifytest.LoadFixture(t, "../spotifytest/testdata/catalog.json"))
+	store := useFakeSpotify(t, srv)
+
+	client, err := VerifyLogin()
+	if err != nil {
+		t.Fatalf("VerifyLogin() error: %v", err)
+	}
+
+	token, saves, err := store.Token()
+	if err != nil {
+		t.Fatalf("stored token: %v", err)
+	}
+	if token.AccessToken != "access-token-1" || saves != 1 || srv.RefreshCount() != 1 {
+		t.Errorf("stored token %q after %d saves and %d refreshes", token.AccessToken, saves, srv.RefreshCount())
+	}
+
+	// The returned client keeps talking to the fake.
+	user, err := client.CurrentUser(context.Background())
+	if err != nil || user.ID != "plattentests" {
+		t.Errorf("CurrentUser() = %v, %v", user, err)
+	}
+}
+
+func TestVerifyLoginRejectsRevokedToken(t *testing.T) {
+	srv := spotifytest.NewServer(t, spotifytest.Fixture{})
+	useFakeSpotify(t, srv)
+	token := srv.Token()
+	token.RefreshToken = "revoked"
+	Store = spotifytest.NewTokenStore(t, token)
+
+	if _, err := VerifyLogin(); err == nil {
+		t.Error("VerifyLogin() succeeded with a revoked refresh token")
+	}
+}
+
+func TestVerifyLoginRetriesThrottledRequests(t *testing.T) {
+	srv := spotifytest.NewServer(t, spotifytest.LoadFixture(t, "../spotifytest/testdata/catalog.json"))
+	useFakeSpotify(t, srv)
+	client, err := VerifyLogin()
+	if err != nil {
+		t.Fatalf("VerifyLogin() error: %v", err)
+	}
+
+	before := Governor.Stats()
+	srv.SetThrottle(spotifytest.Throttle{Burst: 1})
+	if _, err := client.CurrentUser(context.Background()); err != nil {
+		t.Fatalf("CurrentUser() after a 429 error: %v", err)
+	}
+	if got := Governor.Stats().Sub(before); got.Throttled != 1 || got.Retries != 1 || srv.ThrottledCount() != 1 {
+		t.Errorf("governor stats = %+v, server throttled %d", got, srv.ThrottledCount())
+	}
+}
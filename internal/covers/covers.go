@@ -0,0 +1,274 @@
+// Package covers fetches cover art once, keeps it on local disk and serves resized
+// JPEG or WebP thumbnails of it, so visitors never load images from third-party hosts.
+package covers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// Decoders for the cover formats Plattentests.de uses.
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/jetzlstorfer/plattentests-go/internal/fsutil"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Thumbnail formats.
+const (
+	FormatJPEG = "image/jpeg"
+	FormatWebP = "image/webp"
+)
+
+// Widths lists the thumbnail widths that may be requested. Other widths are rejected so
+// the cache cannot be filled with arbitrary sizes.
+var Widths = []int{160, 320, 640}
+
+// maxImageBytes limits the size of a downloaded cover.
+const maxImageBytes = 10 << 20
+
+const jpegQuality = 82
+
+var (
+	// ErrSourceNotAllowed is returned for image URLs outside the allowed hosts.
+	ErrSourceNotAllowed = errors.New("image source not allowed")
+	// ErrUnsupportedWidth is returned for widths not listed in Widths.
+	ErrUnsupportedWidth = errors.New("unsupported thumbnail width")
+	// ErrNotAnImage is returned when the source does not decode as an image.
+	ErrNotAnImage = errors.New("source is not an image")
+)
+
+// Image is a cached original or thumbnail.
+type Image struct {
+	Data        []byte
+	ContentType string
+	ETag        string
+	ModTime     time.Time
+}
+
+// Cache stores originals and thumbnails below a directory. It is safe for concurrent use;
+// concurrent requests for the same image download and resize it only once.
+type Cache struct {
+	dir          string
+	client       *http.Client
+	allowedHosts []string
+
+	mu    sync.Mutex
+	locks map[string]*pathLock
+}
+
+// pathLock serialises work on one path; users counts the requests holding or waiting for
+// it, so the lock can be dropped once the last one is done.
+type pathLock struct {
+	sync.Mutex
+	users int
+}
+
+// maxRedirects is the number of redirects a download follows, as http.Client does by default.
+const maxRedirects = 10
+
+// New returns a cache in dir that only fetches images from the given hosts, including the
+// targets of redirects.
+func New(dir string, client *http.Client, allowedHosts ...string) *Cache {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	c := &Cache{
+		dir:          dir,
+		allowedHosts: allowedHosts,
+		locks:        make(map[string]*pathLock),
+	}
+	// copy the client so the redirect check does not leak into the caller's
+	checked := *client
+	checked.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !c.allowed(req.URL) {
+			return fmt.Errorf("redirect to %s: %w", req.URL.Host, ErrSourceNotAllowed)
+		}
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if client.CheckRedirect != nil {
+			return client.CheckRedirect(req, via)
+		}
+		return nil
+	}
+	c.client = &checked
+	return c
+}
+
+// allowed reports whether images may be fetched from u.
+func (c *Cache) allowed(u *url.URL) bool {
+	return (u.Scheme == "https" || u.Scheme == "http") && slices.Contains(c.allowedHosts, strings.ToLower(u.Hostname()))
+}
+
+// Get returns the image at src. A width of 0 returns the original as downloaded; other
+// widths return a thumbnail in format (FormatJPEG or FormatWebP). Covers narrower than
+// width are re-encoded but never enlarged.
+func (c *Cache) Get(ctx context.Context, src string, width int, format string) (Image, error) {
+	u, err := url.Parse(src)
+	if err != nil || !c.allowed(u) {
+		return Image{}, ErrSourceNotAllowed
+	}
+	if width != 0 && !slices.Contains(Widths, width) {
+		return Image{}, ErrUnsupportedWidth
+	}
+
+	key := cacheKey(u.String())
+	original := filepath.Join(c.dir, "originals", key)
+	if err := c.once(original, func() error { return c.download(ctx, u.String(), original) }); err != nil {
+		return Image{}, err
+	}
+	if width == 0 {
+		return readImage(original, "")
+	}
+
+	ext := "jpg"
+	if format == FormatWebP {
+		ext = "webp"
+	} else {
+		format = FormatJPEG
+	}
+	thumbnail := filepath.Join(c.dir, "thumbnails", key+"-"+strconv.Itoa(width)+"."+ext)
+	if err := c.once(thumbnail, func() error { return resize(original, thumbnail, width, format) }); err != nil {
+		return Image{}, err
+	}
+	return readImage(thumbnail, format)
+}
+
+// once runs create unless path already exists, serialising work on the same path. The lock
+// of a path is removed when no request needs it any more.
+func (c *Cache) once(path string, create func() error) error {
+	c.mu.Lock()
+	lock, ok := c.locks[path]
+	if !ok {
+		lock = &pathLock{}
+		c.locks[path] = lock
+	}
+	lock.users++
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if lock.users--; lock.users == 0 {
+			delete(c.locks, path)
+		}
+	}()
+
+	lock.Lock()
+	defer lock.Unlock()
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	return create()
+}
+
+func (c *Cache) download(ctx context.Context, src, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return fmt.Errorf("request cover %s: %w", src, err)
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request cover %s: %w", src, err)
+	}
+	defer func() {
+		if closeErr := res.Body.Close(); closeErr != nil {
+			log.Printf("failed closing cover response body: %v", closeErr)
+		}
+	}()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("cover %s status: %d %s", src, res.StatusCode, res.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxImageBytes+1))
+	if err != nil {
+		return fmt.Errorf("read cover %s: %w", src, err)
+	}
+	if len(data) > maxImageBytes {
+		return fmt.Errorf("cover %s exceeds %d bytes", src, maxImageBytes)
+	}
+	if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("%w: %s", ErrNotAnImage, src)
+	}
+	return fsutil.WriteFileAtomic(path, data)
+}
+
+// resize scales the original to width on a white background and encodes it in format.
+func resize(originalPath, path string, width int, format string) error {
+	f, err := os.Open(originalPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotAnImage, err)
+	}
+
+	bounds := src.Bounds()
+	if bounds.Dx() < width {
+		width = bounds.Dx()
+	}
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if format == FormatWebP {
+		err = nativewebp.Encode(&buf, dst, nil)
+	} else {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality})
+	}
+	if err != nil {
+		return fmt.Errorf("encode thumbnail: %w", err)
+	}
+	return fsutil.WriteFileAtomic(path, buf.Bytes())
+}
+
+func readImage(path, contentType string) (Image, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Image{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Image{}, err
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	sum := sha256.Sum256(data)
+	return Image{
+		Data:        data,
+		ContentType: contentType,
+		ETag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+		ModTime:     info.ModTime().UTC().Truncate(time.Second),
+	}, nil
+}
+
+func cacheKey(src string) string {
+	sum := sha256.Sum256([]byte(src))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,154 @@
+package covers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func coverServer(t *testing.T, width, height int) (*httptest.Server, *int32) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		t.Fatalf("png.Encode() error: %v", err)
+	}
+
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		switch r.URL.Path {
+		case "/moved.png":
+			http.Redirect(w, r, "/cover.png", http.StatusFound)
+			return
+		case "/elsewhere.png":
+			// same server, but under a host name that is not allowed
+			http.Redirect(w, r, "http://"+strings.Replace(r.Host, "127.0.0.1", "localhost", 1)+"/cover.png", http.StatusFound)
+			return
+		}
+		if r.URL.Path == "/not-an-image.jpg" {
+			_, _ = w.Write([]byte("<html>oops</html>"))
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(encoded.Bytes())
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestCacheThumbnails(t *testing.T) {
+	srv, hits := coverServer(t, 800, 600)
+	cache := New(t.TempDir(), srv.Client(), "127.0.0.1")
+	ctx := context.Background()
+	src := srv.URL + "/cover.png"
+
+	tests := []struct {
+		name   string
+		width  int
+		format string
+		decode func([]byte) (image.Image, error)
+		want   image.Point
+	}{
+		{name: "jpeg", width: 320, format: FormatJPEG, decode: func(b []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(b)) }, want: image.Pt(320, 240)},
+		{name: "webp", width: 160, format: FormatWebP, decode: func(b []byte) (image.Image, error) { return webp.Decode(bytes.NewReader(b)) }, want: image.Pt(160, 120)},
+		{name: "unknown format falls back to jpeg", width: 640, format: "image/avif", decode: func(b []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(b)) }, want: image.Pt(640, 480)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cache.Get(ctx, src, tt.width, tt.format)
+			if err != nil {
+				t.Fatalf("Get() error: %v", err)
+			}
+			img, err := tt.decode(got.Data)
+			if err != nil {
+				t.Fatalf("decode thumbnail: %v", err)
+			}
+			if size := img.Bounds().Size(); size != tt.want {
+				t.Errorf("thumbnail size = %v, want %v", size, tt.want)
+			}
+			if got.ETag == "" || got.ModTime.IsZero() {
+				t.Errorf("missing ETag or ModTime: %+v", got)
+			}
+		})
+	}
+
+	original, err := cache.Get(ctx, src, 0, FormatWebP)
+	if err != nil {
+		t.Fatalf("Get() original error: %v", err)
+	}
+	if original.ContentType != "image/png" {
+		t.Errorf("original ContentType = %q, want image/png", original.ContentType)
+	}
+	if n := atomic.LoadInt32(hits); n != 1 {
+		t.Errorf("cover was downloaded %d times, want once", n)
+	}
+}
+
+func TestCacheConcurrentRequestsDownloadOnce(t *testing.T) {
+	srv, hits := coverServer(t, 100, 100)
+	cache := New(t.TempDir(), srv.Client(), "127.0.0.1")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.Get(context.Background(), srv.URL+"/cover.png", 320, FormatJPEG); err != nil {
+				t.Errorf("Get() error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(hits); n != 1 {
+		t.Errorf("cover was downloaded %d times, want once", n)
+	}
+	if len(cache.locks) != 0 {
+		t.Errorf("%d path locks left after the requests finished", len(cache.locks))
+	}
+}
+
+func TestCacheRejects(t *testing.T) {
+	srv, _ := coverServer(t, 10, 10)
+	cache := New(t.TempDir(), srv.Client(), "127.0.0.1")
+
+	tests := []struct {
+		name  string
+		src   string
+		width int
+		want  error
+	}{
+		{name: "foreign host", src: "https://example.com/cover.jpg", want: ErrSourceNotAllowed},
+		{name: "file scheme", src: "file:///etc/passwd", want: ErrSourceNotAllowed},
+		{name: "width", src: srv.URL + "/cover.png", width: 333, want: ErrUnsupportedWidth},
+		{name: "not an image", src: srv.URL + "/not-an-image.jpg", width: 320, want: ErrNotAnImage},
+		{name: "redirect to a foreign host", src: srv.URL + "/elsewhere.png", want: ErrSourceNotAllowed},
+		{name: "redirect to an allowed host", src: srv.URL + "/moved.png", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := cache.Get(context.Background(), tt.src, tt.width, FormatJPEG); !errors.Is(err, tt.want) {
+				t.Errorf("Get() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+// Package feed renders the weekly Plattentests highlights as Atom and RSS 2.0 documents.
+package feed
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+)
+
+// Channel describes the feed as a whole. SiteURL is the web UI root and SelfURL the
+// absolute URL the feed document is served from; ID identifies the feed independently of
+// the host and scheme it is requested under and defaults to SelfURL.
+type Channel struct {
+	ID       string
+	Title    string
+	Subtitle string
+	SiteURL  string
+	SelfURL  string
+	Updated  time.Time
+	// EntryUpdated maps the EntryID of a record to the time of its entry; records missing
+	// from it use Updated.
+	EntryUpdated map[string]time.Time
+	Records      []crawler.Record
+}
+
+// entryUpdated returns the time of the entry for record.
+func (ch Channel) entryUpdated(record crawler.Record) time.Time {
+	if updated, ok := ch.EntryUpdated[EntryID(record)]; ok && !updated.IsZero() {
+		return updated
+	}
+	return ch.Updated
+}
+
+// EntryTime returns when record entered the feed: published, the time its review was first
+// crawled, when known, and otherwise its release date. The release date is capped at
+// notAfter, the time the review appeared at the latest, because reviews often precede the
+// release; records without either get notAfter.
+func EntryTime(record crawler.Record, published, notAfter time.Time) time.Time {
+	if !published.IsZero() {
+		return published.UTC().Truncate(time.Second)
+	}
+	released, err := time.Parse("02.01.2006", strings.TrimSpace(record.ReleaseDate))
+	if err != nil || released.After(notAfter) {
+		return notAfter.UTC().Truncate(time.Second)
+	}
+	return released
+}
+
+// EntryID returns the stable identifier for a review. It is derived from the
+// Plattentests review ID so that readers keep read/unread state across crawls; records
+// without a review ID fall back to a hash of their link.
+func EntryID(record crawler.Record) string {
+	if id := record.ReviewID(); id != "" {
+		return "tag:plattentests.de,2005:rezi-" + id
+	}
+	sum := sha256.Sum256([]byte(record.Link))
+	return "tag:plattentests.de,2005:link-" + hex.EncodeToString(sum[:8])
+}
+
+// Fingerprint returns a content hash for records. It changes whenever anything that ends
+// up in a feed entry changes and is used as the ETag for conditional GET.
+func Fingerprint(records []crawler.Record) string {
+	payload, err := json.Marshal(records)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:16])
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Author   atomPerson  `xml:"author"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    atomText       `xml:"content"`
+}
+
+// Atom renders the channel as an Atom 1.0 (RFC 4287) document.
+func Atom(ch Channel) ([]byte, error) {
+	updated := ch.Updated.UTC().Format(time.RFC3339)
+	id := ch.ID
+	if id == "" {
+		id = ch.SelfURL
+	}
+	doc := atomFeed{
+		ID:       id,
+		Title:    ch.Title,
+		Subtitle: ch.Subtitle,
+		Updated:  updated,
+		Author:   atomPerson{Name: "Plattentests.de", URI: "https://www.plattentests.de/"},
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: ch.SelfURL},
+			{Rel: "alternate", Type: "text/html", Href: ch.SiteURL},
+		},
+	}
+
+	for _, record := range ch.Records {
+		content, err := entryHTML(record)
+		if err != nil {
+			return nil, err
+		}
+		entry := atomEntry{
+			ID:         EntryID(record),
+			Title:      entryTitle(record),
+			Updated:    ch.entryUpdated(record).UTC().Format(time.RFC3339),
+			Links:      []atomLink{{Rel: "alternate", Type: "text/html", Href: record.Link}},
+			Categories: []atomCategory{{Term: fmt.Sprintf("score-%d", record.Score), Label: fmt.Sprintf("%d/10", record.Score)}},
+			Content:    atomText{Type: "html", Body: content},
+		}
+		if record.Headline != "" {
+			entry.Summary = &atomText{Type: "text", Body: record.Headline}
+		}
+		if isAbsoluteURL(record.Image) {
+			entry.Links = append(entry.Links, atomLink{Rel: "enclosure", Type: imageType(record.Image), Href: record.Image})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	return marshal(doc)
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	AtomLink      atomSelf  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type atomSelf struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	Category    string  `xml:"category"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+// RSS renders the channel as an RSS 2.0 document.
+func RSS(ch Channel) ([]byte, error) {
+	updated := ch.Updated.UTC().Format(time.RFC1123Z)
+	doc := rssFeed{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         ch.Title,
+			Link:          ch.SiteURL,
+			Description:   ch.Subtitle,
+			Language:      "de",
+			LastBuildDate: updated,
+			AtomLink:      atomSelf{Href: ch.SelfURL, Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+
+	for _, record := range ch.Records {
+		content, err := entryHTML(record)
+		if err != nil {
+			return nil, err
+		}
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       entryTitle(record),
+			Link:        record.Link,
+			Description: content,
+			Category:    fmt.Sprintf("%d/10", record.Score),
+			GUID:        rssGUID{IsPermaLink: "false", Value: EntryID(record)},
+			PubDate:     ch.entryUpdated(record).UTC().Format(time.RFC1123Z),
+		})
+	}
+
+	return marshal(doc)
+}
+
+func marshal(doc interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("encode feed: %w", err)
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+func entryTitle(record crawler.Record) string {
+	title := fmt.Sprintf("%s - %s (%d/10)", record.Band, record.Recordname, record.Score)
+	if record.IsRecordOfTheWeek {
+		title = "Platte der Woche: " + title
+	}
+	return title
+}
+
+var entryTemplate = template.Must(template.New("entry").Parse(
+	`{{if .Image}}<p><img src="{{.Image}}" alt="Cover art for {{.Band}} – {{.Recordname}}"></p>{{end}}` +
+		`<p><strong>{{.Score}}/10</strong></p>` +
+		`{{if .Headline}}<h3>{{.Headline}}</h3>{{end}}` +
+		`{{if .Review}}{{range .Review}}{{if eq .Kind "quote"}}<blockquote>{{template "spans" .Spans}}</blockquote>{{else}}<p>{{template "spans" .Spans}}</p>{{end}}{{end}}` +
+		`{{else if .Description}}<p>{{.Description}}</p>{{end}}` +
+		`{{if .Highlights}}<p>Highlights:</p><ol>{{range .Highlights}}<li>{{.}}</li>{{end}}</ol>{{end}}` +
+		`{{define "spans"}}{{range .}}{{if .Link}}<a href="{{.Link}}">{{end}}{{if .Strong}}<strong>{{end}}{{if .Emphasis}}<em>{{end}}{{.Text}}{{if .Emphasis}}</em>{{end}}{{if .Strong}}</strong>{{end}}{{if .Link}}</a>{{end}}{{end}}{{end}}`))
+
+// entryHTML renders the escaped HTML body shared by Atom content and RSS descriptions.
+func entryHTML(record crawler.Record) (string, error) {
+	var highlights []string
+	for _, track := range record.Tracks {
+		if track.IsHighlight {
+			highlights = append(highlights, track.Trackname)
+		}
+	}
+	image := ""
+	if isAbsoluteURL(record.Image) {
+		image = record.Image
+	}
+
+	var buf bytes.Buffer
+	err := entryTemplate.Execute(&buf, map[string]interface{}{
+		"Image":       image,
+		"Band":        record.Band,
+		"Recordname":  record.Recordname,
+		"Score":       record.Score,
+		"Headline":    record.Headline,
+		"Description": record.Description,
+		"Review":      record.Review,
+		"Highlights":  highlights,
+	})
+	if err != nil {
+		return "", fmt.Errorf("render feed entry for %s: %w", record.Link, err)
+	}
+	return buf.String(), nil
+}
+
+func isAbsoluteURL(s string) bool {
+	return strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://")
+}
+
+func imageType(image string) string {
+	lower := strings.ToLower(image)
+	switch {
+	case strings.HasSuffix(lower, ".png"):
+		return "image/png"
+	case strings.HasSuffix(lower, ".gif"):
+		return "image/gif"
+	case strings.HasSuffix(lower, ".webp"):
+		return "image/webp"
+	default:
+		return "image/jpeg"
+	}
+}
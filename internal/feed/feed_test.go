@@ -0,0 +1,398 @@
+package feed
+
+import (
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+)
+
+func testChannel() Channel {
+	return Channel{
+		Title:    "Plattentests.de - Highlights of the week",
+		Subtitle: "Weekly highlights",
+		SiteURL:  "https://example.com/",
+		SelfURL:  "https://example.com/feed.atom",
+		Updated:  time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC),
+		Records: []crawler.Record{
+			{
+				Band:              "Radiohead",
+				Recordname:        "Kid A",
+				Link:              "https://www.plattentests.de/rezi.php?show=3",
+				Image:             "https://www.plattentests.de/pics/rezis/kida.jpg",
+				Score:             10,
+				Headline:          "Alles an seinem Platz",
+				Description:       "Ein Album <b>jenseits</b> von Gitarren & Refrains.",
+				IsRecordOfTheWeek: true,
+				Tracks: []crawler.Track{
+					{Trackname: "Everything In Its Right Place", IsHighlight: true},
+					{Trackname: "Kid A"},
+					{Trackname: "Idioteque", IsHighlight: true},
+				},
+			},
+			{
+				Band:       "Björk",
+				Recordname: "Vespertine",
+				Link:       "https://www.plattentests.de/rezi.php?show=42",
+				Image:      "no image found",
+				Score:      9,
+			},
+		},
+	}
+}
+
+// atomDocument mirrors the parts of RFC 4287 that validators check.
+type atomDocument struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Author  []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Links   []atomLink `xml:"link"`
+	Entries []struct {
+		ID      string     `xml:"id"`
+		Title   string     `xml:"title"`
+		Updated string     `xml:"updated"`
+		Links   []atomLink `xml:"link"`
+		Content struct {
+			Type string `xml:"type,attr"`
+			Body string `xml:",chardata"`
+		} `xml:"content"`
+	} `xml:"entry"`
+}
+
+// validateAtom applies the RFC 4287 constraints: feed and entries need id, title and
+// updated; ids are IRIs; timestamps are RFC 3339; the feed has an author and a self link;
+// entries have an alternate link and unique ids.
+func validateAtom(t *testing.T, body []byte) atomDocument {
+	t.Helper()
+	var doc atomDocument
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("atom feed is not well-formed: %v\n%s", err, body)
+	}
+	requireIRI(t, "feed id", doc.ID)
+	requireNonEmpty(t, "feed title", doc.Title)
+	requireRFC3339(t, "feed updated", doc.Updated)
+	if len(doc.Author) == 0 || doc.Author[0].Name == "" {
+		t.Errorf("feed must have an author with a name")
+	}
+	if !hasLink(doc.Links, "self") {
+		t.Errorf("feed must have a rel=self link, got %+v", doc.Links)
+	}
+
+	seen := make(map[string]bool)
+	for i, entry := range doc.Entries {
+		requireIRI(t, "entry id", entry.ID)
+		requireNonEmpty(t, "entry title", entry.Title)
+		requireRFC3339(t, "entry updated", entry.Updated)
+		if seen[entry.ID] {
+			t.Errorf("entry %d reuses id %q", i, entry.ID)
+		}
+		seen[entry.ID] = true
+		if !hasLink(entry.Links, "alternate") {
+			t.Errorf("entry %d must have an alternate link", i)
+		}
+		if entry.Content.Type != "html" {
+			t.Errorf("entry %d content type = %q, want html", i, entry.Content.Type)
+		}
+	}
+	return doc
+}
+
+type rssDocument struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Channel struct {
+		Title string `xml:"title"`
+		// Links also collects the namespaced atom:link; channelLink picks the RSS one.
+		Links []struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+		} `xml:"link"`
+		Description   string `xml:"description"`
+		LastBuildDate string `xml:"lastBuildDate"`
+		Items         []struct {
+			Title       string `xml:"title"`
+			Link        string `xml:"link"`
+			Description string `xml:"description"`
+			PubDate     string `xml:"pubDate"`
+			GUID        struct {
+				IsPermaLink string `xml:"isPermaLink,attr"`
+				Value       string `xml:",chardata"`
+			} `xml:"guid"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+// validateRSS applies the RSS 2.0 specification rules: the channel needs title, link and
+// description; each item needs a title or description; dates are RFC 822; guids are unique.
+func validateRSS(t *testing.T, body []byte) rssDocument {
+	t.Helper()
+	var doc rssDocument
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("rss feed is not well-formed: %v\n%s", err, body)
+	}
+	if doc.Version != "2.0" {
+		t.Errorf("rss version = %q, want 2.0", doc.Version)
+	}
+	requireNonEmpty(t, "channel title", doc.Channel.Title)
+	requireIRI(t, "channel link", channelLink(doc))
+	requireNonEmpty(t, "channel description", doc.Channel.Description)
+	requireRFC822(t, "channel lastBuildDate", doc.Channel.LastBuildDate)
+
+	seen := make(map[string]bool)
+	for i, item := range doc.Channel.Items {
+		if item.Title == "" && item.Description == "" {
+			t.Errorf("item %d needs a title or description", i)
+		}
+		requireRFC822(t, "item pubDate", item.PubDate)
+		requireNonEmpty(t, "item guid", item.GUID.Value)
+		if seen[item.GUID.Value] {
+			t.Errorf("item %d reuses guid %q", i, item.GUID.Value)
+		}
+		seen[item.GUID.Value] = true
+	}
+	return doc
+}
+
+func channelLink(doc rssDocument) string {
+	for _, link := range doc.Channel.Links {
+		if link.XMLName.Space == "" {
+			return link.Value
+		}
+	}
+	return ""
+}
+
+func requireNonEmpty(t *testing.T, field, value string) {
+	t.Helper()
+	if strings.TrimSpace(value) == "" {
+		t.Errorf("%s must not be empty", field)
+	}
+}
+
+func requireIRI(t *testing.T, field, value string) {
+	t.Helper()
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" {
+		t.Errorf("%s = %q is not an absolute IRI", field, value)
+	}
+}
+
+func requireRFC3339(t *testing.T, field, value string) {
+	t.Helper()
+	if _, err := time.Parse(time.RFC3339, value); err != nil {
+		t.Errorf("%s = %q is not an RFC 3339 date: %v", field, value, err)
+	}
+}
+
+func requireRFC822(t *testing.T, field, value string) {
+	t.Helper()
+	if _, err := time.Parse(time.RFC1123Z, value); err != nil {
+		t.Errorf("%s = %q is not an RFC 822 date: %v", field, value, err)
+	}
+}
+
+func hasLink(links []atomLink, rel string) bool {
+	for _, link := range links {
+		if link.Rel == rel && link.Href != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAtomIsValid(t *testing.T) {
+	body, err := Atom(testChannel())
+	if err != nil {
+		t.Fatalf("Atom() error: %v", err)
+	}
+	doc := validateAtom(t, body)
+
+	if len(doc.Entries) != 2 {
+		t.Fatalf("len(Entries) = %d, want 2", len(doc.Entries))
+	}
+	first := doc.Entries[0]
+	if first.ID != "tag:plattentests.de,2005:rezi-3" {
+		t.Errorf("entry id = %q, want review based tag URI", first.ID)
+	}
+	if !strings.HasPrefix(first.Title, "Platte der Woche: Radiohead - Kid A (10/10)") {
+		t.Errorf("entry title = %q", first.Title)
+	}
+	for _, want := range []string{
+		`<img src="https://www.plattentests.de/pics/rezis/kida.jpg"`,
+		"<strong>10/10</strong>",
+		"<h3>Alles an seinem Platz</h3>",
+		"<li>Everything In Its Right Place</li>",
+		"<li>Idioteque</li>",
+		"&lt;b&gt;jenseits&lt;/b&gt;",
+	} {
+		if !strings.Contains(first.Content.Body, want) {
+			t.Errorf("entry content missing %q, got %s", want, first.Content.Body)
+		}
+	}
+	if strings.Contains(first.Content.Body, "<li>Kid A</li>") {
+		t.Errorf("entry content should only list highlight tracks, got %s", first.Content.Body)
+	}
+	if !hasLink(first.Links, "enclosure") {
+		t.Errorf("expected cover art enclosure link, got %+v", first.Links)
+	}
+	if hasLink(doc.Entries[1].Links, "enclosure") || strings.Contains(doc.Entries[1].Content.Body, "<img") {
+		t.Errorf("records without cover art must not reference an image")
+	}
+}
+
+func TestRSSIsValid(t *testing.T) {
+	ch := testChannel()
+	ch.SelfURL = "https://example.com/feed.rss"
+	body, err := RSS(ch)
+	if err != nil {
+		t.Fatalf("RSS() error: %v", err)
+	}
+	doc := validateRSS(t, body)
+
+	if len(doc.Channel.Items) != 2 {
+		t.Fatalf("len(Items) = %d, want 2", len(doc.Channel.Items))
+	}
+	item := doc.Channel.Items[1]
+	if item.GUID.Value != "tag:plattentests.de,2005:rezi-42" || item.GUID.IsPermaLink != "false" {
+		t.Errorf("guid = %+v, want non-permalink review tag", item.GUID)
+	}
+	if item.Link != "https://www.plattentests.de/rezi.php?show=42" {
+		t.Errorf("item link = %q", item.Link)
+	}
+	if !strings.Contains(string(body), `<atom:link href="https://example.com/feed.rss" rel="self" type="application/rss+xml">`) {
+		t.Errorf("expected atom:link self reference, got %s", body)
+	}
+}
+
+func TestEmptyFeedsAreValid(t *testing.T) {
+	ch := testChannel()
+	ch.Records = nil
+
+	atom, err := Atom(ch)
+	if err != nil {
+		t.Fatalf("Atom() error: %v", err)
+	}
+	validateAtom(t, atom)
+
+	rss, err := RSS(ch)
+	if err != nil {
+		t.Fatalf("RSS() error: %v", err)
+	}
+	validateRSS(t, rss)
+}
+
+func TestEntryID(t *testing.T) {
+	withID := crawler.Record{Link: "https://www.plattentests.de/rezi.php?show=18199", Score: 7}
+	changed := withID
+	changed.Score = 8
+	if EntryID(withID) != EntryID(changed) {
+		t.Errorf("entry id must only depend on the review id")
+	}
+
+	withoutID := crawler.Record{Link: "https://example.com/other"}
+	if got := EntryID(withoutID); !strings.HasPrefix(got, "tag:plattentests.de,2005:link-") {
+		t.Errorf("EntryID() fallback = %q", got)
+	}
+	if EntryID(withoutID) != EntryID(crawler.Record{Link: "https://example.com/other"}) {
+		t.Errorf("fallback entry id must be stable")
+	}
+}
+
+func TestEntryTime(t *testing.T) {
+	notAfter := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	published := time.Date(2026, 10, 20, 7, 15, 30, 500, time.UTC)
+	tests := []struct {
+		name      string
+		record    crawler.Record
+		published time.Time
+		want      time.Time
+	}{
+		{name: "first crawled", record: crawler.Record{ReleaseDate: "01.02.2020"}, published: published, want: published.Truncate(time.Second)},
+		{name: "release date", record: crawler.Record{ReleaseDate: "16.10.2026"}, want: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+		{name: "release after the review", record: crawler.Record{ReleaseDate: "30.10.2026"}, want: notAfter},
+		{name: "no date", record: crawler.Record{}, want: notAfter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EntryTime(tt.record, tt.published, notAfter); !got.Equal(tt.want) {
+				t.Errorf("EntryTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntriesUseTheirOwnTime(t *testing.T) {
+	ch := testChannel()
+	ch.ID = "tag:plattentests.de,2005:feed.atom"
+	ch.EntryUpdated = map[string]time.Time{
+		EntryID(ch.Records[0]): time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+	}
+
+	atom, err := Atom(ch)
+	if err != nil {
+		t.Fatalf("Atom() error: %v", err)
+	}
+	doc := validateAtom(t, atom)
+	if doc.ID != ch.ID {
+		t.Errorf("feed id = %q, want %q", doc.ID, ch.ID)
+	}
+	if doc.Entries[0].Updated != "2026-10-12T00:00:00Z" || doc.Entries[1].Updated != "2026-10-19T08:30:00Z" {
+		t.Errorf("entry updated = %q, %q, want the entry time and the feed time", doc.Entries[0].Updated, doc.Entries[1].Updated)
+	}
+
+	rss, err := RSS(ch)
+	if err != nil {
+		t.Fatalf("RSS() error: %v", err)
+	}
+	if !strings.Contains(string(rss), "<pubDate>Mon, 12 Oct 2026 00:00:00 +0000</pubDate>") {
+		t.Errorf("RSS items must use the entry time:\n%s", rss)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	records := testChannel().Records
+	if Fingerprint(records) != Fingerprint(testChannel().Records) {
+		t.Errorf("fingerprint must be deterministic")
+	}
+	changed := testChannel().Records
+	changed[1].Score = 3
+	if Fingerprint(records) == Fingerprint(changed) {
+		t.Errorf("fingerprint must change when record content changes")
+	}
+}
+
+func TestEntryHTMLPrefersReviewParagraphs(t *testing.T) {
+	record := crawler.Record{
+		Band:        "Band",
+		Recordname:  "Album",
+		Description: "flattened description",
+		Review: []crawler.Paragraph{
+			{Kind: crawler.ParagraphText, Spans: []crawler.Span{{Text: "Erster "}, {Text: "Absatz", Emphasis: true}}},
+			{Kind: crawler.ParagraphQuote, Spans: []crawler.Span{{Text: "Zitat", Link: "https://example.com/"}}},
+		},
+	}
+
+	got, err := entryHTML(record)
+	if err != nil {
+		t.Fatalf("entryHTML() error: %v", err)
+	}
+	for _, want := range []string{
+		"<p>Erster <em>Absatz</em></p>",
+		`<blockquote><a href="https://example.com/">Zitat</a></blockquote>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("entryHTML() missing %q, got %s", want, got)
+		}
+	}
+	if strings.Contains(got, "flattened description") {
+		t.Errorf("entryHTML() should not repeat the flattened description, got %s", got)
+	}
+}
@@ -0,0 +1,75 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadJSON(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		missing bool
+		want    []string
+		wantErr string
+	}{
+		{name: "missing file leaves the value untouched", missing: true, want: []string{"default"}},
+		{name: "decoded", content: `["a","b"]`, want: []string{"a", "b"}},
+		{name: "invalid", content: `[`, want: []string{"default"}, wantErr: "decode"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.Repeat("x", i+1)+".json")
+			if !tt.missing {
+				if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := []string{"default"}
+			err := ReadJSON(path, &got)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("ReadJSON() error = %v, want %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadJSON() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nested", "store.json")
+
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFileAtomic(path, []byte(content)); err != nil {
+			t.Fatalf("WriteFileAtomic() error: %v", err)
+		}
+		data, err := ReadFile(path)
+		if err != nil || string(data) != content {
+			t.Errorf("ReadFile() = %q, %v, want %q", data, err, content)
+		}
+	}
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("directory = %v, %v, want only the store", entries, err)
+	}
+}
+
+func TestWriteFileAtomicFailsWithoutDirectory(t *testing.T) {
+	blocker := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocker, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(filepath.Join(blocker, "store.json"), []byte("x")); err == nil {
+		t.Error("WriteFileAtomic() below a file must fail")
+	}
+}
@@ -0,0 +1,263 @@
+// Package matchbench measures how well the playlist creator matches highlights to Spotify
+// tracks. A Corpus lists reviewed records with the Spotify tracks their highlights should
+// match; a Recording holds the Spotify responses the matcher received for them, so matching
+// can be replayed offline and compared with a baseline of earlier Reports. Hand-written
+// corpora and recordings are marked Synthetic: their metrics catch regressions of the matcher
+// but say nothing about how it does against the real Spotify catalog.
+package matchbench
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/jetzlstorfer/plattentests-go/internal/fsutil"
+	"github.com/zmb3/spotify/v2"
+)
+
+// Record is a reviewed record of the corpus.
+type Record struct {
+	Band        string
+	Recordname  string
+	ReleaseYear string
+	// TrackCount is the length of the reviewed tracklist; the album-first strategy prefers
+	// albums of that length.
+	TrackCount int `json:",omitempty"`
+	Highlights []Highlight
+	// Note says what the record tests, e.g. a remastered version or a renamed band.
+	Note string `json:",omitempty"`
+}
+
+// Highlight is a highlight track and the Spotify tracks that are a correct match for it.
+type Highlight struct {
+	Track string
+	// Expected are the IDs of acceptable tracks, e.g. the album version and its remaster. A
+	// highlight without any is not on Spotify, so every match is wrong.
+	Expected []spotify.ID `json:",omitempty"`
+}
+
+// Corpus is the labelled set of records the benchmark matches.
+type Corpus struct {
+	// Synthetic marks invented records whose expected IDs are no real Spotify tracks.
+	Synthetic bool `json:",omitempty"`
+	Records   []Record
+}
+
+// Highlights counts the highlights of all records.
+func (c Corpus) Highlights() int {
+	n := 0
+	for _, record := range c.Records {
+		n += len(record.Highlights)
+	}
+	return n
+}
+
+// LoadCorpus reads the corpus at path.
+func LoadCorpus(path string) (Corpus, error) {
+	var corpus *Corpus
+	if err := fsutil.ReadJSON(path, &corpus); err != nil {
+		return Corpus{}, fmt.Errorf("read benchmark corpus: %w", err)
+	}
+	if corpus == nil {
+		return Corpus{}, fmt.Errorf("read benchmark corpus: %s does not exist", path)
+	}
+	return *corpus, nil
+}
+
+// Recording holds the Spotify responses of a matching run, keyed by search query or ID.
+type Recording struct {
+	// Synthetic marks hand-written responses that were not received from Spotify. Responses
+	// recorded into such a recording later do not clear it.
+	Synthetic bool `json:",omitempty"`
+	// Markets are the markets the responses were recorded for.
+	Markets     []string                             `json:",omitempty"`
+	Tracks      map[string][]spotify.FullTrack       `json:",omitempty"`
+	Albums      map[string][]spotify.SimpleAlbum     `json:",omitempty"`
+	AlbumTracks map[spotify.ID][]spotify.SimpleTrack `json:",omitempty"`
+	// Details are tracks looked up by ID, with ISRC, playability and relinking.
+	Details map[spotify.ID]spotify.FullTrack `json:",omitempty"`
+}
+
+// NewRecording returns an empty recording.
+func NewRecording() *Recording {
+	return &Recording{
+		Tracks:      make(map[string][]spotify.FullTrack),
+		Albums:      make(map[string][]spotify.SimpleAlbum),
+		AlbumTracks: make(map[spotify.ID][]spotify.SimpleTrack),
+		Details:     make(map[spotify.ID]spotify.FullTrack),
+	}
+}
+
+// LoadRecording reads the recording at path; a missing file yields an empty recording.
+func LoadRecording(path string) (*Recording, error) {
+	recording := NewRecording()
+	if err := fsutil.ReadJSON(path, recording); err != nil {
+		return nil, fmt.Errorf("read benchmark recording: %w", err)
+	}
+	// maps that were empty when saving are left out of the file
+	if recording.Tracks == nil {
+		recording.Tracks = make(map[string][]spotify.FullTrack)
+	}
+	if recording.Albums == nil {
+		recording.Albums = make(map[string][]spotify.SimpleAlbum)
+	}
+	if recording.AlbumTracks == nil {
+		recording.AlbumTracks = make(map[spotify.ID][]spotify.SimpleTrack)
+	}
+	if recording.Details == nil {
+		recording.Details = make(map[spotify.ID]spotify.FullTrack)
+	}
+	return recording, nil
+}
+
+// Save writes the recording to path.
+func (r *Recording) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode benchmark recording: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("save benchmark recording: %w", err)
+	}
+	return nil
+}
+
+// Result is how one highlight was matched.
+type Result struct {
+	Band     string
+	Track    string
+	Expected []spotify.ID `json:",omitempty"`
+	// Got is the matched track; empty when nothing was accepted.
+	Got spotify.ID `json:",omitempty"`
+	// Reason is the matcher's explanation of the decision.
+	Reason string
+}
+
+// Correct reports whether the highlight was matched to an expected track.
+func (r Result) Correct() bool {
+	return r.Got != "" && slices.Contains(r.Expected, r.Got)
+}
+
+// Wrong reports whether the highlight was matched to a track that is not expected.
+func (r Result) Wrong() bool {
+	return r.Got != "" && !r.Correct()
+}
+
+// Missed reports whether nothing was matched although the highlight is on Spotify.
+func (r Result) Missed() bool {
+	return r.Got == "" && len(r.Expected) > 0
+}
+
+// Report sums up the results of one matching strategy.
+type Report struct {
+	Strategy   string
+	Highlights int
+	Correct    int
+	Wrong      int
+	Missed     int
+	NotFound   int
+	// Precision is the share of matches that are correct, Recall the share of highlights on
+	// Spotify that were matched correctly and NotFoundRate the share of highlights without a
+	// match.
+	Precision    float64
+	Recall       float64
+	NotFoundRate float64
+	// Mistakes are the wrong and missed results; they are not part of a baseline.
+	Mistakes []Result `json:",omitempty"`
+	// Unrecorded are the Spotify requests the recording had no response for; they were
+	// answered with no results.
+	Unrecorded []string `json:",omitempty"`
+}
+
+// Summarize computes the report of strategy from its results.
+func Summarize(strategy string, results []Result) Report {
+	report := Report{Strategy: strategy, Highlights: len(results)}
+	onSpotify := 0
+	for _, result := range results {
+		if len(result.Expected) > 0 {
+			onSpotify++
+		}
+		switch {
+		case result.Correct():
+			report.Correct++
+		case result.Wrong():
+			report.Wrong++
+			report.Mistakes = append(report.Mistakes, result)
+		case result.Missed():
+			report.Missed++
+			report.Mistakes = append(report.Mistakes, result)
+		}
+		if result.Got == "" {
+			report.NotFound++
+		}
+	}
+	report.Precision = ratio(report.Correct, report.Correct+report.Wrong, 1)
+	report.Recall = ratio(report.Correct, onSpotify, 1)
+	report.NotFoundRate = ratio(report.NotFound, report.Highlights, 0)
+	return report
+}
+
+func ratio(n, total int, none float64) float64 {
+	if total == 0 {
+		return none
+	}
+	return float64(n) / float64(total)
+}
+
+// tolerance absorbs rounding when metrics are compared with a baseline.
+const tolerance = 1e-9
+
+// Regressions compares reports with the baseline of the same strategy and lists every metric
+// that got worse: lower precision or recall, or a higher not-found rate. Strategies missing in
+// the baseline are not compared.
+func Regressions(baseline, reports []Report) []string {
+	var regressions []string
+	for _, report := range reports {
+		i := slices.IndexFunc(baseline, func(b Report) bool { return b.Strategy == report.Strategy })
+		if i < 0 {
+			continue
+		}
+		base := baseline[i]
+		if report.Precision < base.Precision-tolerance {
+			regressions = append(regressions, fmt.Sprintf("%s: precision %.3f is below the baseline %.3f", report.Strategy, report.Precision, base.Precision))
+		}
+		if report.Recall < base.Recall-tolerance {
+			regressions = append(regressions, fmt.Sprintf("%s: recall %.3f is below the baseline %.3f", report.Strategy, report.Recall, base.Recall))
+		}
+		if report.NotFoundRate > base.NotFoundRate+tolerance {
+			regressions = append(regressions, fmt.Sprintf("%s: not-found rate %.3f is above the baseline %.3f", report.Strategy, report.NotFoundRate, base.NotFoundRate))
+		}
+	}
+	return regressions
+}
+
+// LoadBaseline reads the reports at path. A missing baseline is an error, so a benchmark
+// never passes without comparing.
+func LoadBaseline(path string) ([]Report, error) {
+	var baseline []Report
+	if err := fsutil.ReadJSON(path, &baseline); err != nil {
+		return nil, fmt.Errorf("read benchmark baseline: %w", err)
+	}
+	if baseline == nil {
+		return nil, fmt.Errorf("read benchmark baseline: %s does not exist, write it with -update", path)
+	}
+	return baseline, nil
+}
+
+// SaveBaseline writes the metrics of reports to path, without mistakes and unrecorded
+// requests.
+func SaveBaseline(path string, reports []Report) error {
+	baseline := make([]Report, len(reports))
+	for i, report := range reports {
+		report.Mistakes, report.Unrecorded = nil, nil
+		baseline[i] = report
+	}
+	data, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode benchmark baseline: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("save benchmark baseline: %w", err)
+	}
+	return nil
+}
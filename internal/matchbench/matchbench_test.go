@@ -0,0 +1,129 @@
+package matchbench
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+func TestSummarize(t *testing.T) {
+	results := []Result{
+		{Band: "Band", Track: "Correct", Expected: []spotify.ID{"a", "a-remaster"}, Got: "a-remaster"},
+		{Band: "Band", Track: "Wrong", Expected: []spotify.ID{"b"}, Got: "c"},
+		{Band: "Band", Track: "Missed", Expected: []spotify.ID{"d"}},
+		{Band: "Band", Track: "Not on Spotify"},
+		{Band: "Band", Track: "Not on Spotify, matched", Got: "e"},
+	}
+
+	got := Summarize("album", results)
+	want := Report{
+		Strategy: "album", Highlights: 5, Correct: 1, Wrong: 2, Missed: 1, NotFound: 2,
+		Precision: 1.0 / 3, Recall: 1.0 / 3, NotFoundRate: 0.4,
+		Mistakes: []Result{results[1], results[2], results[4]},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Summarize() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	empty := Summarize("track", nil)
+	if empty.Precision != 1 || empty.Recall != 1 || empty.NotFoundRate != 0 {
+		t.Errorf("Summarize() without results = %+v, want perfect metrics", empty)
+	}
+}
+
+func TestRegressions(t *testing.T) {
+	baseline := []Report{{Strategy: "album", Precision: 0.9, Recall: 0.8, NotFoundRate: 0.1}}
+	tests := []struct {
+		name   string
+		report Report
+		want   []string
+	}{
+		{name: "unchanged", report: Report{Strategy: "album", Precision: 0.9, Recall: 0.8, NotFoundRate: 0.1}},
+		{name: "improved", report: Report{Strategy: "album", Precision: 1, Recall: 0.9, NotFoundRate: 0}},
+		{name: "rounding", report: Report{Strategy: "album", Precision: 0.9 - 1e-12, Recall: 0.8, NotFoundRate: 0.1 + 1e-12}},
+		{
+			name:   "worse",
+			report: Report{Strategy: "album", Precision: 0.8, Recall: 0.7, NotFoundRate: 0.2},
+			want: []string{
+				"album: precision 0.800 is below the baseline 0.900",
+				"album: recall 0.700 is below the baseline 0.800",
+				"album: not-found rate 0.200 is above the baseline 0.100",
+			},
+		},
+		{name: "strategy without a baseline", report: Report{Strategy: "track"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Regressions(baseline, []Report{tt.report}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Regressions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordingRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.json")
+	recording, err := LoadRecording(path)
+	if err != nil || recording.Tracks == nil || recording.Details == nil {
+		t.Fatalf("LoadRecording() of a missing file = %+v, %v, want an empty recording", recording, err)
+	}
+
+	recording.Markets = []string{"AT"}
+	recording.Tracks["Band Song"] = []spotify.FullTrack{{SimpleTrack: spotify.SimpleTrack{ID: "t1", Name: "Song"}}}
+	if err := recording.Save(path); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	loaded, err := LoadRecording(path)
+	if err != nil {
+		t.Fatalf("LoadRecording() error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Markets, recording.Markets) || loaded.Tracks["Band Song"][0].ID != "t1" || loaded.Albums == nil || loaded.AlbumTracks == nil {
+		t.Errorf("LoadRecording() = %+v, want the saved recording", loaded)
+	}
+}
+
+func TestBaselineLeavesOutDetails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	report := Report{
+		Strategy: "album", Highlights: 1, Wrong: 1, Recall: 0,
+		Mistakes:   []Result{{Band: "Band", Track: "Song", Got: "x"}},
+		Unrecorded: []string{"track search Band Song"},
+	}
+	if err := SaveBaseline(path, []Report{report}); err != nil {
+		t.Fatalf("SaveBaseline() error: %v", err)
+	}
+	got, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatalf("LoadBaseline() error: %v", err)
+	}
+	want := []Report{{Strategy: "album", Highlights: 1, Wrong: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadBaseline() = %+v, want %+v", got, want)
+	}
+	if len(report.Mistakes) != 1 {
+		t.Error("SaveBaseline() must not change the reports")
+	}
+}
+
+func TestLoadCorpusAndBaselineNeedTheFile(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadCorpus(filepath.Join(dir, "corpus.json")); err == nil {
+		t.Error("LoadCorpus() of a missing file succeeded, want an error")
+	}
+	if _, err := LoadBaseline(filepath.Join(dir, "baseline.json")); err == nil {
+		t.Error("LoadBaseline() of a missing file succeeded, want an error")
+	}
+
+	path := filepath.Join(dir, "corpus.json")
+	if err := os.WriteFile(path, []byte(`{"Synthetic": true, "Records": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	corpus, err := LoadCorpus(path)
+	if err != nil || !corpus.Synthetic {
+		t.Errorf("LoadCorpus() = %+v, %v, want an empty synthetic corpus", corpus, err)
+	}
+}
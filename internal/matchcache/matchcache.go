@@ -0,0 +1,227 @@
+// Package matchcache remembers which Spotify track a highlight was matched to, so restarts,
+// reruns and other instances do not repeat the search. Misses are cached as well, with a
+// shorter lifetime, because a track that is not on Spotify today may be added next week.
+package matchcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	myauth "github.com/jetzlstorfer/plattentests-go/internal/auth"
+	"github.com/jetzlstorfer/plattentests-go/internal/fsutil"
+)
+
+// Entry is a cached search result. An empty TrackID records that nothing matched.
+type Entry struct {
+	TrackID string `json:",omitempty"`
+	// AlbumID is the Spotify album a reviewed record was resolved to; album entries hold it
+	// instead of a TrackID.
+	AlbumID string `json:",omitempty"`
+	// Confidence is how well the match fits the highlight, from 0 to 1.
+	Confidence float64 `json:",omitempty"`
+	// Unavailable marks a miss whose match could not be played in the configured markets.
+	Unavailable bool `json:",omitempty"`
+	// Settings fingerprints the configuration the entry was matched with; callers treat an
+	// entry with other settings as expired.
+	Settings string `json:",omitempty"`
+
+	CachedAt time.Time
+}
+
+// Negative reports whether the entry records a search without a match.
+func (e Entry) Negative() bool {
+	return e.TrackID == "" && e.AlbumID == ""
+}
+
+// Status is the outcome of a lookup.
+type Status int
+
+// Lookup outcomes.
+const (
+	// Miss means the cache has no entry for the key.
+	Miss Status = iota
+	// Hit means the entry is fresh.
+	Hit
+	// Expired means the entry was older than its lifetime and has been dropped.
+	Expired
+)
+
+// Storage reads and writes the serialized cache.
+type Storage interface {
+	// Load returns nil data when nothing has been saved yet.
+	Load() ([]byte, error)
+	Save([]byte) error
+}
+
+// FileStorage keeps the cache in a local JSON file.
+type FileStorage struct {
+	Path string
+}
+
+// Load reads the file; a missing file is an empty cache.
+func (s FileStorage) Load() ([]byte, error) {
+	return fsutil.ReadFile(s.Path)
+}
+
+// Save writes the file atomically.
+func (s FileStorage) Save(data []byte) error {
+	if err := fsutil.WriteFileAtomic(s.Path, data); err != nil {
+		return fmt.Errorf("save match cache: %w", err)
+	}
+	return nil
+}
+
+// BlobStorage keeps the cache in a blob of the Azure Storage container that also holds the
+// Spotify token, so every instance shares it.
+type BlobStorage struct {
+	Name string
+}
+
+// Load downloads the blob; a missing blob is an empty cache.
+func (s BlobStorage) Load() ([]byte, error) {
+	data, err := myauth.DownloadBlob(s.Name)
+	if myauth.IsBlobNotFound(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Save uploads the blob.
+func (s BlobStorage) Save(data []byte) error {
+	_, err := myauth.UploadBlob(s.Name, data)
+	return err
+}
+
+// Cache is an in-memory view of the stored cache. Entries expire after TTL, misses after
+// NegativeTTL; a zero lifetime keeps entries forever. It is safe for concurrent use.
+type Cache struct {
+	mu          sync.Mutex
+	storage     Storage
+	ttl         time.Duration
+	negativeTTL time.Duration
+	entries     map[string]Entry
+	// changed holds the keys stored since the last Flush.
+	changed map[string]bool
+	now     func() time.Time
+}
+
+// Open loads the cache from storage. A nil storage keeps the cache in memory only.
+func Open(storage Storage, ttl, negativeTTL time.Duration) (*Cache, error) {
+	c := &Cache{
+		storage:     storage,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		entries:     make(map[string]Entry),
+		changed:     make(map[string]bool),
+		now:         time.Now,
+	}
+	if storage == nil {
+		return c, nil
+	}
+	entries, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	c.entries = entries
+	return c, nil
+}
+
+// Get returns the entry cached for key. Expired entries are dropped and reported as Expired.
+func (c *Cache) Get(key string) (Entry, Status) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return Entry{}, Miss
+	}
+	if c.expired(entry) {
+		delete(c.entries, key)
+		return Entry{}, Expired
+	}
+	return entry, Hit
+}
+
+// Put caches entry for key; a zero CachedAt is set to the current time. Flush persists it.
+func (c *Cache) Put(key string, entry Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry.CachedAt.IsZero() {
+		entry.CachedAt = c.now()
+	}
+	c.entries[key] = entry
+	c.changed[key] = true
+}
+
+// Len returns the number of cached entries, including ones that expired since they were
+// loaded.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Flush writes the entries stored since the last Flush. It reloads the storage first and keeps
+// the newer entry per key, so instances flushing one after another do not lose each other's
+// results. Expired entries are dropped.
+func (c *Cache) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.storage == nil || len(c.changed) == 0 {
+		return nil
+	}
+
+	stored, err := c.load()
+	if err != nil {
+		return err
+	}
+	for key := range c.changed {
+		if current, ok := stored[key]; !ok || current.CachedAt.Before(c.entries[key].CachedAt) {
+			if entry, ok := c.entries[key]; ok {
+				stored[key] = entry
+			}
+		}
+	}
+	for key, entry := range stored {
+		if c.expired(entry) {
+			delete(stored, key)
+		}
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("encode match cache: %w", err)
+	}
+	if err := c.storage.Save(data); err != nil {
+		return fmt.Errorf("save match cache: %w", err)
+	}
+	c.entries = stored
+	c.changed = make(map[string]bool)
+	return nil
+}
+
+// load reads and decodes the storage.
+func (c *Cache) load() (map[string]Entry, error) {
+	data, err := c.storage.Load()
+	if err != nil {
+		return nil, fmt.Errorf("load match cache: %w", err)
+	}
+	entries := make(map[string]Entry)
+	if len(data) == 0 {
+		return entries, nil
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("decode match cache: %w", err)
+	}
+	return entries, nil
+}
+
+// expired reports whether entry is older than its lifetime.
+func (c *Cache) expired(entry Entry) bool {
+	ttl := c.ttl
+	if entry.Negative() {
+		ttl = c.negativeTTL
+	}
+	return ttl > 0 && c.now().Sub(entry.CachedAt) > ttl
+}
@@ -0,0 +1,105 @@
+package matchcache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheExpiry(t *testing.T) {
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	cache, err := Open(nil, 30*24*time.Hour, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	cache.now = func() time.Time { return start }
+	cache.Put("found", Entry{TrackID: "t1", Confidence: 0.9})
+	cache.Put("missing", Entry{})
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		key   string
+		want  Status
+	}{
+		{name: "unknown key", key: "other", want: Miss},
+		{name: "fresh match", after: time.Hour, key: "found", want: Hit},
+		{name: "fresh miss", after: time.Hour, key: "missing", want: Hit},
+		{name: "miss expires after a day", after: 25 * time.Hour, key: "missing", want: Expired},
+		{name: "expired miss is gone", after: 25 * time.Hour, key: "missing", want: Miss},
+		{name: "match still fresh", after: 29 * 24 * time.Hour, key: "found", want: Hit},
+		{name: "match expires after its ttl", after: 31 * 24 * time.Hour, key: "found", want: Expired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache.now = func() time.Time { return start.Add(tt.after) }
+			entry, status := cache.Get(tt.key)
+			if status != tt.want {
+				t.Fatalf("Get(%q) status = %d, want %d", tt.key, status, tt.want)
+			}
+			if status == Hit && entry.CachedAt != start {
+				t.Errorf("CachedAt = %v, want %v", entry.CachedAt, start)
+			}
+		})
+	}
+}
+
+func TestFileStorageSharedBetweenInstances(t *testing.T) {
+	storage := FileStorage{Path: filepath.Join(t.TempDir(), "cache", "matches.json")}
+	first, err := Open(storage, 0, time.Hour)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	second, err := Open(storage, 0, time.Hour)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	first.Put("a", Entry{TrackID: "a1", Confidence: 1})
+	first.Put("b", Entry{TrackID: "b-old", CachedAt: old})
+	first.Put("stale miss", Entry{CachedAt: old})
+	if err := first.Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+	// the second instance did not see the first one's entries but must not drop them
+	second.Put("b", Entry{TrackID: "b-new"})
+	second.Put("c", Entry{})
+	if err := second.Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+
+	reopened, err := Open(storage, 0, time.Hour)
+	if err != nil {
+		t.Fatalf("reopen error: %v", err)
+	}
+	for key, want := range map[string]string{"a": "a1", "b": "b-new", "c": ""} {
+		entry, status := reopened.Get(key)
+		if status != Hit || entry.TrackID != want {
+			t.Errorf("Get(%q) = %+v, %d, want track %q", key, entry, status, want)
+		}
+	}
+	if reopened.Len() != 3 {
+		t.Errorf("Len() = %d, want the expired miss to be dropped on flush", reopened.Len())
+	}
+
+	files, err := os.ReadDir(filepath.Dir(storage.Path))
+	if err != nil {
+		t.Fatalf("ReadDir() error: %v", err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected only the cache file, found %d entries", len(files))
+	}
+}
+
+func TestOpenCorruptCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "matches.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	if _, err := Open(FileStorage{Path: path}, 0, 0); err == nil {
+		t.Error("Open() of a corrupt file must fail")
+	}
+}
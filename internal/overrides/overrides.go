@@ -0,0 +1,162 @@
+// Package overrides stores manual Spotify matches for highlight tracks, so a track that the
+// search gets wrong or cannot find is fixed once instead of failing every week.
+package overrides
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jetzlstorfer/plattentests-go/internal/fsutil"
+)
+
+// Skip is the TrackID of an override that leaves the track out of the playlist.
+const Skip = "skip"
+
+// Override maps a highlight track to a Spotify track ID or to Skip.
+type Override struct {
+	Band      string
+	Track     string
+	TrackID   string
+	UpdatedAt time.Time
+	// UpdatedBy is the signed-in user who set the override, if known.
+	UpdatedBy string `json:",omitempty"`
+}
+
+// Skipped reports whether the override leaves the track out.
+func (o Override) Skipped() bool {
+	return o.TrackID == Skip
+}
+
+// Key identifies a track independent of case and spacing.
+func Key(band, track string) string {
+	normalize := func(s string) string {
+		return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	}
+	return normalize(band) + "\x00" + normalize(track)
+}
+
+var trackIDPattern = regexp.MustCompile(`^[0-9A-Za-z]+$`)
+
+// ParseTrackID accepts a bare Spotify track ID, a spotify:track: URI, an open.spotify.com track
+// link or Skip, and returns the track ID or Skip.
+func ParseTrackID(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case strings.EqualFold(value, Skip):
+		return Skip, nil
+	case strings.HasPrefix(value, "spotify:track:"):
+		value = strings.TrimPrefix(value, "spotify:track:")
+	case strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "http://"):
+		link, err := url.Parse(value)
+		if err != nil || link.Host != "open.spotify.com" {
+			return "", fmt.Errorf("%q is not a Spotify track link", value)
+		}
+		parts := strings.Split(strings.Trim(link.Path, "/"), "/")
+		if len(parts) < 2 || parts[len(parts)-2] != "track" {
+			return "", fmt.Errorf("%q is not a Spotify track link", value)
+		}
+		value = parts[len(parts)-1]
+	}
+	if !trackIDPattern.MatchString(value) {
+		return "", fmt.Errorf("%q is not a Spotify track ID", value)
+	}
+	return value, nil
+}
+
+// Store is a JSON file backed set of overrides. It is safe for concurrent use.
+type Store struct {
+	mu        sync.RWMutex
+	path      string
+	overrides map[string]Override
+}
+
+// Open loads the store at path. A missing file yields an empty store that is created on the
+// first Put.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, overrides: make(map[string]Override)}
+
+	var overrides []Override
+	if err := fsutil.ReadJSON(path, &overrides); err != nil {
+		return nil, fmt.Errorf("load match overrides: %w", err)
+	}
+	for _, override := range overrides {
+		if override.Band != "" && override.Track != "" && override.TrackID != "" {
+			s.overrides[Key(override.Band, override.Track)] = override
+		}
+	}
+	return s, nil
+}
+
+// Lookup returns the override of a track.
+func (s *Store) Lookup(band, track string) (Override, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	override, ok := s.overrides[Key(band, track)]
+	return override, ok
+}
+
+// Put stores an override, replacing an earlier one for the same track, and writes the store to
+// disk.
+func (s *Store) Put(override Override) error {
+	if strings.TrimSpace(override.Band) == "" || strings.TrimSpace(override.Track) == "" {
+		return fmt.Errorf("match override needs a band and a track, got %+v", override)
+	}
+	if override.TrackID != Skip && !trackIDPattern.MatchString(override.TrackID) {
+		return fmt.Errorf("match override needs a track ID or %q, got %q", Skip, override.TrackID)
+	}
+
+	key := Key(override.Band, override.Track)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, existed := s.overrides[key]
+	s.overrides[key] = override
+	if err := s.save(); err != nil {
+		if existed {
+			s.overrides[key] = prev
+		} else {
+			delete(s.overrides, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// Overrides returns all overrides ordered by band and track.
+func (s *Store) Overrides() []Override {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sorted()
+}
+
+// sorted lists the overrides by key. The caller must hold a lock.
+func (s *Store) sorted() []Override {
+	keys := make([]string, 0, len(s.overrides))
+	for key := range s.overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	overrides := make([]Override, len(keys))
+	for i, key := range keys {
+		overrides[i] = s.overrides[key]
+	}
+	return overrides
+}
+
+// save writes the store atomically. The caller must hold the write lock.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.sorted(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode match overrides: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("save match overrides: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package overrides
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseTrackID(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "bare id", value: " 4uLU6hMCjMI75M1A2tKUQC ", want: "4uLU6hMCjMI75M1A2tKUQC"},
+		{name: "uri", value: "spotify:track:4uLU6hMCjMI75M1A2tKUQC", want: "4uLU6hMCjMI75M1A2tKUQC"},
+		{name: "link", value: "https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC?si=abc", want: "4uLU6hMCjMI75M1A2tKUQC"},
+		{name: "localized link", value: "https://open.spotify.com/intl-de/track/4uLU6hMCjMI75M1A2tKUQC", want: "4uLU6hMCjMI75M1A2tKUQC"},
+		{name: "skip", value: "Skip", want: Skip},
+		{name: "album link", value: "https://open.spotify.com/album/4uLU6hMCjMI75M1A2tKUQC", wantErr: true},
+		{name: "other host", value: "https://example.com/track/4uLU6hMCjMI75M1A2tKUQC", wantErr: true},
+		{name: "garbage", value: "not an id", wantErr: true},
+		{name: "empty", value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTrackID(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTrackID(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTrackID(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStorePutAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "overrides.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if _, ok := store.Lookup("Band", "Song"); ok {
+		t.Fatal("new store must be empty")
+	}
+
+	updated := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	for _, override := range []Override{
+		{Band: "Zebra", Track: "Stripes", TrackID: Skip, UpdatedAt: updated},
+		{Band: "Band", Track: "Song", TrackID: "wrong", UpdatedAt: updated},
+		{Band: "band ", Track: "SONG", TrackID: "right", UpdatedAt: updated, UpdatedBy: "jane@example.com"},
+	} {
+		if err := store.Put(override); err != nil {
+			t.Fatalf("Put() error: %v", err)
+		}
+	}
+	for _, invalid := range []Override{
+		{Band: "Band", TrackID: "id"},
+		{Band: "Band", Track: "Song", TrackID: "spotify:track:id"},
+		{Band: "Band", Track: "Song"},
+	} {
+		if err := store.Put(invalid); err == nil {
+			t.Errorf("Put(%+v) must fail", invalid)
+		}
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen error: %v", err)
+	}
+	got, ok := reopened.Lookup("BAND", "song")
+	if !ok || got.TrackID != "right" || got.UpdatedBy != "jane@example.com" || !got.UpdatedAt.Equal(updated) {
+		t.Errorf("Lookup() = %+v, %v", got, ok)
+	}
+	if skip, ok := reopened.Lookup("Zebra", "Stripes"); !ok || !skip.Skipped() {
+		t.Errorf("Lookup() = %+v, %v, want a skip override", skip, ok)
+	}
+	all := reopened.Overrides()
+	if len(all) != 2 || all[0].TrackID != "right" || all[1].TrackID != Skip {
+		t.Errorf("Overrides() = %+v, want the replaced override once, ordered by band", all)
+	}
+
+	files, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("ReadDir() error: %v", err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected only the store file, found %d entries", len(files))
+	}
+}
+
+func TestOpenCorruptStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Error("Open() of a corrupt file must fail")
+	}
+}
@@ -0,0 +1,319 @@
+// Package ratelimit paces requests to a rate-limited API such as the Spotify Web API. A
+// Governor wraps an http.RoundTripper: it honours Retry-After on 429 Too Many Requests by
+// pausing every request that goes through it, retries throttled requests and idempotent
+// requests that failed, and adapts how many requests may be in flight to the limits it
+// observes.
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Options configure a Governor. Zero values use the defaults.
+type Options struct {
+	// MaxConcurrency is the most requests in flight; the governor starts there, halves the
+	// limit on every 429 and raises it by one after as many successes as the limit. Default 8.
+	MaxConcurrency int
+	// MaxRetries is how often a throttled request, or an idempotent one that failed, is
+	// retried. Default 4.
+	MaxRetries int
+	// Backoff is the wait before the first retry when the response has no Retry-After; it
+	// doubles with every retry. Default 500ms.
+	Backoff time.Duration
+	// MaxWait is the longest Retry-After that is waited for; longer ones still pause every
+	// request, but fail them right away instead of stalling the run. Default 30s.
+	MaxWait time.Duration
+}
+
+// ErrPaused is returned for requests that would have to wait out a Retry-After longer than
+// MaxWait; they are not sent.
+var ErrPaused = errors.New("requests paused by Retry-After")
+
+// Stats count what a Governor did.
+type Stats struct {
+	Requests int
+	// Throttled counts 429 responses, Retries the requests sent again.
+	Throttled int
+	Retries   int
+	// Failed counts requests that still ended in a 429, a server error or a network error,
+	// and those not sent because of ErrPaused.
+	Failed int
+	// Limit is the current number of requests allowed in flight.
+	Limit int
+}
+
+// Sub returns the counts since prev was taken; Limit stays the current one.
+func (s Stats) Sub(prev Stats) Stats {
+	return Stats{
+		Requests:  s.Requests - prev.Requests,
+		Throttled: s.Throttled - prev.Throttled,
+		Retries:   s.Retries - prev.Retries,
+		Failed:    s.Failed - prev.Failed,
+		Limit:     s.Limit,
+	}
+}
+
+// Governor paces the requests of every transport it wraps together, so concurrent workers
+// back off as one when the API throttles any of them.
+type Governor struct {
+	opts Options
+
+	mu        sync.Mutex
+	limit     int
+	inFlight  int
+	successes int
+	// pausedUntil holds back every request after a 429 until the Retry-After has passed.
+	pausedUntil time.Time
+	// changed is closed and replaced whenever a waiting request might be able to go.
+	changed chan struct{}
+	stats   Stats
+}
+
+// New returns a Governor with opts, filling in the defaults.
+func New(opts Options) *Governor {
+	if opts.MaxConcurrency <= 0 {
+		opts.MaxConcurrency = 8
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = 4
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 500 * time.Millisecond
+	}
+	if opts.MaxWait <= 0 {
+		opts.MaxWait = 30 * time.Second
+	}
+	return &Governor{opts: opts, limit: opts.MaxConcurrency, changed: make(chan struct{})}
+}
+
+// Stats returns the counts so far.
+func (g *Governor) Stats() Stats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	stats := g.stats
+	stats.Limit = g.limit
+	return stats
+}
+
+// Transport wraps base, http.DefaultTransport when nil, so its requests are paced by g.
+func (g *Governor) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{governor: g, base: base}
+}
+
+type transport struct {
+	governor *Governor
+	base     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	g := t.governor
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	idempotent := replayable && isIdempotent(req)
+
+	for attempt := 0; ; attempt++ {
+		sent := req
+		if attempt > 0 {
+			sent = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				sent.Body = body
+			}
+		}
+
+		if err := g.acquire(req.Context()); err != nil {
+			return nil, err
+		}
+		resp, err := t.base.RoundTrip(sent)
+		live := req.Context().Err() == nil
+		wait, retry := g.release(resp, err, attempt, replayable && live, idempotent && live)
+		if !retry {
+			return resp, err
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// acquire waits until no pause is in effect and a slot below the limit is free. A pause
+// longer than MaxWait fails with ErrPaused.
+func (g *Governor) acquire(ctx context.Context) error {
+	for {
+		g.mu.Lock()
+		wait := time.Until(g.pausedUntil)
+		if wait > g.opts.MaxWait {
+			g.stats.Failed++
+			g.mu.Unlock()
+			return fmt.Errorf("%w for %s", ErrPaused, wait.Round(time.Second))
+		}
+		if wait <= 0 && g.inFlight < g.limit {
+			g.inFlight++
+			g.stats.Requests++
+			g.mu.Unlock()
+			return nil
+		}
+		changed := g.changed
+		g.mu.Unlock()
+
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			expired = timer.C
+		}
+		select {
+		case <-changed:
+		case <-expired:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// release frees the slot of a finished request, adapts the limit to its outcome and tells
+// whether to retry it and how long to wait first. A 429 pauses all requests instead. A
+// throttled request was not processed, so any request that can be sent again is retried;
+// after an error or a 5xx the server may have acted on it, so only idempotent ones are.
+func (g *Governor) release(resp *http.Response, err error, attempt int, replayable, idempotent bool) (time.Duration, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	defer g.broadcast()
+	g.inFlight--
+
+	retriesLeft := attempt < g.opts.MaxRetries
+	switch {
+	case err != nil:
+		if idempotent && retriesLeft {
+			g.stats.Retries++
+			return g.backoff(attempt), true
+		}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		g.stats.Throttled++
+		g.limit = max(1, g.limit/2)
+		g.successes = 0
+
+		wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok || wait <= 0 {
+			wait = g.backoff(attempt)
+		}
+		// the other requests honour even a pause too long to wait for
+		if until := time.Now().Add(wait); until.After(g.pausedUntil) {
+			g.pausedUntil = until
+		}
+		if wait > g.opts.MaxWait {
+			break
+		}
+		if replayable && retriesLeft {
+			g.stats.Retries++
+			// the pause already holds the request back
+			return 0, true
+		}
+	case resp.StatusCode >= http.StatusInternalServerError:
+		if idempotent && retriesLeft {
+			g.stats.Retries++
+			return g.backoff(attempt), true
+		}
+	default:
+		g.successes++
+		if g.successes >= g.limit && g.limit < g.opts.MaxConcurrency {
+			g.limit++
+			g.successes = 0
+		}
+		return 0, false
+	}
+	g.stats.Failed++
+	return 0, false
+}
+
+// broadcast wakes every request waiting in acquire; g.mu must be held.
+func (g *Governor) broadcast() {
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+func (g *Governor) backoff(attempt int) time.Duration {
+	return min(g.opts.Backoff<<attempt, g.opts.MaxWait)
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, seconds >= 0
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return at.Sub(now), true
+	}
+	return 0, false
+}
+
+// isIdempotent reports whether sending req twice has the same effect as sending it once,
+// which makes it safe to retry when the server may already have acted on it. A PUT is,
+// except a Spotify playlist reorder: its range_start body moves the tracks again every time.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodDelete:
+		return true
+	case http.MethodPut:
+		return !isReorder(req)
+	}
+	return false
+}
+
+// isReorder reports whether req carries a playlist reorder body. A body that cannot be read
+// again counts as one.
+func isReorder(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return false
+	}
+	if req.GetBody == nil {
+		return true
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return true
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	return err != nil || bytes.Contains(data, []byte(`"range_start"`))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,330 @@
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// scriptedServer answers requests with the statuses in order, then with 200 OK.
+func scriptedServer(t *testing.T, retryAfter string, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		if n <= len(statuses) {
+			if statuses[n-1] == http.StatusTooManyRequests && retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestTransportRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		body         string // a playlist replacement when empty
+		retryAfter   string
+		statuses     []int
+		wantStatus   int
+		wantRequests int32
+		wantStats    Stats
+	}{
+		{
+			name:         "throttled get is retried",
+			method:       http.MethodGet,
+			retryAfter:   "0",
+			statuses:     []int{429, 429},
+			wantStatus:   http.StatusOK,
+			wantRequests: 3,
+			wantStats:    Stats{Requests: 3, Throttled: 2, Retries: 2, Limit: 2},
+		},
+		{
+			name:         "server error is retried",
+			method:       http.MethodPut,
+			statuses:     []int{503},
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+			wantStats:    Stats{Requests: 2, Retries: 1, Limit: 8},
+		},
+		{
+			name:         "throttled post is retried",
+			method:       http.MethodPost,
+			retryAfter:   "0",
+			statuses:     []int{429},
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+			wantStats:    Stats{Requests: 2, Throttled: 1, Retries: 1, Limit: 4},
+		},
+		{
+			name:         "post is not retried after a server error",
+			method:       http.MethodPost,
+			statuses:     []int{503},
+			wantStatus:   http.StatusServiceUnavailable,
+			wantRequests: 1,
+			wantStats:    Stats{Requests: 1, Failed: 1, Limit: 8},
+		},
+		{
+			name:         "reorder is not retried after a server error",
+			method:       http.MethodPut,
+			body:         `{"range_start":0,"insert_before":2}`,
+			statuses:     []int{503},
+			wantStatus:   http.StatusServiceUnavailable,
+			wantRequests: 1,
+			wantStats:    Stats{Requests: 1, Failed: 1, Limit: 8},
+		},
+		{
+			name:         "throttled reorder is retried",
+			method:       http.MethodPut,
+			body:         `{"range_start":0,"insert_before":2}`,
+			retryAfter:   "0",
+			statuses:     []int{429},
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+			wantStats:    Stats{Requests: 2, Throttled: 1, Retries: 1, Limit: 4},
+		},
+		{
+			name:         "retry after beyond the longest wait fails right away",
+			method:       http.MethodGet,
+			retryAfter:   "3600",
+			statuses:     []int{429},
+			wantStatus:   http.StatusTooManyRequests,
+			wantRequests: 1,
+			wantStats:    Stats{Requests: 1, Throttled: 1, Failed: 1, Limit: 4},
+		},
+		{
+			name:         "gives up after the last retry",
+			method:       http.MethodGet,
+			statuses:     []int{500, 500, 500},
+			wantStatus:   http.StatusInternalServerError,
+			wantRequests: 3,
+			wantStats:    Stats{Requests: 3, Retries: 2, Failed: 1, Limit: 8},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := scriptedServer(t, tt.retryAfter, tt.statuses...)
+			g := New(Options{MaxRetries: 2, Backoff: time.Millisecond})
+			client := &http.Client{Transport: g.Transport(nil)}
+
+			body := tt.body
+			if body == "" {
+				body = `{"uris":[]}`
+			}
+			req, err := http.NewRequest(tt.method, srv.URL, strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Do() error: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus || requests.Load() != tt.wantRequests {
+				t.Errorf("status %d after %d requests, want %d after %d", resp.StatusCode, requests.Load(), tt.wantStatus, tt.wantRequests)
+			}
+			if got := g.Stats(); got != tt.wantStats {
+				t.Errorf("Stats() = %+v, want %+v", got, tt.wantStats)
+			}
+		})
+	}
+}
+
+// failingTransport fails every request as if the connection broke after sending it.
+type failingTransport struct{ requests atomic.Int32 }
+
+func (f *failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	f.requests.Add(1)
+	return nil, errors.New("connection reset by peer")
+}
+
+func TestTransportRetriesIdempotentRequestsAfterNetworkErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		body         string
+		wantRequests int32
+	}{
+		{name: "get", method: http.MethodGet, wantRequests: 3},
+		{name: "replace", method: http.MethodPut, body: `{"uris":[]}`, wantRequests: 3},
+		{name: "reorder", method: http.MethodPut, body: `{"range_start":0,"insert_before":2}`, wantRequests: 1},
+		{name: "add", method: http.MethodPost, body: `{"uris":[]}`, wantRequests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &failingTransport{}
+			client := &http.Client{Transport: New(Options{MaxRetries: 2, Backoff: time.Millisecond}).Transport(base)}
+
+			req, err := http.NewRequest(tt.method, "http://spotify.invalid/", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.Do(req); err == nil {
+				t.Fatal("Do() must fail")
+			}
+			if got := base.requests.Load(); got != tt.wantRequests {
+				t.Errorf("%d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestRetryAfterPausesEveryRequest(t *testing.T) {
+	var mu sync.Mutex
+	var arrivals []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		arrivals = append(arrivals, time.Now())
+		first := len(arrivals) == 1
+		mu.Unlock()
+		if first {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	g := New(Options{})
+	client := &http.Client{Transport: g.Transport(nil)}
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, path := range []string{"/throttled", "/other"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(srv.URL + path)
+			if err != nil {
+				t.Errorf("Get(%s) error: %v", path, err)
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Get(%s) = %d, want 200", path, resp.StatusCode)
+			}
+		}()
+		// the second request starts once the first one was throttled
+		for g.Stats().Throttled == 0 && path == "/throttled" {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	wg.Wait()
+
+	if len(arrivals) != 3 {
+		t.Fatalf("server saw %d requests, want 3", len(arrivals))
+	}
+	for _, at := range arrivals[1:] {
+		if at.Sub(start) < time.Second {
+			t.Errorf("request arrived %s after the 429, want at least the 1s Retry-After", at.Sub(start))
+		}
+	}
+}
+
+func TestLongRetryAfterFailsEveryRequest(t *testing.T) {
+	srv, requests := scriptedServer(t, "3600", http.StatusTooManyRequests)
+	g := New(Options{})
+	client := &http.Client{Transport: g.Transport(nil)}
+
+	resp, err := client.Get(srv.URL + "/throttled")
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Get() = %d, want 429", resp.StatusCode)
+	}
+
+	start := time.Now()
+	if _, err := client.Get(srv.URL + "/other"); !errors.Is(err, ErrPaused) {
+		t.Errorf("Get() during the pause error = %v, want ErrPaused", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request during the pause took %s, want it to fail right away", elapsed)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("server saw %d requests, want only the throttled one", got)
+	}
+	if want := (Stats{Requests: 1, Throttled: 1, Failed: 2, Limit: 4}); g.Stats() != want {
+		t.Errorf("Stats() = %+v, want %+v", g.Stats(), want)
+	}
+}
+
+func TestConcurrencyAdaptsToThrottling(t *testing.T) {
+	const allowed = 2
+	var inFlight atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		if n > allowed {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}))
+	t.Cleanup(srv.Close)
+
+	g := New(Options{MaxConcurrency: 8, MaxRetries: 10, Backoff: time.Millisecond})
+	client := &http.Client{Transport: g.Transport(nil)}
+	var wg sync.WaitGroup
+	var failed atomic.Int32
+	for range 40 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(srv.URL)
+			if err != nil || resp.StatusCode != http.StatusOK {
+				failed.Add(1)
+			}
+			if resp != nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := g.Stats()
+	if failed.Load() != 0 || stats.Failed != 0 {
+		t.Errorf("%d request(s) failed, stats %+v", failed.Load(), stats)
+	}
+	if stats.Throttled == 0 || stats.Limit >= 8 {
+		t.Errorf("Stats() = %+v, want throttling to lower the limit", stats)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "3", want: 3 * time.Second, wantOK: true},
+		{value: "Mon, 19 Oct 2026 12:00:05 GMT", want: 5 * time.Second, wantOK: true},
+		{value: ""},
+		{value: "soon"},
+		{value: "-1", want: -time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := retryAfter(tt.value, now)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("retryAfter(%q) = %s, %v, want %s, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
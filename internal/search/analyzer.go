@@ -0,0 +1,203 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// germanStopwords are frequent German function words that carry no meaning for search.
+// English words are deliberately absent: band and track names such as "The The" rely on them.
+var germanStopwords = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`
+		aber alle allem allen aller alles als also am an ander andere anderem anderen anderer
+		anderes auch auf aus bei bin bis bist da damit dann das dass dein deine dem den der des
+		dessen dich die dies diese diesem diesen dieser dieses dir doch dort du durch ein eine
+		einem einen einer eines einig einige er es etwas euch euer eure für gegen hab habe haben
+		hat hatte hatten hier hin hinter ich ihm ihn ihnen ihr ihre im in indem ins ist jede jedem
+		jeden jeder jedes jene jenem jenen jener jenes jetzt kann kein keine keinem keinen keiner
+		man manche manchem manchen mancher mein meine mich mir mit muss musste nach nicht nichts
+		noch nun nur ob oder ohne sehr sein seine sich sie sind so solche soll sollte sondern
+		sonst über um und uns unser unter viel vom von vor war waren warst was weg weil weiter
+		welche welchem welchen welcher welches wenn werde werden wie wieder will wir wird wirst
+		wo wollen wollte würde würden zu zum zur zwar zwischen`) {
+		germanStopwords[word] = true
+	}
+}
+
+// tokenize splits text into lowercase words of letters and digits.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// analyze turns text into index terms: tokens without stopwords, stemmed and with
+// diacritics folded so "Bänder", "Band" and "Bands" meet at the same term.
+func analyze(text string) []string {
+	var terms []string
+	for _, token := range tokenize(text) {
+		if germanStopwords[token] {
+			continue
+		}
+		if term := normalizeTerm(token); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// normalizeTerm stems a single lowercase token and removes remaining diacritics.
+func normalizeTerm(token string) string {
+	return foldAccents(stemGerman(token))
+}
+
+func foldAccents(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	result, _, _ := transform.String(t, s)
+	return result
+}
+
+func isGermanVowel(r rune) bool {
+	switch r {
+	case 'a', 'e', 'i', 'o', 'u', 'y', 'ä', 'ö', 'ü':
+		return true
+	}
+	return false
+}
+
+// stemGerman implements the Snowball German stemmer
+// (https://snowballstem.org/algorithms/german/stemmer.html) on a lowercase word.
+func stemGerman(word string) string {
+	w := []rune(strings.ReplaceAll(word, "ß", "ss"))
+	if len(w) < 3 {
+		return string(w)
+	}
+
+	// Mark u and y between vowels as consonants (upper case) while stemming.
+	for i := 1; i < len(w)-1; i++ {
+		if (w[i] == 'u' || w[i] == 'y') && isGermanVowel(w[i-1]) && isGermanVowel(w[i+1]) {
+			w[i] = unicode.ToUpper(w[i])
+		}
+	}
+
+	r1 := region(w, 0)
+	if r1 < 3 {
+		r1 = 3
+	}
+	r2 := region(w, r1)
+
+	inR1 := func(suffix string) bool { return len(w)-len([]rune(suffix)) >= r1 }
+	inR2 := func(suffix string) bool { return len(w)-len([]rune(suffix)) >= r2 }
+	hasSuffix := func(s string) bool { return strings.HasSuffix(string(w), s) }
+	trim := func(suffix string) { w = w[:len(w)-len([]rune(suffix))] }
+	// longest returns the longest of the candidate suffixes the word ends with; the
+	// algorithm only ever considers that one, even if its region condition then fails.
+	longest := func(candidates ...string) string {
+		best := ""
+		for _, candidate := range candidates {
+			if hasSuffix(candidate) && len(candidate) > len(best) {
+				best = candidate
+			}
+		}
+		return best
+	}
+	precededBy := func(suffix, letters string) bool {
+		i := len(w) - len([]rune(suffix)) - 1
+		return i >= 0 && strings.ContainsRune(letters, w[i])
+	}
+
+	// Step 1
+	switch suffix := longest("em", "ern", "er", "e", "en", "es", "s"); suffix {
+	case "em", "ern", "er":
+		if inR1(suffix) {
+			trim(suffix)
+		}
+	case "e", "en", "es":
+		if inR1(suffix) {
+			trim(suffix)
+			if hasSuffix("niss") {
+				trim("s")
+			}
+		}
+	case "s":
+		if inR1(suffix) && precededBy(suffix, "bdfghklmnrt") {
+			trim(suffix)
+		}
+	}
+
+	// Step 2
+	switch suffix := longest("en", "er", "est", "st"); suffix {
+	case "en", "er", "est":
+		if inR1(suffix) {
+			trim(suffix)
+		}
+	case "st":
+		if inR1(suffix) && precededBy(suffix, "bdfghklmnt") && len(w) > 5 {
+			trim(suffix)
+		}
+	}
+
+	// Step 3: derivational suffixes
+	switch suffix := longest("end", "ung", "ig", "ik", "isch", "lich", "heit", "keit"); suffix {
+	case "end", "ung":
+		if inR2(suffix) {
+			trim(suffix)
+			if hasSuffix("ig") && inR2("ig") && !precededBy("ig", "e") {
+				trim("ig")
+			}
+		}
+	case "ig", "ik", "isch":
+		if inR2(suffix) && !precededBy(suffix, "e") {
+			trim(suffix)
+		}
+	case "lich", "heit":
+		if inR2(suffix) {
+			trim(suffix)
+			if (hasSuffix("er") || hasSuffix("en")) && inR1("er") {
+				trim("er")
+			}
+		}
+	case "keit":
+		if inR2(suffix) {
+			trim(suffix)
+			if hasSuffix("lich") && inR2("lich") {
+				trim("lich")
+			} else if hasSuffix("ig") && inR2("ig") {
+				trim("ig")
+			}
+		}
+	}
+
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case 'U':
+			return 'u'
+		case 'Y':
+			return 'y'
+		case 'ä':
+			return 'a'
+		case 'ö':
+			return 'o'
+		case 'ü':
+			return 'u'
+		}
+		return r
+	}, string(w))
+}
+
+// region returns the start of the region after the first non-vowel following a vowel,
+// searching from start. It returns len(w) when there is no such region.
+func region(w []rune, start int) int {
+	for i := start + 1; i < len(w); i++ {
+		if !isGermanVowel(w[i]) && isGermanVowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
@@ -0,0 +1,179 @@
+// Package search provides an in-memory inverted index over archived Plattentests reviews
+// with German stemming, stopword removal and a small field query syntax.
+package search
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+)
+
+// Index is an immutable inverted index over records. Build a new one when the archive
+// changes; concurrent searches on the same Index are safe.
+type Index struct {
+	records []crawler.Record
+	// postings maps field -> term -> term frequency per document.
+	postings map[string]map[string]map[int]int
+}
+
+// Build indexes band, title, headline, review text and tracks of every record.
+func Build(records []crawler.Record) *Index {
+	ix := &Index{
+		records:  append([]crawler.Record(nil), records...),
+		postings: make(map[string]map[string]map[int]int, len(textFields)),
+	}
+	for field := range textFields {
+		ix.postings[field] = make(map[string]map[int]int)
+	}
+
+	for doc, record := range ix.records {
+		description := record.ReviewText()
+		if description == "" {
+			description = record.Description
+		}
+		ix.add(FieldBand, doc, record.Band)
+		ix.add(FieldTitle, doc, record.Recordname)
+		ix.add(FieldHeadline, doc, record.Headline)
+		ix.add(FieldDescription, doc, description)
+		for _, track := range record.Tracks {
+			ix.add(FieldTracks, doc, track.Trackname)
+			if track.IsHighlight {
+				ix.add(FieldHighlights, doc, track.Trackname)
+			}
+		}
+	}
+	return ix
+}
+
+func (ix *Index) add(field string, doc int, text string) {
+	for _, term := range analyze(text) {
+		docs := ix.postings[field][term]
+		if docs == nil {
+			docs = make(map[int]int)
+			ix.postings[field][term] = docs
+		}
+		docs[doc]++
+	}
+}
+
+// Len returns the number of indexed records.
+func (ix *Index) Len() int {
+	if ix == nil {
+		return 0
+	}
+	return len(ix.records)
+}
+
+// Search returns the records matching every clause of q, best matches first. Text
+// relevance uses TF-IDF with per-field boosts; ties are broken by review score.
+func (ix *Index) Search(q Query) []crawler.Record {
+	if ix.Len() == 0 || q.IsEmpty() {
+		return nil
+	}
+
+	// Start from every document and narrow down clause by clause.
+	candidates := make(map[int]float64, len(ix.records))
+	for doc := range ix.records {
+		candidates[doc] = 0
+	}
+
+	for _, clause := range q.Ranges {
+		for doc := range candidates {
+			if clauseMatchesRange(clause, ix.records[doc]) == clause.Negate {
+				delete(candidates, doc)
+			}
+		}
+	}
+
+	for _, clause := range q.Text {
+		scores := ix.matchText(clause)
+		for doc := range candidates {
+			score, ok := scores[doc]
+			if ok == clause.Negate {
+				delete(candidates, doc)
+				continue
+			}
+			candidates[doc] += score
+		}
+	}
+
+	docs := make([]int, 0, len(candidates))
+	for doc := range candidates {
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		a, b := docs[i], docs[j]
+		if candidates[a] != candidates[b] {
+			return candidates[a] > candidates[b]
+		}
+		if ix.records[a].Score != ix.records[b].Score {
+			return ix.records[a].Score > ix.records[b].Score
+		}
+		return a < b
+	})
+
+	results := make([]crawler.Record, len(docs))
+	for i, doc := range docs {
+		results[i] = ix.records[doc]
+	}
+	return results
+}
+
+// matchText returns the documents that contain all terms of clause together with their
+// relevance score.
+func (ix *Index) matchText(clause TextClause) map[int]float64 {
+	fields := []string{clause.Field}
+	if clause.Field == FieldAny {
+		fields = make([]string, 0, len(textFields))
+		for field := range textFields {
+			fields = append(fields, field)
+		}
+	}
+
+	var matched map[int]float64
+	for _, term := range clause.Terms {
+		termScores := make(map[int]float64)
+		for _, field := range fields {
+			docs := ix.postings[field][term]
+			if len(docs) == 0 {
+				continue
+			}
+			idf := math.Log(1 + float64(len(ix.records))/float64(len(docs)))
+			for doc, tf := range docs {
+				termScores[doc] += textFields[field] * idf * (1 + math.Log(float64(tf)))
+			}
+		}
+
+		if matched == nil {
+			matched = termScores
+			continue
+		}
+		for doc, score := range matched {
+			if termScore, ok := termScores[doc]; ok {
+				matched[doc] = score + termScore
+			} else {
+				delete(matched, doc)
+			}
+		}
+	}
+	return matched
+}
+
+func clauseMatchesRange(clause RangeClause, record crawler.Record) bool {
+	var value int
+	switch clause.Field {
+	case "score":
+		value = record.Score
+	case "year":
+		year, err := strconv.Atoi(record.ReleaseYear)
+		if err != nil {
+			return false
+		}
+		value = year
+	default:
+		return false
+	}
+	return value >= clause.Min && value <= clause.Max
+}
@@ -0,0 +1,243 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Searchable text fields. FieldAny matches any of them.
+const (
+	FieldAny         = ""
+	FieldBand        = "band"
+	FieldTitle       = "title"
+	FieldHeadline    = "headline"
+	FieldDescription = "description"
+	FieldTracks      = "track"
+	FieldHighlights  = "highlight"
+)
+
+// textFields lists the indexed text fields with their ranking boost.
+var textFields = map[string]float64{
+	FieldBand:        3,
+	FieldTitle:       3,
+	FieldHeadline:    2,
+	FieldTracks:      1.5,
+	FieldHighlights:  2,
+	FieldDescription: 1,
+}
+
+// fieldAliases maps the names accepted in queries to indexed fields.
+var fieldAliases = map[string]string{
+	"band":        FieldBand,
+	"artist":      FieldBand,
+	"title":       FieldTitle,
+	"album":       FieldTitle,
+	"headline":    FieldHeadline,
+	"description": FieldDescription,
+	"review":      FieldDescription,
+	"text":        FieldDescription,
+	"track":       FieldTracks,
+	"tracks":      FieldTracks,
+	"highlight":   FieldHighlights,
+	"highlights":  FieldHighlights,
+}
+
+// TextClause requires all Terms to occur in Field (or any field for FieldAny).
+type TextClause struct {
+	Field  string
+	Terms  []string
+	Negate bool
+}
+
+// RangeClause constrains a numeric record attribute such as score or release year.
+type RangeClause struct {
+	Field  string
+	Min    int
+	Max    int
+	Negate bool
+}
+
+// Query is a parsed search query. All clauses must hold for a record to match.
+type Query struct {
+	Text   []TextClause
+	Ranges []RangeClause
+}
+
+// IsEmpty reports whether the query has no clauses and would therefore match nothing.
+func (q Query) IsEmpty() bool {
+	return len(q.Text) == 0 && len(q.Ranges) == 0
+}
+
+// HasPositiveText reports whether the query contains free or field text that a live
+// search on Plattentests.de could use.
+func (q Query) HasPositiveText() bool {
+	for _, clause := range q.Text {
+		if !clause.Negate {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseError describes an invalid query.
+type ParseError struct {
+	Token  string
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid search term %q: %s", e.Token, e.Reason)
+}
+
+// Parse reads the query syntax:
+//
+//	radiohead                    free text, matched in every field
+//	"kid a"                      quoted text stays one clause
+//	band:radiohead               text in a field (band, title, headline, review, track, highlight)
+//	score:>=8 score:7..9         score comparison or inclusive range
+//	year:2024 year:<2000         release year comparison or range
+//	-band:coldplay               a leading minus excludes matches
+//
+// Words are analyzed like the indexed text, so stopwords are ignored and stems match.
+func Parse(input string) (Query, error) {
+	var q Query
+	for _, token := range splitQuery(input) {
+		negate := false
+		if strings.HasPrefix(token, "-") && len(token) > 1 {
+			negate = true
+			token = token[1:]
+		}
+
+		name, value, hasField := strings.Cut(token, ":")
+		if !hasField || name == "" || strings.HasPrefix(name, `"`) {
+			if terms := analyze(unquote(token)); len(terms) > 0 {
+				q.Text = append(q.Text, TextClause{Field: FieldAny, Terms: terms, Negate: negate})
+			}
+			continue
+		}
+
+		name = strings.ToLower(name)
+		value = unquote(value)
+		switch name {
+		case "score", "year":
+			clause, err := parseRange(name, value)
+			if err != nil {
+				return Query{}, &ParseError{Token: token, Reason: err.Error()}
+			}
+			clause.Negate = negate
+			q.Ranges = append(q.Ranges, clause)
+		default:
+			field, ok := fieldAliases[name]
+			if !ok {
+				return Query{}, &ParseError{Token: token, Reason: "unknown field " + name}
+			}
+			terms := analyze(value)
+			if len(terms) == 0 {
+				return Query{}, &ParseError{Token: token, Reason: "missing search text"}
+			}
+			q.Text = append(q.Text, TextClause{Field: field, Terms: terms, Negate: negate})
+		}
+	}
+	return q, nil
+}
+
+// parseRange accepts N, =N, >N, >=N, <N, <=N and A..B.
+func parseRange(field, value string) (RangeClause, error) {
+	clause := RangeClause{Field: field, Min: minInt, Max: maxInt}
+	value = strings.TrimSpace(value)
+
+	if lo, hi, ok := strings.Cut(value, ".."); ok {
+		min, err := strconv.Atoi(lo)
+		if err != nil {
+			return clause, fmt.Errorf("range start %q is not a number", lo)
+		}
+		max, err := strconv.Atoi(hi)
+		if err != nil {
+			return clause, fmt.Errorf("range end %q is not a number", hi)
+		}
+		if min > max {
+			return clause, fmt.Errorf("range %d..%d is empty", min, max)
+		}
+		clause.Min, clause.Max = min, max
+		return clause, nil
+	}
+
+	op := strings.TrimRightFunc(value, unicode.IsDigit)
+	number, err := strconv.Atoi(value[len(op):])
+	if err != nil {
+		return clause, fmt.Errorf("%q is not a number", value[len(op):])
+	}
+	switch op {
+	case "", "=":
+		clause.Min, clause.Max = number, number
+	case ">":
+		clause.Min = number + 1
+	case ">=":
+		clause.Min = number
+	case "<":
+		clause.Max = number - 1
+	case "<=":
+		clause.Max = number
+	default:
+		return clause, fmt.Errorf("unknown comparison %q", op)
+	}
+	return clause, nil
+}
+
+const (
+	minInt = -int(^uint(0)>>1) - 1
+	maxInt = int(^uint(0) >> 1)
+)
+
+// splitQuery splits on whitespace outside double quotes.
+func splitQuery(input string) []string {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+	for _, r := range input {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			current.WriteRune(r)
+		case unicode.IsSpace(r) && !inQuotes:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+func unquote(s string) string {
+	return strings.Trim(s, `"`)
+}
+
+// LiveText reduces input to the plain words the Plattentests.de search form understands:
+// field prefixes and quotes are dropped, while negated terms and score or year filters
+// are left out entirely.
+func LiveText(input string) string {
+	var words []string
+	for _, token := range splitQuery(input) {
+		if strings.HasPrefix(token, "-") {
+			continue
+		}
+		if name, value, ok := strings.Cut(token, ":"); ok && name != "" && !strings.HasPrefix(name, `"`) {
+			if _, known := fieldAliases[strings.ToLower(name)]; !known {
+				continue
+			}
+			token = value
+		}
+		if word := strings.TrimSpace(unquote(token)); word != "" {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
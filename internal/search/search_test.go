@@ -0,0 +1,232 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+)
+
+func TestStemGerman(t *testing.T) {
+	// Expected stems taken from the Snowball German reference vocabulary.
+	tests := []struct {
+		word string
+		want string
+	}{
+		{word: "aufeinanderfolgenden", want: "aufeinanderfolg"},
+		{word: "häufigkeit", want: "haufig"},
+		{word: "abgeschlossenen", want: "abgeschloss"},
+		{word: "kategorischen", want: "kategor"},
+		{word: "bänder", want: "band"},
+		{word: "gitarren", want: "gitarr"},
+		{word: "gitarre", want: "gitarr"},
+		{word: "ergebnisse", want: "ergebnis"},
+		{word: "straße", want: "strass"},
+		{word: "bauen", want: "bau"},
+		{word: "ab", want: "ab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			if got := stemGerman(tt.word); got != tt.want {
+				t.Errorf("stemGerman(%q) = %q, want %q", tt.word, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	got := analyze("Die Gitarren und der Bass – laut!")
+	want := []string{"gitarr", "bass", "laut"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("analyze() = %q, want %q", got, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Query
+	}{
+		{
+			name:  "free text drops stopwords",
+			input: "die gitarren",
+			want:  Query{Text: []TextClause{{Field: FieldAny, Terms: []string{"gitarr"}}}},
+		},
+		{
+			name:  "quoted field value",
+			input: `band:"the national"`,
+			want:  Query{Text: []TextClause{{Field: FieldBand, Terms: []string{"the", "national"}}}},
+		},
+		{
+			name:  "score comparison and year",
+			input: "score:>=8 year:2024",
+			want: Query{Ranges: []RangeClause{
+				{Field: "score", Min: 8, Max: maxInt},
+				{Field: "year", Min: 2024, Max: 2024},
+			}},
+		},
+		{
+			name:  "score range and negated band",
+			input: "score:7..9 -band:coldplay",
+			want: Query{
+				Text:   []TextClause{{Field: FieldBand, Terms: []string{"coldplay"}, Negate: true}},
+				Ranges: []RangeClause{{Field: "score", Min: 7, Max: 9}},
+			},
+		},
+		{
+			name:  "field aliases",
+			input: "highlight:idioteque album:kid",
+			want: Query{Text: []TextClause{
+				{Field: FieldHighlights, Terms: []string{"idiotequ"}},
+				{Field: FieldTitle, Terms: []string{"kid"}},
+			}},
+		},
+		{
+			name:  "year less than",
+			input: "year:<2000",
+			want:  Query{Ranges: []RangeClause{{Field: "year", Min: minInt, Max: 1999}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{"genre:rock", "score:abc", "score:=>8", "year:2010..2000", "band:"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := Parse(input)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Parse(%q) error = %v, want *ParseError", input, err)
+			}
+		})
+	}
+}
+
+func testRecords() []crawler.Record {
+	return []crawler.Record{
+		{
+			Band: "Radiohead", Recordname: "Kid A", Score: 10, ReleaseYear: "2000",
+			Link:     "https://www.plattentests.de/rezi.php?show=3",
+			Headline: "Alles an seinem Platz",
+			Review: []crawler.Paragraph{{Kind: crawler.ParagraphText, Spans: []crawler.Span{
+				{Text: "Elektronische Klänge statt Gitarren."},
+			}}},
+			Tracks: []crawler.Track{
+				{Trackname: "Everything In Its Right Place", IsHighlight: true},
+				{Trackname: "Idioteque", IsHighlight: true},
+				{Trackname: "Treefingers"},
+			},
+		},
+		{
+			Band: "The National", Recordname: "Laugh Track", Score: 8, ReleaseYear: "2023",
+			Link:        "https://www.plattentests.de/rezi.php?show=18000",
+			Description: "Melancholische Gitarre und warme Bläser.",
+			Tracks:      []crawler.Track{{Trackname: "Weird Goodbyes", IsHighlight: true}},
+		},
+		{
+			Band: "Coldplay", Recordname: "Moon Music", Score: 4, ReleaseYear: "2024",
+			Link:        "https://www.plattentests.de/rezi.php?show=19000",
+			Description: "Gitarren findet man hier kaum.",
+			Tracks:      []crawler.Track{{Trackname: "Feelslikeimfallinginlove"}, {Trackname: "Idioteque"}},
+		},
+	}
+}
+
+func bands(records []crawler.Record) []string {
+	result := make([]string, len(records))
+	for i, record := range records {
+		result[i] = record.Band
+	}
+	return result
+}
+
+func TestIndexSearch(t *testing.T) {
+	ix := Build(testRecords())
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "radiohead", want: []string{"Radiohead"}},
+		{query: "gitarre", want: []string{"Radiohead", "The National", "Coldplay"}},
+		{query: "gitarre score:>=8", want: []string{"Radiohead", "The National"}},
+		{query: "gitarre -band:coldplay year:2000..2023", want: []string{"Radiohead", "The National"}},
+		{query: "year:2024", want: []string{"Coldplay"}},
+		{query: "highlight:idioteque", want: []string{"Radiohead"}},
+		{query: "track:idioteque", want: []string{"Radiohead", "Coldplay"}},
+		{query: `title:"laugh track"`, want: []string{"The National"}},
+		{query: "klang", want: []string{"Radiohead"}},
+		{query: "bläser", want: []string{"The National"}},
+		{query: "radiohead coldplay", want: []string{}},
+		{query: "die und", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.query, err)
+			}
+			got := bands(ix.Search(q))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexSearchBandBoost(t *testing.T) {
+	records := []crawler.Record{
+		{Band: "Other", Recordname: "Album", Description: "Ein Album, das nach Wilco klingt."},
+		{Band: "Wilco", Recordname: "Cousin"},
+	}
+	q, err := Parse("wilco")
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	got := bands(Build(records).Search(q))
+	if !reflect.DeepEqual(got, []string{"Wilco", "Other"}) {
+		t.Errorf("band matches must rank above description matches, got %q", got)
+	}
+}
+
+func TestEmptyIndex(t *testing.T) {
+	var ix *Index
+	if ix.Len() != 0 {
+		t.Fatalf("nil index must be empty")
+	}
+	q, _ := Parse("anything")
+	if got := Build(nil).Search(q); len(got) != 0 {
+		t.Errorf("empty index returned %d results", len(got))
+	}
+}
+
+func TestLiveText(t *testing.T) {
+	tests := map[string]string{
+		"radiohead":                     "radiohead",
+		`band:"the national" score:>=8`: "the national",
+		"kid -band:coldplay year:2000":  "kid",
+		"score:9":                       "",
+	}
+	for input, want := range tests {
+		t.Run(input, func(t *testing.T) {
+			if got := LiveText(input); got != want {
+				t.Errorf("LiveText(%q) = %q, want %q", input, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+// Package similarity rates how similar two band or track names are, from 0 for nothing in
+// common to 1 for equal names. The measures expect names that are already normalized: lower
+// case, without punctuation and with single spaces.
+package similarity
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/agnivade/levenshtein"
+)
+
+// Scorer rates the similarity of two normalized names from 0 to 1.
+type Scorer interface {
+	Similarity(a, b string) float64
+}
+
+// Func adapts a function to a Scorer.
+type Func func(a, b string) float64
+
+// Similarity calls f.
+func (f Func) Similarity(a, b string) float64 {
+	return f(a, b)
+}
+
+// Names of the scorers, see ByName.
+const (
+	NameCombined    = "combined"
+	NameLevenshtein = "levenshtein"
+	NameJaroWinkler = "jaro-winkler"
+	NameTokenSet    = "token-set"
+)
+
+var (
+	// Levenshtein is 1 minus the edit distance relative to the longer name. It tolerates
+	// typos but also accepts short names that differ in a letter, like "lover" and "loser".
+	Levenshtein Scorer = Func(levenshteinSimilarity)
+	// JaroWinkler counts the characters both names share in about the same place and favours
+	// a common prefix; it is lenient with spacing and small typos in longer names.
+	JaroWinkler Scorer = Func(jaroWinkler)
+	// TokenSet compares the words of both names regardless of their order and how often they
+	// occur. Words count as equal when their Jaro-Winkler similarity is at least
+	// TokenMatchSimilarity; the result is the share of words found on both sides.
+	TokenSet Scorer = Func(tokenSet)
+	// Combined is the product of TokenSet and JaroWinkler: names need the same words and about
+	// the same letters, so a different or an extra word rejects a match that a typo within a
+	// word does not.
+	Combined Scorer = Func(func(a, b string) float64 {
+		return tokenSet(a, b) * jaroWinkler(a, b)
+	})
+	// Default is the scorer used when none is configured.
+	Default = Combined
+)
+
+// TokenMatchSimilarity is the Jaro-Winkler similarity above which TokenSet counts two words
+// as the same word.
+const TokenMatchSimilarity = 0.9
+
+var scorers = map[string]Scorer{
+	NameCombined:    Combined,
+	NameLevenshtein: Levenshtein,
+	NameJaroWinkler: JaroWinkler,
+	NameTokenSet:    TokenSet,
+}
+
+// Names lists the names ByName accepts.
+func Names() []string {
+	names := make([]string, 0, len(scorers))
+	for name := range scorers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ByName returns the scorer called name; empty returns Default.
+func ByName(name string) (Scorer, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return Default, nil
+	}
+	if scorer, ok := scorers[name]; ok {
+		return scorer, nil
+	}
+	return nil, fmt.Errorf("unknown similarity scorer %q, want one of %s", name, strings.Join(Names(), ", "))
+}
+
+func levenshteinSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	longest := max(len([]rune(a)), len([]rune(b)))
+	return 1 - float64(levenshtein.ComputeDistance(a, b))/float64(longest)
+}
+
+// jaroWinkler is the Jaro similarity raised by 0.1 for every shared leading character, up to
+// four.
+func jaroWinkler(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	window := max(max(len(ra), len(rb))/2-1, 0)
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+	matches := 0
+	for i := range ra {
+		for j := max(0, i-window); j < min(len(rb), i+window+1); j++ {
+			if !matchedB[j] && ra[i] == rb[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions, j := 0, 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// tokenSet is the Dice coefficient of the distinct words of a and b, pairing every word with
+// at most one similar word of the other name.
+func tokenSet(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	wordsA, wordsB := distinctWords(a), distinctWords(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+
+	used := make([]bool, len(wordsB))
+	pairs := 0
+	for _, word := range wordsA {
+		best, bestScore := -1, TokenMatchSimilarity
+		for j, other := range wordsB {
+			if used[j] {
+				continue
+			}
+			if score := jaroWinkler(word, other); score >= bestScore {
+				best, bestScore = j, score
+			}
+		}
+		if best >= 0 {
+			used[best] = true
+			pairs++
+		}
+	}
+	return 2 * float64(pairs) / float64(len(wordsA)+len(wordsB))
+}
+
+func distinctWords(s string) []string {
+	var words []string
+	seen := make(map[string]bool)
+	for _, word := range strings.Fields(s) {
+		if !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// versionSuffix is a trailing part in parentheses or brackets, or after " - ", that names a
+// version of a song rather than the song.
+var versionSuffix = regexp.MustCompile(`(?i)\s*(?:\(([^()]*)\)|\[([^\[\]]*)\]|\s-\s+([^-]*))\s*$`)
+
+var versionWord = regexp.MustCompile(`(?i)\b(?:remaster(?:ed)?|live|version|edit|mono|stereo|demo|acoustic|bonus|explicit|clean|deluxe|anniversary|single|radio|original|unplugged|session|re-?recorded|\d{4})\b`)
+
+// StripVersion removes version suffixes such as "(Remastered 2011)", "[Live]" or
+// " - Live at Wembley" from a raw, not yet normalized name. Other suffixes, like "(Part 2)",
+// belong to the title and are kept.
+func StripVersion(name string) string {
+	for {
+		match := versionSuffix.FindStringSubmatchIndex(name)
+		if match == nil || match[0] == 0 {
+			return name
+		}
+		suffix := name[match[0]:match[1]]
+		if !versionWord.MatchString(suffix) {
+			return name
+		}
+		name = name[:match[0]]
+	}
+}
@@ -0,0 +1,85 @@
+package similarity
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScorers(t *testing.T) {
+	tests := []struct {
+		name   string
+		scorer Scorer
+		a, b   string
+		want   float64
+	}{
+		{name: "levenshtein equal", scorer: Levenshtein, a: "song", b: "song", want: 1},
+		{name: "levenshtein one letter", scorer: Levenshtein, a: "lover", b: "loser", want: 0.8},
+		{name: "levenshtein counts letters, not bytes", scorer: Levenshtein, a: "sigur rós", b: "sigur ros", want: 1 - 1.0/9},
+		{name: "levenshtein empty", scorer: Levenshtein, a: "", b: "song", want: 0},
+		{name: "jaro-winkler textbook", scorer: JaroWinkler, a: "martha", b: "marhta", want: 0.9611},
+		{name: "jaro-winkler nothing shared", scorer: JaroWinkler, a: "abc", b: "xyz", want: 0},
+		{name: "jaro-winkler empty", scorer: JaroWinkler, a: "", b: "song", want: 0},
+		{name: "token-set word order", scorer: TokenSet, a: "seeds bad the", b: "the bad seeds", want: 1},
+		{name: "token-set repeated words", scorer: TokenSet, a: "la la la", b: "la", want: 1},
+		{name: "token-set one of two words", scorer: TokenSet, a: "band a", b: "band b", want: 0.5},
+		{name: "token-set typo within a word", scorer: TokenSet, a: "paranoid android", b: "paranoid androids", want: 1},
+		{name: "combined rejects a different word", scorer: Combined, a: "part one", b: "part two", want: 0.45},
+		{name: "combined rejects a different letter of a short title", scorer: Combined, a: "lover", b: "loser", want: 0},
+		{name: "func adapter", scorer: Func(func(a, b string) float64 { return 0.5 }), want: 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scorer.Similarity(tt.a, tt.b); math.Abs(got-tt.want) > 0.0001 {
+				t.Errorf("Similarity(%q, %q) = %.4f, want %.4f", tt.a, tt.b, got, tt.want)
+			}
+			if got := tt.scorer.Similarity(tt.b, tt.a); math.Abs(got-tt.want) > 0.0001 {
+				t.Errorf("Similarity(%q, %q) = %.4f, want it symmetric %.4f", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestByName(t *testing.T) {
+	for _, name := range Names() {
+		if _, err := ByName(name); err != nil {
+			t.Errorf("ByName(%q) error: %v", name, err)
+		}
+	}
+	if scorer, err := ByName(" "); err != nil || scorer.Similarity("lover", "loser") != Default.Similarity("lover", "loser") {
+		t.Errorf("ByName() of an empty name = %v, want the default scorer", err)
+	}
+	if scorer, err := ByName("Jaro-Winkler"); err != nil || scorer.Similarity("martha", "marhta") < 0.96 {
+		t.Errorf("ByName() must ignore case, got %v", err)
+	}
+	if _, err := ByName("soundex"); err == nil {
+		t.Error("ByName() of an unknown scorer must fail")
+	}
+}
+
+func TestStripVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "Song (Remastered 2011)", want: "Song"},
+		{in: "Song - 2017 Remaster", want: "Song"},
+		{in: "Song - Live at Wembley", want: "Song"},
+		{in: "Song [Live]", want: "Song"},
+		{in: "Song (Radio Edit) - Remastered", want: "Song"},
+		{in: "Song (Single Version)", want: "Song"},
+		{in: "Song (Part 2)", want: "Song (Part 2)"},
+		{in: "Song - Reprise", want: "Song - Reprise"},
+		{in: "Rebellion (Lies)", want: "Rebellion (Lies)"},
+		{in: "(Live)", want: "(Live)"},
+		{in: "Liverpool", want: "Liverpool"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := StripVersion(tt.in); got != tt.want {
+				t.Errorf("StripVersion(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
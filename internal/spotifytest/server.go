@@ -0,0 +1,811 @@
+// Package spotifytest runs an in-process fake of the Spotify Web API and accounts service for
+// integration tests. It models the endpoints the creator and internal/auth use: track and album
+// search, album tracklists, track details,
+// creating playlists, reading and changing playlist details, playlist items, adding, removing, reordering and replacing playlist tracks,
+// the current user and refreshing an access token. State is seeded from a Fixture and kept in
+// memory per server. SetThrottle makes it answer 429 Too Many Requests like a rate-limited API.
+package spotifytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode"
+
+	"github.com/zmb3/spotify/v2"
+	"golang.org/x/oauth2"
+)
+
+// RefreshToken is the refresh token the fake accounts service accepts.
+const RefreshToken = "fake-refresh-token"
+
+// maxPageSize mirrors the Spotify limit for playlist item pages and track modifications.
+const maxPageSize = 100
+
+// Fixture seeds a Server: the logged-in user, the searchable catalog and the initial
+// playlists keyed by playlist ID. An empty ID in a playlist stands for a local file.
+type Fixture struct {
+	User      spotify.User            `json:"user"`
+	Tracks    []spotify.FullTrack     `json:"tracks"`
+	Playlists map[string][]spotify.ID `json:"playlists"`
+}
+
+// LoadFixture reads a JSON fixture and fails the test when it cannot be decoded.
+func LoadFixture(t testing.TB, path string) Fixture {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read spotify fixture: %v", err)
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatalf("decode spotify fixture %s: %v", path, err)
+	}
+	return fixture
+}
+
+// Server is a running fake Spotify API. The Web API lives below APIURL and the accounts
+// service below AccountsURL; both are served by the same httptest.Server.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	user      spotify.User
+	tracks    []spotify.FullTrack
+	playlists map[spotify.ID][]spotify.ID
+	addedAt   map[spotify.ID]map[spotify.ID]time.Time
+	// playlistNames lists the names of playlists created through the API, in order.
+	playlistNames []string
+	details       map[spotify.ID]PlaylistDetails
+	accessToken   string
+	refreshes     int
+	queries       []string
+
+	throttle  Throttle
+	inFlight  int
+	throttled int
+}
+
+// Throttle makes the Web API answer 429 Too Many Requests with a Retry-After header.
+type Throttle struct {
+	// MaxInFlight throttles requests beyond this many concurrent ones; 0 means no limit.
+	MaxInFlight int
+	// Burst throttles the next Burst requests regardless of concurrency.
+	Burst int
+	// RetryAfter is the Retry-After header in seconds.
+	RetryAfter int
+	// Latency delays every admitted request so that concurrent requests overlap.
+	Latency time.Duration
+}
+
+// NewServer starts a fake seeded with fixture and closes it when the test ends.
+func NewServer(t testing.TB, fixture Fixture) *Server {
+	t.Helper()
+	s := &Server{
+		user:        fixture.User,
+		tracks:      append([]spotify.FullTrack(nil), fixture.Tracks...),
+		playlists:   make(map[spotify.ID][]spotify.ID, len(fixture.Playlists)),
+		addedAt:     make(map[spotify.ID]map[spotify.ID]time.Time),
+		details:     make(map[spotify.ID]PlaylistDetails),
+		accessToken: "initial-access-token",
+	}
+	for id, items := range fixture.Playlists {
+		s.playlists[spotify.ID(id)] = append([]spotify.ID(nil), items...)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/token", s.handleToken)
+	mux.HandleFunc("GET /v1/me", s.authorized(s.handleMe))
+	mux.HandleFunc("GET /v1/search", s.authorized(s.handleSearch))
+	mux.HandleFunc("GET /v1/albums/{id}/tracks", s.authorized(s.handleAlbumTracks))
+	mux.HandleFunc("GET /v1/tracks", s.authorized(s.handleTracks))
+	mux.HandleFunc("POST /v1/users/{user}/playlists", s.authorized(s.handleCreatePlaylist))
+	mux.HandleFunc("GET /v1/playlists/{id}", s.authorized(s.handleGetPlaylist))
+	mux.HandleFunc("PUT /v1/playlists/{id}", s.authorized(s.handleChangeDetails))
+	mux.HandleFunc("GET /v1/playlists/{id}/tracks", s.authorized(s.handlePlaylistItems))
+	mux.HandleFunc("PUT /v1/playlists/{id}/tracks", s.authorized(s.handleReplace))
+	mux.HandleFunc("POST /v1/playlists/{id}/tracks", s.authorized(s.handleAdd))
+	mux.HandleFunc("DELETE /v1/playlists/{id}/tracks", s.authorized(s.handleRemove))
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// APIURL is the Web API base URL including the trailing slash spotify.WithBaseURL expects.
+func (s *Server) APIURL() string {
+	return s.URL + "/v1/"
+}
+
+// AccountsURL is the base URL of the fake accounts service; tokens are issued at /api/token.
+func (s *Server) AccountsURL() string {
+	return s.URL
+}
+
+// Token returns an already expired token, so the first API call has to refresh it.
+func (s *Server) Token() *oauth2.Token {
+	return &oauth2.Token{
+		AccessToken:  "expired-access-token",
+		TokenType:    "Bearer",
+		RefreshToken: RefreshToken,
+		Expiry:       time.Now().Add(-time.Hour),
+	}
+}
+
+// Client returns a Spotify client talking to the fake with a valid access token.
+func (s *Server) Client() *spotify.Client {
+	s.mu.Lock()
+	token := &oauth2.Token{AccessToken: s.accessToken, TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)}
+	s.mu.Unlock()
+	httpClient := &http.Client{Transport: &oauth2.Transport{Source: oauth2.StaticTokenSource(token)}}
+	return spotify.New(httpClient, spotify.WithBaseURL(s.APIURL()))
+}
+
+// PlaylistTrackIDs returns the current items of a playlist.
+func (s *Server) PlaylistTrackIDs(playlistID spotify.ID) []spotify.ID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]spotify.ID(nil), s.playlists[playlistID]...)
+}
+
+// SetAddedAt changes when a playlist item was added. Fixture items have no date, like the
+// items of very old Spotify playlists; added tracks get the current time.
+func (s *Server) SetAddedAt(playlistID, trackID spotify.ID, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setAddedAt(playlistID, trackID, at)
+}
+
+func (s *Server) setAddedAt(playlistID, trackID spotify.ID, at time.Time) {
+	if s.addedAt[playlistID] == nil {
+		s.addedAt[playlistID] = make(map[spotify.ID]time.Time)
+	}
+	s.addedAt[playlistID][trackID] = at
+}
+
+// PlaylistDetails is the name and description of a playlist.
+type PlaylistDetails struct {
+	Name        string
+	Description string
+	// Changes counts the successful detail changes through the API.
+	Changes int
+}
+
+// Details returns the name and description of a playlist.
+func (s *Server) Details(playlistID spotify.ID) PlaylistDetails {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.details[playlistID]
+}
+
+// CreatedPlaylists returns the names of the playlists created through the API, in order.
+func (s *Server) CreatedPlaylists() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.playlistNames...)
+}
+
+// Queries returns the search queries received so far, in order.
+func (s *Server) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.queries...)
+}
+
+// SetThrottle changes how the Web API throttles requests; the zero Throttle turns it off.
+func (s *Server) SetThrottle(throttle Throttle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.throttle = throttle
+}
+
+// ThrottledCount reports how many requests were answered with 429 Too Many Requests.
+func (s *Server) ThrottledCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.throttled
+}
+
+// RefreshCount reports how many access tokens were issued through the refresh grant.
+func (s *Server) RefreshCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refreshes
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, "invalid_request")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "refresh_token" || r.PostForm.Get("refresh_token") != RefreshToken {
+		writeOAuthError(w, "invalid_grant")
+		return
+	}
+
+	s.mu.Lock()
+	s.refreshes++
+	s.accessToken = fmt.Sprintf("access-token-%d", s.refreshes)
+	token := s.accessToken
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"scope":        "playlist-modify-private playlist-modify-public",
+	})
+}
+
+// authorized rejects requests without the current access token like Spotify does, and
+// throttles them as configured by SetThrottle.
+func (s *Server) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		want := "Bearer " + s.accessToken
+		throttle := s.throttle
+		if throttle.Burst > 0 || (throttle.MaxInFlight > 0 && s.inFlight >= throttle.MaxInFlight) {
+			if s.throttle.Burst > 0 {
+				s.throttle.Burst--
+			}
+			s.throttled++
+			s.mu.Unlock()
+			w.Header().Set("Retry-After", strconv.Itoa(throttle.RetryAfter))
+			writeAPIError(w, http.StatusTooManyRequests, "API rate limit exceeded")
+			return
+		}
+		s.inFlight++
+		s.mu.Unlock()
+		defer func() {
+			s.mu.Lock()
+			s.inFlight--
+			s.mu.Unlock()
+		}()
+		time.Sleep(throttle.Latency)
+
+		if r.Header.Get("Authorization") != want {
+			writeAPIError(w, http.StatusUnauthorized, "The access token expired")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleMe(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, spotify.PrivateUser{User: s.user})
+}
+
+var yearFilter = regexp.MustCompile(`\s*year:(\d{4})`)
+
+// handleSearch returns catalog tracks whose artist and title both occur in the query, or for
+// album searches the albums whose artist and name do. A "year:" filter restricts matches to
+// albums released in that year. Only albums with an ID in the fixture can be found. Tracks
+// are reported for the market parameter, see inMarket.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	searchType := r.URL.Query().Get("type")
+	if searchType != "track" && searchType != "album" {
+		writeAPIError(w, http.StatusBadRequest, "only track and album searches are supported")
+		return
+	}
+
+	year := ""
+	if match := yearFilter.FindStringSubmatch(query); match != nil {
+		year = match[1]
+	}
+	text := " " + normalize(yearFilter.ReplaceAllString(query, "")) + " "
+
+	s.mu.Lock()
+	s.queries = append(s.queries, query)
+	if searchType == "album" {
+		albums := s.searchAlbums(text, year)
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]any{
+			"albums": map[string]any{"items": albums, "total": len(albums), "limit": 20, "offset": 0},
+		})
+		return
+	}
+	items := []spotify.FullTrack{}
+	for _, track := range s.tracks {
+		if year != "" && !strings.HasPrefix(track.Album.ReleaseDate, year) {
+			continue
+		}
+		if len(track.Artists) == 0 {
+			continue
+		}
+		if strings.Contains(text, " "+normalize(track.Artists[0].Name)+" ") &&
+			strings.Contains(text, " "+normalize(track.Name)+" ") {
+			items = append(items, inMarket(withType(track), r.URL.Query().Get("market")))
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tracks": map[string]any{"items": items, "total": len(items), "limit": 20, "offset": 0},
+	})
+}
+
+// inMarket reports a track the way Spotify does for a market: is_playable tells whether the
+// market is among the fixture's available_markets, where none means everywhere, and the
+// markets themselves are left out. Without a market the track is returned unchanged.
+func inMarket(track spotify.FullTrack, market string) spotify.FullTrack {
+	if market == "" {
+		return track
+	}
+	isPlayable := len(track.AvailableMarkets) == 0 || slices.Contains(track.AvailableMarkets, market)
+	track.IsPlayable = &isPlayable
+	track.AvailableMarkets = nil
+	return track
+}
+
+// searchAlbums must be called with s.mu held.
+func (s *Server) searchAlbums(text, year string) []spotify.SimpleAlbum {
+	albums := []spotify.SimpleAlbum{}
+	seen := make(map[spotify.ID]int)
+	for _, track := range s.tracks {
+		album := track.Album
+		if album.ID == "" {
+			continue
+		}
+		if i, ok := seen[album.ID]; ok {
+			albums[i].TotalTracks++
+			continue
+		}
+		if year != "" && !strings.HasPrefix(album.ReleaseDate, year) {
+			continue
+		}
+		artists := album.Artists
+		if len(artists) == 0 {
+			artists = track.Artists
+		}
+		if len(artists) == 0 {
+			continue
+		}
+		if strings.Contains(text, " "+normalize(artists[0].Name)+" ") &&
+			strings.Contains(text, " "+normalize(album.Name)+" ") {
+			album.Artists = artists
+			album.TotalTracks = 1
+			seen[album.ID] = len(albums)
+			albums = append(albums, album)
+		}
+	}
+	return albums
+}
+
+// handleAlbumTracks pages through the catalog tracks of an album in fixture order.
+func (s *Server) handleAlbumTracks(w http.ResponseWriter, r *http.Request) {
+	limit := queryInt(r, "limit", 20)
+	offset := queryInt(r, "offset", 0)
+	if limit < 1 || limit > 50 || offset < 0 {
+		writeAPIError(w, http.StatusBadRequest, "invalid limit or offset")
+		return
+	}
+
+	albumID := spotify.ID(r.PathValue("id"))
+	s.mu.Lock()
+	var tracks []spotify.SimpleTrack
+	for _, track := range s.tracks {
+		if track.Album.ID == albumID {
+			tracks = append(tracks, withType(track).SimpleTrack)
+		}
+	}
+	s.mu.Unlock()
+	if len(tracks) == 0 {
+		writeAPIError(w, http.StatusNotFound, "non existing id")
+		return
+	}
+
+	items := []spotify.SimpleTrack{}
+	for i := offset; i < len(tracks) && i < offset+limit; i++ {
+		items = append(items, tracks[i])
+	}
+	next := ""
+	if offset+limit < len(tracks) {
+		next = fmt.Sprintf("%salbums/%s/tracks?offset=%d&limit=%d", s.APIURL(), albumID, offset+limit, limit)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items": items, "total": len(tracks), "limit": limit, "offset": offset, "next": next,
+	})
+}
+
+// handleTracks returns the catalog entries for up to 50 comma-separated IDs, null for unknown
+// ones, like Spotify.
+func (s *Server) handleTracks(w http.ResponseWriter, r *http.Request) {
+	ids := strings.Split(r.URL.Query().Get("ids"), ",")
+	if len(ids) > 50 {
+		writeAPIError(w, http.StatusBadRequest, "Too many ids requested")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tracks := make([]*spotify.FullTrack, len(ids))
+	for i, id := range ids {
+		for _, track := range s.tracks {
+			if track.ID == spotify.ID(id) {
+				full := inMarket(withType(track), r.URL.Query().Get("market"))
+				tracks[i] = &full
+				break
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tracks": tracks})
+}
+
+// handleCreatePlaylist creates an empty playlist owned by the fixture user.
+func (s *Server) handleCreatePlaylist(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Public      bool   `json:"public"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Name == "" {
+		writeAPIError(w, http.StatusBadRequest, "a playlist needs a name")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.PathValue("user") != s.user.ID {
+		writeAPIError(w, http.StatusForbidden, "You cannot create a playlist for another user")
+		return
+	}
+	id := spotify.ID(fmt.Sprintf("playlist-%d", len(s.playlists)+1))
+	s.playlists[id] = []spotify.ID{}
+	s.playlistNames = append(s.playlistNames, body.Name)
+	s.details[id] = PlaylistDetails{Name: body.Name, Description: body.Description}
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"id": id, "name": body.Name, "description": body.Description, "public": body.Public,
+		"owner": s.user, "tracks": map[string]any{"total": 0},
+	})
+}
+
+// handleGetPlaylist returns name and description; like Spotify, the description is
+// HTML-escaped.
+func (s *Server) handleGetPlaylist(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	playlistID := spotify.ID(r.PathValue("id"))
+	if _, ok := s.playlists[playlistID]; !ok {
+		writeAPIError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	details := s.details[playlistID]
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id": playlistID, "name": details.Name, "description": html.EscapeString(details.Description),
+	})
+}
+
+// handleChangeDetails changes name and description. Spotify rejects line breaks in
+// descriptions, and so does the fake.
+func (s *Server) handleChangeDetails(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if body.Description != nil && strings.ContainsAny(*body.Description, "\r\n") {
+		writeAPIError(w, http.StatusBadRequest, "description must not contain line breaks")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	playlistID := spotify.ID(r.PathValue("id"))
+	if _, ok := s.playlists[playlistID]; !ok {
+		writeAPIError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	details := s.details[playlistID]
+	if body.Name != nil {
+		details.Name = *body.Name
+	}
+	if body.Description != nil {
+		details.Description = *body.Description
+	}
+	details.Changes++
+	s.details[playlistID] = details
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handlePlaylistItems(w http.ResponseWriter, r *http.Request) {
+	limit := queryInt(r, "limit", maxPageSize)
+	offset := queryInt(r, "offset", 0)
+	if limit < 1 || limit > maxPageSize || offset < 0 {
+		writeAPIError(w, http.StatusBadRequest, "invalid limit or offset")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids, ok := s.playlists[spotify.ID(r.PathValue("id"))]
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	items := []map[string]any{}
+	for i := offset; i < len(ids) && i < offset+limit; i++ {
+		item := map[string]any{"track": withType(s.track(ids[i])), "added_at": nil}
+		if ids[i] == "" {
+			item["track"] = localFile
+		}
+		if at, ok := s.addedAt[spotify.ID(r.PathValue("id"))][ids[i]]; ok {
+			item["added_at"] = at.UTC().Format(spotify.TimestampLayout)
+		}
+		items = append(items, item)
+	}
+	next := ""
+	if offset+limit < len(ids) {
+		next = fmt.Sprintf("%splaylists/%s/tracks?offset=%d&limit=%d", s.APIURL(), r.PathValue("id"), offset+limit, limit)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items": items, "total": len(ids), "limit": limit, "offset": offset, "next": next,
+	})
+}
+
+// handleReplace serves both uses of PUT: a range_start body reorders, anything else replaces.
+func (s *Server) handleReplace(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	var reorder struct {
+		RangeStart   *int `json:"range_start"`
+		RangeLength  int  `json:"range_length"`
+		InsertBefore *int `json:"insert_before"`
+	}
+	if len(body) > 0 && json.Unmarshal(body, &reorder) == nil && reorder.RangeStart != nil {
+		s.reorder(w, r.PathValue("id"), *reorder.RangeStart, reorder.RangeLength, reorder.InsertBefore)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	s.modifyPlaylist(w, r, http.StatusCreated, func(current, ids []spotify.ID) []spotify.ID { return ids })
+}
+
+func (s *Server) reorder(w http.ResponseWriter, playlistID string, start, length int, insertBefore *int) {
+	if length == 0 {
+		length = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, ok := s.playlists[spotify.ID(playlistID)]
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	if insertBefore == nil || start < 0 || length < 1 || start+length > len(items) || *insertBefore < 0 || *insertBefore > len(items) {
+		writeAPIError(w, http.StatusBadRequest, "invalid reorder range")
+		return
+	}
+
+	moved := append([]spotify.ID(nil), items[start:start+length]...)
+	rest := append(append([]spotify.ID(nil), items[:start]...), items[start+length:]...)
+	at := *insertBefore
+	if at > start {
+		at -= length
+	}
+	if at < 0 {
+		at = 0
+	}
+	reordered := append(append(append([]spotify.ID(nil), rest[:at]...), moved...), rest[at:]...)
+	s.playlists[spotify.ID(playlistID)] = reordered
+	writeJSON(w, http.StatusOK, map[string]string{"snapshot_id": fmt.Sprintf("snapshot-%d", len(reordered))})
+}
+
+// handleRemove removes every occurrence of the tracks listed in a {"tracks": [{"uri": ...}]} body.
+func (s *Server) handleRemove(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Tracks []struct {
+			URI string `json:"uri"`
+		} `json:"tracks"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(body.Tracks) > maxPageSize {
+		writeAPIError(w, http.StatusBadRequest, "too many tracks")
+		return
+	}
+	remove := make(map[spotify.ID]bool, len(body.Tracks))
+	for _, track := range body.Tracks {
+		id, ok := strings.CutPrefix(track.URI, "spotify:track:")
+		if !ok || id == "" {
+			writeAPIError(w, http.StatusBadRequest, "invalid track uri: "+track.URI)
+			return
+		}
+		remove[spotify.ID(id)] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	playlistID := spotify.ID(r.PathValue("id"))
+	items, ok := s.playlists[playlistID]
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	kept := []spotify.ID{}
+	for _, id := range items {
+		if !remove[id] {
+			kept = append(kept, id)
+			continue
+		}
+		delete(s.addedAt[playlistID], id)
+	}
+	s.playlists[playlistID] = kept
+	writeJSON(w, http.StatusOK, map[string]string{"snapshot_id": fmt.Sprintf("snapshot-%d", len(kept))})
+}
+
+func (s *Server) handleAdd(w http.ResponseWriter, r *http.Request) {
+	s.modifyPlaylist(w, r, http.StatusCreated, func(current, ids []spotify.ID) []spotify.ID {
+		return append(current, ids...)
+	})
+}
+
+// modifyPlaylist reads track URIs from the comma separated uris query parameter or a
+// {"uris": [...]} body, as Spotify accepts both, and stores the result of apply.
+func (s *Server) modifyPlaylist(w http.ResponseWriter, r *http.Request, status int, apply func(current, ids []spotify.ID) []spotify.ID) {
+	var body struct {
+		URIs []string `json:"uris"`
+	}
+	if r.URL.Query().Has("uris") {
+		if uris := r.URL.Query().Get("uris"); uris != "" {
+			body.URIs = strings.Split(uris, ",")
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeAPIError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(body.URIs) > maxPageSize {
+		writeAPIError(w, http.StatusBadRequest, "too many tracks")
+		return
+	}
+	ids := make([]spotify.ID, 0, len(body.URIs))
+	for _, uri := range body.URIs {
+		id, ok := strings.CutPrefix(uri, "spotify:track:")
+		if !ok || id == "" {
+			writeAPIError(w, http.StatusBadRequest, "invalid track uri: "+uri)
+			return
+		}
+		ids = append(ids, spotify.ID(id))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	playlistID := spotify.ID(r.PathValue("id"))
+	current, ok := s.playlists[playlistID]
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	s.playlists[playlistID] = apply(append([]spotify.ID(nil), current...), ids)
+	for _, id := range ids {
+		s.setAddedAt(playlistID, id, time.Now())
+	}
+	writeJSON(w, status, map[string]string{"snapshot_id": fmt.Sprintf("snapshot-%d", len(s.playlists[playlistID]))})
+}
+
+// track returns the catalog entry for id, or a bare track when the playlist references an
+// ID outside the catalog.
+func (s *Server) track(id spotify.ID) spotify.FullTrack {
+	for _, track := range s.tracks {
+		if track.ID == id {
+			return track
+		}
+	}
+	return spotify.FullTrack{SimpleTrack: spotify.SimpleTrack{ID: id}}
+}
+
+// localFile is how Spotify lists a local file in a playlist: a track without an ID. Fixtures
+// add one with an empty ID.
+var localFile = map[string]any{
+	"id": nil, "type": "track", "is_local": true, "name": "Local File", "uri": "spotify:local:::Local+File:0",
+}
+
+// withType sets the item type Spotify sends and the client relies on to decode playlist items.
+func withType(track spotify.FullTrack) spotify.FullTrack {
+	track.Type = "track"
+	if track.URI == "" {
+		track.URI = spotify.URI("spotify:track:" + string(track.ID))
+	}
+	return track
+}
+
+// normalize lowercases s and turns everything except letters and digits into single spaces.
+func normalize(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func queryInt(r *http.Request, key string, fallback int) int {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{"error": map[string]any{"status": status, "message": message}})
+}
+
+func writeOAuthError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+// TokenStore keeps a persisted token in memory. It satisfies auth.TokenStore so tests can
+// log in without Azure.
+type TokenStore struct {
+	mu    sync.Mutex
+	data  []byte
+	saves int
+}
+
+// NewTokenStore returns a store holding the JSON encoding of token.
+func NewTokenStore(t testing.TB, token *oauth2.Token) *TokenStore {
+	t.Helper()
+	data, err := json.Marshal(token)
+	if err != nil {
+		t.Fatalf("marshal token: %v", err)
+	}
+	return &TokenStore{data: data}
+}
+
+// Load returns the stored token.
+func (s *TokenStore) Load() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]byte(nil), s.data...), nil
+}
+
+// Save replaces the stored token.
+func (s *TokenStore) Save(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = append([]byte(nil), b...)
+	s.saves++
+	return nil
+}
+
+// Token decodes the stored token and reports how often it was saved.
+func (s *TokenStore) Token() (*oauth2.Token, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token := new(oauth2.Token)
+	if err := json.Unmarshal(s.data, token); err != nil {
+		return nil, s.saves, err
+	}
+	return token, s.saves, nil
+}
@@ -0,0 +1,346 @@
+package spotifytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zmb3/spotify/v2"
+	"golang.org/x/oauth2"
+)
+
+func TestSearch(t *testing.T) {
+	srv := NewServer(t, LoadFixture(t, "testdata/catalog.json"))
+	client := srv.Client()
+
+	tests := []struct {
+		name  string
+		query string
+		want  []spotify.ID
+	}{
+		{name: "artist and title", query: "Band B Hit Song", want: []spotify.ID{"t2", "t3"}},
+		{name: "year filter", query: "Band B Hit Song year:2023", want: []spotify.ID{"t2"}},
+		{name: "punctuation and case are ignored", query: "band a: OPENER!", want: []spotify.ID{"t1"}},
+		{name: "partial words do not match", query: "Band B Hit Songs", want: nil},
+		{name: "wrong year", query: "Band A Opener year:1999", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := client.Search(context.Background(), tt.query, spotify.SearchTypeTrack)
+			if err != nil {
+				t.Fatalf("Search() error: %v", err)
+			}
+			var got []spotify.ID
+			for _, track := range result.Tracks.Tracks {
+				got = append(got, track.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	if got := srv.Queries(); len(got) != len(tests) || got[1] != "Band B Hit Song year:2023" {
+		t.Errorf("Queries() = %q", got)
+	}
+}
+
+func TestAlbums(t *testing.T) {
+	srv := NewServer(t, LoadFixture(t, "testdata/catalog.json"))
+	client := srv.Client()
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		query      string
+		want       []spotify.ID
+		wantTracks int
+	}{
+		{name: "artist and album", query: "Band B Second Album", want: []spotify.ID{"a2"}, wantTracks: 2},
+		{name: "year filter", query: "Band A First Album year:2024", want: []spotify.ID{"a1"}, wantTracks: 1},
+		{name: "albums without an ID are not found", query: "Band B Best Of", want: nil},
+		{name: "wrong year", query: "Band B Second Album year:2024", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := client.Search(ctx, tt.query, spotify.SearchTypeAlbum)
+			if err != nil {
+				t.Fatalf("Search() error: %v", err)
+			}
+			var got []spotify.ID
+			for _, album := range result.Albums.Albums {
+				got = append(got, album.ID)
+				if int(album.TotalTracks) != tt.wantTracks {
+					t.Errorf("TotalTracks = %d, want %d", album.TotalTracks, tt.wantTracks)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	page, err := client.GetAlbumTracks(ctx, "a2", spotify.Limit(1))
+	if err != nil {
+		t.Fatalf("GetAlbumTracks() error: %v", err)
+	}
+	got := []spotify.ID{page.Tracks[0].ID}
+	if err := client.NextPage(ctx, page); err != nil {
+		t.Fatalf("NextPage() error: %v", err)
+	}
+	got = append(got, page.Tracks[0].ID)
+	if want := []spotify.ID{"t2", "t4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("album tracks = %v, want %v", got, want)
+	}
+	if _, err := client.GetAlbumTracks(ctx, "missing"); err == nil {
+		t.Error("GetAlbumTracks() of an unknown album must fail")
+	}
+}
+
+func TestMarket(t *testing.T) {
+	srv := NewServer(t, LoadFixture(t, "testdata/catalog.json"))
+	client := srv.Client()
+
+	tests := []struct {
+		market string
+		// want is the playability per ID, nil when it is not reported
+		want map[spotify.ID]*bool
+	}{
+		{market: "", want: map[spotify.ID]*bool{"t2": nil, "t3": nil}},
+		{market: "AT", want: map[spotify.ID]*bool{"t2": ptr(true), "t3": ptr(false)}},
+		{market: "CH", want: map[spotify.ID]*bool{"t2": ptr(true), "t3": ptr(true)}},
+	}
+
+	for _, tt := range tests {
+		t.Run("market "+tt.market, func(t *testing.T) {
+			var opts []spotify.RequestOption
+			if tt.market != "" {
+				opts = append(opts, spotify.Market(tt.market))
+			}
+			results, err := client.Search(context.Background(), "Band B Hit Song", spotify.SearchTypeTrack, opts...)
+			if err != nil {
+				t.Fatalf("Search() error: %v", err)
+			}
+			tracks, err := client.GetTracks(context.Background(), []spotify.ID{"t2", "t3"}, opts...)
+			if err != nil {
+				t.Fatalf("GetTracks() error: %v", err)
+			}
+			for _, track := range append(results.Tracks.Tracks, *tracks[0], *tracks[1]) {
+				if !reflect.DeepEqual(track.IsPlayable, tt.want[track.ID]) {
+					t.Errorf("%s is_playable = %v, want %v", track.ID, track.IsPlayable, tt.want[track.ID])
+				}
+				if tt.market != "" && track.AvailableMarkets != nil {
+					t.Errorf("%s available_markets = %v, want none with a market", track.ID, track.AvailableMarkets)
+				}
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestThrottle(t *testing.T) {
+	srv := NewServer(t, LoadFixture(t, "testdata/catalog.json"))
+	client := srv.Client()
+	srv.SetThrottle(Throttle{Burst: 1, RetryAfter: 3})
+
+	_, err := client.GetTracks(context.Background(), []spotify.ID{"t1"})
+	var apiErr spotify.Error
+	if !errors.As(err, &apiErr) || apiErr.Status != 429 {
+		t.Fatalf("throttled GetTracks() error = %v, want 429", err)
+	}
+	if _, err := client.GetTracks(context.Background(), []spotify.ID{"t1"}); err != nil {
+		t.Errorf("GetTracks() after the burst error: %v", err)
+	}
+	if srv.ThrottledCount() != 1 {
+		t.Errorf("ThrottledCount() = %d, want 1", srv.ThrottledCount())
+	}
+}
+
+func TestTracks(t *testing.T) {
+	srv := NewServer(t, LoadFixture(t, "testdata/catalog.json"))
+	tracks, err := srv.Client().GetTracks(context.Background(), []spotify.ID{"t2", "missing", "t1"})
+	if err != nil {
+		t.Fatalf("GetTracks() error: %v", err)
+	}
+	if len(tracks) != 3 || tracks[0].ID != "t2" || tracks[1] != nil || tracks[2].ID != "t1" {
+		t.Errorf("GetTracks() = %v", tracks)
+	}
+	if got := tracks[0].ExternalIDs["isrc"]; got != "DEA012300001" {
+		t.Errorf("ISRC = %q, want DEA012300001", got)
+	}
+}
+
+func TestPlaylistModifications(t *testing.T) {
+	srv := NewServer(t, LoadFixture(t, "testdata/catalog.json"))
+	client := srv.Client()
+	ctx := context.Background()
+
+	if _, err := client.AddTracksToPlaylist(ctx, "weekly", "t2", "t3"); err != nil {
+		t.Fatalf("AddTracksToPlaylist() error: %v", err)
+	}
+	if got, want := srv.PlaylistTrackIDs("weekly"), []spotify.ID{"t1", "t2", "t3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after add = %v, want %v", got, want)
+	}
+
+	if err := client.ReplacePlaylistTracks(ctx, "weekly", "t3"); err != nil {
+		t.Fatalf("ReplacePlaylistTracks() error: %v", err)
+	}
+	if got, want := srv.PlaylistTrackIDs("weekly"), []spotify.ID{"t3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after replace = %v, want %v", got, want)
+	}
+
+	if err := client.ReplacePlaylistTracks(ctx, "weekly"); err != nil {
+		t.Fatalf("ReplacePlaylistTracks() to clear error: %v", err)
+	}
+	if got := srv.PlaylistTrackIDs("weekly"); len(got) != 0 {
+		t.Errorf("after clear = %v, want empty", got)
+	}
+
+	if _, err := client.AddTracksToPlaylist(ctx, "missing", "t1"); err == nil || !strings.Contains(err.Error(), "Not found") {
+		t.Errorf("AddTracksToPlaylist() on unknown playlist error = %v, want not found", err)
+	}
+}
+
+func TestPlaylistItemsPaging(t *testing.T) {
+	fixture := LoadFixture(t, "testdata/catalog.json")
+	for i := 0; i < 150; i++ {
+		fixture.Playlists["empty"] = append(fixture.Playlists["empty"], spotify.ID(fmt.Sprintf("id-%03d", i)))
+	}
+	srv := NewServer(t, fixture)
+	client := srv.Client()
+	ctx := context.Background()
+
+	page, err := client.GetPlaylistItems(ctx, "empty", spotify.Limit(100))
+	if err != nil {
+		t.Fatalf("GetPlaylistItems() error: %v", err)
+	}
+	var ids []spotify.ID
+	pages := 0
+	for {
+		pages++
+		for _, item := range page.Items {
+			ids = append(ids, item.Track.Track.ID)
+		}
+		if err := client.NextPage(ctx, page); err != nil {
+			if errors.Is(err, spotify.ErrNoMorePages) {
+				break
+			}
+			t.Fatalf("NextPage() error: %v", err)
+		}
+	}
+	if pages != 2 || len(ids) != 150 || ids[149] != "id-149" {
+		t.Errorf("got %d pages with %d items", pages, len(ids))
+	}
+}
+
+func TestTokenRefresh(t *testing.T) {
+	srv := NewServer(t, LoadFixture(t, "testdata/catalog.json"))
+	cfg := &oauth2.Config{ClientID: "id", ClientSecret: "secret", Endpoint: oauth2.Endpoint{TokenURL: srv.AccountsURL() + "/api/token"}}
+	ctx := context.Background()
+
+	stale := spotify.New(cfg.Client(ctx, &oauth2.Token{AccessToken: "expired-access-token", TokenType: "Bearer"}), spotify.WithBaseURL(srv.APIURL()))
+	if _, err := stale.CurrentUser(ctx); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("CurrentUser() with stale token error = %v, want unauthorized", err)
+	}
+
+	client := spotify.New(cfg.Client(ctx, srv.Token()), spotify.WithBaseURL(srv.APIURL()))
+	user, err := client.CurrentUser(ctx)
+	if err != nil {
+		t.Fatalf("CurrentUser() error: %v", err)
+	}
+	if user.ID != "plattentests" {
+		t.Errorf("user ID = %q, want plattentests", user.ID)
+	}
+	token, err := client.Token()
+	if err != nil {
+		t.Fatalf("Token() error: %v", err)
+	}
+	if token.AccessToken != "access-token-1" || srv.RefreshCount() != 1 {
+		t.Errorf("token = %q after %d refreshes", token.AccessToken, srv.RefreshCount())
+	}
+
+	invalid := spotify.New(cfg.Client(ctx, &oauth2.Token{RefreshToken: "revoked"}), spotify.WithBaseURL(srv.APIURL()))
+	if _, err := invalid.CurrentUser(ctx); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("CurrentUser() with revoked refresh token error = %v, want invalid_grant", err)
+	}
+}
+
+func TestRemoveAndReorder(t *testing.T) {
+	fixture := LoadFixture(t, "testdata/catalog.json")
+	fixture.Playlists["weekly"] = []spotify.ID{"t1", "t2", "t1", "t3"}
+	srv := NewServer(t, fixture)
+	client := srv.Client()
+	ctx := context.Background()
+
+	if _, err := client.RemoveTracksFromPlaylist(ctx, "weekly", "t1"); err != nil {
+		t.Fatalf("RemoveTracksFromPlaylist() error: %v", err)
+	}
+	if got, want := srv.PlaylistTrackIDs("weekly"), []spotify.ID{"t2", "t3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after remove = %v, want %v", got, want)
+	}
+
+	if _, err := client.AddTracksToPlaylist(ctx, "weekly", "t1"); err != nil {
+		t.Fatalf("AddTracksToPlaylist() error: %v", err)
+	}
+	tests := []struct {
+		name string
+		opt  spotify.PlaylistReorderOptions
+		want []spotify.ID
+	}{
+		{name: "move last to front", opt: spotify.PlaylistReorderOptions{RangeStart: 2, InsertBefore: 0}, want: []spotify.ID{"t1", "t2", "t3"}},
+		{name: "move first to end", opt: spotify.PlaylistReorderOptions{RangeStart: 0, InsertBefore: 3}, want: []spotify.ID{"t2", "t3", "t1"}},
+		{name: "move range", opt: spotify.PlaylistReorderOptions{RangeStart: 1, RangeLength: 2, InsertBefore: 0}, want: []spotify.ID{"t3", "t1", "t2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := client.ReorderPlaylistTracks(ctx, "weekly", tt.opt); err != nil {
+				t.Fatalf("ReorderPlaylistTracks() error: %v", err)
+			}
+			if got := srv.PlaylistTrackIDs("weekly"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("after reorder = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := client.ReorderPlaylistTracks(ctx, "weekly", spotify.PlaylistReorderOptions{RangeStart: 5, InsertBefore: 0}); err == nil {
+		t.Error("ReorderPlaylistTracks() out of range succeeded")
+	}
+}
+
+func TestPlaylistDetails(t *testing.T) {
+	srv := NewServer(t, LoadFixture(t, "testdata/catalog.json"))
+	client := srv.Client()
+	ctx := context.Background()
+
+	if err := client.ChangePlaylistName(ctx, "weekly", "Highlights"); err != nil {
+		t.Fatalf("ChangePlaylistName() error: %v", err)
+	}
+	if err := client.ChangePlaylistDescription(ctx, "weekly", "Rock & Roll"); err != nil {
+		t.Fatalf("ChangePlaylistDescription() error: %v", err)
+	}
+	if err := client.ChangePlaylistDescription(ctx, "weekly", "two\nlines"); err == nil {
+		t.Error("description with a line break must be rejected")
+	}
+
+	playlist, err := client.GetPlaylist(ctx, "weekly")
+	if err != nil {
+		t.Fatalf("GetPlaylist() error: %v", err)
+	}
+	if playlist.Name != "Highlights" || playlist.Description != "Rock &amp; Roll" {
+		t.Errorf("playlist = %q / %q, want the name and the escaped description", playlist.Name, playlist.Description)
+	}
+	if got := srv.Details("weekly"); got.Description != "Rock & Roll" || got.Changes != 2 {
+		t.Errorf("Details() = %+v", got)
+	}
+	if _, err := client.GetPlaylist(ctx, "missing"); err == nil {
+		t.Error("GetPlaylist() of an unknown playlist must fail")
+	}
+}
@@ -0,0 +1,191 @@
+// Package stats computes score distributions and trends over crawled Plattentests records.
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+)
+
+// HighScore is the score from which a review counts as a high rating.
+const HighScore = 8
+
+// MaxScore is the best score Plattentests.de awards.
+const MaxScore = 10
+
+// Distribution summarises the scores of one group of reviews.
+type Distribution struct {
+	Key    string
+	Count  int
+	Mean   float64
+	Median float64
+	// HighShare is the share of reviews scored HighScore or better, between 0 and 1.
+	HighShare float64
+	// Histogram[i] counts the reviews scored i+1.
+	Histogram [MaxScore]int
+}
+
+// HighSharePercent returns HighShare in percent.
+func (d Distribution) HighSharePercent() float64 {
+	return d.HighShare * 100
+}
+
+// TrendPoint is the score of one record of the week.
+type TrendPoint struct {
+	Week       string
+	Band       string
+	Recordname string
+	Score      int
+}
+
+// Report holds all statistics for a set of records. Periods are ordered oldest first,
+// genres and reviewers by number of reviews.
+type Report struct {
+	Overall   Distribution
+	Weeks     []Distribution
+	Months    []Distribution
+	Years     []Distribution
+	Genres    []Distribution
+	Reviewers []Distribution
+	// RecordOfTheWeek lists the records of the week in release order.
+	RecordOfTheWeek []TrendPoint
+	// Unrated counts records without a valid score; they are left out of every statistic.
+	Unrated int
+	// Undated counts rated records without a release date; they only appear in Overall,
+	// Genres and Reviewers (and Years when the release year alone is known).
+	Undated int
+}
+
+// Compute builds the report. Records are placed in weeks and months by their release date,
+// which for new reviews is close to the week they were published.
+func Compute(records []crawler.Record) Report {
+	var report Report
+	var all []int
+	weeks := map[string][]int{}
+	months := map[string][]int{}
+	years := map[string][]int{}
+	genres := map[string][]int{}
+	reviewers := map[string][]int{}
+
+	type dated struct {
+		date  time.Time
+		point TrendPoint
+	}
+	var recordsOfTheWeek []dated
+
+	for _, record := range records {
+		if record.Score < 1 || record.Score > MaxScore {
+			report.Unrated++
+			continue
+		}
+		all = append(all, record.Score)
+		if genre := strings.TrimSpace(record.Genre); genre != "" {
+			genres[genre] = append(genres[genre], record.Score)
+		}
+		if reviewer := strings.TrimSpace(record.Reviewer); reviewer != "" {
+			reviewers[reviewer] = append(reviewers[reviewer], record.Score)
+		}
+
+		date, ok := releaseDate(record)
+		if !ok {
+			report.Undated++
+			if year := strings.TrimSpace(record.ReleaseYear); isYear(year) {
+				years[year] = append(years[year], record.Score)
+			}
+			continue
+		}
+		week := WeekKey(date)
+		weeks[week] = append(weeks[week], record.Score)
+		month := date.Format("2006-01")
+		months[month] = append(months[month], record.Score)
+		year := date.Format("2006")
+		years[year] = append(years[year], record.Score)
+
+		if record.IsRecordOfTheWeek {
+			recordsOfTheWeek = append(recordsOfTheWeek, dated{date: date, point: TrendPoint{
+				Week:       week,
+				Band:       record.Band,
+				Recordname: record.Recordname,
+				Score:      record.Score,
+			}})
+		}
+	}
+
+	report.Overall = distribution("all", all)
+	report.Weeks = byKey(weeks)
+	report.Months = byKey(months)
+	report.Years = byKey(years)
+	report.Genres = byCount(genres)
+	report.Reviewers = byCount(reviewers)
+
+	sort.SliceStable(recordsOfTheWeek, func(i, j int) bool {
+		return recordsOfTheWeek[i].date.Before(recordsOfTheWeek[j].date)
+	})
+	for _, r := range recordsOfTheWeek {
+		report.RecordOfTheWeek = append(report.RecordOfTheWeek, r.point)
+	}
+	return report
+}
+
+// WeekKey formats the ISO week of t, e.g. "2024-W05".
+func WeekKey(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+func releaseDate(record crawler.Record) (time.Time, bool) {
+	date, err := time.Parse("02.01.2006", strings.TrimSpace(record.ReleaseDate))
+	return date, err == nil
+}
+
+func isYear(s string) bool {
+	year, err := strconv.Atoi(s)
+	return err == nil && len(s) == 4 && year > 0
+}
+
+func distribution(key string, scores []int) Distribution {
+	d := Distribution{Key: key, Count: len(scores)}
+	if len(scores) == 0 {
+		return d
+	}
+
+	sorted := append([]int(nil), scores...)
+	sort.Ints(sorted)
+	sum, high := 0, 0
+	for _, score := range sorted {
+		sum += score
+		if score >= HighScore {
+			high++
+		}
+		d.Histogram[score-1]++
+	}
+	d.Mean = float64(sum) / float64(len(sorted))
+	d.HighShare = float64(high) / float64(len(sorted))
+	if mid := len(sorted) / 2; len(sorted)%2 == 1 {
+		d.Median = float64(sorted[mid])
+	} else {
+		d.Median = float64(sorted[mid-1]+sorted[mid]) / 2
+	}
+	return d
+}
+
+// byKey returns the groups ordered by key; period keys sort chronologically.
+func byKey(groups map[string][]int) []Distribution {
+	result := make([]Distribution, 0, len(groups))
+	for key, scores := range groups {
+		result = append(result, distribution(key, scores))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
+
+// byCount returns the groups with the most reviews first.
+func byCount(groups map[string][]int) []Distribution {
+	result := byKey(groups)
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Count > result[j].Count })
+	return result
+}
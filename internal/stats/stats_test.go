@@ -0,0 +1,116 @@
+package stats
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+)
+
+func TestCompute(t *testing.T) {
+	records := []crawler.Record{
+		{Band: "A", Score: 9, ReleaseDate: "05.01.2024", Genre: "Indie", Reviewer: "Jane", IsRecordOfTheWeek: true},
+		{Band: "B", Score: 6, ReleaseDate: "05.01.2024", Genre: "Indie", Reviewer: "John"},
+		{Band: "C", Score: 7, ReleaseDate: "12.02.2024", Genre: "Metal", Reviewer: "Jane"},
+		{Band: "D", Score: 8, ReleaseDate: "15.02.2024", Reviewer: "Jane", IsRecordOfTheWeek: true},
+		{Band: "E", Score: 5, ReleaseYear: "2023"},
+		{Band: "F", Score: 0, ReleaseDate: "15.02.2024"},
+	}
+
+	report := Compute(records)
+
+	if report.Unrated != 1 || report.Undated != 1 {
+		t.Errorf("Unrated/Undated = %d/%d, want 1/1", report.Unrated, report.Undated)
+	}
+
+	overall := report.Overall
+	if overall.Count != 5 || overall.Mean != 7 || overall.Median != 7 {
+		t.Errorf("Overall = %+v, want count 5, mean 7, median 7", overall)
+	}
+	if overall.HighShare != 0.4 {
+		t.Errorf("Overall.HighShare = %v, want 0.4", overall.HighShare)
+	}
+	if overall.Histogram[8] != 1 || overall.Histogram[4] != 1 {
+		t.Errorf("Overall.Histogram = %v", overall.Histogram)
+	}
+
+	if got := keys(report.Weeks); got != "2024-W01 2024-W07" {
+		t.Errorf("weeks = %s", got)
+	}
+	if got := keys(report.Months); got != "2024-01 2024-02" {
+		t.Errorf("months = %s", got)
+	}
+	if got := keys(report.Years); got != "2023 2024" {
+		t.Errorf("years = %s", got)
+	}
+	if report.Months[0].Mean != 7.5 || report.Months[0].Median != 7.5 {
+		t.Errorf("January = %+v, want mean and median 7.5", report.Months[0])
+	}
+	if got := keys(report.Genres); got != "Indie Metal" {
+		t.Errorf("genres = %s", got)
+	}
+	if got := keys(report.Reviewers); got != "Jane John" {
+		t.Errorf("reviewers = %s", got)
+	}
+	if report.Reviewers[0].Count != 3 {
+		t.Errorf("Jane reviewed %d records, want 3", report.Reviewers[0].Count)
+	}
+
+	if len(report.RecordOfTheWeek) != 2 || report.RecordOfTheWeek[0].Band != "A" || report.RecordOfTheWeek[1].Week != "2024-W07" {
+		t.Errorf("RecordOfTheWeek = %+v", report.RecordOfTheWeek)
+	}
+}
+
+func TestComputeEmpty(t *testing.T) {
+	report := Compute(nil)
+	if report.Overall.Count != 0 || report.Overall.Mean != 0 || len(report.Years) != 0 {
+		t.Errorf("Compute(nil) = %+v, want an empty report", report)
+	}
+}
+
+func keys(ds []Distribution) string {
+	result := make([]string, len(ds))
+	for i, d := range ds {
+		result[i] = d.Key
+	}
+	return strings.Join(result, " ")
+}
+
+func TestChartsAreWellFormedSVG(t *testing.T) {
+	report := Compute([]crawler.Record{
+		{Score: 8, ReleaseDate: "01.03.2024", IsRecordOfTheWeek: true},
+		{Score: 4, ReleaseDate: "01.03.2023"},
+	})
+
+	charts := map[string]string{
+		"histogram": BarChart("Scores <all>", HistogramPoints(report.Overall), 2),
+		"mean":      LineChart("Mean per year", MeanPoints(report.Years), 0, MaxScore),
+		"single":    LineChart("Record of the week", TrendPoints(report.RecordOfTheWeek), 0, MaxScore),
+		"empty":     LineChart("Empty", nil, 0, 100),
+	}
+
+	for name, svg := range charts {
+		t.Run(name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(svg))
+			for {
+				_, err := decoder.Token()
+				if err != nil {
+					if !errors.Is(err, io.EOF) {
+						t.Fatalf("invalid SVG: %v\n%s", err, svg)
+					}
+					break
+				}
+			}
+			if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, "viewBox") {
+				t.Errorf("unexpected SVG: %s", svg)
+			}
+		})
+	}
+
+	if strings.Count(charts["histogram"], "chart-bar-high") != 3 {
+		t.Errorf("scores 8 to 10 must be highlighted")
+	}
+}
@@ -0,0 +1,177 @@
+package stats
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Point is one labelled value of a chart.
+type Point struct {
+	Label string
+	Value float64
+	// Highlight draws the point in the accent style, e.g. high scores in a histogram.
+	Highlight bool
+}
+
+// Chart geometry in SVG user units; the charts scale with their container via viewBox.
+const (
+	chartWidth  = 640
+	chartHeight = 240
+	chartLeft   = 40
+	chartRight  = 10
+	chartTop    = 10
+	chartBottom = 40
+	maxXLabels  = 12
+	valueFormat = "%.1f"
+)
+
+// BarChart renders points as vertical bars from 0 to yMax.
+func BarChart(title string, points []Point, yMax float64) string {
+	var b strings.Builder
+	openChart(&b, title)
+	plotWidth, plotHeight := plotSize()
+	drawYAxis(&b, 0, yMax)
+
+	if len(points) > 0 && yMax > 0 {
+		slot := plotWidth / float64(len(points))
+		barWidth := slot * 0.7
+		for i, p := range points {
+			height := clamp(p.Value/yMax, 0, 1) * plotHeight
+			x := chartLeft + float64(i)*slot + (slot-barWidth)/2
+			y := chartTop + plotHeight - height
+			class := "chart-bar"
+			if p.Highlight {
+				class += " chart-bar-high"
+			}
+			fmt.Fprintf(&b, `<rect class="%s" x="%.1f" y="%.1f" width="%.1f" height="%.1f"><title>%s: %s</title></rect>`,
+				class, x, y, barWidth, height, html.EscapeString(p.Label), formatValue(p.Value))
+			drawXLabel(&b, i, len(points), chartLeft+float64(i)*slot+slot/2, p.Label)
+		}
+	}
+	return closeChart(&b)
+}
+
+// LineChart renders points as a polyline between yMin and yMax with a marker per point.
+func LineChart(title string, points []Point, yMin, yMax float64) string {
+	var b strings.Builder
+	openChart(&b, title)
+	plotWidth, plotHeight := plotSize()
+	drawYAxis(&b, yMin, yMax)
+
+	if len(points) > 0 && yMax > yMin {
+		step := 0.0
+		if len(points) > 1 {
+			step = plotWidth / float64(len(points)-1)
+		}
+		coords := make([]string, len(points))
+		for i, p := range points {
+			x := chartLeft + float64(i)*step
+			if len(points) == 1 {
+				x = chartLeft + plotWidth/2
+			}
+			y := chartTop + plotHeight - clamp((p.Value-yMin)/(yMax-yMin), 0, 1)*plotHeight
+			coords[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+			drawXLabel(&b, i, len(points), x, p.Label)
+		}
+		fmt.Fprintf(&b, `<polyline class="chart-line" fill="none" points="%s"/>`, strings.Join(coords, " "))
+		for i, p := range points {
+			class := "chart-point"
+			if p.Highlight {
+				class += " chart-point-high"
+			}
+			xy := strings.Split(coords[i], ",")
+			fmt.Fprintf(&b, `<circle class="%s" cx="%s" cy="%s" r="3"><title>%s: %s</title></circle>`,
+				class, xy[0], xy[1], html.EscapeString(p.Label), formatValue(p.Value))
+		}
+	}
+	return closeChart(&b)
+}
+
+// HistogramPoints turns the score histogram of d into bars, highlighting high scores.
+func HistogramPoints(d Distribution) []Point {
+	points := make([]Point, MaxScore)
+	for i, count := range d.Histogram {
+		points[i] = Point{Label: fmt.Sprint(i + 1), Value: float64(count), Highlight: i+1 >= HighScore}
+	}
+	return points
+}
+
+// MeanPoints returns the mean score of each distribution.
+func MeanPoints(ds []Distribution) []Point {
+	points := make([]Point, len(ds))
+	for i, d := range ds {
+		points[i] = Point{Label: d.Key, Value: d.Mean}
+	}
+	return points
+}
+
+// HighSharePoints returns the share of high scores of each distribution in percent.
+func HighSharePoints(ds []Distribution) []Point {
+	points := make([]Point, len(ds))
+	for i, d := range ds {
+		points[i] = Point{Label: d.Key, Value: d.HighSharePercent()}
+	}
+	return points
+}
+
+// TrendPoints returns the record of the week scores, highlighting high scores.
+func TrendPoints(trend []TrendPoint) []Point {
+	points := make([]Point, len(trend))
+	for i, t := range trend {
+		points[i] = Point{Label: t.Week, Value: float64(t.Score), Highlight: t.Score >= HighScore}
+	}
+	return points
+}
+
+func plotSize() (float64, float64) {
+	return chartWidth - chartLeft - chartRight, chartHeight - chartTop - chartBottom
+}
+
+func openChart(b *strings.Builder, title string) {
+	fmt.Fprintf(b, `<svg class="chart" xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" role="img" aria-label="%s"><title>%s</title>`,
+		chartWidth, chartHeight, html.EscapeString(title), html.EscapeString(title))
+}
+
+func closeChart(b *strings.Builder) string {
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// drawYAxis draws the axes with gridlines at the minimum, middle and maximum.
+func drawYAxis(b *strings.Builder, yMin, yMax float64) {
+	plotWidth, plotHeight := plotSize()
+	for i := 0; i <= 2; i++ {
+		y := chartTop + plotHeight - float64(i)*plotHeight/2
+		value := yMin + float64(i)*(yMax-yMin)/2
+		fmt.Fprintf(b, `<line class="chart-grid" x1="%d" y1="%.1f" x2="%.1f" y2="%.1f"/>`, chartLeft, y, chartLeft+plotWidth, y)
+		fmt.Fprintf(b, `<text class="chart-label" x="%d" y="%.1f" text-anchor="end">%s</text>`, chartLeft-6, y+4, formatValue(value))
+	}
+	fmt.Fprintf(b, `<line class="chart-axis" x1="%d" y1="%d" x2="%d" y2="%.1f"/>`, chartLeft, chartTop, chartLeft, chartTop+plotHeight)
+}
+
+// drawXLabel labels at most maxXLabels evenly spaced points so long series stay readable.
+func drawXLabel(b *strings.Builder, i, n int, x float64, label string) {
+	every := (n + maxXLabels - 1) / maxXLabels
+	if every > 1 && i%every != 0 && i != n-1 {
+		return
+	}
+	_, plotHeight := plotSize()
+	fmt.Fprintf(b, `<text class="chart-label" x="%.1f" y="%.1f" text-anchor="middle">%s</text>`,
+		x, chartTop+plotHeight+18, html.EscapeString(label))
+}
+
+func formatValue(v float64) string {
+	s := fmt.Sprintf(valueFormat, v)
+	return strings.TrimSuffix(s, ".0")
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,123 @@
+// Package weekly remembers the dated playlist created for each calendar week, so reruns in the
+// same week update that playlist instead of creating another one.
+package weekly
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jetzlstorfer/plattentests-go/internal/fsutil"
+)
+
+// Playlist is the dated playlist of one ISO week.
+type Playlist struct {
+	Week       string
+	Name       string
+	PlaylistID string
+	CreatedAt  time.Time
+}
+
+// WeekKey identifies the ISO week of t, e.g. "2026-W42".
+func WeekKey(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// Name is the playlist title for the ISO week of t, e.g. "Plattentests KW 42/2026".
+func Name(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("Plattentests KW %02d/%d", week, year)
+}
+
+// Store is a JSON file backed mapping from week to playlist. It is safe for concurrent use.
+type Store struct {
+	mu        sync.RWMutex
+	path      string
+	playlists map[string]Playlist
+}
+
+// Open loads the store at path. A missing file yields an empty store that is created on the
+// first Put.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, playlists: make(map[string]Playlist)}
+
+	var playlists []Playlist
+	if err := fsutil.ReadJSON(path, &playlists); err != nil {
+		return nil, fmt.Errorf("load weekly playlists: %w", err)
+	}
+	for _, playlist := range playlists {
+		if playlist.Week != "" && playlist.PlaylistID != "" {
+			s.playlists[playlist.Week] = playlist
+		}
+	}
+	return s, nil
+}
+
+// Lookup returns the playlist stored for week.
+func (s *Store) Lookup(week string) (Playlist, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	playlist, ok := s.playlists[week]
+	return playlist, ok
+}
+
+// Put stores the playlist of a week and writes the store to disk.
+func (s *Store) Put(playlist Playlist) error {
+	if playlist.Week == "" || playlist.PlaylistID == "" {
+		return fmt.Errorf("weekly playlist needs a week and a playlist ID, got %+v", playlist)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, existed := s.playlists[playlist.Week]
+	s.playlists[playlist.Week] = playlist
+	if err := s.save(); err != nil {
+		if existed {
+			s.playlists[playlist.Week] = prev
+		} else {
+			delete(s.playlists, playlist.Week)
+		}
+		return err
+	}
+	return nil
+}
+
+// Playlists returns all stored playlists, newest week first.
+func (s *Store) Playlists() []Playlist {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	playlists := make([]Playlist, 0, len(s.playlists))
+	for _, playlist := range s.playlists {
+		playlists = append(playlists, playlist)
+	}
+	// "2026-W09" sorts before "2026-W10", so the key orders chronologically.
+	sort.Slice(playlists, func(i, j int) bool {
+		return playlists[i].Week > playlists[j].Week
+	})
+	return playlists
+}
+
+// save writes the store atomically. The caller must hold the write lock.
+func (s *Store) save() error {
+	playlists := make([]Playlist, 0, len(s.playlists))
+	for _, playlist := range s.playlists {
+		playlists = append(playlists, playlist)
+	}
+	sort.Slice(playlists, func(i, j int) bool {
+		return playlists[i].Week < playlists[j].Week
+	})
+
+	data, err := json.MarshalIndent(playlists, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode weekly playlists: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("save weekly playlists: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package weekly
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWeekKeyAndName(t *testing.T) {
+	tests := []struct {
+		name     string
+		date     time.Time
+		wantKey  string
+		wantName string
+	}{
+		{name: "mid year", date: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC), wantKey: "2026-W42", wantName: "Plattentests KW 42/2026"},
+		{name: "single digit week", date: time.Date(2026, 2, 2, 9, 0, 0, 0, time.UTC), wantKey: "2026-W06", wantName: "Plattentests KW 06/2026"},
+		{name: "iso year differs from calendar year", date: time.Date(2026, 12, 31, 9, 0, 0, 0, time.UTC), wantKey: "2026-W53", wantName: "Plattentests KW 53/2026"},
+		{name: "january in last year's week", date: time.Date(2027, 1, 1, 9, 0, 0, 0, time.UTC), wantKey: "2026-W53", wantName: "Plattentests KW 53/2026"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WeekKey(tt.date); got != tt.wantKey {
+				t.Errorf("WeekKey() = %q, want %q", got, tt.wantKey)
+			}
+			if got := Name(tt.date); got != tt.wantName {
+				t.Errorf("Name() = %q, want %q", got, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestStorePutAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "weekly.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if _, ok := store.Lookup("2026-W42"); ok {
+		t.Fatal("new store must be empty")
+	}
+
+	created := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	for _, playlist := range []Playlist{
+		{Week: "2026-W09", Name: "Plattentests KW 09/2026", PlaylistID: "p9", CreatedAt: created},
+		{Week: "2026-W42", Name: "Plattentests KW 42/2026", PlaylistID: "p42", CreatedAt: created},
+		{Week: "2026-W10", Name: "Plattentests KW 10/2026", PlaylistID: "p10", CreatedAt: created},
+	} {
+		if err := store.Put(playlist); err != nil {
+			t.Fatalf("Put() error: %v", err)
+		}
+	}
+	if err := store.Put(Playlist{Week: "2026-W11"}); err == nil {
+		t.Error("Put() without playlist ID must fail")
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen error: %v", err)
+	}
+	got, ok := reopened.Lookup("2026-W42")
+	if !ok || got.PlaylistID != "p42" || !got.CreatedAt.Equal(created) {
+		t.Errorf("Lookup() = %+v, %v", got, ok)
+	}
+	var weeks []string
+	for _, playlist := range reopened.Playlists() {
+		weeks = append(weeks, playlist.Week)
+	}
+	if len(weeks) != 3 || weeks[0] != "2026-W42" || weeks[1] != "2026-W10" || weeks[2] != "2026-W09" {
+		t.Errorf("Playlists() weeks = %v, want newest first", weeks)
+	}
+
+	files, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("ReadDir() error: %v", err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected only the store file, found %d entries", len(files))
+	}
+}
+
+func TestOpenCorruptStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "weekly.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Error("Open() of a corrupt file must fail")
+	}
+}
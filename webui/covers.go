@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jetzlstorfer/plattentests-go/internal/covers"
+)
+
+// coverCacheControl lets browsers and proxies keep covers for a year: the image behind a
+// given source URL and width never changes.
+const coverCacheControl = "public, max-age=31536000, immutable"
+
+// coverCache serves cover art from local disk; main sets it up and tests replace it.
+var coverCache *covers.Cache
+
+// newCoverCache stores covers in COVER_CACHE_DIR, or a temporary directory when unset, and
+// only fetches images from Plattentests.de.
+func newCoverCache() *covers.Cache {
+	dir := strings.TrimSpace(os.Getenv("COVER_CACHE_DIR"))
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "plattentests-covers")
+	}
+	return covers.New(dir, &http.Client{Timeout: 20 * time.Second}, "www.plattentests.de", "plattentests.de")
+}
+
+// coverHandler serves /cover?src=<image URL>&w=<width>. Without w the original is returned;
+// thumbnails are WebP when the browser accepts it and JPEG otherwise.
+func coverHandler(c *gin.Context) {
+	width := 0
+	if w := c.Query("w"); w != "" {
+		parsed, err := strconv.Atoi(w)
+		if err != nil {
+			c.String(http.StatusBadRequest, "invalid width")
+			return
+		}
+		width = parsed
+	}
+	format := covers.FormatJPEG
+	if strings.Contains(c.GetHeader("Accept"), covers.FormatWebP) {
+		format = covers.FormatWebP
+	}
+
+	img, err := coverCache.Get(c.Request.Context(), c.Query("src"), width, format)
+	if err != nil {
+		if errors.Is(err, covers.ErrSourceNotAllowed) || errors.Is(err, covers.ErrUnsupportedWidth) {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("failed to load cover %q: %v", c.Query("src"), err)
+		c.String(http.StatusBadGateway, "Could not load cover")
+		return
+	}
+
+	c.Header("Vary", "Accept")
+	c.Header("ETag", img.ETag)
+	c.Header("Last-Modified", img.ModTime.Format(http.TimeFormat))
+	c.Header("Cache-Control", coverCacheControl)
+	if notModified(c.Request, img.ETag, img.ModTime) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, img.ContentType, img.Data)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jetzlstorfer/plattentests-go/internal/covers"
+)
+
+func useTestCoverCache(t *testing.T) string {
+	t.Helper()
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 400, 400))); err != nil {
+		t.Fatalf("png.Encode() error: %v", err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(encoded.Bytes())
+	}))
+	t.Cleanup(srv.Close)
+
+	prev := coverCache
+	coverCache = covers.New(t.TempDir(), srv.Client(), "127.0.0.1")
+	t.Cleanup(func() { coverCache = prev })
+	return srv.URL + "/cover.png"
+}
+
+func TestCoverHandler(t *testing.T) {
+	src := useTestCoverCache(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/cover", coverHandler)
+
+	tests := []struct {
+		name        string
+		query       string
+		accept      string
+		status      int
+		contentType string
+	}{
+		{name: "jpeg thumbnail", query: "w=320", status: http.StatusOK, contentType: "image/jpeg"},
+		{name: "webp thumbnail", query: "w=320", accept: "image/avif,image/webp,*/*", status: http.StatusOK, contentType: "image/webp"},
+		{name: "original", status: http.StatusOK, contentType: "image/png"},
+		{name: "unsupported width", query: "w=1000", status: http.StatusBadRequest},
+		{name: "invalid width", query: "w=big", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/cover?src="+url.QueryEscape(src)+"&"+tt.query, nil)
+			req.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if w.Header().Get("Cache-Control") != coverCacheControl || w.Header().Get("Vary") != "Accept" {
+				t.Errorf("unexpected cache headers: %v", w.Header())
+			}
+
+			etag := w.Header().Get("ETag")
+			req.Header.Set("If-None-Match", etag)
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusNotModified {
+				t.Errorf("conditional request status = %d, want 304", w.Code)
+			}
+		})
+	}
+}
+
+func TestCoverHandlerRejectsForeignHosts(t *testing.T) {
+	useTestCoverCache(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/cover", coverHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/cover?w=320&src="+url.QueryEscape("https://evil.example/cover.jpg"), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
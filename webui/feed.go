@@ -0,0 +1,170 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/jetzlstorfer/plattentests-go/internal/feed"
+)
+
+const feedTitle = "Plattentests.de - Highlights of the week"
+const feedSubtitle = "Weekly album highlights and their best tracks from Plattentests.de"
+
+// feedCacheControl allows feed readers and proxies to reuse a response for a while; the
+// records of the week only change once a week.
+const feedCacheControl = "public, max-age=900"
+
+// feedIDBase is the prefix of the feed ID when FEED_BASE_URL is not configured; it does not
+// depend on the host or scheme of the request, so readers keep their subscription.
+const feedIDBase = "tag:plattentests.de,2005:"
+
+// feedTimes returns the time of every entry and of the feed as a whole, the newest entry.
+// Entries use the time their review was first archived (ARCHIVE_FILE) or their release
+// date, so they survive restarts; nothing is dated later than the start of the current
+// week, when the records of the week were reviewed at the latest.
+func feedTimes(records []crawler.Record, now time.Time) (map[string]time.Time, time.Time) {
+	weekStart := startOfWeek(now)
+	entries := make(map[string]time.Time, len(records))
+	var newest time.Time
+	for _, record := range records {
+		var published time.Time
+		if entry, ok := archivedEntry(record); ok {
+			published = entry.FirstSeen
+		}
+		updated := feed.EntryTime(record, published, weekStart)
+		entries[feed.EntryID(record)] = updated
+		if updated.After(newest) {
+			newest = updated
+		}
+	}
+	if newest.IsZero() {
+		newest = weekStart
+	}
+	return entries, newest
+}
+
+// startOfWeek returns Monday 00:00 UTC of the week of t.
+func startOfWeek(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// feedURLs returns the base URL for the links of the feed and its ID. FEED_BASE_URL, the
+// public root of the web UI, fixes both; without it links follow the request.
+func feedURLs(r *http.Request, path string) (base, id string) {
+	if configured := strings.TrimRight(strings.TrimSpace(os.Getenv("FEED_BASE_URL")), "/"); configured != "" {
+		return configured, configured + path
+	}
+	return requestBaseURL(r), feedIDBase + strings.TrimPrefix(path, "/")
+}
+
+// feedHandler serves the records of the week in the given format. render is feed.Atom or
+// feed.RSS; contentType is sent with full responses.
+func feedHandler(path, contentType string, render func(feed.Channel) ([]byte, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		records, err := getRecordsOfTheWeek()
+		if err != nil {
+			log.Printf("failed to load records for %s: %v", path, err)
+			c.String(http.StatusBadGateway, "Could not load records of the week")
+			return
+		}
+
+		recordOfTheWeek, err := getRecordOfTheWeekBandName()
+		if err != nil {
+			log.Printf("could not load record of the week for %s: %v", path, err)
+			recordOfTheWeek = ""
+		}
+		records = orderFeedRecords(records, recordOfTheWeek)
+		archiveRecords(records)
+
+		etag := `"` + feed.Fingerprint(records) + `"`
+		entryUpdated, modified := feedTimes(records, time.Now())
+
+		c.Header("ETag", etag)
+		c.Header("Last-Modified", modified.Format(http.TimeFormat))
+		c.Header("Cache-Control", feedCacheControl)
+		if notModified(c.Request, etag, modified) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+
+		base, id := feedURLs(c.Request, path)
+		body, err := render(feed.Channel{
+			ID:           id,
+			Title:        feedTitle,
+			Subtitle:     feedSubtitle,
+			SiteURL:      base + "/",
+			SelfURL:      base + path,
+			Updated:      modified,
+			EntryUpdated: entryUpdated,
+			Records:      records,
+		})
+		if err != nil {
+			log.Printf("failed to render %s: %v", path, err)
+			c.String(http.StatusInternalServerError, "Could not render feed")
+			return
+		}
+		c.Data(http.StatusOK, contentType, body)
+	}
+}
+
+// orderFeedRecords sorts records by score and puts the record of the week first, matching
+// the order of the highlights page.
+func orderFeedRecords(records []crawler.Record, recordOfTheWeek string) []crawler.Record {
+	ordered := append([]crawler.Record(nil), records...)
+	recordOfTheWeek = strings.TrimSpace(recordOfTheWeek)
+	for i := range ordered {
+		if recordOfTheWeek != "" && ordered[i].Band == recordOfTheWeek {
+			ordered[i].IsRecordOfTheWeek = true
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].IsRecordOfTheWeek != ordered[j].IsRecordOfTheWeek {
+			return ordered[i].IsRecordOfTheWeek
+		}
+		return ordered[i].Score > ordered[j].Score
+	})
+	return ordered
+}
+
+// notModified evaluates the conditional GET headers. If-None-Match takes precedence over
+// If-Modified-Since as required by RFC 9110.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		if err == nil && !modified.After(since) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestBaseURL returns scheme and host of the incoming request. Azure Container Apps
+// terminates TLS at the ingress and forwards the original scheme in X-Forwarded-Proto.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := strings.TrimSpace(r.Header.Get("X-Forwarded-Proto")); proto != "" {
+		scheme = strings.ToLower(strings.Split(proto, ",")[0])
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/jetzlstorfer/plattentests-go/internal/feed"
+)
+
+func stubFeedCrawler(t *testing.T, records []crawler.Record, err error) {
+	t.Helper()
+	prevRecords, prevBand := getRecordsOfTheWeek, getRecordOfTheWeekBandName
+	getRecordsOfTheWeek = func() ([]crawler.Record, error) {
+		return append([]crawler.Record(nil), records...), err
+	}
+	getRecordOfTheWeekBandName = func() (string, error) { return "Band B", nil }
+	t.Cleanup(func() {
+		getRecordsOfTheWeek, getRecordOfTheWeekBandName = prevRecords, prevBand
+	})
+}
+
+func feedRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/feed.atom", feedHandler("/feed.atom", "application/atom+xml; charset=utf-8", feed.Atom))
+	r.GET("/feed.rss", feedHandler("/feed.rss", "application/rss+xml; charset=utf-8", feed.RSS))
+	return r
+}
+
+func TestFeedEndpoints(t *testing.T) {
+	stubFeedCrawler(t, []crawler.Record{
+		{Band: "Band A", Recordname: "Album A", Link: "https://www.plattentests.de/rezi.php?show=1", Score: 9},
+		{Band: "Band B", Recordname: "Album B", Link: "https://www.plattentests.de/rezi.php?show=2", Score: 7},
+	}, nil)
+	r := feedRouter()
+
+	tests := []struct {
+		path        string
+		contentType string
+		selfLink    string
+	}{
+		{path: "/feed.atom", contentType: "application/atom+xml", selfLink: `href="https://example.com/feed.atom"`},
+		{path: "/feed.rss", contentType: "application/rss+xml", selfLink: `href="https://example.com/feed.rss"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Host = "example.com"
+			req.Header.Set("X-Forwarded-Proto", "https")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.contentType) {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if w.Header().Get("ETag") == "" || w.Header().Get("Last-Modified") == "" {
+				t.Errorf("expected ETag and Last-Modified headers, got %v", w.Header())
+			}
+			body := w.Body.String()
+			if !strings.Contains(body, tt.selfLink) {
+				t.Errorf("expected self link %s, got %s", tt.selfLink, body)
+			}
+			if strings.Index(body, "Platte der Woche: Band B") > strings.Index(body, "Band A - Album A") {
+				t.Errorf("expected record of the week to be the first entry, got %s", body)
+			}
+		})
+	}
+}
+
+func TestFeedConditionalGet(t *testing.T) {
+	stubFeedCrawler(t, []crawler.Record{
+		{Band: "Band A", Recordname: "Album A", Link: "https://www.plattentests.de/rezi.php?show=1", Score: 9},
+	}, nil)
+	r := feedRouter()
+
+	first := httptest.NewRecorder()
+	r.ServeHTTP(first, httptest.NewRequest("GET", "/feed.atom", nil))
+	etag := first.Header().Get("ETag")
+	lastModified := first.Header().Get("Last-Modified")
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		t.Fatalf("Last-Modified %q: %v", lastModified, err)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{name: "matching etag", header: "If-None-Match", value: etag, want: http.StatusNotModified},
+		{name: "matching weak etag in list", header: "If-None-Match", value: `"other", W/` + etag, want: http.StatusNotModified},
+		{name: "stale etag", header: "If-None-Match", value: `"stale"`, want: http.StatusOK},
+		{name: "not modified since", header: "If-Modified-Since", value: lastModified, want: http.StatusNotModified},
+		{name: "modified since", header: "If-Modified-Since", value: modified.Add(-24 * time.Hour).Format(http.TimeFormat), want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/feed.atom", nil)
+			req.Header.Set(tt.header, tt.value)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("304 response must not have a body, got %q", w.Body.String())
+			}
+		})
+	}
+
+	t.Run("etag changes with content", func(t *testing.T) {
+		stubFeedCrawler(t, []crawler.Record{
+			{Band: "Band A", Recordname: "Album A", Link: "https://www.plattentests.de/rezi.php?show=1", Score: 10},
+		}, nil)
+		req := httptest.NewRequest("GET", "/feed.atom", nil)
+		req.Header.Set("If-None-Match", etag)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200 after content change", w.Code)
+		}
+		if w.Header().Get("ETag") == etag {
+			t.Errorf("expected a new ETag after content change")
+		}
+	})
+}
+
+func TestFeedIDIgnoresRequestURL(t *testing.T) {
+	stubFeedCrawler(t, []crawler.Record{
+		{Band: "Band A", Recordname: "Album A", Link: "https://www.plattentests.de/rezi.php?show=1", Score: 9},
+	}, nil)
+	r := feedRouter()
+	get := func(proto, host string) string {
+		req := httptest.NewRequest("GET", "/feed.atom", nil)
+		req.Host = host
+		req.Header.Set("X-Forwarded-Proto", proto)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	t.Setenv("FEED_BASE_URL", "")
+	for _, body := range []string{get("http", "example.com"), get("https", "www.example.com")} {
+		if !strings.Contains(body, "<id>tag:plattentests.de,2005:feed.atom</id>") {
+			t.Errorf("feed id must not depend on the request:\n%s", body)
+		}
+	}
+
+	t.Setenv("FEED_BASE_URL", "https://plattentests.example/")
+	body := get("http", "internal:8081")
+	if !strings.Contains(body, "<id>https://plattentests.example/feed.atom</id>") || !strings.Contains(body, `href="https://plattentests.example/feed.atom"`) {
+		t.Errorf("feed must use FEED_BASE_URL:\n%s", body)
+	}
+}
+
+func TestFeedTimesSurviveRestarts(t *testing.T) {
+	useTestArchive(t, nil)
+	now := time.Date(2026, 10, 21, 12, 0, 0, 0, time.UTC)
+	seen := time.Date(2026, 10, 19, 6, 0, 0, 0, time.UTC)
+	archived := crawler.Record{Band: "Band A", Link: "https://www.plattentests.de/rezi.php?show=1", ReleaseDate: "01.10.2026"}
+	released := crawler.Record{Band: "Band B", Link: "https://www.plattentests.de/rezi.php?show=2", ReleaseDate: "16.10.2026"}
+	upcoming := crawler.Record{Band: "Band C", Link: "https://www.plattentests.de/rezi.php?show=3", ReleaseDate: "30.10.2026"}
+	if _, _, err := searchState.archive.Add([]crawler.Record{archived}, seen); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+
+	entries, updated := feedTimes([]crawler.Record{archived, released, upcoming}, now)
+	want := map[string]time.Time{
+		feed.EntryID(archived): seen,
+		feed.EntryID(released): time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+		feed.EntryID(upcoming): time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+	}
+	for id, wantTime := range want {
+		if !entries[id].Equal(wantTime) {
+			t.Errorf("entry %s updated = %v, want %v", id, entries[id], wantTime)
+		}
+	}
+	if !updated.Equal(seen) {
+		t.Errorf("feed updated = %v, want the newest entry %v", updated, seen)
+	}
+
+	// a restart an hour later dates the feed the same
+	if _, again := feedTimes([]crawler.Record{archived, released, upcoming}, now.Add(time.Hour)); !again.Equal(updated) {
+		t.Errorf("feed updated after a restart = %v, want %v", again, updated)
+	}
+}
+
+func TestFeedCrawlerError(t *testing.T) {
+	stubFeedCrawler(t, nil, errors.New("plattentests down"))
+	w := httptest.NewRecorder()
+	feedRouter().ServeHTTP(w, httptest.NewRequest("GET", "/feed.rss", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want 502", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "plattentests down") {
+		t.Errorf("internal error must not be exposed, got %q", w.Body.String())
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"html/template"
 	"log"
 	"net"
@@ -15,6 +16,8 @@ import (
 	"github.com/gin-gonic/gin"
 	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
 	creator "github.com/jetzlstorfer/plattentests-go/cmd/creator"
+	"github.com/jetzlstorfer/plattentests-go/internal/feed"
+	"github.com/jetzlstorfer/plattentests-go/internal/weekly"
 )
 
 //const RecordEndPoint = "https://plattentests-go.azurewebsites.net/api/records/"
@@ -41,6 +44,20 @@ type Track struct {
 	IsHighlight bool
 }
 
+// Crawler dependencies of the handlers; tests replace them to avoid hitting Plattentests.de.
+var (
+	getRecordsOfTheWeek        = crawler.GetRecordsOfTheWeekSafe
+	getRecordOfTheWeekBandName = crawler.GetRecordOfTheWeekBandNameSafe
+	getRecordByID              = crawler.GetRecordByIDSafe
+)
+
+// Creator dependencies of /createPlaylist and /matchOverride; tests replace them to avoid
+// hitting Spotify.
+var (
+	runCreator        = creator.Run
+	saveMatchOverride = creator.SaveMatchOverride
+)
+
 type Highlights struct {
 	Records    []Record `json:"Highlights"`
 	NotFound   []string `json:"NotFound"`
@@ -48,6 +65,14 @@ type Highlights struct {
 }
 
 func main() {
+	if path := os.Getenv("ARCHIVE_FILE"); path != "" {
+		if err := openArchive(path); err != nil {
+			log.Printf("review archive disabled: %v", err)
+		}
+	}
+
+	coverCache = newCoverCache()
+
 	// Create a new Gin router
 	r := gin.Default()
 	r.Static("./assets", "./assets")
@@ -57,7 +82,7 @@ func main() {
 	// Define a handler function for the root endpoint
 	r.GET("/", func(c *gin.Context) {
 
-		records, err := crawler.GetRecordsOfTheWeekSafe()
+		records, err := getRecordsOfTheWeek()
 		if err != nil {
 			log.Printf("failed to load records of the week: %v", err)
 			tmpl, tmplErr := template.ParseFiles("templates/utils.tmpl")
@@ -77,7 +102,7 @@ func main() {
 			})
 
 			// put record of the week on top of the playlist
-			recordOfTheWeek, err := crawler.GetRecordOfTheWeekBandNameSafe()
+			recordOfTheWeek, err := getRecordOfTheWeekBandName()
 			if err != nil {
 				log.Printf("could not load record of the week: %v", err)
 				recordOfTheWeek = ""
@@ -96,6 +121,8 @@ func main() {
 			}
 		}
 
+		archiveRecords(records)
+
 		// Load the template file
 		tmpl, err := template.ParseFiles("templates/records.tmpl", "templates/utils.tmpl")
 		if err != nil {
@@ -112,131 +139,164 @@ func main() {
 
 	})
 
-	r.GET("/search", func(c *gin.Context) {
-		query := strings.TrimSpace(c.Query("q"))
+	r.GET("/search", searchPage)
 
-		var records []crawler.Record
-		if query != "" {
-			records = crawler.Search(query)
-			// sort by score, descending — same default as the home page
-			sort.Slice(records, func(i, j int) bool {
-				return records[i].Score > records[j].Score
-			})
-		}
+	r.GET("/review/:id", reviewPage)
 
-		tmpl, err := template.ParseFiles("templates/search.tmpl", "templates/utils.tmpl")
-		if err != nil {
-			log.Fatalf("Error parsing search templates: %v", err)
-		}
+	r.GET("/cover", coverHandler)
 
-		data := commonTemplateData(c)
-		data["Query"] = query
-		data["Records"] = records
+	r.GET("/stats", statsPage)
+	r.GET("/stats.json", statsJSON)
 
-		if err := tmpl.Execute(c.Writer, data); err != nil {
-			log.Fatalf("Error executing search template: %v", err)
-		}
-	})
+	r.GET("/feed.atom", feedHandler("/feed.atom", "application/atom+xml; charset=utf-8", feed.Atom))
+	r.GET("/feed.rss", feedHandler("/feed.rss", "application/rss+xml; charset=utf-8", feed.RSS))
 
-	r.GET("/playlist", func(c *gin.Context) {
-		playlistID := os.Getenv("PLAYLIST_ID_PROD")
+	r.GET("/playlist", playlistPage)
 
-		tmpl, err := template.ParseFiles("templates/playlist.tmpl", "templates/utils.tmpl")
-		if err != nil {
-			log.Fatalf("Error parsing playlist templates: %v", err)
-		}
+	r.GET("/createPlaylist", createPlaylistPage)
+	r.POST("/createPlaylist", createPlaylistPage)
+	r.POST("/matchOverride", matchOverrideHandler)
 
-		data := commonTemplateData(c)
-		data["PlaylistID"] = playlistID
+	// Start the server
+	if err := r.Run(":8081"); err != nil {
+		log.Fatalf("Error starting server: %v", err)
+	}
+}
 
-		if err := tmpl.Execute(c.Writer, data); err != nil {
-			log.Fatalf("Error executing playlist template: %v", err)
-		}
-	})
+// createPlaylistPage previews the playlist on GET and applies it on POST, so the playlist is
+// only changed after the preview has been confirmed. The confirmation carries the plan token of
+// the preview; when the run arrives at different changes, nothing is applied and the new
+// preview is shown instead.
+func createPlaylistPage(c *gin.Context) {
+	if _, ok := requireAuthentication(c); !ok {
+		return
+	}
 
-	r.GET("/createPlaylist", func(c *gin.Context) {
-
-		// Require authentication via Azure Container Apps Easy Auth.
-		// The X-MS-CLIENT-PRINCIPAL-NAME header is injected by the platform after a successful
-		// login; its absence means the request is unauthenticated.
-		principal := easyAuthPrincipal(c)
-		requireEasyAuth := easyAuthEnabled(c.Request)
-		if requireEasyAuth && principal == "" {
-			loginURL := easyAuthLoginURL(c.Request)
-			log.Printf("unauthenticated request to /createPlaylist, redirecting to Easy Auth login: %s", loginURL)
-			c.Redirect(http.StatusTemporaryRedirect, loginURL)
-			return
-		}
-		if requireEasyAuth {
-			log.Printf("user authenticated via Easy Auth: %s", principal)
-		} else {
-			log.Printf("Easy Auth disabled for this request, allowing local access to /createPlaylist")
-		}
+	tmpl, err := template.ParseFiles("templates/createPlaylist.tmpl", "templates/utils.tmpl")
+	if err != nil {
+		log.Fatalf("Error parsing template: %v", err)
+	}
+	data := commonTemplateData(c)
+
+	// the playlist parameter names a profile; without it the first profile is used
+	playlist := c.DefaultQuery("playlist", c.PostForm("playlist"))
+	profiles, err := creator.LoadProfiles()
+	if err != nil {
+		log.Printf("failed to load playlist profiles: %v", err)
+		renderCreatePlaylistError(c, tmpl, data, http.StatusServiceUnavailable, friendlyCreatePlaylistError(err))
+		return
+	}
+	profile, ok := creator.FindProfile(profiles, playlist)
+	if !ok {
+		renderCreatePlaylistError(c, tmpl, data, http.StatusNotFound, fmt.Sprintf("There is no playlist profile called %q.", playlist))
+		return
+	}
+	playlistID := profile.PlaylistID
+
+	data["Records"] = creator.Result{PlaylistID: playlistID}
+	data["Playlist"] = profile.Name
+	data["Profile"] = profile
+	data["Profiles"] = profiles
+
+	// a confirmation without the token of its preview only previews again
+	plan := c.PostForm("plan")
+	confirm := c.Request.Method == http.MethodPost && plan != ""
+	opts := creator.Options{Profile: profile.Name, DryRun: !confirm}
+	if confirm {
+		opts.Confirm = plan
+	}
+	results, err := runCreator(opts)
+	if err != nil {
+		log.Printf("failed to create playlist: %v", err)
+		renderCreatePlaylistError(c, tmpl, data, http.StatusServiceUnavailable, friendlyCreatePlaylistError(err))
+		return
+	}
+	highlights := results
+	if highlights.PlaylistID == "" {
+		highlights.PlaylistID = playlistID
+	}
 
-		playlist := c.DefaultQuery("playlist", "")
-		playlistID := os.Getenv("PLAYLIST_ID")
-		if playlist == "prod" {
-			playlistID = os.Getenv("PLAYLIST_ID_PROD")
-		}
+	// sort by score
+	if c.DefaultQuery("sort", "score") == "score" {
+		sort.Slice(highlights.Records, func(i, j int) bool {
+			return highlights.Records[i].Score > highlights.Records[j].Score
+		})
 
-		tmpl, err := template.ParseFiles("templates/createPlaylist.tmpl", "templates/utils.tmpl")
+		// put record of the week on top of the playlist
+		recordOfTheWeek, err := getRecordOfTheWeekBandName()
 		if err != nil {
-			log.Fatalf("Error parsing template: %v", err)
+			log.Printf("could not load record of the week: %v", err)
+			recordOfTheWeek = ""
 		}
 
-		data := commonTemplateData(c)
-		data["Records"] = creator.Result{PlaylistID: playlistID}
-
-		results, err := creator.CreatePlaylist(playlistID)
-		if err != nil {
-			log.Printf("failed to create playlist: %v", err)
-			data["CreatePlaylistError"] = friendlyCreatePlaylistError(err)
-			c.Status(http.StatusServiceUnavailable)
-			if execErr := tmpl.Execute(c.Writer, data); execErr != nil {
-				log.Fatalf("Error executing template: %v", execErr)
+		// put record of the week on top of the playlist
+		for i, record := range highlights.Records {
+			if record.Band == recordOfTheWeek {
+				highlights.Records[i].IsRecordOfTheWeek = true
+				highlights.Records[0], highlights.Records[i] = highlights.Records[i], highlights.Records[0]
+				break
 			}
-			return
-		}
-		highlights := results
-		if highlights.PlaylistID == "" {
-			highlights.PlaylistID = playlistID
 		}
+	}
 
-		// sort by score
-		if c.DefaultQuery("sort", "score") == "score" {
-			sort.Slice(highlights.Records, func(i, j int) bool {
-				return highlights.Records[i].Score > highlights.Records[j].Score
-			})
+	data["Records"] = highlights
 
-			// put record of the week on top of the playlist
-			recordOfTheWeek, err := crawler.GetRecordOfTheWeekBandNameSafe()
-			if err != nil {
-				log.Printf("could not load record of the week: %v", err)
-				recordOfTheWeek = ""
-			}
+	// Execute the template with the record data
+	if err := tmpl.Execute(c.Writer, data); err != nil {
+		log.Fatalf("Error executing template: %v", err)
+	}
+}
 
-			// put record of the week on top of the playlist
-			for i, record := range highlights.Records {
-				if record.Band == recordOfTheWeek {
-					highlights.Records[i].IsRecordOfTheWeek = true
-					highlights.Records[0], highlights.Records[i] = highlights.Records[i], highlights.Records[0]
-					break
-				}
-			}
-		}
+// reviewPage renders the full review of a single record in a reader layout.
+func reviewPage(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.String(http.StatusBadRequest, "invalid record identifier")
+		return
+	}
 
-		data["Records"] = highlights
+	tmpl, err := template.ParseFiles("templates/review.tmpl", "templates/utils.tmpl")
+	if err != nil {
+		log.Fatalf("Error parsing review templates: %v", err)
+	}
 
-		// Execute the template with the record data
-		if err := tmpl.Execute(c.Writer, data); err != nil {
-			log.Fatalf("Error executing template: %v", err)
+	data := commonTemplateData(c)
+	record, err := getRecordByID(id)
+	if err != nil {
+		log.Printf("failed to load review %d: %v", id, err)
+		data["ErrorMessage"] = "Could not load this review from Plattentests.de. Please try again later."
+		c.Status(http.StatusBadGateway)
+		if execErr := tmpl.ExecuteTemplate(c.Writer, "ErrorPage", data); execErr != nil {
+			log.Printf("Error executing error template: %v", execErr)
 		}
-	})
+		return
+	}
 
-	// Start the server
-	if err := r.Run(":8081"); err != nil {
-		log.Fatalf("Error starting server: %v", err)
+	data["Record"] = record
+	if err := tmpl.Execute(c.Writer, data); err != nil {
+		log.Fatalf("Error executing review template: %v", err)
+	}
+}
+
+// requireAuthentication enforces Azure Container Apps Easy Auth for pages that change
+// playlists. The X-MS-CLIENT-PRINCIPAL-NAME header is injected by the platform after a
+// successful login; its absence means the request is unauthenticated and is redirected to the
+// login. It returns the signed-in user, which is empty when Easy Auth is disabled.
+func requireAuthentication(c *gin.Context) (string, bool) {
+	principal := easyAuthPrincipal(c)
+	requireEasyAuth := easyAuthEnabled(c.Request)
+	if requireEasyAuth && principal == "" {
+		loginURL := easyAuthLoginURL(c.Request)
+		log.Printf("unauthenticated request to %s, redirecting to Easy Auth login: %s", c.Request.URL.Path, loginURL)
+		c.Redirect(http.StatusTemporaryRedirect, loginURL)
+		return "", false
+	}
+	if requireEasyAuth {
+		log.Printf("user authenticated via Easy Auth: %s", principal)
+	} else {
+		log.Printf("Easy Auth disabled for this request, allowing local access to %s", c.Request.URL.Path)
 	}
+	return principal, true
 }
 
 // easyAuthPrincipal returns the value of the X-MS-CLIENT-PRINCIPAL-NAME header injected by
@@ -322,6 +382,15 @@ func getCommitInfo() string {
 	return ""
 }
 
+// renderCreatePlaylistError shows the create page with an error message instead of a run summary.
+func renderCreatePlaylistError(c *gin.Context, tmpl *template.Template, data gin.H, status int, message string) {
+	data["CreatePlaylistError"] = message
+	c.Status(status)
+	if err := tmpl.Execute(c.Writer, data); err != nil {
+		log.Fatalf("Error executing template: %v", err)
+	}
+}
+
 func friendlyCreatePlaylistError(err error) string {
 	if err == nil {
 		return "Unknown error"
@@ -334,3 +403,34 @@ func friendlyCreatePlaylistError(err error) string {
 
 	return msg
 }
+
+// playlistPage embeds the production playlist and lists the playlist profiles and the dated
+// weekly playlists created so far.
+func playlistPage(c *gin.Context) {
+	tmpl, err := template.ParseFiles("templates/playlist.tmpl", "templates/utils.tmpl")
+	if err != nil {
+		log.Fatalf("Error parsing playlist templates: %v", err)
+	}
+
+	data := commonTemplateData(c)
+	data["PlaylistID"] = os.Getenv("PLAYLIST_ID_PROD")
+
+	if profiles, err := creator.LoadProfiles(); err != nil {
+		log.Printf("could not load playlist profiles: %v", err)
+	} else {
+		data["Profiles"] = profiles
+	}
+
+	if path := os.Getenv("WEEKLY_PLAYLISTS_FILE"); path != "" {
+		store, err := weekly.Open(path)
+		if err != nil {
+			log.Printf("could not load weekly playlists: %v", err)
+		} else {
+			data["WeeklyPlaylists"] = store.Playlists()
+		}
+	}
+
+	if err := tmpl.Execute(c.Writer, data); err != nil {
+		log.Fatalf("Error executing playlist template: %v", err)
+	}
+}
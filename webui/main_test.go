@@ -2,14 +2,22 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"html/template"
+	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
 	creator "github.com/jetzlstorfer/plattentests-go/cmd/creator"
+	"github.com/jetzlstorfer/plattentests-go/internal/aliases"
+	"github.com/jetzlstorfer/plattentests-go/internal/ratelimit"
+	"github.com/jetzlstorfer/plattentests-go/internal/weekly"
+	"github.com/zmb3/spotify/v2"
 )
 
 func TestRecordTableSongFoundIndicatorHiddenByDefault(t *testing.T) {
@@ -103,6 +111,12 @@ func TestCreatePlaylistPageShowsProdButtonOnlyWhenTracksMissingFromProd(t *testi
 		t.Run(tt.name, func(t *testing.T) {
 			data := map[string]interface{}{
 				"IsAuthenticated": true,
+				"Playlist":        "test",
+				"Profile":         creator.Profile{Name: "test", Title: "test"},
+				"Profiles": []creator.Profile{
+					{Name: "test", Title: "test", PlaylistID: "test-playlist"},
+					{Name: "prod", Title: "production", PlaylistID: "prod-playlist"},
+				},
 				"Records": creator.Result{
 					PlaylistID:              "test-playlist",
 					ComparedToProd:          tt.comparedToProd,
@@ -345,3 +359,619 @@ func TestRecordTableShowsReleaseDateAndFutureEmoji(t *testing.T) {
 		t.Fatalf("expected future emoji to appear only for future record in both views (2x), got %d", count)
 	}
 }
+
+func TestRecordTableRendersReviewParagraphs(t *testing.T) {
+	tmpl, err := template.ParseFiles("templates/utils.tmpl")
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+
+	data := map[string]interface{}{
+		"Records": []crawler.Record{
+			{
+				Band:       "Band",
+				Recordname: "Record",
+				Link:       "https://www.plattentests.de/rezi.php?show=42",
+				Review: []crawler.Paragraph{
+					{Kind: crawler.ParagraphText, Spans: []crawler.Span{{Text: "Erster Absatz mit "}, {Text: "Betonung", Emphasis: true}}},
+					{Kind: crawler.ParagraphQuote, Spans: []crawler.Span{{Text: "Ein Zitat", Link: "javascript:alert(1)"}}},
+				},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&out, "RecordTable", data); err != nil {
+		t.Fatalf("failed to render RecordTable: %v", err)
+	}
+
+	html := out.String()
+	for _, want := range []string{
+		"record-card-flippable",
+		`<p class="review-paragraph">Erster Absatz mit <em>Betonung</em></p>`,
+		`<blockquote class="review-quote">`,
+		`href="/review/42"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected rendered RecordTable to contain %q, got: %s", want, html)
+		}
+	}
+	if strings.Contains(html, "javascript:") {
+		t.Errorf("unsafe link must not be rendered, got: %s", html)
+	}
+}
+
+func TestReviewPage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	prev := getRecordByID
+	t.Cleanup(func() { getRecordByID = prev })
+
+	getRecordByID = func(id int) (crawler.Record, error) {
+		if id != 42 {
+			return crawler.Record{}, errors.New("record page status: 404")
+		}
+		return crawler.Record{
+			Band:       "Reader Band",
+			Recordname: "Reader Album",
+			Link:       "https://www.plattentests.de/rezi.php?show=42",
+			Score:      8,
+			Headline:   "Ein Fest",
+			Review: []crawler.Paragraph{
+				{Kind: crawler.ParagraphText, Spans: []crawler.Span{{Text: "Absatz eins."}}},
+				{Kind: crawler.ParagraphText, Spans: []crawler.Span{{Text: "Absatz zwei."}}},
+			},
+			Tracks: []crawler.Track{{Trackname: "Hit", IsHighlight: true}},
+		}, nil
+	}
+
+	r := gin.New()
+	r.GET("/review/:id", reviewPage)
+
+	tests := []struct {
+		name     string
+		path     string
+		status   int
+		contains []string
+	}{
+		{
+			name:     "renders paragraphs in order",
+			path:     "/review/42",
+			status:   http.StatusOK,
+			contains: []string{"Reader Album", "<h2 class=\"review-reader-headline\">Ein Fest</h2>", "Absatz eins.</p>", "<strong>Hit</strong>"},
+		},
+		{name: "invalid id", path: "/review/abc", status: http.StatusBadRequest},
+		{name: "crawler error", path: "/review/7", status: http.StatusBadGateway, contains: []string{"Could not load this review"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			body := w.Body.String()
+			for _, want := range tt.contains {
+				if !strings.Contains(body, want) {
+					t.Errorf("expected body to contain %q, got: %s", want, body)
+				}
+			}
+			if tt.status == http.StatusOK && strings.Index(body, "Absatz eins.") > strings.Index(body, "Absatz zwei.") {
+				t.Errorf("paragraphs must keep document order")
+			}
+		})
+	}
+}
+
+func TestCreatePlaylistPagePreviewsBeforeApplying(t *testing.T) {
+	t.Setenv("EASY_AUTH_ENABLED", "false")
+	t.Setenv("PLAYLIST_ID", "test-playlist")
+	t.Setenv("PLAYLIST_ID_PROD", "prod-playlist")
+	t.Setenv("PLAYLIST_PROFILES_FILE", "")
+
+	var calls []string
+	prevRun, prevBand := runCreator, getRecordOfTheWeekBandName
+	runCreator = func(opts creator.Options) (creator.Result, error) {
+		if !opts.DryRun {
+			calls = append(calls, "create "+opts.Profile+" "+opts.Confirm)
+			if opts.Confirm != "plan-1" {
+				return creator.Result{PlaylistID: "prod-playlist", DryRun: true, Stale: true, PlanToken: "plan-2"}, nil
+			}
+			return creator.Result{PlaylistID: "prod-playlist", TotalTracks: 2, FoundTracks: 1}, nil
+		}
+		calls = append(calls, "preview "+opts.Profile)
+		return creator.Result{
+			PlaylistID:        "prod-playlist",
+			DryRun:            true,
+			PlanToken:         "plan-1",
+			TotalTracks:       2,
+			FoundTracks:       1,
+			Planned:           []creator.PlannedTrack{{ID: "t1", Band: "Band", Trackname: "Song", Recordname: "Album"}},
+			ComparedToCurrent: true,
+			Added:             []creator.PlannedTrack{{ID: "t1", Band: "Band", Trackname: "Song", Recordname: "Album"}},
+			Removed:           []spotify.ID{"gone"},
+			NotFound:          []string{"Band - Missing"},
+		}, nil
+	}
+	getRecordOfTheWeekBandName = func() (string, error) { return "", nil }
+	t.Cleanup(func() {
+		runCreator, getRecordOfTheWeekBandName = prevRun, prevBand
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/createPlaylist", createPlaylistPage)
+	r.POST("/createPlaylist", createPlaylistPage)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/createPlaylist?playlist=prod", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("preview status = %d, want 200", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"Nothing has been changed yet",
+		`<form method="post" action="/createPlaylist">`,
+		`name="playlist" value="prod"`,
+		`name="plan" value="plan-1"`,
+		"Apply to production playlist",
+		"Band - Song",
+		"https://open.spotify.com/track/gone",
+		"Band - Missing",
+		"test-playlist-action",
+		"Create test playlist",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("preview page is missing %q", want)
+		}
+	}
+	if strings.Contains(body, "prod-playlist-action") {
+		t.Error("preview of the production playlist must not offer the production button again")
+	}
+
+	post := func(form string) string {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/createPlaylist", strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("apply status = %d, want 200", w.Code)
+		}
+		return w.Body.String()
+	}
+
+	if body := post("playlist=prod&plan=plan-1"); strings.Contains(body, "apply-playlist-action") {
+		t.Error("applied run must not ask for confirmation again")
+	}
+	// the plan changed since the preview: nothing is applied and the new preview is shown
+	if body := post("playlist=prod&plan=stale"); !strings.Contains(body, "stale-preview") || !strings.Contains(body, `name="plan" value="plan-2"`) {
+		t.Errorf("stale confirmation must show the new preview, got: %s", body)
+	}
+	// without the token of a preview there is nothing to confirm
+	post("playlist=prod")
+
+	if want := []string{"preview prod", "create prod plan-1", "create prod stale", "preview prod"}; strings.Join(calls, ",") != strings.Join(want, ",") {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestCreatePlaylistPageRunSummarySections(t *testing.T) {
+	tmpl, err := template.ParseFiles("templates/createPlaylist.tmpl", "templates/utils.tmpl")
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		result  creator.Result
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "no details",
+			result:  creator.Result{PlaylistID: "test-playlist"},
+			notWant: []string{"playlist-details"},
+		},
+		{
+			name:   "preview with name",
+			result: creator.Result{PlaylistID: "test-playlist", DryRun: true, Details: &creator.PlaylistDetails{Name: "KW 42", Description: "Rock & Roll"}},
+			want:   []string{"New description for <strong>KW 42</strong>", "<em>Rock &amp; Roll</em>"},
+		},
+		{
+			name:   "updated",
+			result: creator.Result{PlaylistID: "test-playlist", Details: &creator.PlaylistDetails{Description: "Highlights", Updated: true}},
+			want:   []string{"Updated description: <em>Highlights</em>"},
+		},
+		{
+			name: "rules and skipped records",
+			result: creator.Result{
+				PlaylistID:     "test-playlist",
+				Rules:          creator.Rules{MinScore: 8, ExcludeBands: []string{"Band X"}},
+				SkippedRecords: []creator.SkippedRecord{{Record: "Band C - Third", Reason: "score 6/10 below 8/10"}},
+			},
+			want: []string{"<strong>1</strong> record(s) skipped", "records scored 8/10 or better", "excluding bands: Band X", "Skipped Band C - Third"},
+		},
+		{
+			name:   "full albums",
+			result: creator.Result{PlaylistID: "test-playlist", FullAlbums: []string{"Band A - Great Album", "Band B - Fine Album"}},
+			want:   []string{"Complete tracklist for: <strong>Band A - Great Album</strong>, <strong>Band B - Fine Album</strong>."},
+		},
+		{
+			name: "match picker",
+			result: creator.Result{
+				PlaylistID: "test-playlist",
+				NotFound:   []string{"Band C - Song Live", "Band D - Rare"},
+				Unmatched: []creator.UnmatchedTrack{
+					{Band: "Band C", Trackname: "Song Live", Recordname: "Third Album", Candidates: []creator.Candidate{
+						{ID: "c1", Artists: "Band C", Name: "Song", Album: "Third Album", AlbumType: "album", ReleaseDate: "2024-01-01"},
+						{ID: "c2", Artists: "Band C", Name: "Song (Live)", Album: "Live", AlbumType: "album", ReleaseDate: "2025"},
+					}},
+					{Band: "Band D", Trackname: "Rare", Recordname: "Fourth Album"},
+				},
+			},
+			want: []string{
+				`<form method="post" action="/matchOverride"`,
+				`name="track" value="Song Live"`,
+				`value="c1" checked`,
+				`value="c2">`,
+				"open.spotify.com/track/c2",
+				`value="skip" checked> Leave this track out`,
+			},
+		},
+		{
+			name:    "not found without overrides",
+			result:  creator.Result{PlaylistID: "test-playlist", NotFound: []string{"Band D - Rare"}},
+			want:    []string{"<li>Band D - Rare</li>"},
+			notWant: []string{"/matchOverride"},
+		},
+		{
+			name:   "match cache",
+			result: creator.Result{PlaylistID: "test-playlist", MatchCache: &creator.MatchCacheStats{Storage: "blob", Hits: 5, NegativeHits: 1, Misses: 2, Expired: 1, Stored: 3, Error: "The match cache could not be saved"}},
+			want:   []string{"Match cache (blob): <strong>5</strong> hit(s), 1 of them known misses, <strong>3</strong> searched (1 expired), <strong>3</strong> stored.", "could not be saved"},
+		},
+		{
+			name: "match explanations",
+			result: creator.Result{PlaylistID: "test-playlist", Explanations: []creator.MatchExplanation{
+				{Band: "Band A", Trackname: "Opener", Source: creator.MatchSourceSearch, TrackID: "t1", Confidence: 0.9, Reason: "best score, artist and title match", Attempts: []creator.SearchAttempt{{
+					Query:   "Band A Opener year:2024",
+					Outcome: "accepted Band A - Opener",
+					Candidates: []creator.CandidateScore{
+						{ID: "t1", Artists: "Band A", Name: "Opener", Album: "First Album", AlbumType: "album", Rank: 1, AlbumTypeScore: 100, RankScore: 2, Score: 102, ArtistSimilarity: 1, TrackSimilarity: 0.9, Selected: true, Accepted: true, Reason: "best score, artist and title match"},
+						{ID: "t1-single", Artists: "Band A", Name: "Opener", Album: "Opener", AlbumType: "single", Rank: 2, AlbumTypeScore: 10, RankScore: 1, Score: 11, ArtistSimilarity: 1, TrackSimilarity: 1, Reason: "score 11 is below the best score 102"},
+					},
+				}}},
+				{Band: "Band B", Trackname: "Cached", Source: creator.MatchSourceCache, Reason: "cached miss from 2026-10-18 09:00"},
+				{Band: "Band C", Trackname: "Closer", Source: creator.MatchSourceAlbum, TrackID: "c1", Confidence: 1, Reason: "track 2 of the album Third Album", Album: &creator.AlbumResolution{
+					Queries: []string{"Band C Third Album year:2024"}, ID: "a3", Name: "Third Album", Reason: "best score 1.25, artist and title match",
+				}},
+			}},
+			want: []string{
+				"Match explanations for <strong>3</strong> track(s).",
+				"open.spotify.com/track/t1",
+				"(search, confidence 0.90)",
+				"<code>Band A Opener year:2024</code>: accepted Band A - Opener",
+				`<tr class="selected">`,
+				"rejected: score 11 is below the best score 102",
+				"Band B - Cached: not matched (cache)",
+				"Album <code>Band C Third Album year:2024</code>: <strong>Third Album</strong>, best score 1.25, artist and title match",
+			},
+		},
+		{
+			name: "unavailable tracks",
+			result: creator.Result{PlaylistID: "test-playlist", Unavailable: []string{"Band F - Region Locked"}, Explanations: []creator.MatchExplanation{
+				{Band: "Band F", Trackname: "Region Locked", Source: creator.MatchSourceSearch, Unavailable: true, Reason: "found, but not playable in AT, DE"},
+				{Band: "Band G", Trackname: "Relinked", Source: creator.MatchSourceSearch, TrackID: "g1-at", Confidence: 1, Reason: "best score, artist and title match", Attempts: []creator.SearchAttempt{{
+					Query:      "Band G Relinked",
+					Outcome:    "accepted Band G - Relinked",
+					Candidates: []creator.CandidateScore{{ID: "g1-at", LinkedFrom: "g1", Artists: "Band G", Name: "Relinked", Album: "Album", AlbumType: "album", Rank: 1, Selected: true, Accepted: true}},
+				}}},
+			}},
+			want: []string{
+				"Unavailable: <strong>1</strong> track(s) found on Spotify, but not playable in the configured markets.",
+				"<li>Band F - Region Locked</li>",
+				"Band F - Region Locked: not matched (search, unavailable)",
+				"(Album, album, relinked from g1)",
+			},
+			notWant: []string{"Not found:"},
+		},
+		{
+			name: "learned aliases",
+			result: creator.Result{PlaylistID: "test-playlist", LearnedAliases: []aliases.Alias{{Band: "Кино", Alias: "Kino", Learned: true}}, Explanations: []creator.MatchExplanation{
+				{Band: "Dinosaur", Trackname: "Feel the Pain", Source: creator.MatchSourceSearch, TrackID: "d1", Confidence: 1, Reason: "best score, artist and title match, the artist as Dinosaur Jr.", Attempts: []creator.SearchAttempt{{
+					Query:      "Dinosaur Jr Feel the Pain",
+					Outcome:    "accepted Dinosaur Jr. - Feel the Pain",
+					Candidates: []creator.CandidateScore{{ID: "d1", Artists: "Dinosaur Jr.", Name: "Feel the Pain", Album: "Without a Sound", AlbumType: "album", Rank: 1, ArtistSimilarity: 1, ArtistAlias: "Dinosaur Jr.", Selected: true, Accepted: true}},
+				}}},
+			}},
+			want: []string{
+				"Learned aliases: <strong>Kino</strong> for Кино.",
+				"<td>1.00 as Dinosaur Jr.</td>",
+			},
+		},
+		{
+			name:   "failed searches and throttling",
+			result: creator.Result{PlaylistID: "test-playlist", FailedSearches: []string{"Band E - Busy"}, RateLimit: ratelimit.Stats{Requests: 40, Throttled: 6, Retries: 5, Failed: 1, Limit: 2}},
+			want: []string{
+				"Search failed: <strong>1</strong> track(s), not counted as not found",
+				"<li>Band E - Busy</li>",
+				"Spotify throttled <strong>6</strong> request(s): 5 retried, 1 failed; now 2 request(s) at a time.",
+			},
+			notWant: []string{"Not found:"},
+		},
+		{
+			name: "merged duplicates",
+			result: creator.Result{PlaylistID: "test-playlist", Duplicates: []creator.CollapsedDuplicate{{
+				Kept:    creator.PlannedTrack{ID: "t1", Band: "Band A", Trackname: "Opener", Recordname: "First Album"},
+				Dropped: []creator.PlannedTrack{{ID: "t1-single", Band: "Band A", Trackname: "Opener", Recordname: "Opener EP"}},
+				Reason:  "same recording (ISRC DEA012400001)",
+			}}},
+			want: []string{
+				"Merged duplicates: <strong>1</strong> track(s) kept once.",
+				"Band A - Opener <span class=\"playlist-diff-record\">(First Album)</span>, same recording (ISRC DEA012400001); left out:",
+				"open.spotify.com/track/t1-single",
+			},
+		},
+		{
+			name:   "manual matches",
+			result: creator.Result{PlaylistID: "test-playlist", ManualMatches: 2, SkippedTracks: []string{"Band B - Interlude"}},
+			want:   []string{"<strong>2</strong> track(s) matched by override, left out: <strong>Band B - Interlude</strong>."},
+		},
+		{
+			name:    "error",
+			result:  creator.Result{PlaylistID: "test-playlist", Details: &creator.PlaylistDetails{Error: "The playlist title and description could not be updated"}},
+			want:    []string{"could not be updated"},
+			notWant: []string{"playlist-details"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := tmpl.Execute(&out, map[string]interface{}{"IsAuthenticated": true, "Records": tt.result}); err != nil {
+				t.Fatalf("failed to render template: %v", err)
+			}
+			rendered := out.String()
+			for _, want := range tt.want {
+				if !strings.Contains(rendered, want) {
+					t.Errorf("rendered page is missing %q", want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(rendered, notWant) {
+					t.Errorf("rendered page unexpectedly contains %q", notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestCreatePlaylistPageShowsArchiveSection(t *testing.T) {
+	tmpl, err := template.ParseFiles("templates/createPlaylist.tmpl", "templates/utils.tmpl")
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		result  creator.Result
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "no archive configured",
+			result:  creator.Result{PlaylistID: "test-playlist"},
+			notWant: []string{"archive-summary"},
+		},
+		{
+			name: "applied with rolling window",
+			result: creator.Result{PlaylistID: "test-playlist", Archive: &creator.ArchiveResult{
+				PlaylistID: "archive", Weeks: 8, Added: 3, AlreadyPresent: 1, Trimmed: 2, Total: 40,
+			}},
+			want: []string{"archive-summary", "Added <strong>3</strong> new track(s)", "Keeping the last 8 week(s)", "were removed", "now contains <strong>40</strong>", "open.spotify.com/playlist/archive"},
+		},
+		{
+			name: "preview without window",
+			result: creator.Result{PlaylistID: "test-playlist", DryRun: true, Archive: &creator.ArchiveResult{
+				PlaylistID: "archive", Added: 3, Total: 10,
+			}},
+			want:    []string{"Would add <strong>3</strong>", "would contain <strong>10</strong>"},
+			notWant: []string{"Keeping the last"},
+		},
+		{
+			name: "error",
+			result: creator.Result{PlaylistID: "test-playlist", Archive: &creator.ArchiveResult{
+				PlaylistID: "archive", Error: "The archive playlist could not be updated",
+			}},
+			want:    []string{"The archive playlist could not be updated"},
+			notWant: []string{"now contains"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := tmpl.Execute(&out, map[string]interface{}{"IsAuthenticated": true, "Records": tt.result}); err != nil {
+				t.Fatalf("failed to render template: %v", err)
+			}
+			rendered := out.String()
+			for _, want := range tt.want {
+				if !strings.Contains(rendered, want) {
+					t.Errorf("rendered page is missing %q", want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(rendered, notWant) {
+					t.Errorf("rendered page unexpectedly contains %q", notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestCreatePlaylistPageWeeklyPlaylist(t *testing.T) {
+	t.Setenv("EASY_AUTH_ENABLED", "false")
+	t.Setenv("PLAYLIST_ID", "test-playlist")
+	t.Setenv("PLAYLIST_ID_PROD", "")
+	t.Setenv("PLAYLIST_PROFILES_FILE", "")
+	t.Setenv("WEEKLY_PLAYLISTS_FILE", filepath.Join(t.TempDir(), "weekly.json"))
+
+	var got creator.Options
+	prevRun, prevBand := runCreator, getRecordOfTheWeekBandName
+	runCreator = func(opts creator.Options) (creator.Result, error) {
+		got = opts
+		return creator.Result{DryRun: true, PlaylistName: "Plattentests KW 42/2026"}, nil
+	}
+	getRecordOfTheWeekBandName = func() (string, error) { return "", nil }
+	t.Cleanup(func() {
+		runCreator, getRecordOfTheWeekBandName = prevRun, prevBand
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/createPlaylist", createPlaylistPage)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/createPlaylist?playlist=weekly", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if want := (creator.Options{Profile: "weekly", DryRun: true}); got != want {
+		t.Errorf("creator options = %+v, want %+v", got, want)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"Plattentests KW 42/2026", "(created when applied)", "Apply to dated weekly playlist", "test-playlist-action"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page is missing %q", want)
+		}
+	}
+	for _, notWant := range []string{"weekly-playlist-action", "spotify-embed"} {
+		if strings.Contains(body, notWant) {
+			t.Errorf("page unexpectedly contains %q", notWant)
+		}
+	}
+}
+
+func TestPlaylistPageListsWeeklyPlaylists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "weekly.json")
+	store, err := weekly.Open(path)
+	if err != nil {
+		t.Fatalf("weekly.Open() error: %v", err)
+	}
+	for _, playlist := range []weekly.Playlist{
+		{Week: "2026-W41", Name: "Plattentests KW 41/2026", PlaylistID: "p41"},
+		{Week: "2026-W42", Name: "Plattentests KW 42/2026", PlaylistID: "p42"},
+	} {
+		if err := store.Put(playlist); err != nil {
+			t.Fatalf("Put() error: %v", err)
+		}
+	}
+	t.Setenv("PLAYLIST_ID", "")
+	t.Setenv("PLAYLIST_ID_PROD", "prod-playlist")
+	t.Setenv("PLAYLIST_PROFILES_FILE", "")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/playlist", playlistPage)
+
+	tests := []struct {
+		name    string
+		file    string
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "weekly playlists configured",
+			file: path,
+			want: []string{"embed/playlist/prod-playlist", "open.spotify.com/playlist/p42", "Plattentests KW 41/2026"},
+		},
+		{
+			name:    "weekly playlists disabled",
+			want:    []string{"embed/playlist/prod-playlist"},
+			notWant: []string{"Weekly playlists"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WEEKLY_PLAYLISTS_FILE", tt.file)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/playlist", nil))
+			body := w.Body.String()
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("page is missing %q", want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(body, notWant) {
+					t.Errorf("page unexpectedly contains %q", notWant)
+				}
+			}
+			if tt.file != "" && strings.Index(body, "KW 42/2026") > strings.Index(body, "KW 41/2026") {
+				t.Error("weekly playlists must be listed newest first")
+			}
+		})
+	}
+}
+
+func TestCreatePlaylistPageUnknownProfile(t *testing.T) {
+	t.Setenv("EASY_AUTH_ENABLED", "false")
+	t.Setenv("PLAYLIST_ID", "test-playlist")
+	t.Setenv("PLAYLIST_PROFILES_FILE", "")
+
+	prevRun := runCreator
+	runCreator = func(opts creator.Options) (creator.Result, error) {
+		t.Errorf("creator must not run for an unknown profile, got %+v", opts)
+		return creator.Result{}, nil
+	}
+	t.Cleanup(func() { runCreator = prevRun })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/createPlaylist", createPlaylistPage)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/createPlaylist?playlist=nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", w.Code)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "There is no playlist profile called &#34;nope&#34;.") {
+		t.Errorf("page is missing the unknown profile message, got: %s", body)
+	}
+}
+
+func TestPlaylistPageListsProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	profiles := `{"profiles": [
+		{"name": "prod", "title": "Highlights", "playlistId": "$PLAYLIST_ID_PROD"},
+		{"name": "top", "title": "8+ only", "playlistId": "top-playlist", "rules": {"minScore": 8}},
+		{"name": "weekly", "weekly": true}
+	]}`
+	if err := os.WriteFile(path, []byte(profiles), 0o600); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	t.Setenv("PLAYLIST_ID_PROD", "prod-playlist")
+	t.Setenv("PLAYLIST_PROFILES_FILE", path)
+	t.Setenv("WEEKLY_PLAYLISTS_FILE", "")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/playlist", playlistPage)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/playlist", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		`href="https://open.spotify.com/playlist/prod-playlist" target="_blank" rel="noopener">Highlights</a>`,
+		`href="https://open.spotify.com/playlist/top-playlist"`,
+		"records scored 8/10 or better",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page is missing %q", want)
+		}
+	}
+	if strings.Contains(body, ">weekly</a>") {
+		t.Error("weekly profiles have no fixed playlist to link")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// matchOverrideHandler saves the match picked for a not-found track on /createPlaylist and
+// returns to a fresh preview of the same playlist. A pasted Spotify link wins over the
+// selected candidate.
+func matchOverrideHandler(c *gin.Context) {
+	principal, ok := requireAuthentication(c)
+	if !ok {
+		return
+	}
+
+	band := strings.TrimSpace(c.PostForm("band"))
+	track := strings.TrimSpace(c.PostForm("track"))
+	choice := strings.TrimSpace(c.PostForm("link"))
+	if choice == "" {
+		choice = strings.TrimSpace(c.PostForm("choice"))
+	}
+	if band == "" || track == "" || choice == "" {
+		renderMatchOverrideError(c, "Please pick a candidate, paste a Spotify track link or skip the track.")
+		return
+	}
+
+	if err := saveMatchOverride(band, track, choice, principal); err != nil {
+		log.Printf("failed to save match override for %s - %s: %v", band, track, err)
+		renderMatchOverrideError(c, "The match for "+band+" - "+track+" could not be saved: "+err.Error())
+		return
+	}
+
+	target := "/createPlaylist"
+	if playlist := c.PostForm("playlist"); playlist != "" {
+		target += "?playlist=" + url.QueryEscape(playlist)
+	}
+	c.Redirect(http.StatusSeeOther, target)
+}
+
+func renderMatchOverrideError(c *gin.Context, message string) {
+	tmpl, err := template.ParseFiles("templates/utils.tmpl")
+	if err != nil {
+		log.Fatalf("Error parsing templates: %v", err)
+	}
+	data := commonTemplateData(c)
+	data["ErrorMessage"] = message
+	c.Status(http.StatusBadRequest)
+	if execErr := tmpl.ExecuteTemplate(c.Writer, "ErrorPage", data); execErr != nil {
+		log.Printf("Error executing error template: %v", execErr)
+	}
+}
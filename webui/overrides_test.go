@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMatchOverrideHandler(t *testing.T) {
+	t.Setenv("EASY_AUTH_ENABLED", "false")
+
+	tests := []struct {
+		name         string
+		form         url.Values
+		saveErr      error
+		wantStatus   int
+		wantLocation string
+		wantSaved    string
+	}{
+		{
+			name:         "candidate",
+			form:         url.Values{"playlist": {"prod"}, "band": {"Band C"}, "track": {"Song Live"}, "choice": {"c1"}},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/createPlaylist?playlist=prod",
+			wantSaved:    "Band C|Song Live|c1",
+		},
+		{
+			name:         "pasted link wins",
+			form:         url.Values{"band": {"Band C"}, "track": {"Song Live"}, "choice": {"c1"}, "link": {" https://open.spotify.com/track/abc "}},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/createPlaylist",
+			wantSaved:    "Band C|Song Live|https://open.spotify.com/track/abc",
+		},
+		{
+			name:       "nothing picked",
+			form:       url.Values{"band": {"Band C"}, "track": {"Song Live"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid link",
+			form:       url.Values{"band": {"Band C"}, "track": {"Song Live"}, "link": {"nope"}},
+			saveErr:    errors.New(`"nope" is not a Spotify track ID`),
+			wantStatus: http.StatusBadRequest,
+			wantSaved:  "Band C|Song Live|nope",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := ""
+			prevSave := saveMatchOverride
+			saveMatchOverride = func(band, track, choice, user string) error {
+				saved = band + "|" + track + "|" + choice
+				return tt.saveErr
+			}
+			t.Cleanup(func() { saveMatchOverride = prevSave })
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.POST("/matchOverride", matchOverrideHandler)
+
+			req := httptest.NewRequest("POST", "/matchOverride", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+			if saved != tt.wantSaved {
+				t.Errorf("saved %q, want %q", saved, tt.wantSaved)
+			}
+			if tt.saveErr != nil && !strings.Contains(w.Body.String(), "could not be saved") {
+				t.Errorf("error page is missing the reason, got: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestMatchOverrideHandlerRequiresLogin(t *testing.T) {
+	t.Setenv("EASY_AUTH_ENABLED", "true")
+	prevSave := saveMatchOverride
+	saveMatchOverride = func(band, track, choice, user string) error {
+		t.Error("override must not be saved without login")
+		return nil
+	}
+	t.Cleanup(func() { saveMatchOverride = prevSave })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/matchOverride", matchOverrideHandler)
+
+	req := httptest.NewRequest("POST", "/matchOverride", strings.NewReader("band=B&track=T&choice=skip"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusTemporaryRedirect || !strings.HasPrefix(w.Header().Get("Location"), "/.auth/login/aad") {
+		t.Errorf("status = %d, Location = %q, want a redirect to the login", w.Code, w.Header().Get("Location"))
+	}
+}
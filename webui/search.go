@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/jetzlstorfer/plattentests-go/internal/archive"
+	"github.com/jetzlstorfer/plattentests-go/internal/search"
+)
+
+// Where the results on the search page came from.
+const (
+	searchSourceArchive = "archive"
+	searchSourceLive    = "live"
+)
+
+// searchLive queries suche.php on Plattentests.de; tests replace it.
+var searchLive = crawler.Search
+
+// searchState holds the optional review archive (ARCHIVE_FILE) and the index built from it.
+// Without an archive every search goes to the live site.
+var searchState struct {
+	sync.RWMutex
+	archive *archive.Archive
+	index   *search.Index
+}
+
+// openArchive loads the archive at path and indexes it for /search.
+func openArchive(path string) error {
+	a, err := archive.Open(path)
+	if err != nil {
+		return err
+	}
+	index := search.Build(a.Records())
+
+	searchState.Lock()
+	defer searchState.Unlock()
+	searchState.archive = a
+	searchState.index = index
+	log.Printf("opened review archive %s with %d reviews", path, a.Len())
+	return nil
+}
+
+// archiveRecords stores crawled records in the archive, if one is configured, and
+// rebuilds the search index when reviews were added or changed.
+func archiveRecords(records []crawler.Record) {
+	searchState.RLock()
+	a := searchState.archive
+	searchState.RUnlock()
+	if a == nil || len(records) == 0 {
+		return
+	}
+
+	added, changed, err := a.Add(records, time.Now())
+	if err != nil {
+		log.Printf("failed to archive records: %v", err)
+		return
+	}
+	if added == 0 && changed == 0 {
+		return
+	}
+	index := search.Build(a.Records())
+
+	searchState.Lock()
+	searchState.index = index
+	searchState.Unlock()
+}
+
+// archivedRecords returns every archived record, or nil when no archive is configured.
+func archivedRecords() []crawler.Record {
+	searchState.RLock()
+	a := searchState.archive
+	searchState.RUnlock()
+	if a == nil {
+		return nil
+	}
+	return a.Records()
+}
+
+// archivedEntry returns the archive entry of record, if an archive is configured and has one.
+func archivedEntry(record crawler.Record) (archive.Entry, bool) {
+	searchState.RLock()
+	a := searchState.archive
+	searchState.RUnlock()
+	if a == nil {
+		return archive.Entry{}, false
+	}
+	return a.Entry(record)
+}
+
+func currentSearchIndex() *search.Index {
+	searchState.RLock()
+	defer searchState.RUnlock()
+	return searchState.index
+}
+
+// searchPage answers /search from the local index and falls back to the live site when
+// the archive has no match. ?live=1 skips the index.
+func searchPage(c *gin.Context) {
+	input := strings.TrimSpace(c.Query("q"))
+	forceLive := c.Query("live") == "1"
+
+	tmpl, err := template.ParseFiles("templates/search.tmpl", "templates/utils.tmpl")
+	if err != nil {
+		log.Fatalf("Error parsing search templates: %v", err)
+	}
+
+	data := commonTemplateData(c)
+	data["Query"] = input
+	data["ArchiveSize"] = currentSearchIndex().Len()
+
+	if input != "" {
+		records, source, err := runSearch(input, forceLive)
+		var parseErr *search.ParseError
+		if errors.As(err, &parseErr) {
+			data["QueryError"] = parseErr.Error()
+			c.Status(http.StatusBadRequest)
+		}
+		data["Records"] = records
+		data["Source"] = source
+	}
+
+	if err := tmpl.Execute(c.Writer, data); err != nil {
+		log.Fatalf("Error executing search template: %v", err)
+	}
+}
+
+// runSearch parses input and returns the matching records and where they came from.
+func runSearch(input string, forceLive bool) ([]crawler.Record, string, error) {
+	q, err := search.Parse(input)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if index := currentSearchIndex(); !forceLive && index.Len() > 0 {
+		if records := index.Search(q); len(records) > 0 {
+			return records, searchSourceArchive, nil
+		}
+	}
+
+	liveText := search.LiveText(input)
+	if !q.HasPositiveText() || liveText == "" {
+		return nil, searchSourceArchive, nil
+	}
+
+	found := searchLive(liveText)
+	archiveRecords(found)
+	// Apply the full query to the live hits so field, score and year filters still hold.
+	return search.Build(found).Search(q), searchSourceLive, nil
+}
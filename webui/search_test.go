@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+)
+
+// useTestArchive opens an empty archive in a temp dir and stubs the live search.
+func useTestArchive(t *testing.T, live []crawler.Record) *[]string {
+	t.Helper()
+	prevLive := searchLive
+	searchState.RLock()
+	prevArchive, prevIndex := searchState.archive, searchState.index
+	searchState.RUnlock()
+
+	if err := openArchive(filepath.Join(t.TempDir(), "archive.json")); err != nil {
+		t.Fatalf("openArchive() error: %v", err)
+	}
+	var liveQueries []string
+	searchLive = func(query string) []crawler.Record {
+		liveQueries = append(liveQueries, query)
+		return append([]crawler.Record(nil), live...)
+	}
+	t.Cleanup(func() {
+		searchLive = prevLive
+		searchState.Lock()
+		searchState.archive, searchState.index = prevArchive, prevIndex
+		searchState.Unlock()
+	})
+	return &liveQueries
+}
+
+func getSearch(t *testing.T, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/search", searchPage)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/search?"+query, nil))
+	return w
+}
+
+func TestSearchPageUsesArchiveBeforeLiveSite(t *testing.T) {
+	liveQueries := useTestArchive(t, []crawler.Record{
+		{Band: "Wilco", Recordname: "Cousin", Score: 8, Link: "https://www.plattentests.de/rezi.php?show=20"},
+	})
+	archiveRecords([]crawler.Record{
+		{Band: "Radiohead", Recordname: "Kid A", Score: 10, ReleaseYear: "2000", Link: "https://www.plattentests.de/rezi.php?show=3"},
+		{Band: "Coldplay", Recordname: "Moon Music", Score: 4, ReleaseYear: "2024", Link: "https://www.plattentests.de/rezi.php?show=19"},
+	})
+
+	w := getSearch(t, "q="+url.QueryEscape("band:radiohead score:>=8"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "Kid A") || strings.Contains(body, "Moon Music") {
+		t.Errorf("expected only the archived Radiohead review, got: %s", body)
+	}
+	if !strings.Contains(body, "in the local archive") {
+		t.Errorf("expected the archive to be named as result source")
+	}
+	if len(*liveQueries) != 0 {
+		t.Errorf("archive hit must not query the live site, got %q", *liveQueries)
+	}
+
+	// No archived match: fall back to the live site and remember its results.
+	w = getSearch(t, "q="+url.QueryEscape(`band:wilco -year:1999`))
+	body = w.Body.String()
+	if !strings.Contains(body, "Cousin") || !strings.Contains(body, "live from Plattentests.de") {
+		t.Errorf("expected live fallback result, got: %s", body)
+	}
+	if len(*liveQueries) != 1 || (*liveQueries)[0] != "wilco" {
+		t.Errorf("live queries = %q, want [wilco]", *liveQueries)
+	}
+	if got := currentSearchIndex().Len(); got != 3 {
+		t.Errorf("live results must be archived, index has %d records", got)
+	}
+}
+
+func TestArchiveRecordsReindexesChangedReviews(t *testing.T) {
+	useTestArchive(t, nil)
+	record := crawler.Record{Band: "Radiohead", Recordname: "Kid A", Score: 9, Link: "https://www.plattentests.de/rezi.php?show=3"}
+	archiveRecords([]crawler.Record{record})
+
+	record.Score = 10
+	archiveRecords([]crawler.Record{record})
+
+	body := getSearch(t, "q="+url.QueryEscape("band:radiohead score:10")).Body.String()
+	if !strings.Contains(body, "Kid A") {
+		t.Errorf("a changed review must reach the index, got: %s", body)
+	}
+}
+
+func TestSearchPageForceLive(t *testing.T) {
+	liveQueries := useTestArchive(t, []crawler.Record{{Band: "Radiohead", Recordname: "OK Computer"}})
+	archiveRecords([]crawler.Record{{Band: "Radiohead", Recordname: "Kid A", Link: "https://www.plattentests.de/rezi.php?show=3"}})
+
+	body := getSearch(t, "q=radiohead&live=1").Body.String()
+	if !strings.Contains(body, "OK Computer") || len(*liveQueries) != 1 {
+		t.Errorf("live=1 must bypass the archive, queries %q, body: %s", *liveQueries, body)
+	}
+}
+
+func TestSearchPageInvalidQuery(t *testing.T) {
+	liveQueries := useTestArchive(t, nil)
+
+	w := getSearch(t, "q="+url.QueryEscape("genre:rock"))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "unknown field genre") {
+		t.Errorf("expected parse error in page, got: %s", w.Body.String())
+	}
+	if len(*liveQueries) != 0 {
+		t.Errorf("invalid queries must not reach the live site")
+	}
+}
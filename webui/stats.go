@@ -0,0 +1,86 @@
+package main
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/jetzlstorfer/plattentests-go/internal/stats"
+)
+
+// statsRecords returns the records to compute statistics over: the archive when one is
+// configured, otherwise just the records of the week.
+func statsRecords() ([]crawler.Record, bool, error) {
+	if records := archivedRecords(); len(records) > 0 {
+		return records, true, nil
+	}
+
+	records, err := getRecordsOfTheWeek()
+	if err != nil {
+		return nil, false, err
+	}
+	recordOfTheWeek, err := getRecordOfTheWeekBandName()
+	if err != nil {
+		log.Printf("could not load record of the week for statistics: %v", err)
+		recordOfTheWeek = ""
+	}
+	return orderFeedRecords(records, recordOfTheWeek), false, nil
+}
+
+// statsJSON serves the statistics report as JSON.
+func statsJSON(c *gin.Context) {
+	records, _, err := statsRecords()
+	if err != nil {
+		log.Printf("failed to load records for statistics: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "could not load records"})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, stats.Compute(records))
+}
+
+// statsPage renders score distributions and trends with server-side SVG charts.
+func statsPage(c *gin.Context) {
+	tmpl, err := template.ParseFiles("templates/stats.tmpl", "templates/utils.tmpl")
+	if err != nil {
+		log.Fatalf("Error parsing stats templates: %v", err)
+	}
+
+	data := commonTemplateData(c)
+	records, fromArchive, err := statsRecords()
+	if err != nil {
+		log.Printf("failed to load records for statistics: %v", err)
+		data["ErrorMessage"] = "Could not load records from Plattentests.de. Please try again later."
+		c.Status(http.StatusBadGateway)
+		if execErr := tmpl.ExecuteTemplate(c.Writer, "ErrorPage", data); execErr != nil {
+			log.Printf("Error executing error template: %v", execErr)
+		}
+		return
+	}
+
+	report := stats.Compute(records)
+	data["Report"] = report
+	data["FromArchive"] = fromArchive
+	data["HighScore"] = stats.HighScore
+	// The charts are generated by internal/stats, which escapes every label.
+	data["Charts"] = map[string]template.HTML{
+		"Histogram":       template.HTML(stats.BarChart("Score distribution", stats.HistogramPoints(report.Overall), maxHistogramCount(report.Overall))),
+		"MeanByYear":      template.HTML(stats.LineChart("Mean score per year", stats.MeanPoints(report.Years), 0, stats.MaxScore)),
+		"HighShareByYear": template.HTML(stats.LineChart("Share of high ratings per year in percent", stats.HighSharePoints(report.Years), 0, 100)),
+		"MeanByMonth":     template.HTML(stats.LineChart("Mean score per month", stats.MeanPoints(report.Months), 0, stats.MaxScore)),
+		"RecordOfTheWeek": template.HTML(stats.LineChart("Record of the week scores", stats.TrendPoints(report.RecordOfTheWeek), 0, stats.MaxScore)),
+	}
+
+	if err := tmpl.Execute(c.Writer, data); err != nil {
+		log.Fatalf("Error executing stats template: %v", err)
+	}
+}
+
+func maxHistogramCount(d stats.Distribution) float64 {
+	highest := 1
+	for _, count := range d.Histogram {
+		highest = max(highest, count)
+	}
+	return float64(highest)
+}
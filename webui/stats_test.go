@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	crawler "github.com/jetzlstorfer/plattentests-go/cmd/crawler"
+	"github.com/jetzlstorfer/plattentests-go/internal/stats"
+)
+
+func statsRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/stats", statsPage)
+	r.GET("/stats.json", statsJSON)
+	return r
+}
+
+func TestStatsFromRecordsOfTheWeek(t *testing.T) {
+	useTestArchive(t, nil)
+	stubFeedCrawler(t, []crawler.Record{
+		{Band: "Band A", Score: 9, ReleaseDate: "05.01.2024", Genre: "Indie"},
+		{Band: "Band B", Score: 6, ReleaseDate: "05.01.2024"},
+	}, nil)
+	r := statsRouter()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/stats.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var report stats.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if report.Overall.Count != 2 || report.Overall.HighShare != 0.5 {
+		t.Errorf("Overall = %+v", report.Overall)
+	}
+	if len(report.RecordOfTheWeek) != 1 || report.RecordOfTheWeek[0].Band != "Band B" {
+		t.Errorf("record of the week must be marked, got %+v", report.RecordOfTheWeek)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/stats", nil))
+	body := w.Body.String()
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	for _, want := range []string{`<svg class="chart"`, "Per genre", "Indie", "7.50", "ARCHIVE_FILE"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("stats page is missing %q", want)
+		}
+	}
+	if strings.Contains(body, "&lt;svg") {
+		t.Errorf("charts must not be escaped")
+	}
+}
+
+func TestStatsPrefersArchive(t *testing.T) {
+	useTestArchive(t, nil)
+	stubFeedCrawler(t, nil, errors.New("must not be called"))
+	archiveRecords([]crawler.Record{
+		{Band: "Old", Score: 4, ReleaseDate: "01.06.2010", Link: "https://www.plattentests.de/rezi.php?show=1"},
+	})
+
+	w := httptest.NewRecorder()
+	statsRouter().ServeHTTP(w, httptest.NewRequest("GET", "/stats", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "local archive") || !strings.Contains(w.Body.String(), "2010-06") {
+		t.Errorf("expected statistics over the archive, got: %s", w.Body.String())
+	}
+}
+
+func TestStatsCrawlerError(t *testing.T) {
+	useTestArchive(t, nil)
+	stubFeedCrawler(t, nil, errors.New("boom"))
+
+	for _, path := range []string{"/stats", "/stats.json"} {
+		w := httptest.NewRecorder()
+		statsRouter().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusBadGateway {
+			t.Errorf("%s status = %d, want 502", path, w.Code)
+		}
+	}
+}